@@ -0,0 +1,276 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bbt-t/lets-go-shortener/pkg/client"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// options are command-line flags.
+type options struct {
+	configPath string
+	httpAddr   string
+	grpcAddr   string
+	grpcCA     string
+	grpcCert   string
+	grpcKey    string
+	apiKey     string
+	output     string
+	timeout    time.Duration
+}
+
+// run runs command and saves identity issued by the service.
+func run(ctx context.Context, opts options, command string, args []string) error {
+	if opts.output != "table" && opts.output != "json" {
+		return fmt.Errorf("unknown output format %q", opts.output)
+	}
+
+	cfg, err := loadConfig(opts.configPath)
+	if err != nil {
+		return fmt.Errorf("failed read config: %w", err)
+	}
+
+	c, closeConn, err := newClient(opts, cfg)
+	if err != nil {
+		return err
+	}
+	defer closeConn()
+
+	out := newPrinter(os.Stdout, opts.output)
+
+	switch command {
+	case "shorten":
+		err = shorten(ctx, c, out, args)
+	case "batch":
+		err = batch(ctx, c, out, args)
+	case "resolve":
+		err = resolve(ctx, c, out, args)
+	case "history":
+		err = history(ctx, c, out)
+	case "delete":
+		err = remove(ctx, c, out, args)
+	case "stats":
+		err = stats(ctx, c, out)
+	default:
+		return fmt.Errorf("unknown command %q", command)
+	}
+
+	if identity := c.Identity(); identity != "" && identity != cfg.Identity {
+		cfg.Identity = identity
+		if errSave := saveConfig(opts.configPath, cfg); errSave != nil {
+			return errors.Join(err, fmt.Errorf("failed save identity: %w", errSave))
+		}
+	}
+	return err
+}
+
+// newClient gets client over gRPC if its address is set, otherwise over HTTP.
+func newClient(opts options, cfg ctlConfig) (*client.Client, func(), error) {
+	grpcAddr := firstNonEmpty(opts.grpcAddr, cfg.GrpcAddr)
+	if grpcAddr == "" {
+		httpAddr := firstNonEmpty(opts.httpAddr, cfg.HTTPAddr, defaultHTTPAddr)
+		c := client.New(
+			client.NewHTTPTransport(httpAddr, nil),
+			client.WithIdentity(cfg.Identity),
+			client.WithAPIKey(opts.apiKey),
+		)
+		return c, func() {}, nil
+	}
+
+	creds := insecure.NewCredentials()
+	if opts.grpcCA != "" {
+		tlsCfg, err := newTLSConfig(opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		creds = credentials.NewTLS(tlsCfg)
+	}
+
+	conn, err := grpc.Dial(grpcAddr, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	c := client.New(
+		client.NewGRPCTransport(conn),
+		client.WithIdentity(cfg.Identity),
+		client.WithAPIKey(opts.apiKey),
+	)
+	return c, func() { conn.Close() }, nil
+}
+
+// newTLSConfig gets TLS config for gRPC connection.
+func newTLSConfig(opts options) (*tls.Config, error) {
+	caPEM, err := os.ReadFile(opts.grpcCA)
+	if err != nil {
+		return nil, err
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(caPEM) {
+		return nil, errors.New("failed parse CA bundle")
+	}
+
+	tlsCfg := &tls.Config{RootCAs: roots, MinVersion: tls.VersionTLS12}
+	if opts.grpcCert != "" {
+		cert, err := tls.LoadX509KeyPair(opts.grpcCert, opts.grpcKey)
+		if err != nil {
+			return nil, err
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+	return tlsCfg, nil
+}
+
+// shorten shortens single url.
+func shorten(ctx context.Context, c *client.Client, out *printer, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: shorten <url>")
+	}
+
+	link, err := c.Shorten(ctx, args[0])
+	if err != nil && !errors.Is(err, client.ErrExists) {
+		return err
+	}
+	return out.links([]client.Link{link})
+}
+
+// batch shortens urls from file or stdin.
+func batch(ctx context.Context, c *client.Client, out *printer, args []string) error {
+	var in io.Reader = os.Stdin
+
+	if len(args) > 1 {
+		return errors.New("usage: batch [file]")
+	}
+	if len(args) == 1 && args[0] != "-" {
+		file, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		in = file
+	}
+
+	links, err := readBatch(in)
+	if err != nil {
+		return err
+	}
+	if len(links) == 0 {
+		return errors.New("no urls to shorten")
+	}
+
+	result, err := c.Batch(ctx, links)
+	if err != nil {
+		return err
+	}
+
+	original := make(map[string]string, len(links))
+	for _, link := range links {
+		original[link.CorrelationID] = link.OriginalURL
+	}
+	for i := range result {
+		result[i].OriginalURL = original[result[i].CorrelationID]
+	}
+	return out.links(result)
+}
+
+// readBatch reads JSON array of links or urls one per line.
+func readBatch(in io.Reader) ([]client.Link, error) {
+	var links []client.Link
+
+	data, err := io.ReadAll(in)
+	if err != nil {
+		return nil, err
+	}
+
+	trimmed := strings.TrimSpace(string(data))
+	if strings.HasPrefix(trimmed, "[") {
+		if err = json.Unmarshal([]byte(trimmed), &links); err != nil {
+			return nil, fmt.Errorf("failed parse batch: %w", err)
+		}
+		for i := range links {
+			if links[i].CorrelationID == "" {
+				links[i].CorrelationID = strconv.Itoa(i + 1)
+			}
+		}
+		return links, nil
+	}
+
+	for _, line := range strings.Split(trimmed, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		links = append(links, client.Link{
+			CorrelationID: strconv.Itoa(len(links) + 1),
+			OriginalURL:   line,
+		})
+	}
+	return links, nil
+}
+
+// resolve gets original url.
+func resolve(ctx context.Context, c *client.Client, out *printer, args []string) error {
+	if len(args) != 1 {
+		return errors.New("usage: resolve <id>")
+	}
+
+	original, err := c.Resolve(ctx, args[0])
+	if err != nil {
+		return err
+	}
+	return out.links([]client.Link{{ID: args[0], OriginalURL: original}})
+}
+
+// history lists urls of current user.
+func history(ctx context.Context, c *client.Client, out *printer) error {
+	links, err := c.History(ctx)
+	if err != nil {
+		return err
+	}
+	return out.links(links)
+}
+
+// remove deletes urls of current user.
+func remove(ctx context.Context, c *client.Client, out *printer, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: delete <id> [id...]")
+	}
+
+	if err := c.Delete(ctx, args...); err != nil {
+		return err
+	}
+	return out.deleted(args)
+}
+
+// stats gets service statistic.
+func stats(ctx context.Context, c *client.Client, out *printer) error {
+	stat, err := c.Stats(ctx)
+	if err != nil {
+		return err
+	}
+	return out.stats(stat)
+}
+
+// firstNonEmpty gets first not empty value.
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
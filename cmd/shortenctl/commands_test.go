@@ -0,0 +1,66 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bbt-t/lets-go-shortener/pkg/client"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadBatch(t *testing.T) {
+	tc := []struct {
+		name  string
+		input string
+		want  []client.Link
+	}{
+		{
+			"urls one per line",
+			"https://yandex.ru\n\n# comment\nhttps://google.com\n",
+			[]client.Link{
+				{CorrelationID: "1", OriginalURL: "https://yandex.ru"},
+				{CorrelationID: "2", OriginalURL: "https://google.com"},
+			},
+		},
+		{
+			"JSON array",
+			`[{"correlation_id":"a","original_url":"https://yandex.ru"},{"original_url":"https://google.com"}]`,
+			[]client.Link{
+				{CorrelationID: "a", OriginalURL: "https://yandex.ru"},
+				{CorrelationID: "2", OriginalURL: "https://google.com"},
+			},
+		},
+		{
+			"empty input",
+			"",
+			nil,
+		},
+	}
+
+	for _, test := range tc {
+		links, err := readBatch(strings.NewReader(test.input))
+		assert.NoError(t, err, test.name)
+		assert.Equal(t, test.want, links, test.name)
+	}
+
+	_, err := readBatch(strings.NewReader("[{"))
+	assert.Error(t, err)
+}
+
+func TestConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "shortenctl", "config.json")
+
+	cfg, err := loadConfig(path)
+	require.NoError(t, err)
+	assert.Equal(t, ctlConfig{}, cfg)
+
+	cfg.Identity = "signed-identity"
+	require.NoError(t, saveConfig(path, cfg))
+
+	loaded, err := loadConfig(path)
+	require.NoError(t, err)
+	assert.Equal(t, cfg, loaded)
+}
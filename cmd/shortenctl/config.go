@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+)
+
+// defaultHTTPAddr is used if address isn't set in flags or config.
+const defaultHTTPAddr = "http://127.0.0.1:8080"
+
+// ctlConfig is persisted config of shortenctl.
+type ctlConfig struct {
+	HTTPAddr string `json:"http_addr,omitempty"`
+	GrpcAddr string `json:"grpc_addr,omitempty"`
+	Identity string `json:"identity,omitempty"`
+}
+
+// defaultConfigPath gets path of config in user config dir.
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ".shortenctl.json"
+	}
+	return filepath.Join(dir, "shortenctl", "config.json")
+}
+
+// loadConfig reads config file. Missing file is empty config.
+func loadConfig(path string) (ctlConfig, error) {
+	var cfg ctlConfig
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, err
+	}
+
+	err = json.Unmarshal(data, &cfg)
+	return cfg, err
+}
+
+// saveConfig writes config file readable only by owner, as it contains identity.
+func saveConfig(path string, cfg ctlConfig) error {
+	data, err := json.MarshalIndent(cfg, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
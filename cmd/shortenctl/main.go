@@ -0,0 +1,76 @@
+// Command shortenctl is command-line client for the shortener.
+//
+// Usage:
+//
+//	shortenctl [flags] <command> [args]
+//
+// Commands:
+//
+//	shorten <url>          shorten single url
+//	batch [file]           shorten urls from file or stdin (one per line or JSON array)
+//	resolve <id>           get original url
+//	history                list your urls
+//	delete <id> [id...]    delete your urls
+//	stats                  get service statistic
+//
+// Identity issued by the service is saved to config file and reused by next calls.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+func main() {
+	var opts options
+
+	flag.StringVar(&opts.configPath, "config", defaultConfigPath(), "Config file path")
+	flag.StringVar(&opts.httpAddr, "addr", "", "Shortener HTTP base URL")
+	flag.StringVar(&opts.grpcAddr, "grpc", "", "Shortener gRPC address, used instead of HTTP if set")
+	flag.StringVar(&opts.grpcCA, "grpc-ca", "", "CA bundle to verify gRPC server, enables TLS")
+	flag.StringVar(&opts.grpcCert, "grpc-cert", "", "Client certificate for gRPC mTLS")
+	flag.StringVar(&opts.grpcKey, "grpc-key", "", "Client key for gRPC mTLS")
+	flag.StringVar(&opts.apiKey, "api-key", os.Getenv("SHORTENCTL_API_KEY"), "API key, used instead of saved identity")
+	flag.StringVar(&opts.output, "o", "table", "Output format: table or json")
+	flag.DurationVar(&opts.timeout, "timeout", 10*time.Second, "Request timeout")
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		usage()
+		exitUsage()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), opts.timeout)
+	defer cancel()
+
+	if err := run(ctx, opts, flag.Arg(0), flag.Args()[1:]); err != nil {
+		log.Fatalln(err)
+	}
+}
+
+// usage prints help.
+func usage() {
+	fmt.Fprintf(flag.CommandLine.Output(), `Usage: shortenctl [flags] <command> [args]
+
+Commands:
+  shorten <url>          shorten single url
+  batch [file]           shorten urls from file or stdin (one per line or JSON array)
+  resolve <id>           get original url
+  history                list your urls
+  delete <id> [id...]    delete your urls
+  stats                  get service statistic
+
+Flags:
+`)
+	flag.PrintDefaults()
+}
+
+// exitUsage exits with usage error code.
+func exitUsage() {
+	os.Exit(2)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+
+	"github.com/bbt-t/lets-go-shortener/pkg/client"
+)
+
+// printer prints results as table or JSON.
+type printer struct {
+	w      io.Writer
+	format string
+}
+
+// newPrinter gets new printer.
+func newPrinter(w io.Writer, format string) *printer {
+	return &printer{w: w, format: format}
+}
+
+// links prints links.
+func (p *printer) links(links []client.Link) error {
+	if p.format == "json" {
+		return p.json(links)
+	}
+
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tSHORT URL\tORIGINAL URL\tCORRELATION ID")
+	for _, link := range links {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", link.ID, link.ShortURL, link.OriginalURL, link.CorrelationID)
+	}
+	return tw.Flush()
+}
+
+// deleted prints ids accepted for deletion.
+func (p *printer) deleted(ids []string) error {
+	if p.format == "json" {
+		return p.json(map[string][]string{"deleted": ids})
+	}
+
+	for _, id := range ids {
+		if _, err := fmt.Fprintf(p.w, "%s accepted for deletion\n", id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stats prints statistic.
+func (p *printer) stats(stat client.Statistic) error {
+	if p.format == "json" {
+		return p.json(stat)
+	}
+
+	tw := tabwriter.NewWriter(p.w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "URLS\tUSERS")
+	fmt.Fprintf(tw, "%d\t%d\n", stat.Urls, stat.Users)
+	return tw.Flush()
+}
+
+// json prints value as indented JSON.
+func (p *printer) json(v interface{}) error {
+	enc := json.NewEncoder(p.w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
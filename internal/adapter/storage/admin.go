@@ -0,0 +1,328 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+)
+
+// matchLink checks if link matches filter.
+// Domain matches host of original url and its subdomains, query matches id or part of original url.
+func matchLink(filter entity.LinkFilter, link entity.LinkInfo) bool {
+	if filter.UserID != "" && link.UserID != filter.UserID {
+		return false
+	}
+	if filter.Domain != "" {
+		parsed, err := url.Parse(link.OriginalURL)
+		if err != nil {
+			return false
+		}
+		host, domain := strings.ToLower(parsed.Hostname()), strings.ToLower(filter.Domain)
+		if host != domain && !strings.HasSuffix(host, "."+domain) {
+			return false
+		}
+	}
+	if filter.Query != "" && link.ID != filter.Query &&
+		!strings.Contains(strings.ToLower(link.OriginalURL), strings.ToLower(filter.Query)) {
+		return false
+	}
+	return true
+}
+
+// pageLinks gets page of links by offset and limit of filter.
+func pageLinks(filter entity.LinkFilter, links []entity.LinkInfo) []entity.LinkInfo {
+	if filter.Offset >= len(links) {
+		return []entity.LinkInfo{}
+	}
+	links = links[filter.Offset:]
+	if filter.Limit > 0 && filter.Limit < len(links) {
+		links = links[:filter.Limit]
+	}
+	return links
+}
+
+// sortIDs sorts numeric ids in order of creation.
+func sortIDs(ids []string) {
+	sort.Slice(ids, func(i, j int) bool {
+		a, errA := strconv.Atoi(ids[i])
+		b, errB := strconv.Atoi(ids[j])
+		if errA != nil || errB != nil {
+			return ids[i] < ids[j]
+		}
+		return a < b
+	})
+}
+
+// owners gets owner of every url.
+func (s *MapStorage) owners() map[string]string {
+	owners := make(map[string]string, len(s.Locations))
+	for userID, ids := range s.Users {
+		for _, id := range ids {
+			owners[id] = userID
+		}
+	}
+	return owners
+}
+
+// linkInfo gets link with owner and state.
+func (s *MapStorage) linkInfo(id, owner string) entity.LinkInfo {
+	return entity.LinkInfo{
+		ID:          id,
+		ShortURL:    fmt.Sprintf("%s/%v", s.Cfg.BaseURL, id),
+		OriginalURL: s.Locations[id],
+		UserID:      owner,
+		Deleted:     s.Deleted[id],
+		Disabled:    s.Disabled[id],
+	}
+}
+
+// SearchURLs gets links matching filter.
+func (s *MapStorage) SearchURLs(filter entity.LinkFilter) ([]entity.LinkInfo, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	ids := make([]string, 0, len(s.Locations))
+	for id := range s.Locations {
+		ids = append(ids, id)
+	}
+	sortIDs(ids)
+
+	owners := s.owners()
+	links := make([]entity.LinkInfo, 0)
+	for _, id := range ids {
+		if link := s.linkInfo(id, owners[id]); matchLink(filter, link) {
+			links = append(links, link)
+		}
+	}
+	return pageLinks(filter, links), nil
+}
+
+// GetURLInfo gets link with owner and state.
+func (s *MapStorage) GetURLInfo(id string) (entity.LinkInfo, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.Locations[id]; !ok {
+		return entity.LinkInfo{}, ErrNotFound
+	}
+	return s.linkInfo(id, s.owners()[id]), nil
+}
+
+// SetDisabled disables url for all users or restores it.
+func (s *MapStorage) SetDisabled(id string, disabled bool) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.Locations[id]; !ok {
+		return ErrNotFound
+	}
+	if s.Disabled == nil {
+		s.Disabled = make(map[string]bool)
+	}
+	if disabled {
+		s.Disabled[id] = true
+	} else {
+		delete(s.Disabled, id)
+	}
+	return nil
+}
+
+// ReassignURL changes owner of url.
+func (s *MapStorage) ReassignURL(id, userID string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.Locations[id]; !ok {
+		return ErrNotFound
+	}
+
+	if owner, ok := s.owners()[id]; ok {
+		ids := s.Users[owner]
+		for i := range ids {
+			if ids[i] == id {
+				s.Users[owner] = append(ids[:i:i], ids[i+1:]...)
+				break
+			}
+		}
+		if len(s.Users[owner]) == 0 {
+			delete(s.Users, owner)
+		}
+	}
+	s.Users[userID] = append(s.Users[userID], id)
+	return nil
+}
+
+// linkInfo gets link with owner and state.
+func (s *fileStorage) linkInfo(record fileRecord) entity.LinkInfo {
+	return entity.LinkInfo{
+		ID:          record.ID,
+		ShortURL:    fmt.Sprintf("%s/%v", s.cfg.BaseURL, record.ID),
+		OriginalURL: record.URL,
+		UserID:      record.UserID,
+		Deleted:     record.Deleted,
+		Disabled:    record.Disabled,
+	}
+}
+
+// SearchURLs gets links matching filter.
+func (s *fileStorage) SearchURLs(filter entity.LinkFilter) ([]entity.LinkInfo, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	links := make([]entity.LinkInfo, 0)
+	for _, id := range s.order {
+		if link := s.linkInfo(s.records[id]); matchLink(filter, link) {
+			links = append(links, link)
+		}
+	}
+	return pageLinks(filter, links), nil
+}
+
+// GetURLInfo gets link with owner and state.
+func (s *fileStorage) GetURLInfo(id string) (entity.LinkInfo, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	record, ok := s.records[id]
+	if !ok {
+		return entity.LinkInfo{}, ErrNotFound
+	}
+	return s.linkInfo(record), nil
+}
+
+// SetDisabled disables url for all users or restores it.
+func (s *fileStorage) SetDisabled(id string, disabled bool) error {
+	s.Lock()
+	defer s.Unlock()
+
+	record, ok := s.records[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if record.Disabled == disabled {
+		return nil
+	}
+	record.Disabled = disabled
+	return s.write(record)
+}
+
+// ReassignURL changes owner of url.
+func (s *fileStorage) ReassignURL(id, userID string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	record, ok := s.records[id]
+	if !ok {
+		return ErrNotFound
+	}
+	record.UserID = userID
+	return s.write(record)
+}
+
+// scanLinkInfo scans link from row.
+func (s *dbStorage) scanLinkInfo(row rowScanner) (entity.LinkInfo, error) {
+	var (
+		link     entity.LinkInfo
+		owner    sql.NullString
+		deleted  sql.NullBool
+		disabled sql.NullBool
+	)
+
+	if err := row.Scan(&link.ID, &link.OriginalURL, &owner, &deleted, &disabled); err != nil {
+		return link, err
+	}
+	link.ShortURL = fmt.Sprintf("%s/%v", s.cfg.BaseURL, link.ID)
+	link.UserID, link.Deleted, link.Disabled = owner.String, deleted.Bool, disabled.Bool
+	return link, nil
+}
+
+// SearchURLs gets links matching filter.
+// Query prefilters links by owner and parts of url, exact match is checked by matchLink.
+func (s *dbStorage) SearchURLs(filter entity.LinkFilter) ([]entity.LinkInfo, error) {
+	links := make([]entity.LinkInfo, 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT id, url, cookie, deleted, disabled FROM items
+		WHERE ($1 = '' OR cookie = $1)
+		AND ($2 = '' OR url ILIKE '%' || $2 || '%')
+		AND ($3 = '' OR id = $3 OR url ILIKE '%' || $3 || '%')
+		ORDER BY length(id), id`,
+		filter.UserID, filter.Domain, filter.Query,
+	)
+	if err != nil {
+		return links, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		link, err := s.scanLinkInfo(rows)
+		if err != nil {
+			return links, err
+		}
+		if matchLink(filter, link) {
+			links = append(links, link)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return links, err
+	}
+	return pageLinks(filter, links), nil
+}
+
+// GetURLInfo gets link with owner and state.
+func (s *dbStorage) GetURLInfo(id string) (entity.LinkInfo, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	row := s.db.QueryRowContext(
+		ctx,
+		"SELECT id, url, cookie, deleted, disabled FROM items WHERE id = $1 LIMIT 1",
+		id,
+	)
+	link, err := s.scanLinkInfo(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return link, ErrNotFound
+	}
+	return link, err
+}
+
+// updateItem runs update of one url. Returns ErrNotFound if url doesn't exist.
+func (s *dbStorage) updateItem(query string, args ...interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// SetDisabled disables url for all users or restores it.
+func (s *dbStorage) SetDisabled(id string, disabled bool) error {
+	return s.updateItem("UPDATE items SET disabled = $2 WHERE id = $1", id, disabled)
+}
+
+// ReassignURL changes owner of url.
+func (s *dbStorage) ReassignURL(id, userID string) error {
+	return s.updateItem("UPDATE items SET cookie = $2 WHERE id = $1", id, userID)
+}
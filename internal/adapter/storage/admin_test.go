@@ -0,0 +1,121 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/bbt-t/lets-go-shortener/internal/config"
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAdmin(t *testing.T) {
+	storages, cfg := testStorages(t)
+
+	ids := func(links []entity.LinkInfo) []string {
+		result := make([]string, 0, len(links))
+		for _, link := range links {
+			result = append(result, link.ID)
+		}
+		return result
+	}
+
+	for name, s := range storages {
+		_, err := s.CreateShort("user1", "https://yandex.ru/search", "https://mail.yandex.ru", "https://google.com")
+		require.NoError(t, err, name)
+		_, err = s.CreateShort("user2", "https://notyandex.ru", "https://ya.ru/?q=yandex.ru")
+		require.NoError(t, err, name)
+
+		links, err := s.SearchURLs(entity.LinkFilter{Domain: "yandex.ru"})
+		assert.NoError(t, err, name)
+		assert.Equal(t, []string{"1", "2"}, ids(links), name)
+
+		links, err = s.SearchURLs(entity.LinkFilter{UserID: "user2"})
+		assert.NoError(t, err, name)
+		assert.Equal(t, []string{"4", "5"}, ids(links), name)
+
+		links, err = s.SearchURLs(entity.LinkFilter{Query: "YANDEX"})
+		assert.NoError(t, err, name)
+		assert.Equal(t, []string{"1", "2", "4", "5"}, ids(links), name)
+
+		links, err = s.SearchURLs(entity.LinkFilter{Limit: 2, Offset: 3})
+		assert.NoError(t, err, name)
+		assert.Equal(t, []string{"4", "5"}, ids(links), name)
+
+		links, err = s.SearchURLs(entity.LinkFilter{Offset: 10})
+		assert.NoError(t, err, name)
+		assert.Empty(t, links, name)
+
+		link, err := s.GetURLInfo("3")
+		assert.NoError(t, err, name)
+		assert.Equal(t, entity.LinkInfo{
+			ID:          "3",
+			ShortURL:    cfg.BaseURL + "/3",
+			OriginalURL: "https://google.com",
+			UserID:      "user1",
+		}, link, name)
+
+		_, err = s.GetURLInfo("100")
+		assert.Equal(t, ErrNotFound, err, name)
+
+		require.NoError(t, s.SetDisabled("3", true), name)
+		_, err = s.GetOriginal("3")
+		assert.Equal(t, ErrDeleted, err, name)
+		require.NoError(t, s.SetDisabled("3", false), name)
+		original, err := s.GetOriginal("3")
+		assert.NoError(t, err, name)
+		assert.Equal(t, "https://google.com", original, name)
+		assert.Equal(t, ErrNotFound, s.SetDisabled("100", true), name)
+
+		require.NoError(t, s.ReassignURL("3", "user2"), name)
+		history, err := s.GetURLArrayByUser("user1", entity.HistoryFilter{})
+		assert.NoError(t, err, name)
+		assert.Len(t, history, 2, name)
+		history, err = s.GetURLArrayByUser("user2", entity.HistoryFilter{})
+		assert.NoError(t, err, name)
+		assert.Len(t, history, 3, name)
+		assert.Equal(t, ErrNotFound, s.ReassignURL("100", "user2"), name)
+	}
+
+	// file storage replays changes after restart.
+	require.NoError(t, storages["file"].SetDisabled("1", true))
+	restarted, err := newFileStorage(cfg)
+	require.NoError(t, err)
+	link, err := restarted.GetURLInfo("1")
+	assert.NoError(t, err)
+	assert.True(t, link.Disabled)
+	link, err = restarted.GetURLInfo("3")
+	assert.NoError(t, err)
+	assert.Equal(t, "user2", link.UserID)
+	history, err := restarted.GetURLArrayByUser("user2", entity.HistoryFilter{})
+	assert.NoError(t, err)
+	assert.Len(t, history, 3)
+	stat, err := restarted.GetStatistic()
+	assert.NoError(t, err)
+	assert.Equal(t, entity.Statistic{Urls: 5, Users: 2}, stat)
+}
+
+func TestFileStorage_LegacyFormat(t *testing.T) {
+	cfg := config.GetTestConfig()
+	cfg.StoragePath = filepath.Join(t.TempDir(), "file_storage.db")
+	require.NoError(t, os.WriteFile(cfg.StoragePath, []byte("https://yandex.ru\nhttps://google.com\n"), 0600))
+
+	s, err := newFileStorage(cfg)
+	require.NoError(t, err)
+
+	original, err := s.GetOriginal("2")
+	assert.NoError(t, err)
+	assert.Equal(t, "https://google.com", original)
+
+	ids, err := s.CreateShort("user1", "https://ya.ru")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"3"}, ids)
+
+	require.NoError(t, s.ReassignURL("1", "user1"))
+	history, err := s.GetURLArrayByUser("user1", entity.HistoryFilter{})
+	assert.NoError(t, err)
+	assert.Len(t, history, 2)
+}
@@ -0,0 +1,268 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+)
+
+// AddAPIKey saves new API key.
+func (s *MapStorage) AddAPIKey(key entity.APIKey) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.APIKeys == nil {
+		s.APIKeys = make(map[string]entity.APIKey)
+	}
+	if _, ok := s.APIKeys[key.ID]; ok {
+		return ErrExists
+	}
+	s.APIKeys[key.ID] = key
+	return nil
+}
+
+// GetAPIKey gets API key by id.
+func (s *MapStorage) GetAPIKey(id string) (entity.APIKey, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	key, ok := s.APIKeys[id]
+	if !ok {
+		return entity.APIKey{}, ErrNotFound
+	}
+	return key, nil
+}
+
+// GetAPIKeysByUser gets all API keys of user.
+func (s *MapStorage) GetAPIKeysByUser(userID string) ([]entity.APIKey, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	return keysByUser(s.APIKeys, userID), nil
+}
+
+// RevokeAPIKey revokes API key of user.
+func (s *MapStorage) RevokeAPIKey(userID, id string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	return revokeKey(s.APIKeys, userID, id)
+}
+
+// keysPath gets path of file with API keys.
+func (s *fileStorage) keysPath() string {
+	return s.cfg.StoragePath + ".keys"
+}
+
+// fileAPIKey is API key record in file, it keeps fields hidden from API responses.
+type fileAPIKey struct {
+	entity.APIKey
+	UserID string `json:"user_id"`
+	Hash   string `json:"hash"`
+}
+
+// loadKeys reads API keys from file.
+func (s *fileStorage) loadKeys() error {
+	records := make(map[string]fileAPIKey)
+	if err := loadJSONFile(s.keysPath(), &records); err != nil {
+		return err
+	}
+	for id, record := range records {
+		key := record.APIKey
+		key.UserID, key.Hash = record.UserID, record.Hash
+		s.keys[id] = key
+	}
+	return nil
+}
+
+// saveKeys writes API keys to file.
+func (s *fileStorage) saveKeys() error {
+	records := make(map[string]fileAPIKey, len(s.keys))
+	for id, key := range s.keys {
+		records[id] = fileAPIKey{APIKey: key, UserID: key.UserID, Hash: key.Hash}
+	}
+	return saveJSONFile(s.keysPath(), records)
+}
+
+// AddAPIKey saves new API key.
+func (s *fileStorage) AddAPIKey(key entity.APIKey) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.keys[key.ID]; ok {
+		return ErrExists
+	}
+	s.keys[key.ID] = key
+
+	if err := s.saveKeys(); err != nil {
+		delete(s.keys, key.ID)
+		return err
+	}
+	return nil
+}
+
+// GetAPIKey gets API key by id.
+func (s *fileStorage) GetAPIKey(id string) (entity.APIKey, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	key, ok := s.keys[id]
+	if !ok {
+		return entity.APIKey{}, ErrNotFound
+	}
+	return key, nil
+}
+
+// GetAPIKeysByUser gets all API keys of user.
+func (s *fileStorage) GetAPIKeysByUser(userID string) ([]entity.APIKey, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	return keysByUser(s.keys, userID), nil
+}
+
+// RevokeAPIKey revokes API key of user.
+func (s *fileStorage) RevokeAPIKey(userID, id string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if err := revokeKey(s.keys, userID, id); err != nil {
+		return err
+	}
+	return s.saveKeys()
+}
+
+// AddAPIKey saves new API key.
+func (s *dbStorage) AddAPIKey(key entity.APIKey) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	_, err := s.db.ExecContext(
+		ctx,
+		"INSERT INTO api_keys (id, hash, user_id, name, scopes, created_at) VALUES ($1, $2, $3, $4, $5, $6)",
+		key.ID, key.Hash, key.UserID, key.Name, strings.Join(key.Scopes, ","), key.CreatedAt,
+	)
+	return err
+}
+
+// GetAPIKey gets API key by id.
+func (s *dbStorage) GetAPIKey(id string) (entity.APIKey, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	row := s.db.QueryRowContext(
+		ctx,
+		"SELECT id, hash, user_id, name, scopes, created_at, revoked FROM api_keys WHERE id = $1",
+		id,
+	)
+	key, err := scanAPIKey(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return key, ErrNotFound
+	}
+	return key, err
+}
+
+// GetAPIKeysByUser gets all API keys of user.
+func (s *dbStorage) GetAPIKeysByUser(userID string) ([]entity.APIKey, error) {
+	keys := make([]entity.APIKey, 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		"SELECT id, hash, user_id, name, scopes, created_at, revoked FROM api_keys WHERE user_id = $1 ORDER BY created_at",
+		userID,
+	)
+	if err != nil {
+		return keys, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return keys, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// RevokeAPIKey revokes API key of user.
+func (s *dbStorage) RevokeAPIKey(userID, id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	res, err := s.db.ExecContext(
+		ctx,
+		"UPDATE api_keys SET revoked = true WHERE id = $1 AND user_id = $2",
+		id, userID,
+	)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// rowScanner is *sql.Row or *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanAPIKey scans API key from row.
+func scanAPIKey(row rowScanner) (entity.APIKey, error) {
+	var (
+		key    entity.APIKey
+		name   sql.NullString
+		scopes sql.NullString
+	)
+
+	err := row.Scan(&key.ID, &key.Hash, &key.UserID, &name, &scopes, &key.CreatedAt, &key.Revoked)
+	if err != nil {
+		return key, err
+	}
+
+	key.Name = name.String
+	if scopes.String != "" {
+		key.Scopes = strings.Split(scopes.String, ",")
+	}
+	return key, nil
+}
+
+// keysByUser gets keys of user sorted by creation time.
+func keysByUser(keys map[string]entity.APIKey, userID string) []entity.APIKey {
+	result := make([]entity.APIKey, 0)
+	for _, key := range keys {
+		if key.UserID == userID {
+			result = append(result, key)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+	return result
+}
+
+// revokeKey marks key of user as revoked.
+func revokeKey(keys map[string]entity.APIKey, userID, id string) error {
+	key, ok := keys[id]
+	if !ok || key.UserID != userID {
+		return ErrNotFound
+	}
+	key.Revoked = true
+	keys[id] = key
+	return nil
+}
@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeys(t *testing.T) {
+	storages, cfg := testStorages(t)
+
+	for name, s := range storages {
+		first := entity.APIKey{
+			ID:        "key1",
+			UserID:    "user1",
+			Name:      "backend",
+			Scopes:    []string{entity.ScopeRead},
+			Hash:      "hash1",
+			CreatedAt: time.Unix(100, 0).UTC(),
+		}
+		second := first
+		second.ID, second.Hash, second.CreatedAt = "key2", "hash2", time.Unix(200, 0).UTC()
+
+		require.NoError(t, s.AddAPIKey(second), name)
+		require.NoError(t, s.AddAPIKey(first), name)
+		assert.Equal(t, ErrExists, s.AddAPIKey(first), name)
+
+		key, err := s.GetAPIKey("key1")
+		assert.NoError(t, err, name)
+		assert.Equal(t, first, key, name)
+
+		_, err = s.GetAPIKey("unknown")
+		assert.Equal(t, ErrNotFound, err, name)
+
+		keys, err := s.GetAPIKeysByUser("user1")
+		assert.NoError(t, err, name)
+		assert.Equal(t, []entity.APIKey{first, second}, keys, name)
+
+		keys, err = s.GetAPIKeysByUser("user2")
+		assert.NoError(t, err, name)
+		assert.Empty(t, keys, name)
+
+		assert.Equal(t, ErrNotFound, s.RevokeAPIKey("user2", "key1"), name)
+		assert.NoError(t, s.RevokeAPIKey("user1", "key1"), name)
+
+		key, err = s.GetAPIKey("key1")
+		assert.NoError(t, err, name)
+		assert.True(t, key.Revoked, name)
+	}
+
+	// file storage keeps keys after restart.
+	restarted, err := newFileStorage(cfg)
+	require.NoError(t, err)
+	key, err := restarted.GetAPIKey("key1")
+	assert.NoError(t, err)
+	assert.True(t, key.Revoked)
+	assert.Equal(t, "user1", key.UserID)
+	assert.Equal(t, "hash1", key.Hash)
+}
@@ -0,0 +1,219 @@
+package storage
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/bbt-t/lets-go-shortener/internal/config"
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+)
+
+// defaultAuditLogPath is audit file of storage which isn't kept in file or db.
+const defaultAuditLogPath = "audit.jsonl"
+
+// AuditLog is an interface that describes storage of audit events.
+type AuditLog interface {
+	AddAuditEvent(event entity.AuditEvent) error
+	GetAuditEvents(filter entity.AuditFilter) ([]entity.AuditEvent, error)
+}
+
+// NewAuditLog creates audit log based on config.
+// Events are kept in AuditLogPath file if it's set, otherwise next to storage:
+// in table of db storage, in JSONL file of file storage or in defaultAuditLogPath file for map storage.
+func NewAuditLog(cfg config.Config, s Repository) (AuditLog, error) {
+	if cfg.AuditLogPath != "" {
+		return newFileAuditLog(cfg.AuditLogPath)
+	}
+	if db, ok := s.(*dbStorage); ok {
+		return db, nil
+	}
+	if cfg.StoragePath != "" {
+		return newFileAuditLog(cfg.StoragePath + ".audit")
+	}
+	return newFileAuditLog(defaultAuditLogPath)
+}
+
+// matchAuditEvent checks if event matches filter.
+func matchAuditEvent(filter entity.AuditFilter, event entity.AuditEvent) bool {
+	switch {
+	case filter.UserID != "" && event.UserID != filter.UserID:
+		return false
+	case filter.IP != "" && event.IP != filter.IP:
+		return false
+	case filter.Action != "" && event.Action != filter.Action:
+		return false
+	case filter.Target != "" && event.Target != filter.Target:
+		return false
+	case !filter.Since.IsZero() && event.Time.Before(filter.Since):
+		return false
+	case !filter.Until.IsZero() && !event.Time.Before(filter.Until):
+		return false
+	}
+	return true
+}
+
+// fileAuditLog is audit log in JSONL file, one event per line. Events are kept in memory too.
+type fileAuditLog struct {
+	file   *os.File
+	events []entity.AuditEvent
+	sync.Mutex
+}
+
+// newFileAuditLog creates audit log in file.
+// Last line without line break is left by interrupted write, it's cut off if it isn't whole event.
+func newFileAuditLog(path string) (*fileAuditLog, error) {
+	if path == "" {
+		return nil, errors.New("empty audit log path")
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, err
+	}
+	l := &fileAuditLog{file: file}
+
+	var size int64
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) == 0 && err == io.EOF {
+			return l, nil
+		}
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+
+		var event entity.AuditEvent
+		errEvent := json.Unmarshal(line, &event)
+		switch {
+		case err == io.EOF && errEvent != nil:
+			return l, file.Truncate(size)
+		case err == io.EOF:
+			_, err = file.Write([]byte("\n"))
+			l.events = append(l.events, event)
+			return l, err
+		case errEvent != nil:
+			return nil, fmt.Errorf("wrong audit event on line %d: %w", len(l.events)+1, errEvent)
+		}
+		size += int64(len(line))
+		l.events = append(l.events, event)
+	}
+}
+
+// AddAuditEvent saves event, id is given in order of events.
+func (l *fileAuditLog) AddAuditEvent(event entity.AuditEvent) error {
+	l.Lock()
+	defer l.Unlock()
+
+	event.ID = int64(len(l.events) + 1)
+	line, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if _, err = l.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	l.events = append(l.events, event)
+	return nil
+}
+
+// GetAuditEvents gets events matching filter, newest first.
+func (l *fileAuditLog) GetAuditEvents(filter entity.AuditFilter) ([]entity.AuditEvent, error) {
+	l.Lock()
+	defer l.Unlock()
+
+	events := make([]entity.AuditEvent, 0)
+	skipped := 0
+	for i := len(l.events) - 1; i >= 0; i-- {
+		if filter.Limit > 0 && len(events) == filter.Limit {
+			break
+		}
+		if !matchAuditEvent(filter, l.events[i]) {
+			continue
+		}
+		if skipped < filter.Offset {
+			skipped++
+			continue
+		}
+		events = append(events, l.events[i])
+	}
+	return events, nil
+}
+
+// AddAuditEvent saves event to audit_log table.
+func (s *dbStorage) AddAuditEvent(event entity.AuditEvent) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	_, err := s.db.ExecContext(
+		ctx,
+		"INSERT INTO audit_log (created_at, user_id, ip, action, target, before, after) VALUES ($1, $2, $3, $4, $5, $6, $7)",
+		event.Time, event.UserID, event.IP, event.Action, event.Target, nullJSON(event.Before), nullJSON(event.After),
+	)
+	return err
+}
+
+// GetAuditEvents gets events matching filter, newest first.
+func (s *dbStorage) GetAuditEvents(filter entity.AuditFilter) ([]entity.AuditEvent, error) {
+	events := make([]entity.AuditEvent, 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var limit sql.NullInt64
+	if filter.Limit > 0 {
+		limit = sql.NullInt64{Int64: int64(filter.Limit), Valid: true}
+	}
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT id, created_at, user_id, ip, action, target, before, after FROM audit_log
+		WHERE ($1 = '' OR user_id = $1)
+		AND ($2 = '' OR ip = $2)
+		AND ($3 = '' OR action = $3)
+		AND ($4 = '' OR target = $4)
+		AND ($5::timestamp IS NULL OR created_at >= $5)
+		AND ($6::timestamp IS NULL OR created_at < $6)
+		ORDER BY id DESC LIMIT $7 OFFSET $8`,
+		filter.UserID, filter.IP, filter.Action, filter.Target,
+		nullTime(filter.Since), nullTime(filter.Until), limit, filter.Offset,
+	)
+	if err != nil {
+		return events, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			event         entity.AuditEvent
+			before, after []byte
+		)
+		err = rows.Scan(&event.ID, &event.Time, &event.UserID, &event.IP, &event.Action, &event.Target, &before, &after)
+		if err != nil {
+			return events, err
+		}
+		event.Before, event.After = before, after
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+// nullJSON gets NULL for empty JSON.
+func nullJSON(data json.RawMessage) interface{} {
+	if len(data) == 0 {
+		return nil
+	}
+	return string(data)
+}
+
+// nullTime gets NULL for zero time.
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
@@ -0,0 +1,146 @@
+package storage
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bbt-t/lets-go-shortener/internal/config"
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewAuditLog(t *testing.T) {
+	dir := t.TempDir()
+
+	wd, err := os.Getwd()
+	require.NoError(t, err)
+	require.NoError(t, os.Chdir(dir))
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	cfg := config.GetDefaultConfig()
+	s, err := NewMapStorage(cfg)
+	require.NoError(t, err)
+	auditLog, err := NewAuditLog(cfg, s)
+	require.NoError(t, err)
+	require.NoError(t, auditLog.AddAuditEvent(entity.AuditEvent{Action: "link.create"}))
+	_, err = os.Stat(filepath.Join(dir, defaultAuditLogPath))
+	assert.NoError(t, err, "map storage keeps audit log in default file")
+
+	cfg.StoragePath = filepath.Join(dir, "file_storage.db")
+	auditLog, err = NewAuditLog(cfg, s)
+	require.NoError(t, err)
+	require.NoError(t, auditLog.AddAuditEvent(entity.AuditEvent{Action: "link.create"}))
+	_, err = os.Stat(cfg.StoragePath + ".audit")
+	assert.NoError(t, err)
+
+	cfg.AuditLogPath = filepath.Join(dir, "audit.jsonl")
+	auditLog, err = NewAuditLog(cfg, s)
+	require.NoError(t, err)
+	require.NoError(t, auditLog.AddAuditEvent(entity.AuditEvent{Action: "link.create"}))
+	_, err = os.Stat(cfg.AuditLogPath)
+	assert.NoError(t, err)
+}
+
+func TestFileAuditLog(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	auditLog, err := newFileAuditLog(path)
+	require.NoError(t, err)
+
+	start := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	events := []entity.AuditEvent{
+		{Time: start, Actor: entity.Actor{UserID: "user1", IP: "10.0.0.1"}, Action: "link.create", Target: "1", After: json.RawMessage(`{"id":"1"}`)},
+		{Time: start.Add(time.Minute), Actor: entity.Actor{UserID: "user2", IP: "10.0.0.2"}, Action: "link.create", Target: "2"},
+		{Time: start.Add(2 * time.Minute), Actor: entity.Actor{UserID: "user1", IP: "10.0.0.1"}, Action: "link.delete", Target: "1"},
+		{Time: start.Add(3 * time.Minute), Actor: entity.Actor{IP: "10.0.0.3"}, Action: "link.disable", Target: "2"},
+	}
+	for _, event := range events {
+		require.NoError(t, auditLog.AddAuditEvent(event))
+	}
+
+	ids := func(events []entity.AuditEvent) []int64 {
+		result := make([]int64, 0, len(events))
+		for _, event := range events {
+			result = append(result, event.ID)
+		}
+		return result
+	}
+
+	tests := []struct {
+		name   string
+		filter entity.AuditFilter
+		want   []int64
+	}{
+		{name: "all newest first", filter: entity.AuditFilter{}, want: []int64{4, 3, 2, 1}},
+		{name: "by user", filter: entity.AuditFilter{UserID: "user1"}, want: []int64{3, 1}},
+		{name: "by ip", filter: entity.AuditFilter{IP: "10.0.0.3"}, want: []int64{4}},
+		{name: "by action", filter: entity.AuditFilter{Action: "link.create"}, want: []int64{2, 1}},
+		{name: "by target", filter: entity.AuditFilter{Target: "2"}, want: []int64{4, 2}},
+		{name: "by time", filter: entity.AuditFilter{Since: start.Add(time.Minute), Until: start.Add(3 * time.Minute)}, want: []int64{3, 2}},
+		{name: "page", filter: entity.AuditFilter{Limit: 2, Offset: 1}, want: []int64{3, 2}},
+		{name: "page of filtered", filter: entity.AuditFilter{Action: "link.create", Offset: 1}, want: []int64{1}},
+		{name: "after last page", filter: entity.AuditFilter{Offset: 10}, want: []int64{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := auditLog.GetAuditEvents(tt.filter)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, ids(got))
+		})
+	}
+
+	// events are read back after restart.
+	restarted, err := newFileAuditLog(path)
+	require.NoError(t, err)
+	got, err := restarted.GetAuditEvents(entity.AuditFilter{Target: "1", Action: "link.create"})
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, "user1", got[0].UserID)
+	assert.Equal(t, "10.0.0.1", got[0].IP)
+	assert.True(t, start.Equal(got[0].Time))
+	assert.JSONEq(t, `{"id":"1"}`, string(got[0].After))
+
+	require.NoError(t, restarted.AddAuditEvent(entity.AuditEvent{Action: "key.create"}))
+	got, err = restarted.GetAuditEvents(entity.AuditFilter{Limit: 1})
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), got[0].ID)
+
+	_, err = newFileAuditLog("")
+	assert.Error(t, err)
+}
+
+func TestFileAuditLog_Truncated(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+	require.NoError(t, os.WriteFile(path, []byte(`{"id":1,"action":"link.create"}`+"\n"+`{"id":2,"act`), 0600))
+
+	auditLog, err := newFileAuditLog(path)
+	require.NoError(t, err, "truncated last line is skipped")
+	require.NoError(t, auditLog.AddAuditEvent(entity.AuditEvent{Action: "link.delete"}))
+
+	restarted, err := newFileAuditLog(path)
+	require.NoError(t, err)
+	got, err := restarted.GetAuditEvents(entity.AuditFilter{})
+	require.NoError(t, err)
+	require.Len(t, got, 2)
+	assert.Equal(t, "link.delete", got[0].Action)
+	assert.Equal(t, int64(2), got[0].ID)
+
+	// whole event without line break is kept.
+	require.NoError(t, os.WriteFile(path, []byte(`{"id":1,"action":"link.create"}`), 0600))
+	auditLog, err = newFileAuditLog(path)
+	require.NoError(t, err)
+	require.NoError(t, auditLog.AddAuditEvent(entity.AuditEvent{Action: "link.delete"}))
+	restarted, err = newFileAuditLog(path)
+	require.NoError(t, err)
+	got, err = restarted.GetAuditEvents(entity.AuditFilter{})
+	require.NoError(t, err)
+	assert.Len(t, got, 2)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"id":1,"act`+"\n"+`{"id":2,"action":"link.create"}`+"\n"), 0600))
+	_, err = newFileAuditLog(path)
+	assert.Error(t, err, "broken line in the middle isn't skipped")
+}
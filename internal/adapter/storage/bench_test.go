@@ -7,6 +7,7 @@ import (
 	"testing"
 
 	"github.com/bbt-t/lets-go-shortener/internal/config"
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
 )
 
 func BenchmarkDBStorage(b *testing.B) {
@@ -45,11 +46,16 @@ func BenchmarkDBStorage(b *testing.B) {
 		}
 	})
 
+	stat, err := s.GetStatistic()
+	if err != nil {
+		log.Fatalln("Failed get statistic: ", err)
+	}
+
 	b.Run("Get original urls", func(b *testing.B) {
 
 		for i := 0; i < b.N; i++ {
 			b.StopTimer()
-			id := fmt.Sprint(rand.Intn(s.lastID))
+			id := fmt.Sprint(rand.Intn(stat.Urls))
 			b.StartTimer()
 
 			if _, err := s.GetOriginal(id); err != nil {
@@ -62,7 +68,7 @@ func BenchmarkDBStorage(b *testing.B) {
 
 		for i := 0; i < b.N; i++ {
 			b.StopTimer()
-			id := fmt.Sprint(rand.Intn(s.lastID))
+			id := fmt.Sprint(rand.Intn(stat.Urls))
 			userID := fmt.Sprint(rand.Intn(200))
 			b.StartTimer()
 
@@ -79,7 +85,7 @@ func BenchmarkDBStorage(b *testing.B) {
 			userID := fmt.Sprint(rand.Intn(200))
 			b.StartTimer()
 
-			if _, err := s.GetURLArrayByUser(userID); err != nil {
+			if _, err := s.GetURLArrayByUser(userID, entity.HistoryFilter{}); err != nil {
 				log.Println(err)
 			}
 		}
@@ -147,7 +153,7 @@ func BenchmarkMapStorage(b *testing.B) {
 			userID := fmt.Sprint(rand.Intn(200))
 			b.StartTimer()
 
-			if _, err := s.GetURLArrayByUser(userID); err != nil {
+			if _, err := s.GetURLArrayByUser(userID, entity.HistoryFilter{}); err != nil {
 				log.Println(err)
 			}
 		}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"database/sql"
 	"errors"
-	"fmt"
 	"time"
 
 	"github.com/bbt-t/lets-go-shortener/internal/entity"
@@ -34,7 +33,7 @@ func (s *dbStorage) CreateShort(userID string, urls ...string) ([]string, error)
 
 	stmt, err := tx.PrepareContext(
 		ctx,
-		"INSERT INTO items (id, url, cookie) VALUES ($1, $2, $3)",
+		"INSERT INTO items (id, url, cookie, created_at) VALUES (nextval('items_id_seq')::TEXT, $1, $2, $3) RETURNING id",
 	)
 	if err != nil {
 		return result, err
@@ -44,10 +43,10 @@ func (s *dbStorage) CreateShort(userID string, urls ...string) ([]string, error)
 	for _, url := range urls {
 		var isAdded bool
 
-		rows, err := s.db.QueryContext(
+		rows, err := tx.QueryContext(
 			ctx,
-			"SELECT id FROM items WHERE url = $1 LIMIT 1",
-			url,
+			"SELECT id FROM items WHERE url = $1 AND cookie = $2 AND password_hash = '' AND clicks_left IS NULL LIMIT 1",
+			url, userID,
 		)
 		if err != nil {
 			return result, err
@@ -66,9 +65,8 @@ func (s *dbStorage) CreateShort(userID string, urls ...string) ([]string, error)
 			return result, err
 		}
 		if !isAdded {
-			s.lastID++
-			newID := fmt.Sprint(s.lastID)
-			if _, err := stmt.ExecContext(ctx, newID, url, userID); err != nil {
+			var newID string
+			if err := stmt.QueryRowContext(ctx, url, userID, time.Now().UTC()).Scan(&newID); err != nil {
 				return result, err
 			}
 			result = append(result, newID)
@@ -83,21 +81,50 @@ func (s *dbStorage) CreateShort(userID string, urls ...string) ([]string, error)
 	return result, isErr409
 }
 
+// GetUserURLIDs gets ids of links of user by their original urls, urls are matched like by CreateShort.
+func (s *dbStorage) GetUserURLIDs(userID string, urls ...string) (map[string]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT DISTINCT ON (url) url, id FROM items
+		WHERE cookie = $1 AND url = ANY($2) AND password_hash = '' AND clicks_left IS NULL
+		ORDER BY url, created_at, id`,
+		userID, urls,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make(map[string]string)
+	for rows.Next() {
+		var original, id string
+		if err = rows.Scan(&original, &id); err != nil {
+			return nil, err
+		}
+		result[original] = id
+	}
+	return result, rows.Err()
+}
+
 // GetOriginal gets original url from short.
 func (s *dbStorage) GetOriginal(id string) (string, error) {
 	var (
-		original string
-		deleted  bool
+		original  string
+		deleted   bool
+		exhausted bool
 	)
 	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
 	defer cancel()
 
 	row := s.db.QueryRowContext(
 		ctx,
-		"SELECT url, deleted FROM items WHERE id=$1 LIMIT 1",
+		"SELECT url, deleted OR disabled, COALESCE(clicks_left = 0, false) FROM items WHERE id=$1 LIMIT 1",
 		id,
 	)
-	err := row.Scan(&original, &deleted)
+	err := row.Scan(&original, &deleted, &exhausted)
 
 	if errors.Is(err, sql.ErrNoRows) {
 		return "", ErrNotFound
@@ -108,6 +135,9 @@ func (s *dbStorage) GetOriginal(id string) (string, error) {
 	if deleted {
 		return "", ErrDeleted
 	}
+	if exhausted {
+		return "", ErrExhausted
+	}
 	return original, nil
 }
 
@@ -144,62 +174,16 @@ func (s *dbStorage) MarkAsDeleted(userID string, ids ...string) error {
 	return nil
 }
 
-// GetURLArrayByUser gets all urls.
-func (s *dbStorage) GetURLArrayByUser(userID string) ([]entity.URLs, error) {
-	var history []entity.URLs
-
-	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
-	defer cancel()
-
-	rows, err := s.db.QueryContext(
-		ctx,
-		"SELECT id, url FROM items WHERE cookie=$1",
-		userID,
-	)
-
-	if err != nil {
-		return history, err
-	}
-
-	defer rows.Close()
-
-	for rows.Next() {
-		var id, original string
-
-		err = rows.Scan(&id, &original)
-
-		if err != nil {
-			return history, err
-		}
-
-		history = append(
-			history,
-			entity.URLs{
-				ShortURL:    id,
-				OriginalURL: original,
-			},
-		)
-
-	}
-	if err := rows.Err(); err != nil {
-		return history, err
-	}
-
-	return history, nil
-}
-
 // GetStatistic gets total count of users and urls.
 func (s *dbStorage) GetStatistic() (entity.Statistic, error) {
-	stat := entity.Statistic{
-		Urls: s.lastID,
-	}
+	var stat entity.Statistic
 
 	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
 	defer cancel()
 
-	row := s.db.QueryRowContext(ctx, "SELECT COUNT(DISTINCT cookie) FROM items;")
+	row := s.db.QueryRowContext(ctx, "SELECT COUNT(*), COUNT(DISTINCT cookie) FROM items;")
 
-	err := row.Scan(&stat.Users)
+	err := row.Scan(&stat.Urls, &stat.Users)
 	if err != nil {
 		return stat, err
 	}
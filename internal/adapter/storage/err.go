@@ -6,7 +6,8 @@ import "errors"
 
 // Errors for storage response.
 var (
-	ErrExists   = errors.New("url is already exists")
-	ErrDeleted  = errors.New("url is deleted")
-	ErrNotFound = errors.New("not found")
+	ErrExists    = errors.New("url is already exists")
+	ErrDeleted   = errors.New("url is deleted")
+	ErrNotFound  = errors.New("not found")
+	ErrExhausted = errors.New("url clicks are exhausted")
 )
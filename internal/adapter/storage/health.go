@@ -0,0 +1,302 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+)
+
+// dueHealth checks if link with health is due to check at now.
+func dueHealth(health entity.LinkHealth, checked bool, now time.Time) bool {
+	return !checked || !health.NextCheckAt.After(now)
+}
+
+// sortHealthTargets sorts targets by time of next check, links which were never checked go first.
+func sortHealthTargets(targets []entity.HealthTarget) {
+	sort.SliceStable(targets, func(i, j int) bool {
+		return targets[i].Health.NextCheckAt.Before(targets[j].Health.NextCheckAt)
+	})
+}
+
+// limitHealthTargets gets first targets by limit, zero limit is unlimited.
+func limitHealthTargets(targets []entity.HealthTarget, limit int) []entity.HealthTarget {
+	sortHealthTargets(targets)
+	if limit > 0 && limit < len(targets) {
+		targets = targets[:limit]
+	}
+	return targets
+}
+
+// matchHealth checks if checked link matches filter of report.
+func matchHealth(filter entity.HealthFilter, link entity.LinkHealthInfo) bool {
+	if filter.Failing && !link.Health.Failed() {
+		return false
+	}
+	return matchLink(entity.LinkFilter{Domain: filter.Domain}, link.LinkInfo)
+}
+
+// pageHealth gets page of report by offset and limit of filter.
+func pageHealth(filter entity.HealthFilter, links []entity.LinkHealthInfo) []entity.LinkHealthInfo {
+	if filter.Offset >= len(links) {
+		return []entity.LinkHealthInfo{}
+	}
+	links = links[filter.Offset:]
+	if filter.Limit > 0 && filter.Limit < len(links) {
+		links = links[:filter.Limit]
+	}
+	return links
+}
+
+// SetURLHealth records result of check of url.
+func (s *MapStorage) SetURLHealth(id string, health entity.LinkHealth) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.Locations[id]; !ok {
+		return ErrNotFound
+	}
+	if s.Health == nil {
+		s.Health = make(map[string]entity.LinkHealth)
+	}
+	s.Health[id] = health
+	return nil
+}
+
+// GetHealthTargets gets active links which are due to check at now.
+func (s *MapStorage) GetHealthTargets(now time.Time, limit int) ([]entity.HealthTarget, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	ids := make([]string, 0, len(s.Locations))
+	for id := range s.Locations {
+		if !s.Deleted[id] && !s.Disabled[id] {
+			ids = append(ids, id)
+		}
+	}
+	sortIDs(ids)
+
+	targets := make([]entity.HealthTarget, 0)
+	for _, id := range ids {
+		if health, checked := s.Health[id]; dueHealth(health, checked, now) {
+			targets = append(targets, entity.HealthTarget{ID: id, URL: s.Locations[id], Health: health})
+		}
+	}
+	return limitHealthTargets(targets, limit), nil
+}
+
+// GetHealthReport gets checked links matching filter.
+func (s *MapStorage) GetHealthReport(filter entity.HealthFilter) ([]entity.LinkHealthInfo, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	ids := make([]string, 0, len(s.Health))
+	for id := range s.Health {
+		ids = append(ids, id)
+	}
+	sortIDs(ids)
+
+	owners := s.owners()
+	links := make([]entity.LinkHealthInfo, 0)
+	for _, id := range ids {
+		link := entity.LinkHealthInfo{LinkInfo: s.linkInfo(id, owners[id]), Health: s.Health[id]}
+		if matchHealth(filter, link) {
+			links = append(links, link)
+		}
+	}
+	return pageHealth(filter, links), nil
+}
+
+// SetURLHealth records result of check of url.
+func (s *fileStorage) SetURLHealth(id string, health entity.LinkHealth) error {
+	s.Lock()
+	defer s.Unlock()
+
+	record, ok := s.records[id]
+	if !ok {
+		return ErrNotFound
+	}
+	record.Health = &health
+	return s.write(record)
+}
+
+// GetHealthTargets gets active links which are due to check at now.
+func (s *fileStorage) GetHealthTargets(now time.Time, limit int) ([]entity.HealthTarget, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	targets := make([]entity.HealthTarget, 0)
+	for _, id := range s.order {
+		record := s.records[id]
+		if record.Deleted || record.Disabled {
+			continue
+		}
+		var health entity.LinkHealth
+		if record.Health != nil {
+			health = *record.Health
+		}
+		if dueHealth(health, record.Health != nil, now) {
+			targets = append(targets, entity.HealthTarget{ID: id, URL: record.URL, Health: health})
+		}
+	}
+	return limitHealthTargets(targets, limit), nil
+}
+
+// GetHealthReport gets checked links matching filter.
+func (s *fileStorage) GetHealthReport(filter entity.HealthFilter) ([]entity.LinkHealthInfo, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	links := make([]entity.LinkHealthInfo, 0)
+	for _, id := range s.order {
+		record := s.records[id]
+		if record.Health == nil {
+			continue
+		}
+		link := entity.LinkHealthInfo{LinkInfo: s.linkInfo(record), Health: *record.Health}
+		if matchHealth(filter, link) {
+			links = append(links, link)
+		}
+	}
+	return pageHealth(filter, links), nil
+}
+
+// SetURLHealth records result of check of url.
+func (s *dbStorage) SetURLHealth(id string, health entity.LinkHealth) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	res, err := s.db.ExecContext(
+		ctx,
+		`INSERT INTO link_health (link_id, status, latency_ms, error, failures, checked_at, next_check_at)
+		SELECT id, $2, $3, $4, $5, $6, $7 FROM items WHERE id = $1
+		ON CONFLICT (link_id) DO UPDATE SET status = $2, latency_ms = $3, error = $4, failures = $5,
+		checked_at = $6, next_check_at = $7`,
+		id, health.Status, health.LatencyMS, health.Error, health.Failures,
+		health.CheckedAt.UTC(), health.NextCheckAt.UTC(),
+	)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// healthColumns are columns of link_health scanned by healthRow, they are null if link was never checked.
+const healthColumns = `COALESCE(h.status, 0), COALESCE(h.latency_ms, 0), COALESCE(h.error, ''),
+	COALESCE(h.failures, 0), h.checked_at, h.next_check_at`
+
+// healthRow is health of link scanned from row.
+type healthRow struct {
+	health          entity.LinkHealth
+	checked, nextAt sql.NullTime
+}
+
+// dest gets destinations of health columns.
+func (h *healthRow) dest() []interface{} {
+	return []interface{}{&h.health.Status, &h.health.LatencyMS, &h.health.Error, &h.health.Failures, &h.checked, &h.nextAt}
+}
+
+// value gets scanned health, it's nil if link was never checked.
+func (h *healthRow) value() *entity.LinkHealth {
+	if !h.checked.Valid {
+		return nil
+	}
+	health := h.health
+	health.CheckedAt, health.NextCheckAt = h.checked.Time.UTC(), h.nextAt.Time.UTC()
+	return &health
+}
+
+// GetHealthTargets gets active links which are due to check at now.
+func (s *dbStorage) GetHealthTargets(now time.Time, limit int) ([]entity.HealthTarget, error) {
+	targets := make([]entity.HealthTarget, 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	query := `SELECT i.id, i.url, ` + healthColumns + ` FROM items i
+		LEFT JOIN link_health h ON h.link_id = i.id
+		WHERE NOT i.deleted AND NOT i.disabled AND (h.next_check_at IS NULL OR h.next_check_at <= $1)
+		ORDER BY h.next_check_at NULLS FIRST, length(i.id), i.id`
+	args := []interface{}{now.UTC()}
+	if limit > 0 {
+		query += " LIMIT $2"
+		args = append(args, limit)
+	}
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return targets, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			target entity.HealthTarget
+			health healthRow
+		)
+		if err = rows.Scan(append([]interface{}{&target.ID, &target.URL}, health.dest()...)...); err != nil {
+			return targets, err
+		}
+		if checked := health.value(); checked != nil {
+			target.Health = *checked
+		}
+		targets = append(targets, target)
+	}
+	return targets, rows.Err()
+}
+
+// GetHealthReport gets checked links matching filter.
+// Query prefilters failing links, domain is checked by matchHealth.
+func (s *dbStorage) GetHealthReport(filter entity.HealthFilter) ([]entity.LinkHealthInfo, error) {
+	links := make([]entity.LinkHealthInfo, 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		`SELECT i.id, i.url, i.cookie, i.deleted, i.disabled, `+healthColumns+` FROM items i
+		JOIN link_health h ON h.link_id = i.id
+		WHERE (NOT $1 OR h.error <> '' OR h.status >= 400)
+		AND ($2 = '' OR i.url ILIKE '%' || $2 || '%')
+		ORDER BY length(i.id), i.id`,
+		filter.Failing, filter.Domain,
+	)
+	if err != nil {
+		return links, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			link     entity.LinkHealthInfo
+			owner    sql.NullString
+			deleted  sql.NullBool
+			disabled sql.NullBool
+			health   healthRow
+		)
+		dest := append([]interface{}{&link.ID, &link.OriginalURL, &owner, &deleted, &disabled}, health.dest()...)
+		if err = rows.Scan(dest...); err != nil {
+			return links, err
+		}
+		link.ShortURL = fmt.Sprintf("%s/%v", s.cfg.BaseURL, link.ID)
+		link.UserID, link.Deleted, link.Disabled = owner.String, deleted.Bool, disabled.Bool
+		link.Health = *health.value()
+		if matchHealth(filter, link) {
+			links = append(links, link)
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return links, err
+	}
+	return pageHealth(filter, links), nil
+}
@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestURLHealth(t *testing.T) {
+	storages, cfg := testStorages(t)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	for name, s := range storages {
+		ids, err := s.CreateShort("user1", "https://go.dev/doc", "https://sub.example.com/a", "https://yandex.ru")
+		require.NoError(t, err, name)
+
+		targets, err := s.GetHealthTargets(now, 0)
+		require.NoError(t, err, name)
+		assert.Len(t, targets, 3, name, "never checked links are due")
+
+		ok := entity.LinkHealth{Status: 200, LatencyMS: 15, CheckedAt: now, NextCheckAt: now.Add(time.Hour)}
+		failing := entity.LinkHealth{Status: 404, LatencyMS: 20, Failures: 2, CheckedAt: now, NextCheckAt: now.Add(-time.Minute)}
+		require.NoError(t, s.SetURLHealth(ids[0], ok), name)
+		require.NoError(t, s.SetURLHealth(ids[1], failing), name)
+		assert.ErrorIs(t, s.SetURLHealth("100", ok), ErrNotFound, name)
+
+		targets, err = s.GetHealthTargets(now, 0)
+		require.NoError(t, err, name)
+		require.Len(t, targets, 2, name)
+		assert.Equal(t, ids[2], targets[0].ID, name, "never checked link goes first")
+		assert.Equal(t, entity.LinkHealth{}, targets[0].Health, name)
+		assert.Equal(t, ids[1], targets[1].ID, name)
+		assert.Equal(t, "https://sub.example.com/a", targets[1].URL, name)
+		assert.Equal(t, failing, targets[1].Health, name)
+
+		targets, err = s.GetHealthTargets(now, 1)
+		require.NoError(t, err, name)
+		assert.Len(t, targets, 1, name)
+
+		report, err := s.GetHealthReport(entity.HealthFilter{})
+		require.NoError(t, err, name)
+		assert.Len(t, report, 2, name, "report has only checked links")
+
+		report, err = s.GetHealthReport(entity.HealthFilter{Failing: true})
+		require.NoError(t, err, name)
+		require.Len(t, report, 1, name)
+		assert.Equal(t, ids[1], report[0].ID, name)
+		assert.Equal(t, "user1", report[0].UserID, name)
+		assert.Equal(t, failing, report[0].Health, name)
+
+		report, err = s.GetHealthReport(entity.HealthFilter{Domain: "go.dev"})
+		require.NoError(t, err, name)
+		require.Len(t, report, 1, name)
+		assert.Equal(t, ok, report[0].Health, name)
+
+		report, err = s.GetHealthReport(entity.HealthFilter{Offset: 1, Limit: 10})
+		require.NoError(t, err, name)
+		assert.Len(t, report, 1, name)
+
+		history, err := s.GetURLArrayByUser("user1", entity.HistoryFilter{})
+		require.NoError(t, err, name)
+		require.Len(t, history, 3, name)
+		for _, link := range history {
+			switch link.OriginalURL {
+			case "https://go.dev/doc":
+				assert.Equal(t, &ok, link.Health, name)
+			case "https://yandex.ru":
+				assert.Nil(t, link.Health, name)
+			}
+		}
+
+		// deleted links aren't checked.
+		require.NoError(t, s.MarkAsDeleted("user1", ids[2]), name)
+		targets, err = s.GetHealthTargets(now, 0)
+		require.NoError(t, err, name)
+		require.Len(t, targets, 1, name)
+		assert.Equal(t, ids[1], targets[0].ID, name)
+	}
+
+	restarted, err := newFileStorage(cfg)
+	require.NoError(t, err)
+	report, err := restarted.GetHealthReport(entity.HealthFilter{Failing: true})
+	require.NoError(t, err)
+	require.Len(t, report, 1)
+	assert.Equal(t, 2, report[0].Health.Failures)
+}
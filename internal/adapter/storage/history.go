@@ -0,0 +1,278 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+)
+
+// historyColumns are columns of items by sort orders of history.
+var historyColumns = map[string]string{
+	entity.SortCreated: "i.created_at",
+	entity.SortClicks:  "i.clicks",
+	entity.SortURL:     "i.url",
+}
+
+// likeEscaper escapes wildcards of LIKE pattern.
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// matchHistory checks if link of history matches filter.
+// Tag must be one of tags of link, query matches part of title or original url ignoring case.
+func matchHistory(filter entity.HistoryFilter, item entity.URLs) bool {
+	if filter.Tag != "" {
+		var tagged bool
+		for _, tag := range item.Tags {
+			if tag == filter.Tag {
+				tagged = true
+				break
+			}
+		}
+		if !tagged {
+			return false
+		}
+	}
+	if filter.Query != "" {
+		query := strings.ToLower(filter.Query)
+		if !strings.Contains(strings.ToLower(item.OriginalURL), query) &&
+			!strings.Contains(strings.ToLower(item.Title), query) {
+			return false
+		}
+	}
+	return true
+}
+
+// compareHistory compares keys of links by sort order, links with equal values are compared by id.
+func compareHistory(sortBy string, a, b entity.HistoryKey) int {
+	var result int
+	switch sortBy {
+	case entity.SortClicks:
+		switch {
+		case a.Clicks < b.Clicks:
+			result = -1
+		case a.Clicks > b.Clicks:
+			result = 1
+		}
+	case entity.SortURL:
+		result = strings.Compare(a.URL, b.URL)
+	default:
+		result = a.CreatedAt.Compare(b.CreatedAt)
+	}
+	if result == 0 {
+		result = strings.Compare(a.ID, b.ID)
+	}
+	return result
+}
+
+// historyEntry is link of history with its key.
+type historyEntry struct {
+	key  entity.HistoryKey
+	link entity.URLs
+}
+
+// newHistoryEntry gets entry of link with id.
+func newHistoryEntry(id string, link entity.URLs) historyEntry {
+	return historyEntry{
+		key:  entity.HistoryKey{ID: id, CreatedAt: link.CreatedAt, Clicks: link.Clicks, URL: link.OriginalURL},
+		link: link,
+	}
+}
+
+// pageHistory sorts entries by filter and gets page of links after key of filter.
+func pageHistory(filter entity.HistoryFilter, entries []historyEntry) []entity.URLs {
+	compare := func(a, b entity.HistoryKey) int {
+		if filter.Desc {
+			return compareHistory(filter.Sort, b, a)
+		}
+		return compareHistory(filter.Sort, a, b)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return compare(entries[i].key, entries[j].key) < 0
+	})
+
+	start, end := 0, len(entries)
+	if filter.After != nil {
+		start = sort.Search(len(entries), func(i int) bool {
+			return compare(entries[i].key, *filter.After) > 0
+		})
+	}
+	if filter.Limit > 0 && start+filter.Limit < end {
+		end = start + filter.Limit
+	}
+
+	links := make([]entity.URLs, 0, end-start)
+	for _, entry := range entries[start:end] {
+		links = append(links, entry.link)
+	}
+	return links
+}
+
+// historyItem gets link of history. Caller must hold lock.
+func (s *MapStorage) historyItem(id string) entity.URLs {
+	meta := s.Meta[id]
+	var health *entity.LinkHealth
+	if checked, ok := s.Health[id]; ok {
+		health = &checked
+	}
+	return entity.URLs{
+		ShortURL:     fmt.Sprintf("%s/%v", s.Cfg.BaseURL, id),
+		OriginalURL:  s.Locations[id],
+		Title:        meta.Title,
+		Tags:         meta.Tags,
+		Note:         meta.Note,
+		Interstitial: meta.Interstitial,
+		Clicks:       s.Clicks[id],
+		CreatedAt:    s.Created[id],
+		Health:       health,
+	}
+}
+
+// GetUserURL gets link of user with metadata and counters.
+func (s *MapStorage) GetUserURL(userID, id string) (entity.URLs, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.Locations[id]; !ok || s.owners()[id] != userID {
+		return entity.URLs{}, ErrNotFound
+	}
+	return s.historyItem(id), nil
+}
+
+// historyItem gets link of history from record.
+func (s *fileStorage) historyItem(record fileRecord) entity.URLs {
+	return entity.URLs{
+		ShortURL:     fmt.Sprintf("%s/%v", s.cfg.BaseURL, record.ID),
+		OriginalURL:  record.URL,
+		Title:        record.Title,
+		Tags:         record.Tags,
+		Note:         record.Note,
+		Interstitial: record.Interstitial,
+		Clicks:       record.Clicks,
+		CreatedAt:    record.CreatedAt,
+		Health:       record.Health,
+	}
+}
+
+// GetUserURL gets link of user with metadata and counters.
+func (s *fileStorage) GetUserURL(userID, id string) (entity.URLs, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	record, ok := s.records[id]
+	if !ok || record.UserID != userID {
+		return entity.URLs{}, ErrNotFound
+	}
+	return s.historyItem(record), nil
+}
+
+// historySelect selects links of history with their tags and health.
+const historySelect = `SELECT i.id, i.url, i.title, i.note, i.interstitial, i.clicks, i.created_at,
+	COALESCE((SELECT json_agg(t.tag ORDER BY t.tag) FROM link_tags t WHERE t.link_id = i.id), '[]'),
+	` + healthColumns + `
+	FROM items i LEFT JOIN link_health h ON h.link_id = i.id`
+
+// scanHistoryItem scans link of history from row.
+func (s *dbStorage) scanHistoryItem(row rowScanner) (entity.URLs, error) {
+	var (
+		id     string
+		tags   []byte
+		item   entity.URLs
+		health healthRow
+	)
+
+	dest := []interface{}{&id, &item.OriginalURL, &item.Title, &item.Note, &item.Interstitial, &item.Clicks, &item.CreatedAt, &tags}
+	if err := row.Scan(append(dest, health.dest()...)...); err != nil {
+		return item, err
+	}
+	item.Health = health.value()
+	if err := json.Unmarshal(tags, &item.Tags); err != nil {
+		return item, err
+	}
+	item.ShortURL = fmt.Sprintf("%s/%v", s.cfg.BaseURL, id)
+	item.CreatedAt = item.CreatedAt.UTC()
+	return item, nil
+}
+
+// GetURLArrayByUser gets page of urls of user which match filter.
+// Page is read by index of owner and sort column from position after key of filter.
+func (s *dbStorage) GetURLArrayByUser(userID string, filter entity.HistoryFilter) ([]entity.URLs, error) {
+	history := make([]entity.URLs, 0)
+
+	column, ok := historyColumns[filter.Sort]
+	if !ok {
+		column = historyColumns[entity.SortCreated]
+	}
+	direction, after := "ASC", ">"
+	if filter.Desc {
+		direction, after = "DESC", "<"
+	}
+
+	var pattern string
+	if filter.Query != "" {
+		pattern = "%" + likeEscaper.Replace(filter.Query) + "%"
+	}
+	args := []interface{}{userID, filter.Tag, pattern}
+
+	query := historySelect + `
+		WHERE i.cookie = $1
+		AND ($2 = '' OR EXISTS (SELECT 1 FROM link_tags f WHERE f.link_id = i.id AND f.tag = $2))
+		AND ($3 = '' OR i.url ILIKE $3 OR i.title ILIKE $3)`
+	if filter.After != nil {
+		var value interface{}
+		switch filter.Sort {
+		case entity.SortClicks:
+			value = filter.After.Clicks
+		case entity.SortURL:
+			value = filter.After.URL
+		default:
+			value = filter.After.CreatedAt
+		}
+		args = append(args, value, filter.After.ID)
+		query += fmt.Sprintf(" AND (%s, i.id) %s ($4, $5)", column, after)
+	}
+	query += fmt.Sprintf(" ORDER BY %s %s, i.id %s", column, direction, direction)
+	if filter.Limit > 0 {
+		args = append(args, filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return history, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		item, err := s.scanHistoryItem(rows)
+		if err != nil {
+			return history, err
+		}
+		history = append(history, item)
+	}
+	if err = rows.Err(); err != nil {
+		return history, err
+	}
+	return history, nil
+}
+
+// GetUserURL gets link of user with metadata and counters.
+func (s *dbStorage) GetUserURL(userID, id string) (entity.URLs, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	row := s.db.QueryRowContext(ctx, historySelect+" WHERE i.id = $1 AND i.cookie = $2 LIMIT 1", id, userID)
+	item, err := s.scanHistoryItem(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return item, ErrNotFound
+	}
+	return item, err
+}
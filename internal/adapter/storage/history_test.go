@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHistoryPages(t *testing.T) {
+	storages, cfg := testStorages(t)
+
+	originals := func(links []entity.URLs) []string {
+		result := make([]string, 0, len(links))
+		for _, link := range links {
+			result = append(result, link.OriginalURL)
+		}
+		return result
+	}
+
+	for name, s := range storages {
+		ids, err := s.CreateShort("user1", "https://c.com", "https://a.com", "https://b.com")
+		require.NoError(t, err, name)
+		_, err = s.CreateShort("user2", "https://d.com")
+		require.NoError(t, err, name)
+
+		require.NoError(t, s.TakeClick(ids[1]), name)
+		require.NoError(t, s.TakeClick(ids[1]), name)
+		require.NoError(t, s.TakeClick(ids[2]), name)
+
+		link, err := s.GetUserURL("user1", ids[1])
+		require.NoError(t, err, name)
+		assert.Equal(t, 2, link.Clicks, name)
+		assert.False(t, link.CreatedAt.IsZero(), name)
+		_, err = s.GetUserURL("user2", ids[1])
+		assert.ErrorIs(t, err, ErrNotFound, name)
+
+		history, err := s.GetURLArrayByUser("user1", entity.HistoryFilter{Sort: entity.SortURL})
+		require.NoError(t, err, name)
+		assert.Equal(t, []string{"https://a.com", "https://b.com", "https://c.com"}, originals(history), name)
+
+		history, err = s.GetURLArrayByUser("user1", entity.HistoryFilter{Sort: entity.SortClicks, Desc: true})
+		require.NoError(t, err, name)
+		assert.Equal(t, []string{"https://a.com", "https://b.com", "https://c.com"}, originals(history), name)
+
+		first, err := s.GetURLArrayByUser("user1", entity.HistoryFilter{Sort: entity.SortCreated, Limit: 2})
+		require.NoError(t, err, name)
+		require.Len(t, first, 2, name)
+		assert.Equal(t, []string{"https://c.com", "https://a.com"}, originals(first), name)
+
+		after := entity.HistoryKey{ID: ids[1], CreatedAt: first[1].CreatedAt, Clicks: first[1].Clicks, URL: first[1].OriginalURL}
+		second, err := s.GetURLArrayByUser("user1", entity.HistoryFilter{Sort: entity.SortCreated, Limit: 2, After: &after})
+		require.NoError(t, err, name)
+		assert.Equal(t, []string{"https://b.com"}, originals(second), name)
+
+		after = entity.HistoryKey{ID: ids[1], URL: "https://a.com"}
+		history, err = s.GetURLArrayByUser("user1", entity.HistoryFilter{Sort: entity.SortURL, Desc: true, After: &after})
+		require.NoError(t, err, name)
+		assert.Empty(t, history, name)
+	}
+
+	restarted, err := newFileStorage(cfg)
+	require.NoError(t, err)
+	history, err := restarted.GetURLArrayByUser("user1", entity.HistoryFilter{Sort: entity.SortClicks, Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, 0, history[0].Clicks)
+	assert.Equal(t, "https://c.com", history[0].OriginalURL)
+}
@@ -0,0 +1,33 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+)
+
+// loadJSONFile reads value from JSON file. Missing file leaves value untouched.
+func loadJSONFile(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// saveJSONFile writes value to JSON file atomically.
+func saveJSONFile(path string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err = os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
@@ -0,0 +1,154 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+)
+
+// SetURLMeta replaces title, tags, note and interstitial flag of link of user.
+func (s *MapStorage) SetURLMeta(userID, id string, meta entity.LinkMeta) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.Locations[id]; !ok || s.owners()[id] != userID {
+		return ErrNotFound
+	}
+	if s.Deleted[id] || s.Disabled[id] {
+		return ErrDeleted
+	}
+
+	if s.Meta == nil {
+		s.Meta = make(map[string]entity.LinkMeta)
+	}
+	s.Meta[id] = meta
+	return nil
+}
+
+// GetURLMeta gets title, tags, note and interstitial flag of link.
+func (s *MapStorage) GetURLMeta(id string) (entity.LinkMeta, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.Locations[id]; !ok {
+		return entity.LinkMeta{}, ErrNotFound
+	}
+	return s.Meta[id], nil
+}
+
+// SetURLMeta replaces title, tags, note and interstitial flag of link of user.
+func (s *fileStorage) SetURLMeta(userID, id string, meta entity.LinkMeta) error {
+	s.Lock()
+	defer s.Unlock()
+
+	record, ok := s.records[id]
+	if !ok || record.UserID != userID {
+		return ErrNotFound
+	}
+	if record.Deleted || record.Disabled {
+		return ErrDeleted
+	}
+
+	record.Title, record.Tags, record.Note, record.Interstitial = meta.Title, meta.Tags, meta.Note, meta.Interstitial
+	return s.write(record)
+}
+
+// GetURLMeta gets title, tags, note and interstitial flag of link.
+func (s *fileStorage) GetURLMeta(id string) (entity.LinkMeta, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	record, ok := s.records[id]
+	if !ok {
+		return entity.LinkMeta{}, ErrNotFound
+	}
+	return entity.LinkMeta{
+		Title:        record.Title,
+		Tags:         record.Tags,
+		Note:         record.Note,
+		Interstitial: record.Interstitial,
+	}, nil
+}
+
+// SetURLMeta replaces title, tags, note and interstitial flag of link of user, tags are kept in link_tags table.
+func (s *dbStorage) SetURLMeta(userID, id string, meta entity.LinkMeta) error {
+	var deleted sql.NullBool
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	row := tx.QueryRowContext(
+		ctx,
+		"SELECT deleted OR disabled FROM items WHERE id = $1 AND cookie = $2 FOR UPDATE",
+		id, userID,
+	)
+	err = row.Scan(&deleted)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if deleted.Bool {
+		return ErrDeleted
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		"UPDATE items SET title = $1, note = $2, interstitial = $3 WHERE id = $4",
+		meta.Title, meta.Note, meta.Interstitial, id,
+	)
+	if err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, "DELETE FROM link_tags WHERE link_id = $1", id); err != nil {
+		return err
+	}
+	for _, tag := range meta.Tags {
+		if _, err = tx.ExecContext(ctx, "INSERT INTO link_tags (link_id, tag) VALUES ($1, $2)", id, tag); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetURLMeta gets title, tags, note and interstitial flag of link.
+func (s *dbStorage) GetURLMeta(id string) (entity.LinkMeta, error) {
+	var meta entity.LinkMeta
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	row := s.db.QueryRowContext(ctx, "SELECT title, note, interstitial FROM items WHERE id = $1 LIMIT 1", id)
+	err := row.Scan(&meta.Title, &meta.Note, &meta.Interstitial)
+	if errors.Is(err, sql.ErrNoRows) {
+		return meta, ErrNotFound
+	}
+	if err != nil {
+		return meta, err
+	}
+
+	rows, err := s.db.QueryContext(ctx, "SELECT tag FROM link_tags WHERE link_id = $1 ORDER BY tag", id)
+	if err != nil {
+		return meta, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var tag string
+		if err = rows.Scan(&tag); err != nil {
+			return meta, err
+		}
+		meta.Tags = append(meta.Tags, tag)
+	}
+	return meta, rows.Err()
+}
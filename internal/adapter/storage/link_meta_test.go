@@ -0,0 +1,64 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetURLMeta(t *testing.T) {
+	storages, cfg := testStorages(t)
+
+	for name, s := range storages {
+		ids, err := s.CreateShort("user1", "https://go.dev/doc", "https://yandex.ru", "https://example.com")
+		require.NoError(t, err, name)
+
+		meta := entity.LinkMeta{Title: "Go Docs", Tags: []string{"go", "docs"}, Note: "read later", Interstitial: true}
+		assert.ErrorIs(t, s.SetURLMeta("user2", ids[0], meta), ErrNotFound, name, "only owner sets metadata")
+		require.NoError(t, s.SetURLMeta("user1", ids[0], meta), name)
+		require.NoError(t, s.SetURLMeta("user1", ids[1], entity.LinkMeta{Title: "Search", Tags: []string{"search"}}), name)
+
+		got, err := s.GetURLMeta(ids[0])
+		require.NoError(t, err, name)
+		assert.Equal(t, meta, got, name)
+		_, err = s.GetURLMeta("100")
+		assert.ErrorIs(t, err, ErrNotFound, name)
+
+		history, err := s.GetURLArrayByUser("user1", entity.HistoryFilter{})
+		require.NoError(t, err, name)
+		assert.Len(t, history, 3, name)
+
+		history, err = s.GetURLArrayByUser("user1", entity.HistoryFilter{Tag: "go"})
+		require.NoError(t, err, name)
+		require.Len(t, history, 1, name)
+		assert.Equal(t, "https://go.dev/doc", history[0].OriginalURL, name)
+		assert.Equal(t, "Go Docs", history[0].Title, name)
+		assert.Equal(t, []string{"go", "docs"}, history[0].Tags, name)
+
+		history, err = s.GetURLArrayByUser("user1", entity.HistoryFilter{Query: "SEARCH"})
+		require.NoError(t, err, name)
+		require.Len(t, history, 1, name, "query matches title")
+		assert.Equal(t, "https://yandex.ru", history[0].OriginalURL, name)
+
+		history, err = s.GetURLArrayByUser("user1", entity.HistoryFilter{Query: "example"})
+		require.NoError(t, err, name)
+		assert.Len(t, history, 1, name, "query matches url")
+
+		history, err = s.GetURLArrayByUser("user1", entity.HistoryFilter{Tag: "go", Query: "yandex"})
+		require.NoError(t, err, name)
+		assert.Empty(t, history, name)
+
+		require.NoError(t, s.MarkAsDeleted("user1", ids[2]), name)
+		assert.ErrorIs(t, s.SetURLMeta("user1", ids[2], meta), ErrDeleted, name)
+	}
+
+	restarted, err := newFileStorage(cfg)
+	require.NoError(t, err)
+	history, err := restarted.GetURLArrayByUser("user1", entity.HistoryFilter{Tag: "docs"})
+	require.NoError(t, err)
+	require.Len(t, history, 1)
+	assert.Equal(t, "read later", history[0].Note)
+}
@@ -0,0 +1,203 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// CreateShortWithOptions creates short url protected by password or limited by count of clicks.
+// Zero maxClicks is unlimited. Link with options is always new, it's never returned for the same url without them.
+func (s *MapStorage) CreateShortWithOptions(userID, original, passwordHash string, maxClicks int) (string, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	id := fmt.Sprint(len(s.Locations) + 1)
+	s.Locations[id], s.Users[userID] = original, append(s.Users[userID], id)
+	if s.Created == nil {
+		s.Created = make(map[string]time.Time)
+	}
+	s.Created[id] = time.Now().UTC()
+	if s.Passwords == nil {
+		s.Passwords = make(map[string]string)
+	}
+	s.Passwords[id] = passwordHash
+	if maxClicks > 0 {
+		if s.ClicksLeft == nil {
+			s.ClicksLeft = make(map[string]int)
+		}
+		s.ClicksLeft[id] = maxClicks
+	}
+	return id, nil
+}
+
+// hasOptions checks if link has password or limit of clicks.
+func (s *MapStorage) hasOptions(id string) bool {
+	_, limited := s.ClicksLeft[id]
+	return limited || s.Passwords[id] != ""
+}
+
+// GetPasswordHash gets password hash of url, it's empty if url isn't protected.
+func (s *MapStorage) GetPasswordHash(id string) (string, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.Locations[id]; !ok {
+		return "", ErrNotFound
+	}
+	return s.Passwords[id], nil
+}
+
+// TakeClick counts click of url. Returns ErrExhausted if url is limited by count of clicks and no clicks left.
+func (s *MapStorage) TakeClick(id string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.Locations[id]; !ok {
+		return ErrNotFound
+	}
+	if left, limited := s.ClicksLeft[id]; limited {
+		if left <= 0 {
+			return ErrExhausted
+		}
+		s.ClicksLeft[id] = left - 1
+	}
+
+	if s.Clicks == nil {
+		s.Clicks = make(map[string]int)
+	}
+	s.Clicks[id]++
+	return nil
+}
+
+// exhausted checks if record is limited by count of clicks and has no clicks left.
+func (r fileRecord) exhausted() bool {
+	return r.MaxClicks > 0 && r.Clicks >= r.MaxClicks
+}
+
+// CreateShortWithOptions creates short url protected by password or limited by count of clicks.
+// Zero maxClicks is unlimited. Link with options is always new, it's never returned for the same url without them.
+func (s *fileStorage) CreateShortWithOptions(userID, original, passwordHash string, maxClicks int) (string, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	id := fmt.Sprint(s.lastID + 1)
+	record := fileRecord{
+		ID:           id,
+		URL:          original,
+		UserID:       userID,
+		PasswordHash: passwordHash,
+		MaxClicks:    maxClicks,
+		CreatedAt:    time.Now().UTC(),
+	}
+	if err := s.write(record); err != nil {
+		return "", err
+	}
+	s.lastID++
+	return id, nil
+}
+
+// GetPasswordHash gets password hash of url, it's empty if url isn't protected.
+func (s *fileStorage) GetPasswordHash(id string) (string, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	record, ok := s.records[id]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return record.PasswordHash, nil
+}
+
+// TakeClick counts click of url. Returns ErrExhausted if url is limited by count of clicks and no clicks left.
+// Click of limited url is appended to file as changed record, clicks of other urls are saved by flushClicks.
+func (s *fileStorage) TakeClick(id string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	record, ok := s.records[id]
+	switch {
+	case !ok:
+		return ErrNotFound
+	case record.exhausted():
+		return ErrExhausted
+	}
+	record.Clicks++
+	if record.MaxClicks > 0 {
+		return s.write(record)
+	}
+
+	s.records[id] = record
+	s.unsaved[id] = true
+	return s.flushClicks(time.Now())
+}
+
+// CreateShortWithOptions creates short url protected by password or limited by count of clicks.
+// Zero maxClicks is unlimited. Link with options is always new, it's never returned for the same url without them.
+func (s *dbStorage) CreateShortWithOptions(userID, original, passwordHash string, maxClicks int) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	clicksLeft := sql.NullInt64{Int64: int64(maxClicks), Valid: maxClicks > 0}
+
+	var id string
+	err := s.db.QueryRowContext(
+		ctx,
+		`INSERT INTO items (id, url, cookie, created_at, password_hash, clicks_left)
+		VALUES (nextval('items_id_seq')::TEXT, $1, $2, $3, $4, $5) RETURNING id`,
+		original, userID, time.Now().UTC(), passwordHash, clicksLeft,
+	).Scan(&id)
+	if err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+// GetPasswordHash gets password hash of url, it's empty if url isn't protected.
+func (s *dbStorage) GetPasswordHash(id string) (string, error) {
+	var hash string
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	err := s.db.QueryRowContext(ctx, "SELECT password_hash FROM items WHERE id = $1", id).Scan(&hash)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", ErrNotFound
+	}
+	return hash, err
+}
+
+// TakeClick counts click of url. Returns ErrExhausted if url is limited by count of clicks and no clicks left.
+// Counters are changed by one statement with row lock, so the last click can't be taken twice.
+func (s *dbStorage) TakeClick(id string) error {
+	var (
+		limited bool
+		taken   bool
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	row := s.db.QueryRowContext(
+		ctx,
+		`WITH taken AS (
+			UPDATE items SET clicks = clicks + 1, clicks_left = clicks_left - 1
+			WHERE id = $1 AND (clicks_left IS NULL OR clicks_left > 0) RETURNING id
+		)
+		SELECT clicks_left IS NOT NULL, EXISTS (SELECT 1 FROM taken) FROM items WHERE id = $1`,
+		id,
+	)
+	err := row.Scan(&limited, &taken)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if limited && !taken {
+		return ErrExhausted
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bbt-t/lets-go-shortener/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShortWithOptions(t *testing.T) {
+	storages, cfg := testStorages(t)
+
+	for name, s := range storages {
+		ids, err := s.CreateShort("user1", "https://yandex.ru")
+		require.NoError(t, err, name)
+
+		id, err := s.CreateShortWithOptions("user2", "https://yandex.ru", "hash", 0)
+		require.NoError(t, err, name)
+		assert.NotEqual(t, ids[0], id, name, "protected link is always new")
+
+		hash, err := s.GetPasswordHash(id)
+		assert.NoError(t, err, name)
+		assert.Equal(t, "hash", hash, name)
+		hash, err = s.GetPasswordHash(ids[0])
+		assert.NoError(t, err, name)
+		assert.Empty(t, hash, name)
+		_, err = s.GetPasswordHash("100")
+		assert.ErrorIs(t, err, ErrNotFound, name)
+
+		original, err := s.GetOriginal(id)
+		assert.NoError(t, err, name)
+		assert.Equal(t, "https://yandex.ru", original, name)
+
+		// protected link isn't returned for url without password.
+		protected, err := s.CreateShortWithOptions("user2", "https://ya.ru", "hash", 0)
+		require.NoError(t, err, name)
+		ids, err = s.CreateShort("user1", "https://ya.ru")
+		assert.NoError(t, err, name)
+		assert.NotEqual(t, protected, ids[0], name)
+	}
+
+	restarted, err := newFileStorage(cfg)
+	require.NoError(t, err)
+	hash, err := restarted.GetPasswordHash("2")
+	assert.NoError(t, err)
+	assert.Equal(t, "hash", hash)
+	ids, err := restarted.CreateShort("user1", "https://ya.ru")
+	assert.ErrorIs(t, err, ErrExists)
+	assert.Equal(t, []string{"4"}, ids)
+}
+
+func TestTakeClick(t *testing.T) {
+	storages, cfg := testStorages(t)
+
+	for name, s := range storages {
+		ids, err := s.CreateShort("user1", "https://yandex.ru")
+		require.NoError(t, err, name)
+		id, err := s.CreateShortWithOptions("user1", "https://yandex.ru", "", 2)
+		require.NoError(t, err, name)
+		assert.NotEqual(t, ids[0], id, name, "limited link is always new")
+
+		for i := 0; i < 2; i++ {
+			_, err = s.GetOriginal(id)
+			assert.NoError(t, err, name)
+			assert.NoError(t, s.TakeClick(id), name)
+		}
+		_, err = s.GetOriginal(id)
+		assert.ErrorIs(t, err, ErrExhausted, name)
+		assert.ErrorIs(t, s.TakeClick(id), ErrExhausted, name)
+
+		for i := 0; i < 3; i++ {
+			assert.NoError(t, s.TakeClick(ids[0]), name, "unlimited link")
+		}
+		assert.ErrorIs(t, s.TakeClick("100"), ErrNotFound, name)
+	}
+
+	restarted, err := newFileStorage(cfg)
+	require.NoError(t, err)
+	_, err = restarted.GetOriginal("2")
+	assert.ErrorIs(t, err, ErrExhausted, "clicks are read after restart")
+}
+
+func TestFlushClicks(t *testing.T) {
+	cfg := config.GetTestConfig()
+	cfg.StoragePath = filepath.Join(t.TempDir(), "file_storage.db")
+
+	lines := func() int {
+		data, err := os.ReadFile(cfg.StoragePath)
+		require.NoError(t, err)
+		return strings.Count(string(data), "\n")
+	}
+
+	s, err := newFileStorage(cfg)
+	require.NoError(t, err)
+	ids, err := s.CreateShort("user1", "https://yandex.ru", "https://ya.ru")
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, s.TakeClick(ids[0]))
+	}
+	assert.Equal(t, 2, lines(), "clicks of unlimited link are kept in memory")
+	assert.Equal(t, 3, s.records[ids[0]].Clicks)
+
+	require.NoError(t, s.flushClicks(time.Now()))
+	assert.Equal(t, 2, lines(), "flush interval isn't passed")
+	require.NoError(t, s.flushClicks(time.Now().Add(clickFlushInterval)))
+	assert.Equal(t, 3, lines())
+	require.NoError(t, s.flushClicks(time.Now().Add(2*clickFlushInterval)))
+	assert.Equal(t, 3, lines(), "nothing to flush")
+
+	restarted, err := newFileStorage(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, 3, restarted.records[ids[0]].Clicks)
+	assert.Equal(t, 2, lines(), "file is compacted on load")
+
+	require.NoError(t, restarted.TakeClick(ids[1]))
+	require.NoError(t, restarted.SetDisabled(ids[1], true))
+	assert.Equal(t, 3, lines(), "record is saved with its clicks")
+	assert.Empty(t, restarted.unsaved)
+}
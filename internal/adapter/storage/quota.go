@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"context"
+	"time"
+)
+
+// CountUserURLs gets count of not deleted urls of user and of urls created by user since moment.
+func (s *MapStorage) CountUserURLs(userID string, since time.Time) (active, created int, err error) {
+	s.Lock()
+	defer s.Unlock()
+
+	for _, id := range s.Users[userID] {
+		if !s.Deleted[id] {
+			active++
+		}
+		if !s.Created[id].Before(since) {
+			created++
+		}
+	}
+	return active, created, nil
+}
+
+// CountUserURLs gets count of not deleted urls of user and of urls created by user since moment.
+func (s *fileStorage) CountUserURLs(userID string, since time.Time) (active, created int, err error) {
+	s.Lock()
+	defer s.Unlock()
+
+	for _, record := range s.records {
+		if record.UserID != userID {
+			continue
+		}
+		if !record.Deleted {
+			active++
+		}
+		if !record.CreatedAt.Before(since) {
+			created++
+		}
+	}
+	return active, created, nil
+}
+
+// CountUserURLs gets count of not deleted urls of user and of urls created by user since moment.
+func (s *dbStorage) CountUserURLs(userID string, since time.Time) (active, created int, err error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	row := s.db.QueryRowContext(
+		ctx,
+		`SELECT COUNT(*) FILTER (WHERE NOT COALESCE(deleted, false)), COUNT(*) FILTER (WHERE created_at >= $2)
+		FROM items WHERE cookie = $1`,
+		userID, since,
+	)
+	err = row.Scan(&active, &created)
+	return active, created, err
+}
@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCountUserURLs(t *testing.T) {
+	storages, cfg := testStorages(t)
+
+	for name, s := range storages {
+		start := time.Now().UTC().Add(-time.Second)
+
+		_, err := s.CreateShort("user1", "https://yandex.ru", "https://google.com", "https://ya.ru")
+		require.NoError(t, err, name)
+		_, err = s.CreateShort("user2", "https://practicum.yandex.ru")
+		require.NoError(t, err, name)
+		require.NoError(t, s.MarkAsDeleted("user1", "1"), name)
+
+		active, created, err := s.CountUserURLs("user1", start)
+		assert.NoError(t, err, name)
+		assert.Equal(t, 2, active, name)
+		assert.Equal(t, 3, created, name, "deleted links are counted as created")
+
+		_, created, err = s.CountUserURLs("user1", time.Now().UTC().Add(time.Minute))
+		assert.NoError(t, err, name)
+		assert.Zero(t, created, name)
+
+		active, created, err = s.CountUserURLs("nobody", start)
+		assert.NoError(t, err, name)
+		assert.Zero(t, active, name)
+		assert.Zero(t, created, name)
+	}
+
+	// time of creation is kept in file.
+	restarted, err := newFileStorage(cfg)
+	require.NoError(t, err)
+	_, created, err := restarted.CountUserURLs("user1", time.Now().UTC().Add(-time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, created)
+}
@@ -0,0 +1,139 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/bbt-t/lets-go-shortener/internal/config"
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+)
+
+// sweepInterval is how often full buckets are removed from memory.
+const sweepInterval = time.Minute
+
+// ErrNoSharedLimiter is returned if shared rate limiter is asked without db storage.
+var ErrNoSharedLimiter = errors.New("shared rate limiter needs database storage")
+
+// Limiter is an interface that describes token bucket rate limiter.
+type Limiter interface {
+	// Take takes token from bucket of key. Returns time to wait for next token if bucket is empty.
+	Take(key string, limit entity.RateLimit) (time.Duration, error)
+}
+
+// NewLimiter creates rate limiter based on config.
+// Buckets are kept in memory of replica, shared limiter keeps them in db for all replicas.
+func NewLimiter(cfg config.Config, s Repository) (Limiter, error) {
+	if !cfg.RateLimitShared {
+		return newMemoryLimiter(time.Now), nil
+	}
+	if db, ok := s.(*dbStorage); ok {
+		return db, nil
+	}
+	return nil, ErrNoSharedLimiter
+}
+
+// waitForToken gets time to wait until bucket has one token.
+func waitForToken(tokens float64, limit entity.RateLimit) time.Duration {
+	return time.Duration((1 - tokens) / limit.PerSecond() * float64(time.Second))
+}
+
+// bucket is token bucket of key.
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	limit   entity.RateLimit
+}
+
+// refill gets tokens of bucket at moment now.
+func (b *bucket) refill(now time.Time) float64 {
+	tokens := b.tokens + now.Sub(b.updated).Seconds()*b.limit.PerSecond()
+	if burst := float64(b.limit.Burst); tokens > burst {
+		return burst
+	}
+	return tokens
+}
+
+// memoryLimiter is rate limiter of one replica.
+type memoryLimiter struct {
+	mu        sync.Mutex
+	now       func() time.Time
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// newMemoryLimiter creates rate limiter in memory.
+func newMemoryLimiter(now func() time.Time) *memoryLimiter {
+	return &memoryLimiter{
+		now:       now,
+		buckets:   make(map[string]*bucket),
+		lastSweep: now(),
+	}
+}
+
+// Take takes token from bucket of key.
+func (l *memoryLimiter) Take(key string, limit entity.RateLimit) (time.Duration, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if now.Sub(l.lastSweep) >= sweepInterval {
+		l.sweep(now)
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), updated: now}
+		l.buckets[key] = b
+	}
+	b.limit = limit
+	b.tokens, b.updated = b.refill(now), now
+
+	if b.tokens < 1 {
+		return waitForToken(b.tokens, limit), nil
+	}
+	b.tokens--
+	return 0, nil
+}
+
+// sweep removes full buckets, they are the same as missing ones.
+func (l *memoryLimiter) sweep(now time.Time) {
+	for key, b := range l.buckets {
+		if b.refill(now) >= float64(b.limit.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
+
+// Take takes token from bucket of key in rate_limits table.
+// Bucket is refilled and taken by one statement, so replicas can't take the same token.
+func (s *dbStorage) Take(key string, limit entity.RateLimit) (time.Duration, error) {
+	var (
+		tokens  float64
+		allowed bool
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	row := s.db.QueryRowContext(
+		ctx,
+		`INSERT INTO rate_limits AS r (key, tokens, allowed, updated_at) VALUES ($1, $2::float8 - 1, true, now())
+		ON CONFLICT (key) DO UPDATE SET
+		allowed = LEAST($2::float8, r.tokens + EXTRACT(EPOCH FROM now() - r.updated_at) * $3::float8) >= 1,
+		tokens = LEAST($2::float8, r.tokens + EXTRACT(EPOCH FROM now() - r.updated_at) * $3::float8)
+			- CASE WHEN LEAST($2::float8, r.tokens + EXTRACT(EPOCH FROM now() - r.updated_at) * $3::float8) >= 1 THEN 1 ELSE 0 END,
+		updated_at = now()
+		RETURNING tokens, allowed`,
+		key, float64(limit.Burst), limit.PerSecond(),
+	)
+	if err := row.Scan(&tokens, &allowed); err != nil {
+		return 0, err
+	}
+	if allowed {
+		return 0, nil
+	}
+	return waitForToken(tokens, limit), nil
+}
@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bbt-t/lets-go-shortener/internal/config"
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewLimiter(t *testing.T) {
+	cfg := config.GetDefaultConfig()
+	s, err := NewMapStorage(cfg)
+	require.NoError(t, err)
+
+	limiter, err := NewLimiter(cfg, s)
+	require.NoError(t, err)
+	assert.IsType(t, &memoryLimiter{}, limiter)
+
+	cfg.RateLimitShared = true
+	_, err = NewLimiter(cfg, s)
+	assert.Equal(t, ErrNoSharedLimiter, err)
+}
+
+func TestMemoryLimiter(t *testing.T) {
+	now := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	limiter := newMemoryLimiter(func() time.Time { return now })
+	limit := entity.RateLimit{Burst: 2, Period: time.Second}
+
+	take := func(key string) time.Duration {
+		wait, err := limiter.Take(key, limit)
+		require.NoError(t, err)
+		return wait
+	}
+
+	assert.Zero(t, take("a"))
+	assert.Zero(t, take("a"))
+	assert.Equal(t, 500*time.Millisecond, take("a"))
+	assert.Zero(t, take("b"), "buckets of keys are separate")
+
+	now = now.Add(250 * time.Millisecond)
+	assert.Equal(t, 250*time.Millisecond, take("a"))
+
+	now = now.Add(250 * time.Millisecond)
+	assert.Zero(t, take("a"))
+	assert.Equal(t, 500*time.Millisecond, take("a"))
+
+	// bucket isn't refilled above burst.
+	now = now.Add(time.Hour)
+	assert.Zero(t, take("a"))
+	assert.Zero(t, take("a"))
+	assert.NotZero(t, take("a"))
+
+	// full buckets are swept.
+	now = now.Add(sweepInterval)
+	assert.Zero(t, take("c"))
+	assert.Len(t, limiter.buckets, 1)
+}
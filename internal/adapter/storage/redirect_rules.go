@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+)
+
+// SetRedirectRules replaces redirect rules of link of user, empty rules remove them.
+func (s *MapStorage) SetRedirectRules(userID, id string, rules []entity.RedirectRule) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.Locations[id]; !ok || s.owners()[id] != userID {
+		return ErrNotFound
+	}
+	if s.Deleted[id] || s.Disabled[id] {
+		return ErrDeleted
+	}
+
+	if len(rules) == 0 {
+		delete(s.Rules, id)
+		return nil
+	}
+	if s.Rules == nil {
+		s.Rules = make(map[string][]entity.RedirectRule)
+	}
+	s.Rules[id] = rules
+	return nil
+}
+
+// GetRedirectRules gets redirect rules of link in order of evaluation.
+func (s *MapStorage) GetRedirectRules(id string) ([]entity.RedirectRule, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.Locations[id]; !ok {
+		return nil, ErrNotFound
+	}
+	return s.Rules[id], nil
+}
+
+// SetRedirectRules replaces redirect rules of link of user, empty rules remove them.
+func (s *fileStorage) SetRedirectRules(userID, id string, rules []entity.RedirectRule) error {
+	s.Lock()
+	defer s.Unlock()
+
+	record, ok := s.records[id]
+	if !ok || record.UserID != userID {
+		return ErrNotFound
+	}
+	if record.Deleted || record.Disabled {
+		return ErrDeleted
+	}
+
+	record.Rules = rules
+	if len(rules) == 0 {
+		record.Rules = nil
+	}
+	return s.write(record)
+}
+
+// GetRedirectRules gets redirect rules of link in order of evaluation.
+func (s *fileStorage) GetRedirectRules(id string) ([]entity.RedirectRule, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	record, ok := s.records[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return record.Rules, nil
+}
+
+// SetRedirectRules replaces redirect rules of link of user, empty rules remove them. Rules are kept as JSON array.
+func (s *dbStorage) SetRedirectRules(userID, id string, rules []entity.RedirectRule) error {
+	var deleted sql.NullBool
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	var value sql.NullString
+	if len(rules) != 0 {
+		data, err := json.Marshal(rules)
+		if err != nil {
+			return err
+		}
+		value = sql.NullString{String: string(data), Valid: true}
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	row := tx.QueryRowContext(
+		ctx,
+		"SELECT deleted OR disabled FROM items WHERE id = $1 AND cookie = $2 FOR UPDATE",
+		id, userID,
+	)
+	err = row.Scan(&deleted)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if deleted.Bool {
+		return ErrDeleted
+	}
+
+	if _, err = tx.ExecContext(ctx, "UPDATE items SET redirect_rules = $1 WHERE id = $2", value, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetRedirectRules gets redirect rules of link in order of evaluation.
+func (s *dbStorage) GetRedirectRules(id string) ([]entity.RedirectRule, error) {
+	var (
+		value []byte
+		rules []entity.RedirectRule
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	row := s.db.QueryRowContext(ctx, "SELECT redirect_rules FROM items WHERE id = $1 LIMIT 1", id)
+	err := row.Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil || value == nil {
+		return nil, err
+	}
+	err = json.Unmarshal(value, &rules)
+	return rules, err
+}
@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedirectRules(t *testing.T) {
+	storages, cfg := testStorages(t)
+
+	rules := []entity.RedirectRule{
+		{Platform: entity.PlatformIOS, URL: "https://apps.apple.com/app/id1"},
+		{Language: "ru", Country: "RU", From: "09:00", To: "18:00", URL: "https://example.ru"},
+	}
+	var (
+		ids []string
+		err error
+	)
+	for name, s := range storages {
+		ids, err = s.CreateShort("user1", "https://example.com", "https://example.org")
+		require.NoError(t, err, name)
+
+		got, err := s.GetRedirectRules(ids[0])
+		require.NoError(t, err, name)
+		assert.Empty(t, got, name)
+		_, err = s.GetRedirectRules("100")
+		assert.ErrorIs(t, err, ErrNotFound, name)
+
+		assert.ErrorIs(t, s.SetRedirectRules("user2", ids[0], rules), ErrNotFound, name, "only owner sets rules")
+		require.NoError(t, s.SetRedirectRules("user1", ids[0], rules), name)
+		require.NoError(t, s.SetRedirectRules("user1", ids[1], rules[:1]), name)
+		got, err = s.GetRedirectRules(ids[0])
+		require.NoError(t, err, name)
+		assert.Equal(t, rules, got, name)
+
+		require.NoError(t, s.SetRedirectRules("user1", ids[1], nil), name)
+		got, err = s.GetRedirectRules(ids[1])
+		require.NoError(t, err, name)
+		assert.Empty(t, got, name)
+
+		require.NoError(t, s.MarkAsDeleted("user1", ids[1]), name)
+		assert.ErrorIs(t, s.SetRedirectRules("user1", ids[1], rules), ErrDeleted, name)
+	}
+
+	restarted, err := newFileStorage(cfg)
+	require.NoError(t, err)
+	got, err := restarted.GetRedirectRules(ids[0])
+	require.NoError(t, err)
+	assert.Equal(t, rules, got)
+}
@@ -3,6 +3,8 @@
 package storage
 
 import (
+	"time"
+
 	"github.com/bbt-t/lets-go-shortener/internal/config"
 	"github.com/bbt-t/lets-go-shortener/internal/entity"
 )
@@ -10,12 +12,61 @@ import (
 // Repository is an interface that describes storage.
 type Repository interface {
 	CreateShort(userID string, urls ...string) ([]string, error)
+	GetUserURLIDs(userID string, urls ...string) (map[string]string, error)
 	GetOriginal(id string) (string, error)
 	MarkAsDeleted(userID string, ids ...string) error
-	GetURLArrayByUser(userID string) ([]entity.URLs, error)
+	GetURLArrayByUser(userID string, filter entity.HistoryFilter) ([]entity.URLs, error)
 	PingDB() error
 	GetConfig() config.Config
 	GetStatistic() (entity.Statistic, error)
+
+	AddAPIKey(key entity.APIKey) error
+	GetAPIKey(id string) (entity.APIKey, error)
+	GetAPIKeysByUser(userID string) ([]entity.APIKey, error)
+	RevokeAPIKey(userID, id string) error
+
+	AddUser(user entity.User) error
+	GetUserByLogin(login string) (entity.User, error)
+	AddSession(session entity.Session) error
+	GetSession(hash string) (entity.Session, error)
+	DeleteSession(hash string) error
+	ClaimURLs(fromUserID, toUserID string) (int, error)
+
+	SearchURLs(filter entity.LinkFilter) ([]entity.LinkInfo, error)
+	GetURLInfo(id string) (entity.LinkInfo, error)
+	SetDisabled(id string, disabled bool) error
+	ReassignURL(id, userID string) error
+
+	CountUserURLs(userID string, since time.Time) (active, created int, err error)
+
+	CreateShortWithOptions(userID, original, passwordHash string, maxClicks int) (string, error)
+	GetPasswordHash(id string) (string, error)
+	TakeClick(id string) error
+
+	UpdateURL(userID, id, original string) error
+	GetURLVersions(id string) ([]entity.LinkVersion, error)
+
+	SetURLMeta(userID, id string, meta entity.LinkMeta) error
+	GetURLMeta(id string) (entity.LinkMeta, error)
+	GetUserURL(userID, id string) (entity.URLs, error)
+
+	SetURLHealth(id string, health entity.LinkHealth) error
+	GetHealthTargets(now time.Time, limit int) ([]entity.HealthTarget, error)
+	GetHealthReport(filter entity.HealthFilter) ([]entity.LinkHealthInfo, error)
+
+	AddWebhook(hook entity.Webhook) error
+	GetWebhook(id string) (entity.Webhook, error)
+	GetWebhooksByUser(userID string) ([]entity.Webhook, error)
+	DeleteWebhook(userID, id string) error
+	AddWebhookDeliveries(deliveries ...entity.WebhookDelivery) error
+	GetDueDeliveries(now time.Time, limit int) ([]entity.WebhookDelivery, error)
+	UpdateDelivery(delivery entity.WebhookDelivery) error
+	DeleteDelivery(id int64) error
+	GetDeadDeliveries(userID string) ([]entity.WebhookDelivery, error)
+	RequeueDelivery(userID string, id int64, at time.Time) error
+
+	SetRedirectRules(userID, id string, rules []entity.RedirectRule) error
+	GetRedirectRules(id string) ([]entity.RedirectRule, error)
 }
 
 // NewStorage creates new storage based on config.
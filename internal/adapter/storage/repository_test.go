@@ -0,0 +1,24 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/bbt-t/lets-go-shortener/internal/config"
+
+	"github.com/stretchr/testify/require"
+)
+
+// testStorages gets map and file storages by their names, file of storage is in temp dir of test.
+// Config is returned to restart file storage.
+func testStorages(t *testing.T) (map[string]Repository, config.Config) {
+	cfg := config.GetTestConfig()
+	cfg.StoragePath = filepath.Join(t.TempDir(), "file_storage.db")
+
+	mapStorage, err := NewMapStorage(cfg)
+	require.NoError(t, err)
+	fileStorage, err := newFileStorage(cfg)
+	require.NoError(t, err)
+
+	return map[string]Repository{"map": mapStorage, "file": fileStorage}, cfg
+}
@@ -2,22 +2,61 @@ package storage
 
 import (
 	"bufio"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"os"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/bbt-t/lets-go-shortener/internal/config"
 	"github.com/bbt-t/lets-go-shortener/internal/entity"
 )
 
-// fileStorage struct of file storage.
+// fileRecord is line of storage file. Changed record is appended again, last line of id wins.
+// Old files with one original url per line are read as records without owner.
+type fileRecord struct {
+	ID           string                `json:"id"`
+	URL          string                `json:"url"`
+	UserID       string                `json:"user_id,omitempty"`
+	Deleted      bool                  `json:"deleted,omitempty"`
+	Disabled     bool                  `json:"disabled,omitempty"`
+	PasswordHash string                `json:"password_hash,omitempty"`
+	MaxClicks    int                   `json:"max_clicks,omitempty"`
+	Clicks       int                   `json:"clicks,omitempty"`
+	Versions     []entity.LinkVersion  `json:"versions,omitempty"`
+	Title        string                `json:"title,omitempty"`
+	Tags         []string              `json:"tags,omitempty"`
+	Note         string                `json:"note,omitempty"`
+	Interstitial bool                  `json:"interstitial,omitempty"`
+	Health       *entity.LinkHealth    `json:"health,omitempty"`
+	Rules        []entity.RedirectRule `json:"rules,omitempty"`
+	CreatedAt    time.Time             `json:"created_at"`
+}
+
+// clickFlushInterval is how often clicks of unlimited links are saved to file.
+const clickFlushInterval = time.Minute
+
+// fileStorage struct of file storage. Records are kept in memory, file is append-only journal.
+// Unsaved are ids of records with clicks which aren't saved to file since flushedAt.
+// Deliveries are queue of webhooks, deliverySeq is id of last queued delivery.
 type fileStorage struct {
-	cfg    config.Config
-	file   *os.File
-	lastID int
+	cfg         config.Config
+	file        *os.File
+	lastID      int
+	records     map[string]fileRecord
+	unsaved     map[string]bool
+	flushedAt   time.Time
+	order       []string
+	byURL       map[string]string
+	byUser      map[string][]string
+	keys        map[string]entity.APIKey
+	accounts    map[string]entity.User
+	sessions    map[string]entity.Session
+	webhooks    map[string]entity.Webhook
+	deliveries  map[int64]entity.WebhookDelivery
+	deliverySeq int64
 	*sync.Mutex
 }
 
@@ -33,118 +72,346 @@ func (s *fileStorage) PingDB() error {
 
 // newFileStorage creates new file storage.
 func newFileStorage(cfg config.Config) (*fileStorage, error) {
-	var id int
-
-	s := &fileStorage{cfg: cfg, Mutex: &sync.Mutex{}}
+	s := &fileStorage{
+		cfg:        cfg,
+		records:    make(map[string]fileRecord),
+		unsaved:    make(map[string]bool),
+		flushedAt:  time.Now(),
+		byURL:      make(map[string]string),
+		byUser:     make(map[string][]string),
+		keys:       make(map[string]entity.APIKey),
+		accounts:   make(map[string]entity.User),
+		sessions:   make(map[string]entity.Session),
+		webhooks:   make(map[string]entity.Webhook),
+		deliveries: make(map[int64]entity.WebhookDelivery),
+		Mutex:      &sync.Mutex{},
+	}
 
 	if cfg.StoragePath == "" {
 		return s, errors.New("empty file path")
 	}
-	file, err := os.OpenFile(
-		cfg.StoragePath,
-		os.O_RDWR|os.O_APPEND|os.O_CREATE|os.O_SYNC,
-		0700,
-	)
+	file, err := openJournal(cfg.StoragePath)
 	if err != nil {
 		return s, err
 	}
 
 	s.file = file
-	scanner := bufio.NewScanner(file)
+	if err = s.load(); err != nil {
+		return s, err
+	}
+
+	if err = s.loadKeys(); err != nil {
+		return s, err
+	}
+	if err = s.loadUsers(); err != nil {
+		return s, err
+	}
+	if err = s.loadWebhooks(); err != nil {
+		return s, err
+	}
+
+	return s, nil
+}
+
+// openJournal opens file of records for appending.
+func openJournal(path string) (*os.File, error) {
+	return os.OpenFile(path, os.O_RDWR|os.O_APPEND|os.O_CREATE|os.O_SYNC, 0700)
+}
+
+// load reads records from file. File is compacted if it has old states of records.
+func (s *fileStorage) load() error {
+	var line int
+
+	scanner := bufio.NewScanner(s.file)
 	for scanner.Scan() {
-		id++
+		var record fileRecord
+
+		text := scanner.Text()
+		if strings.HasPrefix(text, "{") {
+			if err := json.Unmarshal([]byte(text), &record); err != nil {
+				return fmt.Errorf("wrong record on line %d: %w", line+1, err)
+			}
+		} else {
+			record = fileRecord{ID: fmt.Sprint(len(s.order) + 1), URL: text}
+		}
+		line++
+
+		s.put(record)
 	}
+
+	s.lastID = len(s.order)
 	if err := scanner.Err(); err != nil {
-		return s, err
+		return err
 	}
+	if line > len(s.order) {
+		return s.compact()
+	}
+	return nil
+}
 
-	s.lastID = id
+// compact rewrites file with the last states of records.
+// New file is written beside and renamed, so file isn't lost if rewrite fails.
+func (s *fileStorage) compact() error {
+	records := make([]fileRecord, 0, len(s.order))
+	for _, id := range s.order {
+		records = append(records, s.records[id])
+	}
+	lines, err := encodeRecords(records...)
+	if err != nil {
+		return err
+	}
 
-	return s, nil
+	path := s.cfg.StoragePath + ".tmp"
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0700)
+	if err != nil {
+		return err
+	}
+	if _, err = file.WriteString(lines); err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if err = os.Rename(path, s.cfg.StoragePath); err != nil {
+		return err
+	}
+
+	if file, err = openJournal(s.cfg.StoragePath); err != nil {
+		return err
+	}
+	s.file.Close()
+	s.file = file
+	return nil
+}
+
+// encodeRecords gets lines of records for file.
+func encodeRecords(records ...fileRecord) (string, error) {
+	var builder strings.Builder
+
+	for _, record := range records {
+		line, err := json.Marshal(record)
+		if err != nil {
+			return "", err
+		}
+		builder.Write(line)
+		builder.WriteRune('\n')
+	}
+	return builder.String(), nil
+}
+
+// write appends records to file and keeps them in memory.
+func (s *fileStorage) write(records ...fileRecord) error {
+	lines, err := encodeRecords(records...)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.file.Write([]byte(lines)); err != nil {
+		return err
+	}
+	if err := s.file.Sync(); err != nil {
+		return err
+	}
+
+	for _, record := range records {
+		s.put(record)
+		delete(s.unsaved, record.ID)
+	}
+	return nil
+}
+
+// flushClicks saves records with unsaved clicks if flush interval is passed since last flush.
+func (s *fileStorage) flushClicks(now time.Time) error {
+	if len(s.unsaved) == 0 || now.Sub(s.flushedAt) < clickFlushInterval {
+		return nil
+	}
+
+	records := make([]fileRecord, 0, len(s.unsaved))
+	for id := range s.unsaved {
+		records = append(records, s.records[id])
+	}
+	s.flushedAt = now
+	return s.write(records...)
+}
+
+// dedupKey gets key of record in index of urls, urls are deduplicated per owner.
+func (r fileRecord) dedupKey() string {
+	return r.UserID + "\x00" + r.URL
+}
+
+// put keeps record in memory. Original url of owner is indexed by its first id, links with options aren't indexed.
+func (s *fileStorage) put(record fileRecord) {
+	previous, ok := s.records[record.ID]
+	if !ok {
+		s.order = append(s.order, record.ID)
+	}
+	s.records[record.ID] = record
+
+	if !ok || previous.UserID != record.UserID {
+		if ok {
+			s.unlinkOwner(previous)
+		}
+		s.byUser[record.UserID] = append(s.byUser[record.UserID], record.ID)
+	}
+
+	if ok && previous.dedupKey() != record.dedupKey() && s.byURL[previous.dedupKey()] == record.ID {
+		// retargeted or reassigned link gives its place in index to other link of the same url.
+		delete(s.byURL, previous.dedupKey())
+		for _, id := range s.order {
+			s.index(s.records[id])
+		}
+	}
+	s.index(record)
+}
+
+// unlinkOwner removes record from ids of its owner.
+func (s *fileStorage) unlinkOwner(record fileRecord) {
+	ids := s.byUser[record.UserID]
+	for i, id := range ids {
+		if id == record.ID {
+			ids = append(ids[:i:i], ids[i+1:]...)
+			break
+		}
+	}
+	if len(ids) == 0 {
+		delete(s.byUser, record.UserID)
+		return
+	}
+	s.byUser[record.UserID] = ids
+}
+
+// index indexes record by url of owner if url isn't indexed yet.
+func (s *fileStorage) index(record fileRecord) {
+	if record.PasswordHash != "" || record.MaxClicks != 0 {
+		return
+	}
+	if _, ok := s.byURL[record.dedupKey()]; !ok {
+		s.byURL[record.dedupKey()] = record.ID
+	}
 }
 
 // CreateShort creates short url from original.
+// Returns ErrExists with id of url already saved by user, the same url in batch is saved once.
 func (s *fileStorage) CreateShort(userID string, urls ...string) ([]string, error) {
-	var builder strings.Builder
+	var err error
 
 	s.Lock()
 	defer s.Unlock()
 
-	s.file.Seek(2, io.SeekEnd)
-
 	result := make([]string, 0, len(urls))
+	records := make([]fileRecord, 0, len(urls))
+	added := make(map[string]string)
+	now := time.Now().UTC()
 
 	for _, original := range urls {
-		builder.WriteString(original)
-		builder.WriteRune('\n')
-		s.lastID++
-		result = append(result, fmt.Sprint(s.lastID))
+		if id, ok := s.byURL[fileRecord{UserID: userID, URL: original}.dedupKey()]; ok {
+			result, err = append(result, id), ErrExists
+			continue
+		}
+		if id, ok := added[original]; ok {
+			result, err = append(result, id), ErrExists
+			continue
+		}
+
+		id := fmt.Sprint(s.lastID + len(records) + 1)
+		records = append(records, fileRecord{ID: id, URL: original, UserID: userID, CreatedAt: now})
+		added[original] = id
+		result = append(result, id)
 	}
 
-	_, err := s.file.Write([]byte(builder.String()))
-	if err != nil {
-		return nil, err
+	if len(records) == 0 {
+		return result, err
 	}
-	err = s.file.Sync()
-	if err != nil {
+	if err := s.write(records...); err != nil {
 		return nil, err
 	}
+	s.lastID += len(records)
+
+	return result, err
+}
+
+// GetUserURLIDs gets ids of links of user by their original urls, urls are matched like by CreateShort.
+func (s *fileStorage) GetUserURLIDs(userID string, urls ...string) (map[string]string, error) {
+	s.Lock()
+	defer s.Unlock()
 
+	result := make(map[string]string)
+	for _, original := range urls {
+		if id, ok := s.byURL[fileRecord{UserID: userID, URL: original}.dedupKey()]; ok {
+			result[original] = id
+		}
+	}
 	return result, nil
 }
 
 // GetOriginal gets original url from short.
 func (s *fileStorage) GetOriginal(id string) (string, error) {
-	var i int
-
 	s.Lock()
 	defer s.Unlock()
 
-	s.file.Seek(0, io.SeekStart)
-
-	scanner := bufio.NewScanner(s.file)
-
-	for scanner.Scan() {
-		i++
-		original := scanner.Text()
-		if fmt.Sprint(i) == id {
-			return original, scanner.Err()
-		}
+	record, ok := s.records[id]
+	if !ok {
+		return "", ErrNotFound
+	}
+	if record.Deleted || record.Disabled {
+		return record.URL, ErrDeleted
 	}
-	return "", ErrNotFound
+	if record.exhausted() {
+		return record.URL, ErrExhausted
+	}
+	return record.URL, nil
 }
 
-// MarkAsDeleted does nothing.
+// MarkAsDeleted deletes urls of user.
 func (s *fileStorage) MarkAsDeleted(userID string, ids ...string) error {
-	// do nothing for file storage.
-	return nil
+	s.Lock()
+	defer s.Unlock()
+
+	changed := make([]fileRecord, 0, len(ids))
+	for _, id := range ids {
+		record, ok := s.records[id]
+		if !ok || record.UserID != userID || record.Deleted {
+			continue
+		}
+		record.Deleted = true
+		changed = append(changed, record)
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+	return s.write(changed...)
 }
 
-// GetURLArrayByUser gets history of urls.
-func (s *fileStorage) GetURLArrayByUser(_ string) ([]entity.URLs, error) {
-	var (
-		id      int
-		allURLs []entity.URLs
-	)
+// GetURLArrayByUser gets page of urls of user which match filter.
+func (s *fileStorage) GetURLArrayByUser(userID string, filter entity.HistoryFilter) ([]entity.URLs, error) {
+	s.Lock()
+	defer s.Unlock()
 
-	scanner := bufio.NewScanner(s.file)
-	for scanner.Scan() {
-		id++
-		original := scanner.Text()
-		allURLs = append(
-			allURLs, entity.URLs{
-				ShortURL:    fmt.Sprintf("%s/%v", s.cfg.BaseURL, id),
-				OriginalURL: original,
-			},
-		)
+	entries := make([]historyEntry, 0, len(s.byUser[userID]))
+	for _, id := range s.byUser[userID] {
+		if item := s.historyItem(s.records[id]); matchHistory(filter, item) {
+			entries = append(entries, newHistoryEntry(id, item))
+		}
 	}
-	return allURLs, scanner.Err()
+	return pageHistory(filter, entries), nil
 }
 
 // GetStatistic gets total count of users and urls.
 func (s *fileStorage) GetStatistic() (entity.Statistic, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	users := make(map[string]bool)
+	for _, record := range s.records {
+		if record.UserID != "" {
+			users[record.UserID] = true
+		}
+	}
 	return entity.Statistic{
 		Urls:  s.lastID,
-		Users: 0,
+		Users: len(users),
 	}, nil
 }
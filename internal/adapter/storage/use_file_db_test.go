@@ -1,14 +1,17 @@
 package storage
 
 import (
+	"path/filepath"
 	"testing"
 
 	"github.com/bbt-t/lets-go-shortener/internal/config"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestFileStorage_GetConfig(t *testing.T) {
 	cfg := config.GetTestConfig()
+	cfg.StoragePath = filepath.Join(t.TempDir(), "file_storage.db")
 	s, err := newFileStorage(cfg)
 
 	assert.NoError(t, err)
@@ -17,7 +20,9 @@ func TestFileStorage_GetConfig(t *testing.T) {
 }
 
 func TestFileStorage_PingDB(t *testing.T) {
-	s, err := newFileStorage(config.GetTestConfig())
+	cfg := config.GetTestConfig()
+	cfg.StoragePath = filepath.Join(t.TempDir(), "file_storage.db")
+	s, err := newFileStorage(cfg)
 
 	assert.NoError(t, err)
 	assert.NoError(t, s.PingDB())
@@ -25,7 +30,9 @@ func TestFileStorage_PingDB(t *testing.T) {
 }
 
 func TestFileStorage_MarkAsDeleted(t *testing.T) {
-	s, err := newFileStorage(config.GetTestConfig())
+	cfg := config.GetTestConfig()
+	cfg.StoragePath = filepath.Join(t.TempDir(), "file_storage.db")
+	s, err := newFileStorage(cfg)
 
 	assert.NoError(t, err)
 
@@ -34,3 +41,51 @@ func TestFileStorage_MarkAsDeleted(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NoError(t, err)
 }
+
+func TestFileStorage_CreateShortExists(t *testing.T) {
+	cfg := config.GetTestConfig()
+	cfg.StoragePath = filepath.Join(t.TempDir(), "file_storage.db")
+	s, err := newFileStorage(cfg)
+	require.NoError(t, err)
+
+	ids, err := s.CreateShort("user1", "https://yandex.ru", "https://ya.ru", "https://yandex.ru")
+	assert.ErrorIs(t, err, ErrExists, "the same url in batch")
+	assert.Equal(t, []string{"1", "2", "1"}, ids)
+
+	ids, err = s.CreateShort("user1", "https://ya.ru", "https://go.dev")
+	assert.ErrorIs(t, err, ErrExists)
+	assert.Equal(t, []string{"2", "3"}, ids)
+
+	// urls are deduplicated per owner.
+	ids, err = s.CreateShort("user2", "https://ya.ru")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"4"}, ids)
+
+	// urls are found after restart.
+	restarted, err := newFileStorage(cfg)
+	require.NoError(t, err)
+	ids, err = restarted.CreateShort("user1", "https://go.dev")
+	assert.ErrorIs(t, err, ErrExists)
+	assert.Equal(t, []string{"3"}, ids)
+
+	statistic, err := restarted.GetStatistic()
+	require.NoError(t, err)
+	assert.Equal(t, 4, statistic.Urls)
+}
+
+func TestGetUserURLIDs(t *testing.T) {
+	storages, _ := testStorages(t)
+
+	for name, s := range storages {
+		ids, err := s.CreateShort("user1", "https://yandex.ru", "https://ya.ru")
+		require.NoError(t, err, name)
+		_, err = s.CreateShortWithOptions("user1", "https://go.dev", "", 5)
+		require.NoError(t, err, name)
+		_, err = s.CreateShort("user2", "https://go.dev")
+		require.NoError(t, err, name)
+
+		owned, err := s.GetUserURLIDs("user1", "https://ya.ru", "https://go.dev", "https://example.com")
+		require.NoError(t, err, name)
+		assert.Equal(t, map[string]string{"https://ya.ru": ids[1]}, owned, name, "links with options aren't matched")
+	}
+}
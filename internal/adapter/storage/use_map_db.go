@@ -4,17 +4,33 @@ import (
 	"fmt"
 	"net/url"
 	"sync"
+	"time"
 
 	"github.com/bbt-t/lets-go-shortener/internal/config"
 	"github.com/bbt-t/lets-go-shortener/internal/entity"
 )
 
-// MapStorage is storage that storages in map.
+// MapStorage is storage that storages in map. DeliverySeq is id of last queued webhook delivery.
 type MapStorage struct {
-	Cfg       config.Config
-	Locations map[string]string
-	Users     map[string][]string
-	Deleted   map[string]bool
+	Cfg         config.Config
+	Locations   map[string]string
+	Users       map[string][]string
+	Deleted     map[string]bool
+	Disabled    map[string]bool
+	Created     map[string]time.Time
+	Passwords   map[string]string
+	ClicksLeft  map[string]int
+	Versions    map[string][]entity.LinkVersion
+	Meta        map[string]entity.LinkMeta
+	Clicks      map[string]int
+	Health      map[string]entity.LinkHealth
+	Rules       map[string][]entity.RedirectRule
+	APIKeys     map[string]entity.APIKey
+	Accounts    map[string]entity.User
+	Sessions    map[string]entity.Session
+	Webhooks    map[string]entity.Webhook
+	Deliveries  map[int64]entity.WebhookDelivery
+	DeliverySeq int64
 	*sync.Mutex
 }
 
@@ -24,6 +40,11 @@ func NewMapStorage(cfg config.Config) (*MapStorage, error) {
 		Locations: make(map[string]string),
 		Users:     make(map[string][]string),
 		Deleted:   make(map[string]bool),
+		Disabled:  make(map[string]bool),
+		Created:   make(map[string]time.Time),
+		APIKeys:   make(map[string]entity.APIKey),
+		Accounts:  make(map[string]entity.User),
+		Sessions:  make(map[string]entity.Session),
 		Cfg:       cfg,
 		Mutex:     &sync.Mutex{},
 	}, nil
@@ -55,8 +76,8 @@ func (s *MapStorage) CreateShort(userID string, urls ...string) ([]string, error
 		var foundThisURL bool
 		newID := fmt.Sprint(len(s.Locations) + 1)
 
-		for id, originalURL := range s.Locations {
-			if originalURL == longURL {
+		for _, id := range s.Users[userID] {
+			if s.Locations[id] == longURL && !s.hasOptions(id) {
 				newID = id
 				err = ErrExists
 				foundThisURL = true
@@ -69,10 +90,34 @@ func (s *MapStorage) CreateShort(userID string, urls ...string) ([]string, error
 		}
 
 		s.Locations[newID], s.Users[userID] = longURL, append(s.Users[userID], newID)
+		if s.Created == nil {
+			s.Created = make(map[string]time.Time)
+		}
+		s.Created[newID] = time.Now().UTC()
 	}
 	return result, err
 }
 
+// GetUserURLIDs gets ids of links of user by their original urls, urls are matched like by CreateShort.
+func (s *MapStorage) GetUserURLIDs(userID string, urls ...string) (map[string]string, error) {
+	wanted := make(map[string]bool, len(urls))
+	for _, original := range urls {
+		wanted[original] = true
+	}
+	result := make(map[string]string)
+
+	s.Lock()
+	defer s.Unlock()
+
+	for _, id := range s.Users[userID] {
+		original := s.Locations[id]
+		if _, ok := result[original]; !ok && wanted[original] && !s.hasOptions(id) {
+			result[original] = id
+		}
+	}
+	return result, nil
+}
+
 // GetOriginal gets original url from short.
 func (s *MapStorage) GetOriginal(id string) (string, error) {
 	var err error
@@ -81,8 +126,10 @@ func (s *MapStorage) GetOriginal(id string) (string, error) {
 	defer s.Unlock()
 
 	if item, ok := s.Locations[id]; ok {
-		if s.Deleted[id] {
+		if s.Deleted[id] || s.Disabled[id] {
 			err = ErrDeleted
+		} else if left, limited := s.ClicksLeft[id]; limited && left <= 0 {
+			err = ErrExhausted
 		}
 		return item, err
 	}
@@ -105,22 +152,18 @@ func (s *MapStorage) MarkAsDeleted(userID string, ids ...string) error {
 	return nil
 }
 
-// GetURLArrayByUser gets all urls.
-func (s *MapStorage) GetURLArrayByUser(userID string) ([]entity.URLs, error) {
+// GetURLArrayByUser gets page of urls of user which match filter.
+func (s *MapStorage) GetURLArrayByUser(userID string, filter entity.HistoryFilter) ([]entity.URLs, error) {
 	s.Lock()
 	defer s.Unlock()
 
-	allShort := s.Users[userID]
-	history := make([]entity.URLs, len(allShort))
-
-	for i, id := range allShort {
-		original := s.Locations[id]
-		history[i] = entity.URLs{
-			ShortURL:    fmt.Sprintf("%s/%v", s.Cfg.BaseURL, id),
-			OriginalURL: original,
+	entries := make([]historyEntry, 0, len(s.Users[userID]))
+	for _, id := range s.Users[userID] {
+		if item := s.historyItem(id); matchHistory(filter, item) {
+			entries = append(entries, newHistoryEntry(id, item))
 		}
 	}
-	return history, nil
+	return pageHistory(filter, entries), nil
 }
 
 // GetStatistic gets total count of users and urls.
@@ -273,7 +273,7 @@ func TestMapStorage_GetURLArrayByUser(t *testing.T) {
 		s.Locations = test.loc
 		s.Users = test.users
 
-		res, err := s.GetURLArrayByUser(test.cookie)
+		res, err := s.GetURLArrayByUser(test.cookie, entity.HistoryFilter{})
 		assert.Equal(t, test.err, err)
 		assert.Equal(t, test.want, res)
 	}
@@ -1,10 +1,8 @@
 package storage
 
 import (
-	"context"
 	"database/sql"
 	"log"
-	"time"
 
 	"github.com/bbt-t/lets-go-shortener/internal/config"
 
@@ -16,9 +14,8 @@ import (
 
 // dbStorage is storage that uses db.
 type dbStorage struct {
-	cfg    config.Config
-	db     *sql.DB
-	lastID int
+	cfg config.Config
+	db  *sql.DB
 }
 
 // GetConfig gets config from storage.
@@ -36,9 +33,6 @@ func newDBStorage(cfg config.Config) (*dbStorage, error) {
 		return s, err
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
-	defer cancel()
-
 	err = MigrateUP(db, cfg)
 
 	if err != nil {
@@ -46,19 +40,6 @@ func newDBStorage(cfg config.Config) (*dbStorage, error) {
 		return s, err
 	}
 
-	row := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM items")
-
-	err = row.Scan(&s.lastID)
-
-	if err != nil {
-		return s, err
-	}
-
-	err = row.Err()
-	if err != nil {
-		return s, err
-	}
-
 	s.db = db
 
 	return s, nil
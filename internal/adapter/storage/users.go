@@ -0,0 +1,319 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+)
+
+// AddUser saves new user. Returns ErrExists if login is taken.
+func (s *MapStorage) AddUser(user entity.User) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.Accounts == nil {
+		s.Accounts = make(map[string]entity.User)
+	}
+	if _, ok := s.Accounts[user.Login]; ok {
+		return ErrExists
+	}
+	s.Accounts[user.Login] = user
+	return nil
+}
+
+// GetUserByLogin gets user by login.
+func (s *MapStorage) GetUserByLogin(login string) (entity.User, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	user, ok := s.Accounts[login]
+	if !ok {
+		return entity.User{}, ErrNotFound
+	}
+	return user, nil
+}
+
+// AddSession saves new session.
+func (s *MapStorage) AddSession(session entity.Session) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.Sessions == nil {
+		s.Sessions = make(map[string]entity.Session)
+	}
+	s.Sessions[session.Hash] = session
+	return nil
+}
+
+// GetSession gets session by hash of token.
+func (s *MapStorage) GetSession(hash string) (entity.Session, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	session, ok := s.Sessions[hash]
+	if !ok {
+		return entity.Session{}, ErrNotFound
+	}
+	return session, nil
+}
+
+// DeleteSession deletes session by hash of token.
+func (s *MapStorage) DeleteSession(hash string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	delete(s.Sessions, hash)
+	return nil
+}
+
+// ClaimURLs moves urls of one user to another.
+func (s *MapStorage) ClaimURLs(fromUserID, toUserID string) (int, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if fromUserID == toUserID {
+		return 0, nil
+	}
+
+	claimed := s.Users[fromUserID]
+	if len(claimed) == 0 {
+		return 0, nil
+	}
+	s.Users[toUserID] = append(s.Users[toUserID], claimed...)
+	delete(s.Users, fromUserID)
+	return len(claimed), nil
+}
+
+// usersPath gets path of file with users.
+func (s *fileStorage) usersPath() string {
+	return s.cfg.StoragePath + ".users"
+}
+
+// sessionsPath gets path of file with sessions.
+func (s *fileStorage) sessionsPath() string {
+	return s.cfg.StoragePath + ".sessions"
+}
+
+// fileUser is user record in file, it keeps password hash hidden from API responses.
+type fileUser struct {
+	entity.User
+	PasswordHash string `json:"password_hash"`
+}
+
+// loadUsers reads users and sessions from files.
+func (s *fileStorage) loadUsers() error {
+	records := make(map[string]fileUser)
+	if err := loadJSONFile(s.usersPath(), &records); err != nil {
+		return err
+	}
+	for login, record := range records {
+		user := record.User
+		user.PasswordHash = record.PasswordHash
+		s.accounts[login] = user
+	}
+	return loadJSONFile(s.sessionsPath(), &s.sessions)
+}
+
+// saveUsers writes users to file.
+func (s *fileStorage) saveUsers() error {
+	records := make(map[string]fileUser, len(s.accounts))
+	for login, user := range s.accounts {
+		records[login] = fileUser{User: user, PasswordHash: user.PasswordHash}
+	}
+	return saveJSONFile(s.usersPath(), records)
+}
+
+// AddUser saves new user. Returns ErrExists if login is taken.
+func (s *fileStorage) AddUser(user entity.User) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.accounts[user.Login]; ok {
+		return ErrExists
+	}
+	s.accounts[user.Login] = user
+
+	if err := s.saveUsers(); err != nil {
+		delete(s.accounts, user.Login)
+		return err
+	}
+	return nil
+}
+
+// GetUserByLogin gets user by login.
+func (s *fileStorage) GetUserByLogin(login string) (entity.User, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	user, ok := s.accounts[login]
+	if !ok {
+		return entity.User{}, ErrNotFound
+	}
+	return user, nil
+}
+
+// AddSession saves new session.
+func (s *fileStorage) AddSession(session entity.Session) error {
+	s.Lock()
+	defer s.Unlock()
+
+	s.sessions[session.Hash] = session
+
+	if err := saveJSONFile(s.sessionsPath(), s.sessions); err != nil {
+		delete(s.sessions, session.Hash)
+		return err
+	}
+	return nil
+}
+
+// GetSession gets session by hash of token.
+func (s *fileStorage) GetSession(hash string) (entity.Session, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	session, ok := s.sessions[hash]
+	if !ok {
+		return entity.Session{}, ErrNotFound
+	}
+	return session, nil
+}
+
+// DeleteSession deletes session by hash of token.
+func (s *fileStorage) DeleteSession(hash string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.sessions[hash]; !ok {
+		return nil
+	}
+	delete(s.sessions, hash)
+	return saveJSONFile(s.sessionsPath(), s.sessions)
+}
+
+// ClaimURLs moves urls of one user to another.
+func (s *fileStorage) ClaimURLs(fromUserID, toUserID string) (int, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if fromUserID == toUserID {
+		return 0, nil
+	}
+
+	var changed []fileRecord
+	for _, id := range s.byUser[fromUserID] {
+		record := s.records[id]
+		record.UserID = toUserID
+		changed = append(changed, record)
+	}
+	if len(changed) == 0 {
+		return 0, nil
+	}
+	return len(changed), s.write(changed...)
+}
+
+// AddUser saves new user. Returns ErrExists if login is taken.
+func (s *dbStorage) AddUser(user entity.User) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	res, err := s.db.ExecContext(
+		ctx,
+		"INSERT INTO users (id, login, password_hash, created_at) VALUES ($1, $2, $3, $4) ON CONFLICT (login) DO NOTHING",
+		user.ID, user.Login, user.PasswordHash, user.CreatedAt,
+	)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrExists
+	}
+	return nil
+}
+
+// GetUserByLogin gets user by login.
+func (s *dbStorage) GetUserByLogin(login string) (entity.User, error) {
+	var user entity.User
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	row := s.db.QueryRowContext(
+		ctx,
+		"SELECT id, login, password_hash, created_at FROM users WHERE login = $1",
+		login,
+	)
+	err := row.Scan(&user.ID, &user.Login, &user.PasswordHash, &user.CreatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return user, ErrNotFound
+	}
+	return user, err
+}
+
+// AddSession saves new session.
+func (s *dbStorage) AddSession(session entity.Session) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	_, err := s.db.ExecContext(
+		ctx,
+		"INSERT INTO sessions (hash, user_id, expires_at, role) VALUES ($1, $2, $3, $4)",
+		session.Hash, session.UserID, session.ExpiresAt, session.Role,
+	)
+	return err
+}
+
+// GetSession gets session by hash of token.
+func (s *dbStorage) GetSession(hash string) (entity.Session, error) {
+	var session entity.Session
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	row := s.db.QueryRowContext(
+		ctx,
+		"SELECT hash, user_id, expires_at, role FROM sessions WHERE hash = $1",
+		hash,
+	)
+	err := row.Scan(&session.Hash, &session.UserID, &session.ExpiresAt, &session.Role)
+	if errors.Is(err, sql.ErrNoRows) {
+		return session, ErrNotFound
+	}
+	return session, err
+}
+
+// DeleteSession deletes session by hash of token.
+func (s *dbStorage) DeleteSession(hash string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, "DELETE FROM sessions WHERE hash = $1", hash)
+	return err
+}
+
+// ClaimURLs moves urls of one user to another.
+func (s *dbStorage) ClaimURLs(fromUserID, toUserID string) (int, error) {
+	if fromUserID == toUserID {
+		return 0, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	res, err := s.db.ExecContext(
+		ctx,
+		"UPDATE items SET cookie = $2 WHERE cookie = $1",
+		fromUserID, toUserID,
+	)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
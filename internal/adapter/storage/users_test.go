@@ -0,0 +1,86 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bbt-t/lets-go-shortener/internal/config"
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUsers(t *testing.T) {
+	storages, cfg := testStorages(t)
+
+	user := entity.User{
+		ID:           "user1",
+		Login:        "alice",
+		PasswordHash: "hash",
+		CreatedAt:    time.Unix(100, 0).UTC(),
+	}
+	session := entity.Session{
+		Hash:      "session1",
+		UserID:    "user1",
+		ExpiresAt: time.Unix(200, 0).UTC(),
+	}
+
+	for name, s := range storages {
+		require.NoError(t, s.AddUser(user), name)
+		assert.Equal(t, ErrExists, s.AddUser(entity.User{ID: "user2", Login: "alice"}), name)
+
+		got, err := s.GetUserByLogin("alice")
+		assert.NoError(t, err, name)
+		assert.Equal(t, user, got, name)
+
+		_, err = s.GetUserByLogin("bob")
+		assert.Equal(t, ErrNotFound, err, name)
+
+		require.NoError(t, s.AddSession(session), name)
+		gotSession, err := s.GetSession("session1")
+		assert.NoError(t, err, name)
+		assert.Equal(t, session, gotSession, name)
+
+		require.NoError(t, s.AddSession(entity.Session{Hash: "session2", UserID: "user1"}), name)
+		require.NoError(t, s.DeleteSession("session2"), name)
+		_, err = s.GetSession("session2")
+		assert.Equal(t, ErrNotFound, err, name)
+	}
+
+	// file storage keeps users and sessions after restart.
+	restarted, err := newFileStorage(cfg)
+	require.NoError(t, err)
+	got, err := restarted.GetUserByLogin("alice")
+	assert.NoError(t, err)
+	assert.Equal(t, user, got)
+	gotSession, err := restarted.GetSession("session1")
+	assert.NoError(t, err)
+	assert.Equal(t, session, gotSession)
+}
+
+func TestMapStorage_ClaimURLs(t *testing.T) {
+	s, err := NewMapStorage(config.GetTestConfig())
+	require.NoError(t, err)
+
+	_, err = s.CreateShort("anonymous", "https://yandex.ru", "https://google.com")
+	require.NoError(t, err)
+	_, err = s.CreateShort("account", "https://ya.ru")
+	require.NoError(t, err)
+
+	claimed, err := s.ClaimURLs("anonymous", "account")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, claimed)
+
+	history, err := s.GetURLArrayByUser("account", entity.HistoryFilter{})
+	assert.NoError(t, err)
+	assert.Len(t, history, 3)
+
+	history, err = s.GetURLArrayByUser("anonymous", entity.HistoryFilter{})
+	assert.NoError(t, err)
+	assert.Empty(t, history)
+
+	claimed, err = s.ClaimURLs("anonymous", "account")
+	assert.NoError(t, err)
+	assert.Zero(t, claimed)
+}
@@ -0,0 +1,175 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+)
+
+// newestFirst gets copy of versions in reverse order.
+func newestFirst(versions []entity.LinkVersion) []entity.LinkVersion {
+	result := make([]entity.LinkVersion, 0, len(versions))
+	for i := len(versions) - 1; i >= 0; i-- {
+		result = append(result, versions[i])
+	}
+	return result
+}
+
+// UpdateURL changes original url of link of user, previous url is kept in versions.
+func (s *MapStorage) UpdateURL(userID, id, original string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	current, ok := s.Locations[id]
+	if !ok || s.owners()[id] != userID {
+		return ErrNotFound
+	}
+	if s.Deleted[id] || s.Disabled[id] {
+		return ErrDeleted
+	}
+	if current == original {
+		return nil
+	}
+
+	if s.Versions == nil {
+		s.Versions = make(map[string][]entity.LinkVersion)
+	}
+	s.Versions[id] = append(s.Versions[id], entity.LinkVersion{URL: current, ReplacedAt: time.Now().UTC()})
+	s.Locations[id] = original
+	return nil
+}
+
+// GetURLVersions gets past original urls of link, newest first.
+func (s *MapStorage) GetURLVersions(id string) ([]entity.LinkVersion, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.Locations[id]; !ok {
+		return nil, ErrNotFound
+	}
+	return newestFirst(s.Versions[id]), nil
+}
+
+// UpdateURL changes original url of link of user, previous url is kept in versions.
+func (s *fileStorage) UpdateURL(userID, id, original string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	record, ok := s.records[id]
+	if !ok || record.UserID != userID {
+		return ErrNotFound
+	}
+	if record.Deleted || record.Disabled {
+		return ErrDeleted
+	}
+	if record.URL == original {
+		return nil
+	}
+
+	versions := make([]entity.LinkVersion, len(record.Versions), len(record.Versions)+1)
+	copy(versions, record.Versions)
+	record.Versions = append(versions, entity.LinkVersion{URL: record.URL, ReplacedAt: time.Now().UTC()})
+	record.URL = original
+	return s.write(record)
+}
+
+// GetURLVersions gets past original urls of link, newest first.
+func (s *fileStorage) GetURLVersions(id string) ([]entity.LinkVersion, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	record, ok := s.records[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return newestFirst(record.Versions), nil
+}
+
+// UpdateURL changes original url of link of user, previous url is kept in link_versions table.
+// Row of link is locked, so concurrent updates keep all versions.
+func (s *dbStorage) UpdateURL(userID, id, original string) error {
+	var (
+		current string
+		deleted sql.NullBool
+	)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	row := tx.QueryRowContext(
+		ctx,
+		"SELECT url, deleted OR disabled FROM items WHERE id = $1 AND cookie = $2 FOR UPDATE",
+		id, userID,
+	)
+	err = row.Scan(&current, &deleted)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if deleted.Bool {
+		return ErrDeleted
+	}
+	if current == original {
+		return nil
+	}
+
+	_, err = tx.ExecContext(
+		ctx,
+		"INSERT INTO link_versions (link_id, url, replaced_at) VALUES ($1, $2, $3)",
+		id, current, time.Now().UTC(),
+	)
+	if err != nil {
+		return err
+	}
+	if _, err = tx.ExecContext(ctx, "UPDATE items SET url = $1 WHERE id = $2", original, id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// GetURLVersions gets past original urls of link, newest first.
+func (s *dbStorage) GetURLVersions(id string) ([]entity.LinkVersion, error) {
+	var exists bool
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	err := s.db.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM items WHERE id = $1)", id).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, ErrNotFound
+	}
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		"SELECT url, replaced_at FROM link_versions WHERE link_id = $1 ORDER BY id DESC",
+		id,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := make([]entity.LinkVersion, 0)
+	for rows.Next() {
+		var version entity.LinkVersion
+		if err = rows.Scan(&version.URL, &version.ReplacedAt); err != nil {
+			return nil, err
+		}
+		versions = append(versions, version)
+	}
+	return versions, rows.Err()
+}
@@ -0,0 +1,54 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpdateURL(t *testing.T) {
+	storages, cfg := testStorages(t)
+
+	for name, s := range storages {
+		ids, err := s.CreateShort("user1", "https://yandex.ru", "https://ya.ru")
+		require.NoError(t, err, name)
+
+		assert.ErrorIs(t, s.UpdateURL("user2", ids[0], "https://go.dev"), ErrNotFound, name, "only owner updates link")
+		assert.ErrorIs(t, s.UpdateURL("user1", "100", "https://go.dev"), ErrNotFound, name)
+
+		require.NoError(t, s.UpdateURL("user1", ids[0], "https://go.dev"), name)
+		require.NoError(t, s.UpdateURL("user1", ids[0], "https://go.dev"), name, "the same url isn't new version")
+		require.NoError(t, s.UpdateURL("user1", ids[0], "https://golang.org"), name)
+
+		original, err := s.GetOriginal(ids[0])
+		assert.NoError(t, err, name)
+		assert.Equal(t, "https://golang.org", original, name)
+
+		versions, err := s.GetURLVersions(ids[0])
+		require.NoError(t, err, name)
+		require.Len(t, versions, 2, name)
+		assert.Equal(t, "https://go.dev", versions[0].URL, name)
+		assert.Equal(t, "https://yandex.ru", versions[1].URL, name)
+		_, err = s.GetURLVersions("100")
+		assert.ErrorIs(t, err, ErrNotFound, name)
+
+		// link is deduplicated by its new url, old url is free.
+		found, err := s.CreateShort("user1", "https://golang.org", "https://yandex.ru")
+		assert.ErrorIs(t, err, ErrExists, name)
+		assert.Equal(t, ids[0], found[0], name)
+		assert.NotEqual(t, ids[0], found[1], name)
+
+		require.NoError(t, s.MarkAsDeleted("user1", ids[1]), name)
+		assert.ErrorIs(t, s.UpdateURL("user1", ids[1], "https://go.dev"), ErrDeleted, name)
+	}
+
+	restarted, err := newFileStorage(cfg)
+	require.NoError(t, err)
+	versions, err := restarted.GetURLVersions("1")
+	require.NoError(t, err)
+	assert.Len(t, versions, 2)
+	ids, err := restarted.CreateShort("user1", "https://golang.org")
+	assert.ErrorIs(t, err, ErrExists)
+	assert.Equal(t, []string{"1"}, ids)
+}
@@ -0,0 +1,620 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+)
+
+// webhooksByUser gets webhooks of user sorted by creation time.
+func webhooksByUser(webhooks map[string]entity.Webhook, userID string) []entity.Webhook {
+	result := make([]entity.Webhook, 0)
+	for _, hook := range webhooks {
+		if hook.UserID == userID {
+			result = append(result, hook)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].CreatedAt.Equal(result[j].CreatedAt) {
+			return result[i].ID < result[j].ID
+		}
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+	return result
+}
+
+// dueDeliveries gets alive deliveries which are due at now, the oldest first. Zero limit is unlimited.
+func dueDeliveries(deliveries map[int64]entity.WebhookDelivery, now time.Time, limit int) []entity.WebhookDelivery {
+	result := make([]entity.WebhookDelivery, 0)
+	for _, delivery := range deliveries {
+		if !delivery.Dead && !delivery.NextAttemptAt.After(now) {
+			result = append(result, delivery)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].NextAttemptAt.Equal(result[j].NextAttemptAt) {
+			return result[i].ID < result[j].ID
+		}
+		return result[i].NextAttemptAt.Before(result[j].NextAttemptAt)
+	})
+	if limit > 0 && limit < len(result) {
+		result = result[:limit]
+	}
+	return result
+}
+
+// deadDeliveries gets dead deliveries of user, newest first.
+func deadDeliveries(deliveries map[int64]entity.WebhookDelivery, userID string) []entity.WebhookDelivery {
+	result := make([]entity.WebhookDelivery, 0)
+	for _, delivery := range deliveries {
+		if delivery.Dead && delivery.UserID == userID {
+			result = append(result, delivery)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID > result[j].ID
+	})
+	return result
+}
+
+// deleteWebhook deletes webhook of user with its deliveries.
+func deleteWebhook(webhooks map[string]entity.Webhook, deliveries map[int64]entity.WebhookDelivery, userID, id string) error {
+	hook, ok := webhooks[id]
+	if !ok || hook.UserID != userID {
+		return ErrNotFound
+	}
+	delete(webhooks, id)
+	for deliveryID, delivery := range deliveries {
+		if delivery.WebhookID == id {
+			delete(deliveries, deliveryID)
+		}
+	}
+	return nil
+}
+
+// requeueDelivery makes dead delivery of user alive again with fresh attempts.
+func requeueDelivery(deliveries map[int64]entity.WebhookDelivery, userID string, id int64, at time.Time) error {
+	delivery, ok := deliveries[id]
+	if !ok || !delivery.Dead || delivery.UserID != userID {
+		return ErrNotFound
+	}
+	delivery.Dead, delivery.Attempts, delivery.NextAttemptAt = false, 0, at
+	deliveries[id] = delivery
+	return nil
+}
+
+// AddWebhook saves new webhook.
+func (s *MapStorage) AddWebhook(hook entity.Webhook) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.Webhooks == nil {
+		s.Webhooks = make(map[string]entity.Webhook)
+	}
+	if _, ok := s.Webhooks[hook.ID]; ok {
+		return ErrExists
+	}
+	s.Webhooks[hook.ID] = hook
+	return nil
+}
+
+// GetWebhook gets webhook by id.
+func (s *MapStorage) GetWebhook(id string) (entity.Webhook, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	hook, ok := s.Webhooks[id]
+	if !ok {
+		return entity.Webhook{}, ErrNotFound
+	}
+	return hook, nil
+}
+
+// GetWebhooksByUser gets webhooks of user.
+func (s *MapStorage) GetWebhooksByUser(userID string) ([]entity.Webhook, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	return webhooksByUser(s.Webhooks, userID), nil
+}
+
+// DeleteWebhook deletes webhook of user with its deliveries.
+func (s *MapStorage) DeleteWebhook(userID, id string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	return deleteWebhook(s.Webhooks, s.Deliveries, userID, id)
+}
+
+// AddWebhookDeliveries queues deliveries, ids are given in order of deliveries.
+func (s *MapStorage) AddWebhookDeliveries(deliveries ...entity.WebhookDelivery) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if s.Deliveries == nil {
+		s.Deliveries = make(map[int64]entity.WebhookDelivery)
+	}
+	for _, delivery := range deliveries {
+		s.DeliverySeq++
+		delivery.ID = s.DeliverySeq
+		s.Deliveries[delivery.ID] = delivery
+	}
+	return nil
+}
+
+// GetDueDeliveries gets alive deliveries which are due at now, the oldest first.
+func (s *MapStorage) GetDueDeliveries(now time.Time, limit int) ([]entity.WebhookDelivery, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	return dueDeliveries(s.Deliveries, now, limit), nil
+}
+
+// UpdateDelivery saves result of failed attempt of delivery.
+func (s *MapStorage) UpdateDelivery(delivery entity.WebhookDelivery) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.Deliveries[delivery.ID]; !ok {
+		return ErrNotFound
+	}
+	s.Deliveries[delivery.ID] = delivery
+	return nil
+}
+
+// DeleteDelivery deletes delivered delivery.
+func (s *MapStorage) DeleteDelivery(id int64) error {
+	s.Lock()
+	defer s.Unlock()
+
+	delete(s.Deliveries, id)
+	return nil
+}
+
+// GetDeadDeliveries gets dead deliveries of user, newest first.
+func (s *MapStorage) GetDeadDeliveries(userID string) ([]entity.WebhookDelivery, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	return deadDeliveries(s.Deliveries, userID), nil
+}
+
+// RequeueDelivery makes dead delivery of user alive again, it's due at time.
+func (s *MapStorage) RequeueDelivery(userID string, id int64, at time.Time) error {
+	s.Lock()
+	defer s.Unlock()
+
+	return requeueDelivery(s.Deliveries, userID, id, at)
+}
+
+// webhooksPath gets path of file with webhooks and their queue.
+func (s *fileStorage) webhooksPath() string {
+	return s.cfg.StoragePath + ".webhooks"
+}
+
+// fileWebhook is webhook record in file, it keeps fields hidden from API responses.
+type fileWebhook struct {
+	entity.Webhook
+	UserID string `json:"user_id"`
+	Secret string `json:"secret"`
+}
+
+// fileDelivery is delivery record in file, it keeps owner hidden from API responses.
+type fileDelivery struct {
+	entity.WebhookDelivery
+	UserID string `json:"user_id"`
+}
+
+// fileWebhooks is content of file with webhooks.
+type fileWebhooks struct {
+	Webhooks   map[string]fileWebhook `json:"webhooks"`
+	Deliveries []fileDelivery         `json:"deliveries"`
+	LastID     int64                  `json:"last_delivery_id"`
+}
+
+// loadWebhooks reads webhooks and their queue from file.
+func (s *fileStorage) loadWebhooks() error {
+	var records fileWebhooks
+	if err := loadJSONFile(s.webhooksPath(), &records); err != nil {
+		return err
+	}
+	for id, record := range records.Webhooks {
+		hook := record.Webhook
+		hook.UserID, hook.Secret = record.UserID, record.Secret
+		s.webhooks[id] = hook
+	}
+	for _, record := range records.Deliveries {
+		delivery := record.WebhookDelivery
+		delivery.UserID = record.UserID
+		s.deliveries[delivery.ID] = delivery
+	}
+	s.deliverySeq = records.LastID
+	return nil
+}
+
+// saveWebhooks writes webhooks and their queue to file.
+func (s *fileStorage) saveWebhooks() error {
+	records := fileWebhooks{
+		Webhooks:   make(map[string]fileWebhook, len(s.webhooks)),
+		Deliveries: make([]fileDelivery, 0, len(s.deliveries)),
+		LastID:     s.deliverySeq,
+	}
+	for id, hook := range s.webhooks {
+		records.Webhooks[id] = fileWebhook{Webhook: hook, UserID: hook.UserID, Secret: hook.Secret}
+	}
+	for _, delivery := range s.deliveries {
+		records.Deliveries = append(records.Deliveries, fileDelivery{WebhookDelivery: delivery, UserID: delivery.UserID})
+	}
+	sort.Slice(records.Deliveries, func(i, j int) bool {
+		return records.Deliveries[i].ID < records.Deliveries[j].ID
+	})
+	return saveJSONFile(s.webhooksPath(), records)
+}
+
+// AddWebhook saves new webhook.
+func (s *fileStorage) AddWebhook(hook entity.Webhook) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.webhooks[hook.ID]; ok {
+		return ErrExists
+	}
+	s.webhooks[hook.ID] = hook
+
+	if err := s.saveWebhooks(); err != nil {
+		delete(s.webhooks, hook.ID)
+		return err
+	}
+	return nil
+}
+
+// GetWebhook gets webhook by id.
+func (s *fileStorage) GetWebhook(id string) (entity.Webhook, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	hook, ok := s.webhooks[id]
+	if !ok {
+		return entity.Webhook{}, ErrNotFound
+	}
+	return hook, nil
+}
+
+// GetWebhooksByUser gets webhooks of user.
+func (s *fileStorage) GetWebhooksByUser(userID string) ([]entity.Webhook, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	return webhooksByUser(s.webhooks, userID), nil
+}
+
+// DeleteWebhook deletes webhook of user with its deliveries.
+func (s *fileStorage) DeleteWebhook(userID, id string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if err := deleteWebhook(s.webhooks, s.deliveries, userID, id); err != nil {
+		return err
+	}
+	return s.saveWebhooks()
+}
+
+// AddWebhookDeliveries queues deliveries, ids are given in order of deliveries.
+func (s *fileStorage) AddWebhookDeliveries(deliveries ...entity.WebhookDelivery) error {
+	s.Lock()
+	defer s.Unlock()
+
+	for _, delivery := range deliveries {
+		s.deliverySeq++
+		delivery.ID = s.deliverySeq
+		s.deliveries[delivery.ID] = delivery
+	}
+	return s.saveWebhooks()
+}
+
+// GetDueDeliveries gets alive deliveries which are due at now, the oldest first.
+func (s *fileStorage) GetDueDeliveries(now time.Time, limit int) ([]entity.WebhookDelivery, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	return dueDeliveries(s.deliveries, now, limit), nil
+}
+
+// UpdateDelivery saves result of failed attempt of delivery.
+func (s *fileStorage) UpdateDelivery(delivery entity.WebhookDelivery) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.deliveries[delivery.ID]; !ok {
+		return ErrNotFound
+	}
+	s.deliveries[delivery.ID] = delivery
+	return s.saveWebhooks()
+}
+
+// DeleteDelivery deletes delivered delivery.
+func (s *fileStorage) DeleteDelivery(id int64) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.deliveries[id]; !ok {
+		return nil
+	}
+	delete(s.deliveries, id)
+	return s.saveWebhooks()
+}
+
+// GetDeadDeliveries gets dead deliveries of user, newest first.
+func (s *fileStorage) GetDeadDeliveries(userID string) ([]entity.WebhookDelivery, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	return deadDeliveries(s.deliveries, userID), nil
+}
+
+// RequeueDelivery makes dead delivery of user alive again, it's due at time.
+func (s *fileStorage) RequeueDelivery(userID string, id int64, at time.Time) error {
+	s.Lock()
+	defer s.Unlock()
+
+	if err := requeueDelivery(s.deliveries, userID, id, at); err != nil {
+		return err
+	}
+	return s.saveWebhooks()
+}
+
+// AddWebhook saves new webhook.
+func (s *dbStorage) AddWebhook(hook entity.Webhook) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	_, err := s.db.ExecContext(
+		ctx,
+		"INSERT INTO webhooks (id, user_id, url, events, secret, created_at) VALUES ($1, $2, $3, $4, $5, $6)",
+		hook.ID, hook.UserID, hook.URL, strings.Join(hook.Events, ","), hook.Secret, hook.CreatedAt,
+	)
+	return err
+}
+
+// webhookColumns are columns of webhooks scanned by scanWebhook.
+const webhookColumns = "id, user_id, url, events, secret, created_at"
+
+// scanWebhook scans webhook from row.
+func scanWebhook(row rowScanner) (entity.Webhook, error) {
+	var (
+		hook   entity.Webhook
+		events string
+	)
+	if err := row.Scan(&hook.ID, &hook.UserID, &hook.URL, &events, &hook.Secret, &hook.CreatedAt); err != nil {
+		return hook, err
+	}
+	if events != "" {
+		hook.Events = strings.Split(events, ",")
+	}
+	hook.CreatedAt = hook.CreatedAt.UTC()
+	return hook, nil
+}
+
+// GetWebhook gets webhook by id.
+func (s *dbStorage) GetWebhook(id string) (entity.Webhook, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	row := s.db.QueryRowContext(ctx, "SELECT "+webhookColumns+" FROM webhooks WHERE id = $1", id)
+	hook, err := scanWebhook(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return hook, ErrNotFound
+	}
+	return hook, err
+}
+
+// GetWebhooksByUser gets webhooks of user.
+func (s *dbStorage) GetWebhooksByUser(userID string) ([]entity.Webhook, error) {
+	webhooks := make([]entity.Webhook, 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(
+		ctx,
+		"SELECT "+webhookColumns+" FROM webhooks WHERE user_id = $1 ORDER BY created_at, id",
+		userID,
+	)
+	if err != nil {
+		return webhooks, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		hook, err := scanWebhook(rows)
+		if err != nil {
+			return webhooks, err
+		}
+		webhooks = append(webhooks, hook)
+	}
+	return webhooks, rows.Err()
+}
+
+// DeleteWebhook deletes webhook of user, its deliveries are deleted by cascade.
+func (s *dbStorage) DeleteWebhook(userID, id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	res, err := s.db.ExecContext(ctx, "DELETE FROM webhooks WHERE id = $1 AND user_id = $2", id, userID)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// AddWebhookDeliveries queues deliveries in one transaction.
+func (s *dbStorage) AddWebhookDeliveries(deliveries ...entity.WebhookDelivery) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, delivery := range deliveries {
+		_, err = tx.ExecContext(
+			ctx,
+			`INSERT INTO webhook_deliveries (webhook_id, user_id, event, payload, created_at, next_attempt_at)
+			VALUES ($1, $2, $3, $4, $5, $6)`,
+			delivery.WebhookID, delivery.UserID, delivery.Event, string(delivery.Payload),
+			delivery.CreatedAt.UTC(), delivery.NextAttemptAt.UTC(),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// deliveryColumns are columns of webhook_deliveries scanned by scanDelivery.
+// deliveryLease is time for which due delivery taken by replica isn't due for others.
+const deliveryLease = 5 * time.Minute
+
+const deliveryColumns = "id, webhook_id, user_id, event, payload, attempts, last_error, created_at, next_attempt_at, dead"
+
+// scanDelivery scans delivery from row.
+func scanDelivery(row rowScanner) (entity.WebhookDelivery, error) {
+	var (
+		delivery entity.WebhookDelivery
+		payload  string
+	)
+	err := row.Scan(
+		&delivery.ID, &delivery.WebhookID, &delivery.UserID, &delivery.Event, &payload, &delivery.Attempts,
+		&delivery.LastError, &delivery.CreatedAt, &delivery.NextAttemptAt, &delivery.Dead,
+	)
+	if err != nil {
+		return delivery, err
+	}
+	delivery.Payload = []byte(payload)
+	delivery.CreatedAt, delivery.NextAttemptAt = delivery.CreatedAt.UTC(), delivery.NextAttemptAt.UTC()
+	return delivery, nil
+}
+
+// queryDeliveries gets deliveries by query.
+func (s *dbStorage) queryDeliveries(query string, args ...interface{}) ([]entity.WebhookDelivery, error) {
+	deliveries := make([]entity.WebhookDelivery, 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return deliveries, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return deliveries, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+// GetDueDeliveries gets alive deliveries which are due at now, the oldest first.
+// Deliveries are leased: they are due again only after deliveryLease, so other replicas don't take them,
+// rows locked by other replicas are skipped.
+func (s *dbStorage) GetDueDeliveries(now time.Time, limit int) ([]entity.WebhookDelivery, error) {
+	var count sql.NullInt64
+	if limit > 0 {
+		count = sql.NullInt64{Int64: int64(limit), Valid: true}
+	}
+	return s.queryDeliveries(
+		`WITH due AS (
+			SELECT id FROM webhook_deliveries WHERE NOT dead AND next_attempt_at <= $1
+			ORDER BY next_attempt_at, id LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		), leased AS (
+			UPDATE webhook_deliveries SET next_attempt_at = $3
+			WHERE id IN (SELECT id FROM due)
+			RETURNING `+deliveryColumns+`
+		)
+		SELECT `+deliveryColumns+` FROM leased ORDER BY id`,
+		now.UTC(), count, now.UTC().Add(deliveryLease),
+	)
+}
+
+// UpdateDelivery saves result of failed attempt of delivery.
+func (s *dbStorage) UpdateDelivery(delivery entity.WebhookDelivery) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	res, err := s.db.ExecContext(
+		ctx,
+		"UPDATE webhook_deliveries SET attempts = $2, last_error = $3, next_attempt_at = $4, dead = $5 WHERE id = $1",
+		delivery.ID, delivery.Attempts, delivery.LastError, delivery.NextAttemptAt.UTC(), delivery.Dead,
+	)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteDelivery deletes delivered delivery.
+func (s *dbStorage) DeleteDelivery(id int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, "DELETE FROM webhook_deliveries WHERE id = $1", id)
+	return err
+}
+
+// GetDeadDeliveries gets dead deliveries of user, newest first.
+func (s *dbStorage) GetDeadDeliveries(userID string) ([]entity.WebhookDelivery, error) {
+	return s.queryDeliveries(
+		"SELECT "+deliveryColumns+" FROM webhook_deliveries WHERE dead AND user_id = $1 ORDER BY id DESC",
+		userID,
+	)
+}
+
+// RequeueDelivery makes dead delivery of user alive again, it's due at time.
+func (s *dbStorage) RequeueDelivery(userID string, id int64, at time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Second)
+	defer cancel()
+
+	res, err := s.db.ExecContext(
+		ctx,
+		`UPDATE webhook_deliveries SET dead = false, attempts = 0, next_attempt_at = $3
+		WHERE id = $1 AND user_id = $2 AND dead`,
+		id, userID, at.UTC(),
+	)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
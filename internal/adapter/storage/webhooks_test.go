@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhooks(t *testing.T) {
+	storages, cfg := testStorages(t)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	for name, s := range storages {
+		first := entity.Webhook{ID: "a", UserID: "user1", URL: "https://example.com/hook", Secret: "s1", CreatedAt: now}
+		second := entity.Webhook{
+			ID: "b", UserID: "user1", URL: "https://example.com/clicks",
+			Events: []string{entity.EventLinkClicked}, Secret: "s2", CreatedAt: now.Add(time.Second),
+		}
+		require.NoError(t, s.AddWebhook(second), name)
+		require.NoError(t, s.AddWebhook(first), name)
+		assert.ErrorIs(t, s.AddWebhook(first), ErrExists, name)
+		require.NoError(t, s.AddWebhook(entity.Webhook{ID: "c", UserID: "user2", URL: "https://example.org", CreatedAt: now}), name)
+
+		hooks, err := s.GetWebhooksByUser("user1")
+		require.NoError(t, err, name)
+		assert.Equal(t, []entity.Webhook{first, second}, hooks, name)
+		hook, err := s.GetWebhook("b")
+		require.NoError(t, err, name)
+		assert.Equal(t, "s2", hook.Secret, name)
+		_, err = s.GetWebhook("x")
+		assert.ErrorIs(t, err, ErrNotFound, name)
+
+		delivery := func(hookID string, at time.Time) entity.WebhookDelivery {
+			return entity.WebhookDelivery{
+				WebhookID: hookID, UserID: "user1", Event: entity.EventLinkCreated,
+				Payload: []byte(`{"type":"link.created"}`), CreatedAt: now, NextAttemptAt: at,
+			}
+		}
+		require.NoError(t, s.AddWebhookDeliveries(delivery("a", now), delivery("b", now.Add(-time.Minute)), delivery("a", now.Add(time.Hour))), name)
+
+		due, err := s.GetDueDeliveries(now, 0)
+		require.NoError(t, err, name)
+		require.Len(t, due, 2, name)
+		assert.Equal(t, int64(2), due[0].ID, name, "the oldest delivery goes first")
+		assert.Equal(t, int64(1), due[1].ID, name)
+		assert.Equal(t, "user1", due[1].UserID, name)
+		assert.JSONEq(t, `{"type":"link.created"}`, string(due[1].Payload), name)
+		due, err = s.GetDueDeliveries(now, 1)
+		require.NoError(t, err, name)
+		assert.Len(t, due, 1, name)
+
+		failed := due[0]
+		failed.Attempts, failed.LastError, failed.Dead = 8, "status 500", true
+		require.NoError(t, s.UpdateDelivery(failed), name)
+		require.NoError(t, s.DeleteDelivery(1), name)
+		assert.ErrorIs(t, s.UpdateDelivery(entity.WebhookDelivery{ID: 100}), ErrNotFound, name)
+
+		due, err = s.GetDueDeliveries(now, 0)
+		require.NoError(t, err, name)
+		assert.Empty(t, due, name, "dead deliveries aren't due")
+
+		dead, err := s.GetDeadDeliveries("user1")
+		require.NoError(t, err, name)
+		require.Len(t, dead, 1, name)
+		assert.Equal(t, "status 500", dead[0].LastError, name)
+		dead, err = s.GetDeadDeliveries("user2")
+		require.NoError(t, err, name)
+		assert.Empty(t, dead, name)
+
+		assert.ErrorIs(t, s.RequeueDelivery("user2", 2, now), ErrNotFound, name)
+		assert.ErrorIs(t, s.RequeueDelivery("user1", 3, now), ErrNotFound, name, "alive delivery isn't requeued")
+		require.NoError(t, s.RequeueDelivery("user1", 2, now), name)
+		due, err = s.GetDueDeliveries(now, 0)
+		require.NoError(t, err, name)
+		require.Len(t, due, 1, name)
+		assert.Zero(t, due[0].Attempts, name)
+		assert.False(t, due[0].Dead, name)
+
+		// deleted webhook takes its deliveries with it.
+		assert.ErrorIs(t, s.DeleteWebhook("user2", "a"), ErrNotFound, name)
+		require.NoError(t, s.DeleteWebhook("user1", "a"), name)
+		due, err = s.GetDueDeliveries(now.Add(2*time.Hour), 0)
+		require.NoError(t, err, name)
+		require.Len(t, due, 1, name)
+		assert.Equal(t, "b", due[0].WebhookID, name)
+
+		require.NoError(t, s.AddWebhookDeliveries(delivery("b", now)), name)
+		due, err = s.GetDueDeliveries(now, 0)
+		require.NoError(t, err, name)
+		require.Len(t, due, 2, name)
+		assert.Equal(t, int64(4), due[1].ID, name, "ids aren't reused")
+	}
+
+	restarted, err := newFileStorage(cfg)
+	require.NoError(t, err)
+	hooks, err := restarted.GetWebhooksByUser("user1")
+	require.NoError(t, err)
+	require.Len(t, hooks, 1)
+	assert.Equal(t, "s2", hooks[0].Secret)
+	require.NoError(t, restarted.AddWebhookDeliveries(entity.WebhookDelivery{WebhookID: "b", UserID: "user1", NextAttemptAt: now}))
+	due, err := restarted.GetDueDeliveries(now, 0)
+	require.NoError(t, err)
+	require.Len(t, due, 3)
+	assert.Equal(t, int64(5), due[2].ID)
+}
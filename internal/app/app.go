@@ -21,6 +21,15 @@ import (
 
 	"golang.org/x/crypto/acme/autocert"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// Timeouts and workers of background requests to original urls and webhooks.
+const (
+	titleFetchTimeout  = 5 * time.Second
+	titleFetchWorkers  = 4
+	healthCheckTimeout = 10 * time.Second
+	webhookTimeout     = 10 * time.Second
 )
 
 // Run service.
@@ -40,24 +49,121 @@ func Run(cfg config.Config) {
 		Prompt:     autocert.AcceptTOS,
 		HostPolicy: autocert.HostWhitelist(baseURL.Host),
 	}
+	// Cookie signing keys
+	keys, err := handlers.NewKeyRing(cfg)
+	if err != nil {
+		log.Fatalln("Failed load cookie keys:", err)
+	}
+	handlers.SetCookieKeys(keys)
+	// Audit log
+	auditLog, err := storage.NewAuditLog(cfg, s)
+	if err != nil {
+		log.Fatalln("Failed open audit log:", err)
+	}
+	// Domain policy
+	policy, err := usecase.NewPolicy(cfg)
+	if err != nil {
+		log.Fatalln("Failed load domain policy:", err)
+	}
+	// Logo of QR codes
+	qrLogo, err := usecase.LoadQRLogo(cfg)
+	if err != nil {
+		log.Fatalln("Failed load QR logo:", err)
+	}
+	// Countries of visitors for redirect rules
+	geoIP, err := usecase.LoadGeoIP(cfg)
+	if err != nil {
+		log.Fatalln("Failed load GeoIP database:", err)
+	}
 	// New service
 	service := usecase.NewShortenerService(cfg, s)
+	service.SetAuditLog(auditLog)
+	service.SetPolicy(policy)
+	service.SetQRLogo(qrLogo)
+	service.SetGeoIP(geoIP)
+	// Titles of link previews
+	ctxWorkers, cancelWorkers := context.WithCancel(context.Background())
+	defer cancelWorkers()
+	titles := usecase.NewTitleFetcher(usecase.NewPublicClient(titleFetchTimeout))
+	go titles.Run(ctxWorkers, titleFetchWorkers)
+	service.SetTitleFetcher(titles)
+	// Health checks of original urls
+	checker, err := usecase.NewHealthChecker(cfg, s, usecase.NewPublicClient(healthCheckTimeout))
+	if err != nil {
+		log.Fatalln("Failed create health checker:", err)
+	}
+	if checker != nil {
+		go checker.Run(ctxWorkers)
+	}
+	// Webhooks on link events
+	webhooks := usecase.NewWebhookDispatcher(s, usecase.NewPublicClient(webhookTimeout))
+	service.SetWebhooks(webhooks)
+	webhooksDone := make(chan struct{})
+	go func() {
+		webhooks.Run(ctxWorkers)
+		close(webhooksDone)
+	}()
+
+	// Live clicks of links
+	clickHub := usecase.NewClickHub()
+	service.SetClickHub(clickHub)
 
 	// New router
 	h := handlers.NewShortenerHandler(cfg, service)
 
+	// gRPC service, also served as HTTP/JSON under /v2/
+	grpcService := handlers.NewShortenerServer(cfg, service)
+	gateway, err := handlers.NewGatewayHandler(context.Background(), grpcService)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Rate limits
+	limits, err := handlers.ParseRateLimits(cfg.RateLimits)
+	if err != nil {
+		log.Fatalln("Failed parse rate limits:", err)
+	}
+	limiter, err := storage.NewLimiter(cfg, s)
+	if err != nil {
+		log.Fatalln("Failed create rate limiter:", err)
+	}
+	rateLimiter := handlers.NewRateLimiter(limiter, limits)
+	service.SetLimiter(limiter)
+
 	// New server
-	server := controller.NewRouter(cfg, h, manager)
+	server := controller.NewRouter(cfg, h, gateway, rateLimiter, manager)
 
 	// Init TCP listener for gRPC
 	listen, err := net.Listen("tcp", cfg.GrpcPort)
 	if err != nil {
 		log.Fatal("Error listening -> ", err)
 	}
+	// TLS for gRPC
+	grpcTLS, err := handlers.NewGrpcTLSConfig(cfg)
+	if err != nil {
+		log.Fatal("Failed load gRPC TLS config -> ", err)
+	}
+	grpcOpts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(
+			handlers.NewClientCertInterceptor(cfg.GrpcClientCA != ""),
+			handlers.NewAPIKeyInterceptor(service),
+			handlers.NewRateLimitInterceptor(rateLimiter),
+		),
+		grpc.ChainStreamInterceptor(
+			handlers.NewClientCertStreamInterceptor(cfg.GrpcClientCA != ""),
+			handlers.NewAPIKeyStreamInterceptor(service),
+			handlers.NewRateLimitStreamInterceptor(rateLimiter),
+		),
+	}
+	if grpcTLS != nil {
+		grpcOpts = append(grpcOpts, grpc.Creds(credentials.NewTLS(grpcTLS)))
+	} else if cfg.EnableHTTPS {
+		log.Println("! gRPC runs without TLS: certificate and key are not set !")
+	}
 	// Create gRPC-server without service
-	grpcServ := grpc.NewServer()
+	grpcServ := grpc.NewServer(grpcOpts...)
 	// Init gRPC service
-	pb.RegisterShortenerServer(grpcServ, handlers.NewShortenerServer(cfg, service))
+	pb.RegisterShortenerServer(grpcServ, grpcService)
 
 	go func() {
 		log.Println("-> Start gRPC service <-")
@@ -79,6 +185,8 @@ func Run(cfg config.Config) {
 	signal.Notify(gracefulStop, os.Interrupt, syscall.SIGTERM, syscall.SIGQUIT)
 	<-gracefulStop
 
+	// Click streams never get idle, they are ended before shutdown waits for idle connections.
+	clickHub.Stop()
 	ctxGrace, cancelGrace := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancelGrace()
 	if err := server.Stop(ctxGrace); err != nil {
@@ -86,4 +194,7 @@ func Run(cfg config.Config) {
 	} else {
 		log.Println("! SERVER STOPPED !")
 	}
+	// Counted clicks are queued to webhooks before exit.
+	cancelWorkers()
+	<-webhooksDone
 }
@@ -27,6 +27,30 @@ type Config struct {
 	EnableHTTPS     bool   `env:"ENABLE_HTTPS" json:"enable_https,omitempty"`
 	TrustedSubnet   string `env:"TRUSTED_SUBNET" json:"trusted_subnet"`
 	GrpcPort        string `env:"GRPC_RUN_PORT" json:"grpc_port"`
+	GrpcCertFile    string `env:"GRPC_TLS_CERT" json:"grpc_tls_cert,omitempty"`
+	GrpcKeyFile     string `env:"GRPC_TLS_KEY" json:"grpc_tls_key,omitempty"`
+	GrpcClientCA    string `env:"GRPC_CLIENT_CA" json:"grpc_client_ca,omitempty"`
+	GrpcAdminCNs    string `env:"GRPC_ADMIN_CNS" json:"grpc_admin_cns,omitempty"`
+	CookieSecret    string `env:"COOKIE_SECRET" json:"cookie_secret,omitempty"`
+	CookieKeysFile  string `env:"COOKIE_KEYS_FILE" json:"cookie_keys_file,omitempty"`
+	OIDCIssuer      string `env:"OIDC_ISSUER" json:"oidc_issuer,omitempty"`
+	OIDCClientID    string `env:"OIDC_CLIENT_ID" json:"oidc_client_id,omitempty"`
+	OIDCSecret      string `env:"OIDC_CLIENT_SECRET" json:"oidc_client_secret,omitempty"`
+	OIDCRedirectURL string `env:"OIDC_REDIRECT_URL" json:"oidc_redirect_url,omitempty"`
+	AdminLogins     string `env:"ADMIN_LOGINS" json:"admin_logins,omitempty"`
+	AuditLogPath    string `env:"AUDIT_LOG_PATH" json:"audit_log_path,omitempty"`
+	RateLimits      string `env:"RATE_LIMITS" json:"rate_limits,omitempty"`
+	RateLimitShared bool   `env:"RATE_LIMIT_SHARED" json:"rate_limit_shared,omitempty"`
+	QuotaActive     int    `env:"QUOTA_ACTIVE_LINKS" json:"quota_active_links,omitempty"`
+	QuotaPerDay     int    `env:"QUOTA_LINKS_PER_DAY" json:"quota_links_per_day,omitempty"`
+	DomainAllowlist string `env:"DOMAIN_ALLOWLIST" json:"domain_allowlist,omitempty"`
+	DomainDenylist  string `env:"DOMAIN_DENYLIST" json:"domain_denylist,omitempty"`
+	BlocklistFile   string `env:"DOMAIN_BLOCKLIST_FILE" json:"domain_blocklist_file,omitempty"`
+	QRLogoFile      string `env:"QR_LOGO_FILE" json:"qr_logo_file,omitempty"`
+	HealthInterval  string `env:"HEALTH_CHECK_INTERVAL" json:"health_check_interval,omitempty"`
+	HealthWorkers   int    `env:"HEALTH_CHECK_WORKERS" json:"health_check_workers,omitempty"`
+	HealthHostDelay string `env:"HEALTH_HOST_DELAY" json:"health_host_delay,omitempty"`
+	GeoIPFile       string `env:"GEOIP_FILE" json:"geoip_file,omitempty"`
 }
 
 // ChangeByPriority changes config by priority.
@@ -56,6 +80,30 @@ func GetConfig() Config {
 		flag.BoolVar(&flagCfg.EnableHTTPS, "s", false, "Enable HTTPS")
 		flag.StringVar(&flagCfg.TrustedSubnet, "t", "", "Trusted subnet")
 		flag.StringVar(&flagCfg.GrpcPort, "gp", "", "gRPC port")
+		flag.StringVar(&flagCfg.GrpcCertFile, "gc", "", "gRPC TLS certificate file")
+		flag.StringVar(&flagCfg.GrpcKeyFile, "gk", "", "gRPC TLS key file")
+		flag.StringVar(&flagCfg.GrpcClientCA, "gca", "", "gRPC client CA bundle file")
+		flag.StringVar(&flagCfg.GrpcAdminCNs, "gacn", "", "Comma separated CNs of gRPC client certificates with admin role")
+		flag.StringVar(&flagCfg.CookieSecret, "cs", "", "Cookie signing secret")
+		flag.StringVar(&flagCfg.CookieKeysFile, "ck", "", "Cookie signing key ring file")
+		flag.StringVar(&flagCfg.OIDCIssuer, "oi", "", "OIDC issuer URL")
+		flag.StringVar(&flagCfg.OIDCClientID, "oc", "", "OIDC client ID")
+		flag.StringVar(&flagCfg.OIDCSecret, "os", "", "OIDC client secret")
+		flag.StringVar(&flagCfg.OIDCRedirectURL, "or", "", "OIDC redirect URL, BaseURL/api/user/oidc/callback by default")
+		flag.StringVar(&flagCfg.AdminLogins, "al", "", "Comma separated ext: logins of OIDC users with admin role")
+		flag.StringVar(&flagCfg.AuditLogPath, "au", "", "Audit log JSONL file, next to storage or audit.jsonl by default")
+		flag.StringVar(&flagCfg.RateLimits, "rl", "", "Rate limits of route groups, e.g. create=20/s,redirect=100/s,history=10/s,internal=1/s")
+		flag.BoolVar(&flagCfg.RateLimitShared, "rls", false, "Share rate limits between replicas through database")
+		flag.IntVar(&flagCfg.QuotaActive, "qa", 0, "Max not deleted links of user, 0 is unlimited")
+		flag.IntVar(&flagCfg.QuotaPerDay, "qd", 0, "Max links created by user per UTC day, 0 is unlimited")
+		flag.StringVar(&flagCfg.DomainAllowlist, "da", "", "Comma separated allowed domains, *.domain or CIDR, all domains by default")
+		flag.StringVar(&flagCfg.DomainDenylist, "dd", "", "Comma separated denied domains, *.domain or CIDR")
+		flag.StringVar(&flagCfg.BlocklistFile, "bf", "", "Blocklist file of denied domains in hosts format or one per line, reloaded on change")
+		flag.StringVar(&flagCfg.QRLogoFile, "ql", "", "PNG or JPEG logo embedded in QR codes on request")
+		flag.StringVar(&flagCfg.HealthInterval, "hi", "", "Interval of health checks of original urls, e.g. 1h, checks are off by default")
+		flag.IntVar(&flagCfg.HealthWorkers, "hw", 0, "Max concurrent health checks, 4 by default")
+		flag.StringVar(&flagCfg.HealthHostDelay, "hd", "", "Min delay between health checks of one host, 1s by default")
+		flag.StringVar(&flagCfg.GeoIPFile, "geo", "", "GeoIP CSV database of countries for redirect rules")
 
 		flag.StringVar(&cfgFilePath, "c", "", "Config file path")
 		flag.StringVar(&cfgFilePath, "config", "", "Config file path")
@@ -0,0 +1,408 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bbt-t/lets-go-shortener/internal/adapter/storage"
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+	"github.com/bbt-t/lets-go-shortener/internal/usecase"
+	pb "github.com/bbt-t/lets-go-shortener/pkg/grpc"
+
+	"github.com/go-chi/chi/v5"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// adminActorKey is context key for actor of admin request.
+type adminActorKey struct{}
+
+// adminActor gets actor of admin request from context.
+func adminActor(ctx context.Context) (entity.Actor, bool) {
+	actor, ok := ctx.Value(adminActorKey{}).(entity.Actor)
+	return actor, ok
+}
+
+// inTrustedSubnet checks if ip is in trusted subnet.
+func inTrustedSubnet(trustedSubnet, ip string) bool {
+	if trustedSubnet == "" || ip == "" {
+		return false
+	}
+	_, subnet, err := net.ParseCIDR(trustedSubnet)
+	if err != nil {
+		return false
+	}
+	parsed := net.ParseIP(ip)
+	return parsed != nil && subnet.Contains(parsed)
+}
+
+// identityActor gets actor of identity with moderate scope.
+func identityActor(ctx context.Context, ip string) (entity.Actor, bool) {
+	identity, ok := IdentityFromContext(ctx)
+	if !ok || !identity.HasScope(entity.ScopeModerate) {
+		return entity.Actor{}, false
+	}
+	return entity.Actor{UserID: identity.UserID, IP: ip}, true
+}
+
+// RequireAdmin allows request for users with admin role or from trusted subnet.
+// Actor of request is added to context for audit log.
+func RequireAdmin(trustedSubnet string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			actor, ok := identityActor(r.Context(), clientIP(r))
+			if !ok {
+				realIP := r.Header.Get("X-Real-IP")
+				if !inTrustedSubnet(trustedSubnet, realIP) {
+					http.Error(w, "403 Forbidden", http.StatusForbidden)
+					return
+				}
+				actor = entity.Actor{IP: realIP}
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), adminActorKey{}, actor)))
+		})
+	}
+}
+
+// writeJSON sends value as JSON.
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(data)
+}
+
+// writeLink sends link or error of admin action.
+func writeLink(w http.ResponseWriter, link entity.LinkInfo, err error) {
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, usecase.ErrEmptyOwner):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	default:
+		writeJSON(w, http.StatusOK, link)
+	}
+}
+
+// parsePage gets limit and offset of page from query.
+func parsePage(r *http.Request) (limit, offset int, err error) {
+	query := r.URL.Query()
+	if v := query.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil {
+			return 0, 0, errors.New("wrong limit")
+		}
+	}
+	if v := query.Get("offset"); v != "" {
+		if offset, err = strconv.Atoi(v); err != nil {
+			return 0, 0, errors.New("wrong offset")
+		}
+	}
+	return limit, offset, nil
+}
+
+// parseTime gets time from query, empty value is zero time.
+func parseTime(r *http.Request, name string) (time.Time, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return t, errors.New("wrong " + name + ", RFC 3339 time is expected")
+	}
+	return t, nil
+}
+
+// searchLinks sends links matching filter.
+func searchLinks(s *ShortenerHandler, w http.ResponseWriter, r *http.Request, filter entity.LinkFilter) {
+	var err error
+
+	if filter.Limit, filter.Offset, err = parsePage(r); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	actor, _ := adminActor(r.Context())
+	links, err := s.storage.SearchURLs(actor, filter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, links)
+}
+
+// SearchLinks searches links by domain, owner and query.
+func SearchLinks(s *ShortenerHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		searchLinks(s, w, r, entity.LinkFilter{
+			Domain: query.Get("domain"),
+			UserID: query.Get("user_id"),
+			Query:  query.Get("q"),
+		})
+	}
+}
+
+// UserLinks gets links of user.
+func UserLinks(s *ShortenerHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		searchLinks(s, w, r, entity.LinkFilter{UserID: chi.URLParam(r, "userID")})
+	}
+}
+
+// GetLinkInfo gets link with owner and state.
+func GetLinkInfo(s *ShortenerHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		actor, _ := adminActor(r.Context())
+		link, err := s.storage.GetURLInfo(actor, chi.URLParam(r, "id"))
+		writeLink(w, link, err)
+	}
+}
+
+// DisableLink disables link for all users.
+func DisableLink(s *ShortenerHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		actor, _ := adminActor(r.Context())
+		link, err := s.storage.DisableURL(actor, chi.URLParam(r, "id"))
+		writeLink(w, link, err)
+	}
+}
+
+// RestoreLink restores disabled link.
+func RestoreLink(s *ShortenerHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		actor, _ := adminActor(r.Context())
+		link, err := s.storage.RestoreURL(actor, chi.URLParam(r, "id"))
+		writeLink(w, link, err)
+	}
+}
+
+// ReassignLink changes owner of link.
+func ReassignLink(s *ShortenerHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req entity.ReassignRequest
+
+		resBody, err := io.ReadAll(r.Body)
+		defer r.Body.Close()
+		if err != nil || string(resBody) == "" {
+			http.Error(w, "wrong body", http.StatusBadRequest)
+			return
+		}
+		if err = json.Unmarshal(resBody, &req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		actor, _ := adminActor(r.Context())
+		link, err := s.storage.ReassignURL(actor, chi.URLParam(r, "id"), req.UserID)
+		writeLink(w, link, err)
+	}
+}
+
+// AuditEvents gets audit events by user, ip, action, target and time, newest first.
+func AuditEvents(s *ShortenerHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var err error
+
+		query := r.URL.Query()
+		filter := entity.AuditFilter{
+			UserID: query.Get("user_id"),
+			IP:     query.Get("ip"),
+			Action: query.Get("action"),
+			Target: query.Get("target"),
+		}
+		if filter.Limit, filter.Offset, err = parsePage(r); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if filter.Since, err = parseTime(r, "since"); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if filter.Until, err = parseTime(r, "until"); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		events, err := s.storage.GetAuditEvents(filter)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, events)
+	}
+}
+
+// HealthReport gets checked links by domain, failing=true keeps only links whose last check failed.
+func HealthReport(s *ShortenerHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var err error
+
+		query := r.URL.Query()
+		filter := entity.HealthFilter{Domain: query.Get("domain")}
+		if v := query.Get("failing"); v != "" {
+			if filter.Failing, err = strconv.ParseBool(v); err != nil {
+				http.Error(w, "wrong failing", http.StatusBadRequest)
+				return
+			}
+		}
+		if filter.Limit, filter.Offset, err = parsePage(r); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		actor, _ := adminActor(r.Context())
+		links, err := s.storage.GetHealthReport(actor, filter)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, links)
+	}
+}
+
+// authorizeAdmin gets actor of admin RPC.
+// Caller must have admin role or verified client certificate with admin CN, come from trusted subnet
+// or pass admin check of HTTP gateway.
+func (server *ShortenerServer) authorizeAdmin(ctx context.Context) (entity.Actor, error) {
+	if actor, ok := adminActor(ctx); ok {
+		return actor, nil
+	}
+	if actor, ok := identityActor(ctx, peerIP(ctx)); ok {
+		return actor, nil
+	}
+	if actor, ok := certActor(ctx, server.cfg.GrpcAdminCNs); ok {
+		return actor, nil
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil && inTrustedSubnet(server.cfg.TrustedSubnet, host) {
+			return entity.Actor{IP: host}, nil
+		}
+	}
+	return entity.Actor{}, status.Error(codes.PermissionDenied, "admin role or trusted subnet is required")
+}
+
+// linkToProto gets proto message of link.
+func linkToProto(link entity.LinkInfo) *pb.AdminLink {
+	return &pb.AdminLink{
+		Id:       link.ID,
+		ShortUrl: link.ShortURL,
+		LongUrl:  link.OriginalURL,
+		UserId:   link.UserID,
+		Deleted:  link.Deleted,
+		Disabled: link.Disabled,
+	}
+}
+
+// linkResult gets proto message of link or status error of admin action.
+func linkResult(link entity.LinkInfo, err error) (*pb.AdminLink, error) {
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		return nil, statusWithReason(codes.NotFound, err.Error(), pb.ReasonNotFound)
+	case errors.Is(err, usecase.ErrEmptyOwner):
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	case err != nil:
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return linkToProto(link), nil
+}
+
+// SearchLinks searches links by domain, owner and query.
+func (server *ShortenerServer) SearchLinks(ctx context.Context, in *pb.LinkFilter) (*pb.AdminLinkList, error) {
+	actor, err := server.authorizeAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	links, err := server.service.SearchURLs(actor, entity.LinkFilter{
+		Domain: in.Domain,
+		UserID: in.UserId,
+		Query:  in.Query,
+		Limit:  int(in.Limit),
+		Offset: int(in.Offset),
+	})
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	result := &pb.AdminLinkList{Links: make([]*pb.AdminLink, 0, len(links))}
+	for _, link := range links {
+		result.Links = append(result.Links, linkToProto(link))
+	}
+	return result, nil
+}
+
+// GetLinkInfo gets link with owner and state.
+func (server *ShortenerServer) GetLinkInfo(ctx context.Context, in *pb.AdminLink) (*pb.AdminLink, error) {
+	actor, err := server.authorizeAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return linkResult(server.service.GetURLInfo(actor, in.Id))
+}
+
+// DisableLink disables link for all users.
+func (server *ShortenerServer) DisableLink(ctx context.Context, in *pb.AdminLink) (*pb.AdminLink, error) {
+	actor, err := server.authorizeAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return linkResult(server.service.DisableURL(actor, in.Id))
+}
+
+// RestoreLink restores disabled link.
+func (server *ShortenerServer) RestoreLink(ctx context.Context, in *pb.AdminLink) (*pb.AdminLink, error) {
+	actor, err := server.authorizeAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return linkResult(server.service.RestoreURL(actor, in.Id))
+}
+
+// ReassignLink changes owner of link.
+func (server *ShortenerServer) ReassignLink(ctx context.Context, in *pb.AdminLink) (*pb.AdminLink, error) {
+	actor, err := server.authorizeAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return linkResult(server.service.ReassignURL(actor, in.Id, in.UserId))
+}
+
+// HealthReport gets checked links by domain, failing keeps only links whose last check failed.
+func (server *ShortenerServer) HealthReport(ctx context.Context, in *pb.HealthFilter) (*pb.AdminLinkList, error) {
+	actor, err := server.authorizeAdmin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	links, err := server.service.GetHealthReport(actor, entity.HealthFilter{
+		Failing: in.Failing,
+		Domain:  in.Domain,
+		Limit:   int(in.Limit),
+		Offset:  int(in.Offset),
+	})
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	result := &pb.AdminLinkList{Links: make([]*pb.AdminLink, 0, len(links))}
+	for _, link := range links {
+		message := linkToProto(link.LinkInfo)
+		message.Health = healthToProto(link.Health)
+		result.Links = append(result.Links, message)
+	}
+	return result, nil
+}
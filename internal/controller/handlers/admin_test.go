@@ -0,0 +1,358 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bbt-t/lets-go-shortener/internal/adapter/storage"
+	"github.com/bbt-t/lets-go-shortener/internal/config"
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+	"github.com/bbt-t/lets-go-shortener/internal/usecase"
+	pb "github.com/bbt-t/lets-go-shortener/pkg/grpc"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+func TestAdminAPI(t *testing.T) {
+	cfg := config.GetDefaultConfig()
+	cfg.TrustedSubnet = "10.0.0.0/8"
+	cfg.AdminLogins = usecase.ExternalLogin("https://accounts.example.com", "root") + ", root"
+	api := newTestAPI(t, cfg, NewSessionMiddleware)
+	s, h := api.s, api.h
+	api.router.Post("/api/user/register", Register(h))
+	api.router.Get("/{id}", RecoverOriginalURL(h))
+	api.router.Route("/api/admin", func(r chi.Router) {
+		r.Use(RequireAdmin(cfg.TrustedSubnet))
+		r.Get("/links", SearchLinks(h))
+		r.Get("/users/{userID}/links", UserLinks(h))
+		r.Get("/links/{id}", GetLinkInfo(h))
+		r.Post("/links/{id}/disable", DisableLink(h))
+		r.Post("/links/{id}/restore", RestoreLink(h))
+		r.Put("/links/{id}/owner", ReassignLink(h))
+	})
+
+	_, err := s.CreateShort("user1", "https://yandex.ru", "https://google.com")
+	require.NoError(t, err)
+
+	register := func(login string) *http.Cookie {
+		res := api.do(http.MethodPost, "/api/user/register", `{"login":"`+login+`","password":"correct horse"}`)
+		defer res.Body.Close()
+		require.Equal(t, http.StatusCreated, res.StatusCode)
+		for _, c := range res.Cookies() {
+			if c.Name == sessionCookie {
+				return c
+			}
+		}
+		t.Fatal("session cookie is not set")
+		return nil
+	}
+	adminUser, err := h.storage.LoginExternal("https://accounts.example.com", "root")
+	require.NoError(t, err)
+	token, _, err := h.storage.CreateSession(adminUser)
+	require.NoError(t, err)
+	admin := &http.Cookie{Name: sessionCookie, Value: token}
+	user := register("alice")
+
+	// listed password login isn't admin, anyone could register it.
+	res := api.do(http.MethodGet, "/api/admin/links", "", withCookie(register("root")))
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusForbidden, res.StatusCode)
+
+	res = api.do(http.MethodGet, "/api/admin/links", "")
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusForbidden, res.StatusCode)
+
+	res = api.do(http.MethodGet, "/api/admin/links", "", withCookie(user))
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusForbidden, res.StatusCode)
+
+	res = api.do(http.MethodGet, "/api/admin/links", "", fromIP("192.168.1.1"))
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusForbidden, res.StatusCode)
+
+	res = api.do(http.MethodGet, "/api/admin/links?domain=yandex.ru", "", withCookie(admin))
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	var links []entity.LinkInfo
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&links))
+	require.Len(t, links, 1)
+	assert.Equal(t, "user1", links[0].UserID)
+
+	res = api.do(http.MethodGet, "/api/admin/users/user1/links?limit=1&offset=1", "", fromIP("10.1.1.1"))
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&links))
+	require.Len(t, links, 1)
+	assert.Equal(t, "https://google.com", links[0].OriginalURL)
+
+	res = api.do(http.MethodGet, "/api/admin/links?limit=many", "", fromIP("10.1.1.1"))
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+	// disabled link is gone for everybody until it is restored.
+	res = api.do(http.MethodPost, "/api/admin/links/1/disable", "", withCookie(admin))
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	var link entity.LinkInfo
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&link))
+	assert.True(t, link.Disabled)
+
+	res = api.do(http.MethodGet, "/1", "")
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusGone, res.StatusCode)
+
+	res = api.do(http.MethodPost, "/api/admin/links/1/restore", "", withCookie(admin))
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	res = api.do(http.MethodGet, "/1", "")
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusTemporaryRedirect, res.StatusCode)
+
+	res = api.do(http.MethodPut, "/api/admin/links/2/owner", `{"user_id":"user2"}`, withCookie(admin))
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&link))
+	assert.Equal(t, "user2", link.UserID)
+
+	res = api.do(http.MethodPut, "/api/admin/links/2/owner", `{"user_id":""}`, withCookie(admin))
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+	res = api.do(http.MethodGet, "/api/admin/links/100", "", withCookie(admin))
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+}
+
+func TestAuthorizeAdmin(t *testing.T) {
+	cfg := config.GetDefaultConfig()
+	cfg.TrustedSubnet = "10.0.0.0/8"
+	cfg.GrpcAdminCNs = "ops, deploy"
+	s, err := storage.NewMapStorage(cfg)
+	require.NoError(t, err)
+	server := NewShortenerServer(cfg, usecase.NewShortenerService(cfg, s))
+
+	_, err = s.CreateShort("user1", "https://yandex.ru")
+	require.NoError(t, err)
+
+	withPeer := func(ip string) context.Context {
+		return peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(ip), Port: 5000}})
+	}
+
+	moderator := WithIdentity(context.Background(), entity.Identity{
+		UserID: "admin",
+		Scopes: append(append([]string{}, entity.AllScopes...), entity.ScopeModerate),
+	})
+	link, err := server.DisableLink(moderator, &pb.AdminLink{Id: "1"})
+	require.NoError(t, err)
+	assert.True(t, link.Disabled)
+
+	list, err := server.SearchLinks(withPeer("10.2.3.4"), &pb.LinkFilter{UserId: "user1"})
+	require.NoError(t, err)
+	require.Len(t, list.Links, 1)
+	assert.True(t, list.Links[0].Disabled)
+
+	user := WithIdentity(context.Background(), entity.Identity{UserID: "user1", Scopes: entity.AllScopes})
+	_, err = server.RestoreLink(user, &pb.AdminLink{Id: "1"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = server.GetLinkInfo(withPeer("192.168.1.1"), &pb.AdminLink{Id: "1"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	ca := newTestCert(t, "test-ca", true, nil)
+	withCert, err := clientCertContext(peerContext(newTestCert(t, "ops", false, ca).cert, ca.cert), "", false)
+	require.NoError(t, err)
+	actor, err := server.authorizeAdmin(withCert)
+	require.NoError(t, err)
+	assert.Equal(t, "cert:ops", actor.UserID)
+
+	// certificate of the same CA without admin CN isn't admin.
+	withCert, err = clientCertContext(peerContext(newTestCert(t, "billing", false, ca).cert, ca.cert), "", false)
+	require.NoError(t, err)
+	identity, _ := ClientIdentityFromContext(withCert)
+	assert.Equal(t, "billing", identity)
+	_, err = server.authorizeAdmin(withCert)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = server.GetLinkInfo(moderator, &pb.AdminLink{Id: "100"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = server.ReassignLink(moderator, &pb.AdminLink{Id: "1"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestAuditEvents(t *testing.T) {
+	cfg := config.GetDefaultConfig()
+	cfg.AuditLogPath = filepath.Join(t.TempDir(), "audit.jsonl")
+	cfg.TrustedSubnet = "10.0.0.0/8"
+	api := newTestAPI(t, cfg)
+	h := api.h
+	auditLog, err := storage.NewAuditLog(cfg, api.s)
+	require.NoError(t, err)
+	api.service.SetAuditLog(auditLog)
+
+	api.router.Post("/api/shorten", RecoverOriginalURLPost(h))
+	api.router.Delete("/api/user/urls", DeleteURL(h))
+	api.router.Post("/api/user/keys", CreateAPIKey(h))
+	api.router.Route("/api/admin", func(r chi.Router) {
+		r.Use(RequireAdmin(cfg.TrustedSubnet))
+		r.Post("/links/{id}/disable", DisableLink(h))
+		r.Get("/audit", AuditEvents(h))
+	})
+	query := func(target string) []entity.AuditEvent {
+		res := api.do(http.MethodGet, target, "", fromIP("10.1.1.1"))
+		defer res.Body.Close()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		var events []entity.AuditEvent
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&events))
+		return events
+	}
+
+	res := api.do(http.MethodPost, "/api/shorten", `{"url":"https://yandex.ru"}`, fromIP("192.168.1.1"))
+	defer res.Body.Close()
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+	user := res.Cookies()[0]
+
+	res = api.do(http.MethodPost, "/api/shorten", `{"url":"https://google.com"}`, fromIP("192.168.1.1"), withCookie(user))
+	defer res.Body.Close()
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+
+	res = api.do(http.MethodDelete, "/api/user/urls", `["1","2","100"]`, fromIP("192.168.1.2"), withCookie(user))
+	defer res.Body.Close()
+	require.Equal(t, http.StatusAccepted, res.StatusCode)
+
+	res = api.do(http.MethodPost, "/api/user/keys", `{"name":"ci","scopes":["read"]}`, fromIP("192.168.1.2"), withCookie(user))
+	defer res.Body.Close()
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+
+	res = api.do(http.MethodPost, "/api/admin/links/2/disable", "", fromIP("10.1.1.1"))
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	res = api.do(http.MethodGet, "/api/admin/audit", "", fromIP("192.168.1.1"), withCookie(user))
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusForbidden, res.StatusCode)
+
+	events := query("/api/admin/audit")
+	actions := make([]string, 0, len(events))
+	for _, event := range events {
+		actions = append(actions, event.Action+" "+event.Target)
+	}
+	assert.Equal(t, []string{
+		"link.disable 2", "key.create " + events[1].Target, "link.delete 2", "link.delete 1", "link.create 2", "link.create 1",
+	}, actions)
+
+	events = query("/api/admin/audit?action=link.delete&target=1")
+	require.Len(t, events, 1)
+	assert.NotEmpty(t, events[0].UserID)
+	assert.Equal(t, "192.168.1.2", events[0].IP)
+	var before, after entity.LinkInfo
+	require.NoError(t, json.Unmarshal(events[0].Before, &before))
+	require.NoError(t, json.Unmarshal(events[0].After, &after))
+	assert.False(t, before.Deleted)
+	assert.True(t, after.Deleted)
+
+	userID := events[0].UserID
+
+	events = query("/api/admin/audit?ip=10.1.1.1")
+	require.Len(t, events, 1)
+	assert.Empty(t, events[0].UserID)
+	assert.Equal(t, "link.disable", events[0].Action)
+
+	events = query("/api/admin/audit?user_id=" + userID + "&limit=2&offset=1")
+	require.Len(t, events, 2)
+	assert.Equal(t, "link.delete", events[0].Action)
+	assert.Equal(t, "link.delete", events[1].Action)
+
+	events = query("/api/admin/audit?until=2000-01-01T00:00:00Z")
+	assert.Empty(t, events)
+
+	res = api.do(http.MethodGet, "/api/admin/audit?since=yesterday", "", fromIP("10.1.1.1"))
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+}
+
+func TestHealthReport(t *testing.T) {
+	cfg := config.GetDefaultConfig()
+	cfg.TrustedSubnet = "10.0.0.0/8"
+	api := newTestAPI(t, cfg)
+	s, h := api.s, api.h
+	server := NewShortenerServer(cfg, api.service)
+	api.router.Route("/api/admin", func(r chi.Router) {
+		r.Use(RequireAdmin(cfg.TrustedSubnet))
+		r.Get("/health", HealthReport(h))
+	})
+
+	ids, err := s.CreateShort("user1", "https://yandex.ru", "https://go.dev/doc", "https://google.com")
+	require.NoError(t, err)
+	now := time.Now().UTC().Truncate(time.Second)
+	require.NoError(t, s.SetURLHealth(ids[0], entity.LinkHealth{Status: 200, LatencyMS: 12, CheckedAt: now, NextCheckAt: now.Add(time.Hour)}))
+	require.NoError(t, s.SetURLHealth(ids[1], entity.LinkHealth{Error: "timeout", Failures: 1, CheckedAt: now, NextCheckAt: now.Add(2 * time.Hour)}))
+
+	res := api.do(http.MethodGet, "/api/admin/health", "", fromIP("192.168.1.1"))
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusForbidden, res.StatusCode)
+
+	res = api.do(http.MethodGet, "/api/admin/health", "", fromIP("10.1.1.1"))
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	var links []entity.LinkHealthInfo
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&links))
+	assert.Len(t, links, 2, "links which were never checked aren't reported")
+
+	res = api.do(http.MethodGet, "/api/admin/health?failing=true", "", fromIP("10.1.1.1"))
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&links))
+	require.Len(t, links, 1)
+	assert.Equal(t, "https://go.dev/doc", links[0].OriginalURL)
+	assert.Equal(t, "timeout", links[0].Health.Error)
+	assert.Equal(t, now.Add(2*time.Hour), links[0].Health.NextCheckAt)
+
+	res = api.do(http.MethodGet, "/api/admin/health?domain=yandex.ru", "", fromIP("10.1.1.1"))
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&links))
+	require.Len(t, links, 1)
+	assert.Equal(t, 200, links[0].Health.Status)
+
+	res = api.do(http.MethodGet, "/api/admin/health?failing=sometimes", "", fromIP("10.1.1.1"))
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+	moderator := WithIdentity(context.Background(), entity.Identity{
+		UserID: "admin",
+		Scopes: append(append([]string{}, entity.AllScopes...), entity.ScopeModerate),
+	})
+	list, err := server.HealthReport(moderator, &pb.HealthFilter{Failing: true})
+	require.NoError(t, err)
+	require.Len(t, list.Links, 1)
+	assert.Equal(t, ids[1], list.Links[0].Id)
+	assert.Equal(t, uint32(1), list.Links[0].Health.Failures)
+	assert.Equal(t, now.Add(2*time.Hour), list.Links[0].Health.NextCheckAt.AsTime())
+
+	user := WithIdentity(context.Background(), entity.Identity{UserID: "user1", Scopes: entity.AllScopes})
+	_, err = server.HealthReport(user, &pb.HealthFilter{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	history, err := server.GetHistory(user, &pb.HistoryFilter{})
+	require.NoError(t, err)
+	require.Len(t, history.Result, 3)
+	checked := 0
+	for _, link := range history.Result {
+		if link.Health != nil {
+			checked++
+		}
+	}
+	assert.Equal(t, 2, checked, "history has health of checked links")
+}
@@ -0,0 +1,229 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/bbt-t/lets-go-shortener/internal/adapter/storage"
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+	"github.com/bbt-t/lets-go-shortener/internal/usecase"
+	pb "github.com/bbt-t/lets-go-shortener/pkg/grpc"
+
+	"github.com/go-chi/chi/v5"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// CreateAPIKey creates API key for caller.
+func CreateAPIKey(s *ShortenerHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req entity.APIKeyRequest
+
+		userID, err := getUserID(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		resBody, err := io.ReadAll(r.Body)
+		defer r.Body.Close()
+		if err != nil || string(resBody) == "" {
+			http.Error(w, "wrong body", http.StatusBadRequest)
+			return
+		}
+		if err = json.Unmarshal(resBody, &req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		key, plain, err := s.storage.CreateAPIKey(requestActor(r, userID), req.Name, req.Scopes)
+		if errors.Is(err, usecase.ErrUnknownScope) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		data, err := json.Marshal(entity.APIKeyResponse{APIKey: key, Key: plain})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write(data)
+	}
+}
+
+// ListAPIKeys gets API keys of caller.
+func ListAPIKeys(s *ShortenerHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := getUserID(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		keys, err := s.storage.GetAPIKeysByUser(userID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if len(keys) == 0 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		data, err := json.Marshal(keys)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	}
+}
+
+// RevokeAPIKey revokes API key of caller.
+func RevokeAPIKey(s *ShortenerHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := getUserID(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		err = s.storage.RevokeAPIKey(requestActor(r, userID), chi.URLParam(r, "id"))
+		if errors.Is(err, storage.ErrNotFound) {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// CreateAPIKey creates API key for caller.
+func (server *ShortenerServer) CreateAPIKey(ctx context.Context, in *pb.APIKey) (*pb.APIKey, error) {
+	userID, err := authorize(ctx, entity.ScopeAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	key, plain, err := server.service.CreateAPIKey(callerActor(ctx, userID), in.Name, in.Scopes)
+	if errors.Is(err, usecase.ErrUnknownScope) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	result := apiKeyToProto(key)
+	result.Key = plain
+	return result, nil
+}
+
+// ListAPIKeys gets API keys of caller.
+func (server *ShortenerServer) ListAPIKeys(ctx context.Context, _ *emptypb.Empty) (*pb.APIKeyList, error) {
+	userID, err := authorize(ctx, entity.ScopeAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	keys, err := server.service.GetAPIKeysByUser(userID)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	result := &pb.APIKeyList{Keys: make([]*pb.APIKey, 0, len(keys))}
+	for _, key := range keys {
+		result.Keys = append(result.Keys, apiKeyToProto(key))
+	}
+	return result, nil
+}
+
+// RevokeAPIKey revokes API key of caller.
+func (server *ShortenerServer) RevokeAPIKey(ctx context.Context, in *pb.APIKey) (*emptypb.Empty, error) {
+	userID, err := authorize(ctx, entity.ScopeAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	err = server.service.RevokeAPIKey(callerActor(ctx, userID), in.Id)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, status.Error(codes.NotFound, "api key not found")
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// NewAPIKeyInterceptor authenticates calls with API key in "authorization: Bearer" metadata.
+func NewAPIKeyInterceptor(service *usecase.ShortenerService) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := apiKeyContext(ctx, service)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// NewAPIKeyStreamInterceptor authenticates streams with API key like NewAPIKeyInterceptor.
+func NewAPIKeyStreamInterceptor(service *usecase.ShortenerService) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := apiKeyContext(stream.Context(), service)
+		if err != nil {
+			return err
+		}
+		return handler(srv, contextStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+// apiKeyContext gets context with identity of API key from metadata, context is unchanged without API key.
+func apiKeyContext(ctx context.Context, service *usecase.ShortenerService) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return ctx, nil
+	}
+
+	token, ok := bearerToken(values[0])
+	if !ok {
+		return nil, status.Error(codes.Unauthenticated, "wrong authorization metadata")
+	}
+
+	identity, err := service.AuthenticateAPIKey(token)
+	if errors.Is(err, usecase.ErrInvalidAPIKey) {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return WithIdentity(ctx, identity), nil
+}
+
+// apiKeyToProto converts API key to proto message.
+func apiKeyToProto(key entity.APIKey) *pb.APIKey {
+	return &pb.APIKey{
+		Id:        key.ID,
+		Name:      key.Name,
+		Scopes:    key.Scopes,
+		CreatedAt: timestamppb.New(key.CreatedAt),
+		Revoked:   key.Revoked,
+	}
+}
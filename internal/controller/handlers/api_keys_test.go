@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/bbt-t/lets-go-shortener/internal/config"
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+	pb "github.com/bbt-t/lets-go-shortener/pkg/grpc"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// newAPIKeysTestAPI gets handler with authentication and scopes like in controller.
+func newAPIKeysTestAPI(t *testing.T) *testAPI {
+	api := newTestAPI(t, config.GetDefaultConfig(), NewAPIKeyMiddleware)
+	h := api.h
+	api.router.With(RequireScope(entity.ScopeRead)).Get("/api/user/urls", RecoverAllURL(h))
+	api.router.With(RequireScope(entity.ScopeWrite)).Post("/api/shorten", RecoverOriginalURLPost(h))
+	api.router.Group(func(r chi.Router) {
+		r.Use(RequireScope(entity.ScopeAdmin))
+		r.Post("/api/user/keys", CreateAPIKey(h))
+		r.Get("/api/user/keys", ListAPIKeys(h))
+		r.Delete("/api/user/keys/{id}", RevokeAPIKey(h))
+	})
+	return api
+}
+
+func TestAPIKeys(t *testing.T) {
+	api := newAPIKeysTestAPI(t)
+
+	// owner of cookie creates key.
+	res := api.do(http.MethodPost, "/api/user/keys", `{"name":"reporting","scopes":["read"]}`)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+	require.Len(t, res.Cookies(), 1)
+	owner := res.Cookies()[0]
+
+	var created entity.APIKeyResponse
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&created))
+	assert.True(t, strings.HasPrefix(created.Key, "sk_"+created.ID+"."))
+	assert.Equal(t, []string{entity.ScopeRead}, created.Scopes)
+
+	withKey := func(key string) requestOption { return withHeader("Authorization", "Bearer "+key) }
+
+	res = api.do(http.MethodPost, "/api/shorten", `{"url":"https://yandex.ru"}`, withCookie(owner))
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusCreated, res.StatusCode)
+
+	// key acts as owner of cookie and doesn't get cookie.
+	res = api.do(http.MethodGet, "/api/user/urls", "", withKey(created.Key))
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Empty(t, res.Cookies())
+	body, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), "https://yandex.ru")
+
+	// key has no write scope.
+	res = api.do(http.MethodPost, "/api/shorten", `{"url":"https://google.com"}`, withKey(created.Key))
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusForbidden, res.StatusCode)
+
+	res = api.do(http.MethodGet, "/api/user/urls", "", withKey("sk_unknown.secret"))
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+
+	res = api.do(http.MethodPost, "/api/user/keys", `{"scopes":["superuser"]}`, withCookie(owner))
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+	res = api.do(http.MethodGet, "/api/user/keys", "", withCookie(owner))
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	body, err = io.ReadAll(res.Body)
+	require.NoError(t, err)
+	assert.NotContains(t, string(body), created.Key[len("sk_"+created.ID+"."):], "secret mustn't be listed")
+
+	res = api.do(http.MethodDelete, "/api/user/keys/"+created.ID, "", withCookie(owner))
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusNoContent, res.StatusCode)
+
+	res = api.do(http.MethodGet, "/api/user/urls", "", withKey(created.Key))
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+}
+
+func TestAPIKeyInterceptor(t *testing.T) {
+	service := newAPIKeysTestAPI(t).service
+	server := NewShortenerServer(service.GetConfig(), service)
+	interceptor := NewAPIKeyInterceptor(service)
+
+	_, key, err := service.CreateAPIKey(entity.Actor{UserID: "user1"}, "", []string{entity.ScopeRead})
+	require.NoError(t, err)
+
+	call := func(ctx context.Context, method string, handler grpc.UnaryHandler) error {
+		_, err := interceptor(ctx, &emptypb.Empty{}, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+		return err
+	}
+	withKey := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+key))
+
+	err = call(withKey, pb.Shortener_GetHistory_FullMethodName, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return server.GetHistory(ctx, &pb.HistoryFilter{})
+	})
+	assert.NoError(t, err)
+
+	err = call(withKey, pb.Shortener_CreateShort_FullMethodName, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return server.CreateShort(ctx, &pb.Link{LongUrl: "https://yandex.ru"})
+	})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	badKey := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer sk_bad.key"))
+	err = call(badKey, pb.Shortener_GetHistory_FullMethodName, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return server.GetHistory(ctx, &pb.HistoryFilter{})
+	})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
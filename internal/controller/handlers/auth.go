@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+	"github.com/bbt-t/lets-go-shortener/internal/usecase"
+)
+
+// identityKey is context key for authenticated caller.
+type identityKey struct{}
+
+// WithIdentity adds identity of caller to context.
+func WithIdentity(ctx context.Context, identity entity.Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// IdentityFromContext gets identity of caller from context.
+func IdentityFromContext(ctx context.Context) (entity.Identity, bool) {
+	identity, ok := ctx.Value(identityKey{}).(entity.Identity)
+	return identity, ok
+}
+
+// bearerToken gets token from "Authorization: Bearer <token>" value.
+func bearerToken(header string) (string, bool) {
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+// NewAPIKeyMiddleware authenticates requests with API key in "Authorization: Bearer" header.
+// Requests without header are passed to cookie authentication.
+func NewAPIKeyMiddleware(s *ShortenerHandler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header := r.Header.Get("Authorization")
+			if header == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			token, ok := bearerToken(header)
+			if !ok {
+				unauthorized(w)
+				return
+			}
+
+			identity, err := s.storage.AuthenticateAPIKey(token)
+			if errors.Is(err, usecase.ErrInvalidAPIKey) {
+				unauthorized(w)
+				return
+			}
+			if err != nil {
+				log.Println("Failed authenticate api key:", err)
+				http.Error(w, "internal server error", http.StatusInternalServerError)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(WithIdentity(r.Context(), identity)))
+		})
+	}
+}
+
+// RequireScope allows request only if caller has scope.
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			identity, ok := IdentityFromContext(r.Context())
+			if !ok {
+				unauthorized(w)
+				return
+			}
+			if !identity.HasScope(scope) {
+				http.Error(w, "403 Forbidden", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// getUserID gets userID of caller from identity or userID cookie.
+func getUserID(r *http.Request) (string, error) {
+	if identity, ok := IdentityFromContext(r.Context()); ok {
+		return identity.UserID, nil
+	}
+
+	userCookie, err := r.Cookie("userID")
+	if err != nil {
+		return "", err
+	}
+	return userCookie.Value, nil
+}
+
+// clientIP gets IP of caller from X-Real-IP header or remote address.
+func clientIP(r *http.Request) string {
+	if realIP := r.Header.Get("X-Real-IP"); realIP != "" {
+		return realIP
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// requestActor gets actor of request made by user.
+func requestActor(r *http.Request, userID string) entity.Actor {
+	return entity.Actor{UserID: userID, IP: clientIP(r)}
+}
+
+// unauthorized sends 401 response.
+func unauthorized(w http.ResponseWriter) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="shortener"`)
+	http.Error(w, "401 Unauthorized", http.StatusUnauthorized)
+}
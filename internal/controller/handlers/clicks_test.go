@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/bbt-t/lets-go-shortener/internal/adapter/storage"
+	"github.com/bbt-t/lets-go-shortener/internal/config"
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+	"github.com/bbt-t/lets-go-shortener/internal/usecase"
+	pb "github.com/bbt-t/lets-go-shortener/pkg/grpc"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestMaxClicks(t *testing.T) {
+	cfg := config.GetDefaultConfig()
+	s, err := storage.NewMapStorage(cfg)
+	require.NoError(t, err)
+	service := usecase.NewShortenerService(cfg, s)
+	h := NewShortenerHandler(cfg, service)
+
+	router := chi.NewRouter()
+	router.Use(CookieMiddleware)
+	router.Post("/api/shorten", RecoverOriginalURLPost(h))
+	router.Get("/{id}", RecoverOriginalURL(h))
+	router.Post("/{id}", UnlockURL(h))
+
+	do := func(request *http.Request) (int, string) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		return res.StatusCode, string(body)
+	}
+	shorten := func(body string) (int, string) {
+		request := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(body))
+		request.Header.Set("Content-Type", "application/json")
+		code, resBody := do(request)
+		var created entity.RespJSON
+		if code == http.StatusCreated {
+			require.NoError(t, json.Unmarshal([]byte(resBody), &created))
+		}
+		return code, created.Result[strings.LastIndex(created.Result, "/")+1:]
+	}
+
+	code, _ := shorten(`{"url":"https://yandex.ru","max_clicks":-1}`)
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, id := shorten(`{"url":"https://yandex.ru/reset","max_clicks":1}`)
+	require.Equal(t, http.StatusCreated, code)
+	code, _ = do(httptest.NewRequest(http.MethodGet, "/"+id, nil))
+	assert.Equal(t, http.StatusTemporaryRedirect, code)
+	code, body := do(httptest.NewRequest(http.MethodGet, "/"+id, nil))
+	assert.Equal(t, http.StatusGone, code)
+	assert.Contains(t, body, "url clicks are exhausted")
+
+	server := NewShortenerServer(cfg, service)
+	_, err = server.GetLong(context.Background(), &pb.Link{Id: id})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	// form of protected link doesn't take click.
+	code, id = shorten(`{"url":"https://yandex.ru/reset","max_clicks":1,"password":"secret"}`)
+	require.Equal(t, http.StatusCreated, code)
+	code, _ = do(httptest.NewRequest(http.MethodGet, "/"+id, nil))
+	assert.Equal(t, http.StatusUnauthorized, code)
+	unlock := httptest.NewRequest(http.MethodPost, "/"+id, strings.NewReader(url.Values{"password": {"secret"}}.Encode()))
+	unlock.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	code, _ = do(unlock)
+	assert.Equal(t, http.StatusSeeOther, code)
+	code, _ = do(httptest.NewRequest(http.MethodGet, "/"+id, nil))
+	assert.Equal(t, http.StatusGone, code)
+
+	ctx := WithIdentity(context.Background(), entity.Identity{UserID: "user1", Scopes: entity.AllScopes})
+	link, err := server.CreateShort(ctx, &pb.Link{LongUrl: "https://yandex.ru/once", MaxClicks: 1})
+	require.NoError(t, err)
+	_, err = server.GetLong(context.Background(), &pb.Link{Id: link.Id})
+	assert.NoError(t, err)
+	_, err = server.GetLong(context.Background(), &pb.Link{Id: link.Id})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/bbt-t/lets-go-shortener/internal/adapter/storage"
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+	"github.com/bbt-t/lets-go-shortener/internal/usecase"
+	pb "github.com/bbt-t/lets-go-shortener/pkg/grpc"
+
+	"github.com/go-chi/chi/v5"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// sseKeepAlive is period of comments sent to idle event stream, so proxies don't close it.
+const sseKeepAlive = 15 * time.Second
+
+// contextStream is server stream with context changed by interceptor.
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context gets context of stream.
+func (s contextStream) Context() context.Context {
+	return s.ctx
+}
+
+// ClickEvents streams clicks of link of caller as server-sent events until client goes away.
+// Stream ends with "error" event if watcher is dropped.
+func ClickEvents(s *ShortenerHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := getUserID(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+			return
+		}
+
+		watcher, err := s.storage.WatchClicks(userID, chi.URLParam(r, "id"))
+		if errors.Is(err, storage.ErrNotFound) {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		if errors.Is(err, usecase.ErrClicksStopped) {
+			http.Error(w, err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		defer watcher.Close()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+		flusher.Flush()
+
+		keepAlive := time.NewTicker(sseKeepAlive)
+		defer keepAlive.Stop()
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-keepAlive.C:
+				io.WriteString(w, ": keep-alive\n\n")
+			case event, ok := <-watcher.Clicks():
+				if !ok {
+					fmt.Fprintf(w, "event: error\ndata: %s\n\n", watcher.Err())
+					flusher.Flush()
+					return
+				}
+				data, err := json.Marshal(event)
+				if err != nil {
+					return
+				}
+				fmt.Fprintf(w, "event: click\ndata: %s\n\n", data)
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// WatchClicks pushes clicks of link of caller until call is canceled.
+func (server *ShortenerServer) WatchClicks(in *pb.Link, stream pb.Shortener_WatchClicksServer) error {
+	ctx := stream.Context()
+	userID, err := authorize(ctx, entity.ScopeRead)
+	if err != nil {
+		return err
+	}
+
+	watcher, err := server.service.WatchClicks(userID, in.Id)
+	if errors.Is(err, storage.ErrNotFound) {
+		return status.Error(codes.NotFound, "link not found")
+	}
+	if errors.Is(err, usecase.ErrClicksStopped) {
+		return status.Error(codes.Unavailable, err.Error())
+	}
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	defer watcher.Close()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case event, ok := <-watcher.Clicks():
+			if !ok {
+				if errors.Is(watcher.Err(), usecase.ErrSlowWatcher) {
+					return status.Error(codes.ResourceExhausted, watcher.Err().Error())
+				}
+				return status.Error(codes.Unavailable, usecase.ErrClicksStopped.Error())
+			}
+			err = stream.Send(&pb.ClickEvent{
+				LinkId: event.LinkID,
+				Time:   timestamppb.New(event.Time),
+				Clicks: uint64(event.Clicks),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
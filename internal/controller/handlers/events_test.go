@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bbt-t/lets-go-shortener/internal/adapter/storage"
+	"github.com/bbt-t/lets-go-shortener/internal/config"
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+	"github.com/bbt-t/lets-go-shortener/internal/usecase"
+	pb "github.com/bbt-t/lets-go-shortener/pkg/grpc"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newClickEventsService gets service with click hub and link of user.
+func newClickEventsService(t *testing.T, userID string) (*usecase.ShortenerService, *usecase.ClickHub, string) {
+	cfg := config.GetDefaultConfig()
+	s, err := storage.NewMapStorage(cfg)
+	require.NoError(t, err)
+	service := usecase.NewShortenerService(cfg, s)
+	hub := usecase.NewClickHub()
+	service.SetClickHub(hub)
+
+	ids, err := service.CreateShort(entity.Actor{UserID: userID}, "https://example.com")
+	require.NoError(t, err)
+	return service, hub, ids[0]
+}
+
+func TestClickEvents(t *testing.T) {
+	userID, err := newUserID()
+	require.NoError(t, err)
+	service, hub, id := newClickEventsService(t, userID)
+	h := NewShortenerHandler(service.GetConfig(), service)
+
+	router := chi.NewRouter()
+	router.Use(CookieMiddleware, GzipHandle)
+	router.Get("/{id}", RecoverOriginalURL(h))
+	router.Get("/api/user/urls/{id}/events", ClickEvents(h))
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	client := server.Client()
+	client.Timeout = 5 * time.Second
+	client.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	watch := func(cookie string, id string) *http.Response {
+		request, err := http.NewRequest(http.MethodGet, server.URL+"/api/user/urls/"+id+"/events", nil)
+		require.NoError(t, err)
+		request.AddCookie(&http.Cookie{Name: "userID", Value: cookie})
+		res, err := client.Do(request)
+		require.NoError(t, err)
+		return res
+	}
+
+	other, err := newUserID()
+	require.NoError(t, err)
+	res := watch(other, id)
+	res.Body.Close()
+	assert.Equal(t, http.StatusNotFound, res.StatusCode, "link of other user can't be watched")
+
+	res = watch(userID, id)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "text/event-stream", res.Header.Get("Content-Type"))
+
+	// event is read by lines, data line is returned.
+	reader := bufio.NewReader(res.Body)
+	next := func() (string, string) {
+		var event, data string
+		for {
+			line, err := reader.ReadString('\n')
+			require.NoError(t, err)
+			line = strings.TrimRight(line, "\n")
+			switch {
+			case line == "":
+				return event, data
+			case strings.HasPrefix(line, "event: "):
+				event = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				data = strings.TrimPrefix(line, "data: ")
+			}
+		}
+	}
+
+	for i := 1; i <= 2; i++ {
+		redirect, err := client.Get(server.URL + "/" + id)
+		require.NoError(t, err)
+		redirect.Body.Close()
+		require.Equal(t, http.StatusTemporaryRedirect, redirect.StatusCode)
+
+		event, data := next()
+		assert.Equal(t, "click", event)
+		var click entity.ClickEvent
+		require.NoError(t, json.Unmarshal([]byte(data), &click))
+		assert.Equal(t, id, click.LinkID)
+		assert.Equal(t, i, click.Clicks)
+	}
+
+	hub.Stop()
+	event, data := next()
+	assert.Equal(t, "error", event)
+	assert.Equal(t, usecase.ErrClicksStopped.Error(), data)
+}
+
+func TestWatchClicksStream(t *testing.T) {
+	service, hub, id := newClickEventsService(t, "user1")
+	_, key, err := service.CreateAPIKey(entity.Actor{UserID: "user1"}, "", []string{entity.ScopeRead})
+	require.NoError(t, err)
+
+	listen := bufconn.Listen(1 << 20)
+	grpcServ := grpc.NewServer(grpc.StreamInterceptor(NewAPIKeyStreamInterceptor(service)))
+	pb.RegisterShortenerServer(grpcServ, NewShortenerServer(service.GetConfig(), service))
+	go grpcServ.Serve(listen)
+	defer grpcServ.Stop()
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return listen.Dial()
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	defer conn.Close()
+	client := pb.NewShortenerClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	withKey := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+key)
+
+	stream, err := client.WatchClicks(ctx, &pb.Link{Id: id})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	stream, err = client.WatchClicks(withKey, &pb.Link{Id: "missing"})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	stream, err = client.WatchClicks(withKey, &pb.Link{Id: id})
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return hub.Watched(id) }, time.Second, 10*time.Millisecond)
+
+	_, err = service.GetOriginal(id)
+	require.NoError(t, err)
+	event, err := stream.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, id, event.LinkId)
+	assert.Equal(t, uint64(1), event.Clicks)
+	assert.WithinDuration(t, time.Now(), event.Time.AsTime(), time.Second)
+
+	hub.Stop()
+	_, err = stream.Recv()
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
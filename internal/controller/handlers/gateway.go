@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	pb "github.com/bbt-t/lets-go-shortener/pkg/grpc"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// NewGatewayHandler gets HTTP/JSON handler for /v2/ API generated from service.proto.
+// Requests are passed to ShortenerServer directly, without network round trip.
+func NewGatewayHandler(ctx context.Context, server *ShortenerServer) (http.Handler, error) {
+	mux := runtime.NewServeMux(
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.JSONPb{
+			MarshalOptions: protojson.MarshalOptions{
+				UseProtoNames: true,
+			},
+			UnmarshalOptions: protojson.UnmarshalOptions{
+				DiscardUnknown: true,
+			},
+		}),
+		runtime.WithMetadata(gatewayMetadata),
+		runtime.WithErrorHandler(gatewayErrorHandler),
+	)
+
+	if err := pb.RegisterShortenerHandlerServer(ctx, mux, server); err != nil {
+		return nil, err
+	}
+	return mux, nil
+}
+
+// gatewayMetadata passes userID cookie, X-Link-Password header and IP of caller to gRPC metadata.
+func gatewayMetadata(_ context.Context, r *http.Request) metadata.MD {
+	md := metadata.MD{}
+	md.Set(clientIPMetadata, clientIP(r))
+	if userCookie, err := r.Cookie("userID"); err == nil {
+		md.Set("userID", userCookie.Value)
+	}
+	if password := r.Header.Get("X-Link-Password"); password != "" {
+		md.Set(linkPasswordMetadata, password)
+	}
+	return md
+}
+
+// gatewayErrorHandler sends error of gRPC call like runtime.DefaultHTTPErrorHandler,
+// but deleted and exhausted links are 410 Gone like in v1 API.
+func gatewayErrorHandler(ctx context.Context, mux *runtime.ServeMux, marshaler runtime.Marshaler, w http.ResponseWriter, r *http.Request, err error) {
+	for _, detail := range status.Convert(err).Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok && (info.Reason == pb.ReasonDeleted || info.Reason == pb.ReasonExhausted) {
+			err = &runtime.HTTPStatusError{HTTPStatus: http.StatusGone, Err: err}
+			break
+		}
+	}
+	runtime.DefaultHTTPErrorHandler(ctx, mux, marshaler, w, r, err)
+}
@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/bbt-t/lets-go-shortener/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestGatewayHandler(t *testing.T) {
+	cfg := config.GetDefaultConfig()
+	api := newTestAPI(t, cfg)
+	gateway, err := NewGatewayHandler(context.Background(), NewShortenerServer(cfg, api.service))
+	require.NoError(t, err)
+	api.router.Handle("/v2/*", gateway)
+
+	userID, err := newUserID()
+	require.NoError(t, err)
+	asOwner := withCookie(&http.Cookie{Name: "userID", Value: userID})
+
+	code, body := api.text(http.MethodPost, "/v2/shorten", `{"long_url":"https://yandex.ru"}`, asOwner)
+	assert.Equal(t, http.StatusOK, code)
+	assert.JSONEq(t, `{"short_url":"http://127.0.0.1:8080/1","id":"1"}`, body)
+
+	code, body = api.text(http.MethodPost, "/v2/shorten", `{"long_url":"not_url"}`, asOwner)
+	assert.Equal(t, http.StatusBadRequest, code, body)
+
+	code, body = api.text(http.MethodGet, "/v2/links/1", "", asOwner)
+	assert.Equal(t, http.StatusOK, code)
+	assert.JSONEq(t, `{"id":"1","long_url":"https://yandex.ru","short_url":"http://127.0.0.1:8080/1"}`, body)
+
+	code, body = api.text(http.MethodGet, "/v2/user/urls", "", asOwner)
+	assert.Equal(t, http.StatusOK, code)
+	assert.JSONEq(t, `{"result":[{"id":"1","long_url":"https://yandex.ru","short_url":"http://127.0.0.1:8080/1"}]}`, body)
+
+	code, _ = api.text(http.MethodDelete, "/v2/user/urls/1", "", asOwner)
+	assert.Equal(t, http.StatusOK, code)
+
+	code, _ = api.text(http.MethodGet, "/v2/links/1", "", asOwner)
+	assert.Equal(t, http.StatusGone, code, "deleted link is gone like in v1 API")
+
+	code, _ = api.text(http.MethodGet, "/v2/links/2", "", asOwner)
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, body = api.text(http.MethodPost, "/v2/shorten", `{"long_url":"https://ya.ru","max_clicks":1}`, asOwner)
+	require.Equal(t, http.StatusOK, code, body)
+	code, _ = api.text(http.MethodGet, "/v2/links/2", "", asOwner)
+	assert.Equal(t, http.StatusOK, code)
+	code, _ = api.text(http.MethodGet, "/v2/links/2", "", asOwner)
+	assert.Equal(t, http.StatusGone, code, "exhausted link is gone like in v1 API")
+}
+
+func TestGatewayPeerIP(t *testing.T) {
+	request := httptest.NewRequest(http.MethodGet, "/v2/links/1", nil)
+	request.RemoteAddr = "10.0.0.1:1234"
+	request.Header.Set("X-Forwarded-For", "1.2.3.4")
+	request.Header.Set("Grpc-Metadata-X-Client-Ip", "5.6.7.8")
+
+	// gateway puts headers to metadata before gatewayMetadata.
+	md := metadata.Join(metadata.Pairs(
+		"x-forwarded-for", "1.2.3.4, 10.0.0.1",
+		clientIPMetadata, "5.6.7.8",
+	), gatewayMetadata(context.Background(), request))
+	assert.Equal(t, "10.0.0.1", peerIP(metadata.NewIncomingContext(context.Background(), md)))
+}
@@ -2,6 +2,9 @@ package handlers
 
 import (
 	"context"
+	"errors"
+	"net"
+	"strings"
 
 	"github.com/bbt-t/lets-go-shortener/internal/adapter/storage"
 	"github.com/bbt-t/lets-go-shortener/internal/config"
@@ -9,12 +12,25 @@ import (
 	"github.com/bbt-t/lets-go-shortener/internal/usecase"
 	pb "github.com/bbt-t/lets-go-shortener/pkg/grpc"
 
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+// linkPasswordMetadata is metadata key of password of protected link.
+const linkPasswordMetadata = "link-password"
+
+// linkExistsMetadata is header key which is set if url was shortened by user before.
+const linkExistsMetadata = "link-exists"
+
+// clientIPMetadata is metadata key of IP of HTTP gateway caller.
+const clientIPMetadata = "x-client-ip"
+
 // ShortenerServer is struct for grpc.
 type ShortenerServer struct {
 	pb.UnimplementedShortenerServer
@@ -44,29 +60,42 @@ func (server *ShortenerServer) Ping(ctx context.Context, in *emptypb.Empty) (*em
 func (server *ShortenerServer) CreateShort(ctx context.Context, in *pb.Link) (*pb.Link, error) {
 	result := &pb.Link{}
 
-	md, ok := metadata.FromIncomingContext(ctx)
-	if !ok {
-		return nil, status.Error(codes.Unknown, "wrong metadata")
+	userID, err := issueUserID(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	if len(md.Get("userID")) == 0 {
-		return nil, status.Error(codes.Unknown, "wrong metadata")
+	options := entity.LinkOptions{Password: in.Password, MaxClicks: int(in.MaxClicks)}
+	if in.Meta != nil {
+		options.Meta = entity.LinkMeta{
+			Title:        in.Meta.Title,
+			Tags:         in.Meta.Tags,
+			Note:         in.Meta.Note,
+			Interstitial: in.Meta.Interstitial,
+		}
 	}
+	id, err := server.service.CreateShortWithOptions(callerActor(ctx, userID), in.LongUrl, options)
 
-	id, err := ShortSingleURL(server.service, md.Get("userID")[0], in.LongUrl)
-
+	if errors.Is(err, usecase.ErrQuotaExceeded) {
+		return result, status.Error(codes.ResourceExhausted, err.Error())
+	}
+	if errors.Is(err, usecase.ErrBlockedURL) {
+		return result, statusWithReason(codes.InvalidArgument, err.Error(), pb.ReasonBlocked)
+	}
 	if err != storage.ErrExists && err != nil {
-		return result, err
+		return result, status.Error(codes.InvalidArgument, err.Error())
 	}
 
 	result.ShortUrl = server.cfg.BaseURL + "/" + id
 	result.Id = id
 
 	if err == storage.ErrExists {
-		err = nil
+		if err = grpc.SetHeader(ctx, metadata.Pairs(linkExistsMetadata, "true")); err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
 	}
 
-	return result, err
+	return result, nil
 }
 
 // GetStatistics gets count of urls and users.
@@ -79,69 +108,185 @@ func (server *ShortenerServer) GetStatistics(ctx context.Context, _ *emptypb.Emp
 	return result, err
 }
 
-// GetLong gets long url from short one.
+// GetLong gets long url from short one, it's chosen by redirect rules of link for visitor of request.
 func (server *ShortenerServer) GetLong(ctx context.Context, in *pb.Link) (*pb.Link, error) {
 	result := &pb.Link{}
-	long, err := server.service.GetOriginal(in.Id)
-	if err == storage.ErrNotFound {
-		return nil, status.Error(codes.NotFound, "Link not in storage")
+	var password string
+	if md, ok := metadata.FromIncomingContext(ctx); ok && len(md.Get(linkPasswordMetadata)) != 0 {
+		password = md.Get(linkPasswordMetadata)[0]
+	}
+
+	long, err := server.service.UnlockOriginal(callerActor(ctx, ""), in.Id, password)
+	if err == nil {
+		long, err = server.service.RouteOriginal(in.Id, long, visitorOf(in.Visitor))
+	}
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, statusWithReason(codes.NotFound, "Link not in storage", pb.ReasonNotFound)
+	}
+	if errors.Is(err, storage.ErrDeleted) {
+		return nil, statusWithReason(codes.NotFound, "Link is deleted", pb.ReasonDeleted)
+	}
+	if errors.Is(err, storage.ErrExhausted) {
+		return nil, statusWithReason(codes.NotFound, "Link clicks are exhausted", pb.ReasonExhausted)
+	}
+	if errors.Is(err, usecase.ErrBlockedURL) {
+		return nil, statusWithReason(codes.FailedPrecondition, "Link leads to blocked domain", pb.ReasonBlocked)
+	}
+	if errors.Is(err, usecase.ErrPasswordRequired) {
+		return nil, statusWithReason(codes.Unauthenticated, "Link is protected by password", pb.ReasonPasswordRequired)
+	}
+	if errors.Is(err, usecase.ErrWrongPassword) {
+		return nil, statusWithReason(codes.PermissionDenied, "Wrong password of link", pb.ReasonWrongPassword)
 	}
+	if errors.Is(err, usecase.ErrTooManyAttempts) {
+		return nil, status.Error(codes.ResourceExhausted, err.Error())
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	result.Id = in.Id
+	result.ShortUrl = server.cfg.BaseURL + "/" + in.Id
 	result.LongUrl = long
-	return result, err
+	return result, nil
+}
+
+// visitorOf converts visitor of GetLong request, nil is unknown visitor.
+func visitorOf(visitor *pb.Visitor) entity.Visitor {
+	if visitor == nil {
+		return entity.Visitor{}
+	}
+	return entity.Visitor{
+		UserAgent:      visitor.UserAgent,
+		AcceptLanguage: visitor.AcceptLanguage,
+		IP:             visitor.Ip,
+		Country:        visitor.Country,
+	}
+}
+
+// GetQRCode renders QR code of short url.
+func (server *ShortenerServer) GetQRCode(ctx context.Context, in *pb.QRRequest) (*pb.QRCode, error) {
+	options := entity.QROptions{Size: int(in.Size), Format: in.Format, Level: in.Level, Logo: in.Logo}
+	content, contentType, err := server.service.RenderQR(in.Id, options)
+	if errors.Is(err, usecase.ErrInvalidQR) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, statusWithReason(codes.NotFound, "Link not in storage", pb.ReasonNotFound)
+	}
+	if errors.Is(err, storage.ErrDeleted) {
+		return nil, statusWithReason(codes.NotFound, "Link is deleted", pb.ReasonDeleted)
+	}
+	if errors.Is(err, storage.ErrExhausted) {
+		return nil, statusWithReason(codes.NotFound, "Link clicks are exhausted", pb.ReasonExhausted)
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &pb.QRCode{Content: content, ContentType: contentType}, nil
 }
 
 // Delete deletes url from storage.
 func (server *ShortenerServer) Delete(ctx context.Context, in *pb.Link) (*emptypb.Empty, error) {
-	md, ok := metadata.FromIncomingContext(ctx)
-	if !ok {
-		return nil, status.Error(codes.Unknown, "wrong metadata")
+	userID, err := authorize(ctx, entity.ScopeDelete)
+	if err != nil {
+		return nil, err
 	}
 
-	if len(md.Get("userID")) == 0 {
-		return nil, status.Error(codes.Unknown, "wrong metadata")
+	if err := server.service.MarkAsDeleted(callerActor(ctx, userID), in.Id); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
 	}
+	return &emptypb.Empty{}, nil
+}
 
-	userID := md.Get("userID")[0]
+// UpdateLink changes original url and metadata of link of caller.
+func (server *ShortenerServer) UpdateLink(ctx context.Context, in *pb.Link) (*pb.Link, error) {
+	userID, err := authorize(ctx, entity.ScopeWrite)
+	if err != nil {
+		return nil, err
+	}
 
-	err := server.service.MarkAsDeleted(userID, []string{in.Id}...)
-	return nil, err
-}
+	patch := entity.LinkPatch{URL: in.LongUrl}
+	if in.Meta != nil {
+		patch.Title, patch.Tags, patch.Note = &in.Meta.Title, &in.Meta.Tags, &in.Meta.Note
+		patch.Interstitial = &in.Meta.Interstitial
+	}
 
-// GetHistory gets history.
-func (server *ShortenerServer) GetHistory(ctx context.Context, in *emptypb.Empty) (*pb.Batch, error) {
-	md, ok := metadata.FromIncomingContext(ctx)
-	if !ok || len(md.Get("userID")) == 0 {
-		return nil, status.Error(codes.Unknown, "wrong metadata")
+	link, err := server.service.EditLink(callerActor(ctx, userID), in.Id, patch)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, statusWithReason(codes.NotFound, "Link not in storage", pb.ReasonNotFound)
+	}
+	if errors.Is(err, storage.ErrDeleted) {
+		return nil, statusWithReason(codes.NotFound, "Link is deleted", pb.ReasonDeleted)
+	}
+	if errors.Is(err, usecase.ErrBlockedURL) {
+		return nil, statusWithReason(codes.InvalidArgument, err.Error(), pb.ReasonBlocked)
+	}
+	if errors.Is(err, usecase.ErrInvalidURL) || errors.Is(err, usecase.ErrInvalidMeta) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
 	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return server.linkOf(in.Id, link), nil
+}
 
-	userID := md.Get("userID")[0]
+// GetHistory gets page of history filtered by tag and query.
+func (server *ShortenerServer) GetHistory(ctx context.Context, in *pb.HistoryFilter) (*pb.Batch, error) {
+	userID, err := authorize(ctx, entity.ScopeRead)
+	if err != nil {
+		return nil, err
+	}
 
-	history, err := server.service.GetURLArrayByUser(userID)
+	filter := entity.HistoryFilter{Tag: in.Tag, Query: in.Query, Limit: int(in.Limit)}
+	filter.Sort, filter.Desc = usecase.ParseSort(in.Sort)
+	page, err := server.service.GetURLArrayByUser(userID, filter, in.Cursor)
 
+	if errors.Is(err, usecase.ErrInvalidPage) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
 	if err != nil {
 		return nil, err
 	}
 
-	result := &pb.Batch{}
-	for _, elem := range history {
-		result.Result = append(result.Result, &pb.Link{
-			LongUrl:  elem.OriginalURL,
-			ShortUrl: elem.ShortURL,
-		})
+	result := &pb.Batch{NextCursor: page.NextCursor}
+	for _, elem := range page.Links {
+		result.Result = append(result.Result, server.linkOf(strings.TrimPrefix(elem.ShortURL, server.cfg.BaseURL+"/"), elem))
 	}
 
 	return result, nil
 }
 
+// linkOf gets link message of link of history.
+func (server *ShortenerServer) linkOf(id string, link entity.URLs) *pb.Link {
+	result := &pb.Link{Id: id, ShortUrl: link.ShortURL, LongUrl: link.OriginalURL}
+	if link.Title != "" || len(link.Tags) != 0 || link.Note != "" || link.Interstitial {
+		result.Meta = &pb.LinkMeta{Title: link.Title, Tags: link.Tags, Note: link.Note, Interstitial: link.Interstitial}
+	}
+	if link.Health != nil {
+		result.Health = healthToProto(*link.Health)
+	}
+	return result
+}
+
+// healthToProto gets proto message of health of link.
+func healthToProto(health entity.LinkHealth) *pb.LinkHealth {
+	return &pb.LinkHealth{
+		Status:      uint32(health.Status),
+		LatencyMs:   health.LatencyMS,
+		Error:       health.Error,
+		Failures:    uint32(health.Failures),
+		CheckedAt:   timestamppb.New(health.CheckedAt),
+		NextCheckAt: timestamppb.New(health.NextCheckAt),
+	}
+}
+
 // BatchShort shorts many urls, not single one.
 func (server *ShortenerServer) BatchShort(ctx context.Context, in *pb.Batch) (*pb.Batch, error) {
-	md, ok := metadata.FromIncomingContext(ctx)
-	if !ok || len(md.Get("userID")) == 0 {
-		return nil, status.Error(codes.Unknown, "wrong metadata")
+	userID, err := issueUserID(ctx)
+	if err != nil {
+		return nil, err
 	}
 
-	userID := md.Get("userID")[0]
-
 	result := &pb.Batch{}
 	query := make([]entity.URLBatch, 0, len(in.Result))
 
@@ -152,12 +297,21 @@ func (server *ShortenerServer) BatchShort(ctx context.Context, in *pb.Batch) (*p
 		})
 	}
 
-	urls, err := ShortURLs(server.service, userID, query)
+	urls, err := ShortURLs(server.service, callerActor(ctx, userID), query)
 
 	if err == storage.ErrExists {
 		err = nil
 	}
 
+	if errors.Is(err, usecase.ErrQuotaExceeded) {
+		return nil, status.Error(codes.ResourceExhausted, err.Error())
+	}
+	if errors.Is(err, usecase.ErrBlockedURL) {
+		return nil, statusWithReason(codes.InvalidArgument, err.Error(), pb.ReasonBlocked)
+	}
+	if errors.Is(err, usecase.ErrInvalidURL) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -168,8 +322,80 @@ func (server *ShortenerServer) BatchShort(ctx context.Context, in *pb.Batch) (*p
 		result.Result = append(result.Result, &pb.Link{
 			CorrelationId: url.CorrelationID,
 			ShortUrl:      url.ShortURL,
+			Id:            strings.TrimPrefix(url.ShortURL, server.cfg.BaseURL+"/"),
 		})
 	}
 
 	return result, nil
 }
+
+// authorize gets userID of caller which has scope.
+// Caller is identified by API key or by signed userID metadata.
+func authorize(ctx context.Context, scope string) (string, error) {
+	if identity, ok := IdentityFromContext(ctx); ok {
+		if !identity.HasScope(scope) {
+			return "", status.Error(codes.PermissionDenied, "api key has no scope "+scope)
+		}
+		return identity.UserID, nil
+	}
+
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok || len(md.Get("userID")) == 0 {
+		return "", status.Error(codes.Unauthenticated, "wrong metadata")
+	}
+	if !verifyUserID(md.Get("userID")[0]) {
+		return "", status.Error(codes.Unauthenticated, "wrong userID signature")
+	}
+	return md.Get("userID")[0], nil
+}
+
+// peerIP gets IP of gRPC caller. Calls from HTTP gateway have it in metadata set by gatewayMetadata,
+// it's last value of key as caller can add own values with Grpc-Metadata- headers.
+func peerIP(ctx context.Context) string {
+	if p, ok := peer.FromContext(ctx); ok {
+		if host, _, err := net.SplitHostPort(p.Addr.String()); err == nil {
+			return host
+		}
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if ips := md.Get(clientIPMetadata); len(ips) > 0 {
+			return ips[len(ips)-1]
+		}
+	}
+	return ""
+}
+
+// callerActor gets actor of gRPC call made by user.
+func callerActor(ctx context.Context, userID string) entity.Actor {
+	return entity.Actor{UserID: userID, IP: peerIP(ctx)}
+}
+
+// issueUserID gets userID of caller which can write.
+// If caller is unknown, new signed userID is issued and sent back in header.
+func issueUserID(ctx context.Context) (string, error) {
+	userID, err := authorize(ctx, entity.ScopeWrite)
+	if status.Code(err) != codes.Unauthenticated {
+		return userID, err
+	}
+
+	userID, err = newUserID()
+	if err != nil {
+		return "", status.Error(codes.Internal, err.Error())
+	}
+	if err = grpc.SetHeader(ctx, metadata.Pairs("userID", userID)); err != nil {
+		return "", status.Error(codes.Internal, err.Error())
+	}
+	return userID, nil
+}
+
+// statusWithReason gets gRPC status error with ErrorInfo details.
+func statusWithReason(code codes.Code, msg, reason string) error {
+	st, err := status.New(code, msg).WithDetails(&errdetails.ErrorInfo{
+		Reason: reason,
+		Domain: pb.ErrorDomain,
+	})
+	if err != nil {
+		return status.Error(code, msg)
+	}
+	return st.Err()
+}
@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/bbt-t/lets-go-shortener/internal/config"
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+	pb "github.com/bbt-t/lets-go-shortener/pkg/grpc"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// clientIdentityKey is context key for identity from client certificate.
+type clientIdentityKey struct{}
+
+// internalMethods are RPCs which need verified client certificate when mTLS is on.
+var internalMethods = map[string]bool{
+	pb.Shortener_GetStatistics_FullMethodName: true,
+}
+
+// NewGrpcTLSConfig gets TLS config for gRPC server.
+// Returns nil if certificate isn't set in config.
+func NewGrpcTLSConfig(cfg config.Config) (*tls.Config, error) {
+	if cfg.GrpcCertFile == "" && cfg.GrpcKeyFile == "" {
+		if cfg.GrpcClientCA != "" {
+			return nil, errors.New("client CA is set without server certificate")
+		}
+		return nil, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(cfg.GrpcCertFile, cfg.GrpcKeyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsCfg := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if cfg.GrpcClientCA != "" {
+		caPEM, err := os.ReadFile(cfg.GrpcClientCA)
+		if err != nil {
+			return nil, err
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, errors.New("failed parse client CA bundle")
+		}
+
+		tlsCfg.ClientCAs = pool
+		tlsCfg.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+
+	return tlsCfg, nil
+}
+
+// NewClientCertInterceptor maps CN of verified client certificate to identity.
+// If requireForInternal is set, internal RPCs are allowed only for such identities.
+func NewClientCertInterceptor(requireForInternal bool) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := clientCertContext(ctx, info.FullMethod, requireForInternal)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// NewClientCertStreamInterceptor maps CN of verified client certificate to identity of stream like NewClientCertInterceptor.
+func NewClientCertStreamInterceptor(requireForInternal bool) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := clientCertContext(stream.Context(), info.FullMethod, requireForInternal)
+		if err != nil {
+			return err
+		}
+		return handler(srv, contextStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+// clientCertContext gets context with identity from verified client certificate, context is unchanged without it.
+func clientCertContext(ctx context.Context, method string, requireForInternal bool) (context.Context, error) {
+	identity, ok := clientCertIdentity(ctx)
+	if requireForInternal && internalMethods[method] && !ok {
+		return nil, status.Error(codes.PermissionDenied, "client certificate required")
+	}
+	if ok {
+		ctx = context.WithValue(ctx, clientIdentityKey{}, identity)
+	}
+	return ctx, nil
+}
+
+// ClientIdentityFromContext gets identity from verified client certificate.
+func ClientIdentityFromContext(ctx context.Context) (string, bool) {
+	identity, ok := ctx.Value(clientIdentityKey{}).(string)
+	return identity, ok
+}
+
+// certActor gets admin actor of caller with verified client certificate, its user is "cert:" with CN.
+// Only CNs listed in adminCNs are admins, other certificates are just identity of caller.
+func certActor(ctx context.Context, adminCNs string) (entity.Actor, bool) {
+	identity, ok := ClientIdentityFromContext(ctx)
+	if !ok {
+		return entity.Actor{}, false
+	}
+	for _, cn := range strings.Split(adminCNs, ",") {
+		if cn = strings.TrimSpace(cn); cn != "" && cn == identity {
+			return entity.Actor{UserID: "cert:" + identity, IP: peerIP(ctx)}, true
+		}
+	}
+	return entity.Actor{}, false
+}
+
+// clientCertIdentity gets CN from verified client certificate of the peer.
+func clientCertIdentity(ctx context.Context) (string, bool) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return "", false
+	}
+
+	tlsInfo, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(tlsInfo.State.VerifiedChains) == 0 || len(tlsInfo.State.VerifiedChains[0]) == 0 {
+		return "", false
+	}
+
+	cn := tlsInfo.State.VerifiedChains[0][0].Subject.CommonName
+	return cn, cn != ""
+}
@@ -0,0 +1,228 @@
+package handlers
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bbt-t/lets-go-shortener/internal/adapter/storage"
+	"github.com/bbt-t/lets-go-shortener/internal/config"
+	"github.com/bbt-t/lets-go-shortener/internal/usecase"
+	pb "github.com/bbt-t/lets-go-shortener/pkg/grpc"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// testCert is generated certificate with its key.
+type testCert struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+// newTestCert generates certificate signed by parent (self-signed if parent is nil).
+func newTestCert(t *testing.T, cn string, isCA bool, parent *testCert) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+	if isCA {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage |= x509.KeyUsageCertSign
+	}
+
+	signCert, signKey := tmpl, key
+	if parent != nil {
+		signCert, signKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signCert, &key.PublicKey, signKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	return &testCert{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+// writeTestFile writes data to temp dir and returns path.
+func writeTestFile(t *testing.T, dir, name string, data []byte) string {
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, data, 0600))
+	return path
+}
+
+func TestGrpcMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, "test-ca", true, nil)
+	serverCert := newTestCert(t, "shortener", false, ca)
+	clientCert := newTestCert(t, "stats-collector", false, ca)
+
+	cfg := config.GetTestConfig()
+	cfg.GrpcCertFile = writeTestFile(t, dir, "server.crt", serverCert.certPEM)
+	cfg.GrpcKeyFile = writeTestFile(t, dir, "server.key", serverCert.keyPEM)
+	cfg.GrpcClientCA = writeTestFile(t, dir, "ca.crt", ca.certPEM)
+
+	tlsCfg, err := NewGrpcTLSConfig(cfg)
+	require.NoError(t, err)
+	require.NotNil(t, tlsCfg)
+
+	s, err := storage.NewMapStorage(cfg)
+	require.NoError(t, err)
+	service := usecase.NewShortenerService(cfg, s)
+
+	grpcServ := grpc.NewServer(
+		grpc.Creds(credentials.NewTLS(tlsCfg)),
+		grpc.UnaryInterceptor(NewClientCertInterceptor(true)),
+	)
+	pb.RegisterShortenerServer(grpcServ, NewShortenerServer(cfg, service))
+
+	listen, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go grpcServ.Serve(listen)
+	defer grpcServ.Stop()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+
+	dial := func(certs ...tls.Certificate) pb.ShortenerClient {
+		conn, err := grpc.Dial(
+			listen.Addr().String(),
+			grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{
+				RootCAs:      roots,
+				Certificates: certs,
+			})),
+		)
+		require.NoError(t, err)
+		t.Cleanup(func() { conn.Close() })
+		return pb.NewShortenerClient(conn)
+	}
+
+	keyPair, err := tls.X509KeyPair(clientCert.certPEM, clientCert.keyPEM)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	withCert := dial(keyPair)
+	_, err = withCert.GetStatistics(ctx, &emptypb.Empty{})
+	assert.NoError(t, err)
+
+	withoutCert := dial()
+	_, err = withoutCert.Ping(ctx, &emptypb.Empty{})
+	assert.NoError(t, err)
+
+	_, err = withoutCert.GetStatistics(ctx, &emptypb.Empty{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	// certificate from another CA is rejected on handshake.
+	otherCA := newTestCert(t, "other-ca", true, nil)
+	foreign := newTestCert(t, "stranger", false, otherCA)
+	foreignPair, err := tls.X509KeyPair(foreign.certPEM, foreign.keyPEM)
+	require.NoError(t, err)
+
+	_, err = dial(foreignPair).GetStatistics(ctx, &emptypb.Empty{})
+	assert.Error(t, err)
+}
+
+func TestClientCertInterceptor(t *testing.T) {
+	ca := newTestCert(t, "test-ca", true, nil)
+	client := newTestCert(t, "stats-collector", false, ca)
+
+	var identity string
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		identity, _ = ClientIdentityFromContext(ctx)
+		return nil, nil
+	}
+
+	ctx := peerContext(client.cert, ca.cert)
+	info := &grpc.UnaryServerInfo{FullMethod: pb.Shortener_GetStatistics_FullMethodName}
+
+	_, err := NewClientCertInterceptor(true)(ctx, nil, info, handler)
+	assert.NoError(t, err)
+	assert.Equal(t, "stats-collector", identity)
+
+	_, err = NewClientCertInterceptor(true)(context.Background(), nil, info, handler)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestClientCertStreamInterceptor(t *testing.T) {
+	ca := newTestCert(t, "test-ca", true, nil)
+	client := newTestCert(t, "ops", false, ca)
+
+	var identity string
+	handler := func(srv interface{}, stream grpc.ServerStream) error {
+		identity, _ = ClientIdentityFromContext(stream.Context())
+		return nil
+	}
+	info := &grpc.StreamServerInfo{FullMethod: pb.Shortener_WatchClicks_FullMethodName}
+
+	stream := contextStream{ctx: peerContext(client.cert, ca.cert)}
+	assert.NoError(t, NewClientCertStreamInterceptor(true)(nil, stream, info, handler))
+	assert.Equal(t, "ops", identity)
+
+	identity = ""
+	stream = contextStream{ctx: context.Background()}
+	assert.NoError(t, NewClientCertStreamInterceptor(true)(nil, stream, info, handler), "stream isn't internal")
+	assert.Empty(t, identity)
+}
+
+func TestNewGrpcTLSConfig(t *testing.T) {
+	cfg := config.GetTestConfig()
+
+	tlsCfg, err := NewGrpcTLSConfig(cfg)
+	assert.NoError(t, err)
+	assert.Nil(t, tlsCfg)
+
+	cfg.GrpcClientCA = "ca.crt"
+	_, err = NewGrpcTLSConfig(cfg)
+	assert.Error(t, err)
+}
+
+// peerContext gets context of peer which presented verified certificate chain.
+func peerContext(chain ...*x509.Certificate) context.Context {
+	return peer.NewContext(context.Background(), &peer.Peer{
+		Addr: &net.TCPAddr{IP: net.ParseIP("127.0.0.1"), Port: 5000},
+		AuthInfo: credentials.TLSInfo{
+			State: tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{chain}},
+		},
+	})
+}
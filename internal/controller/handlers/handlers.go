@@ -4,8 +4,11 @@ package handlers
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strconv"
 
 	"github.com/bbt-t/lets-go-shortener/internal/adapter/storage"
 	"github.com/bbt-t/lets-go-shortener/internal/config"
@@ -42,12 +45,11 @@ func Ping(s *ShortenerHandler) http.HandlerFunc {
 // DeleteURL deletes url from storage.
 func DeleteURL(s *ShortenerHandler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		userCookie, err := r.Cookie("userID")
+		userID, err := getUserID(r)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
-		userID := userCookie.Value
 
 		resBody, err := io.ReadAll(r.Body)
 		defer r.Body.Close()
@@ -62,7 +64,7 @@ func DeleteURL(s *ShortenerHandler) http.HandlerFunc {
 			return
 		}
 
-		if err = s.storage.MarkAsDeleted(userID, toDelete...); err != nil {
+		if err = s.storage.MarkAsDeleted(requestActor(r, userID), toDelete...); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
@@ -75,12 +77,11 @@ func URLBatch(s *ShortenerHandler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var reqURLs, respURLs []entity.URLBatch
 
-		userCookie, err := r.Cookie("userID")
+		userID, err := getUserID(r)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
-		userID := userCookie.Value
 
 		resBody, err := io.ReadAll(r.Body)
 		defer r.Body.Close()
@@ -94,8 +95,20 @@ func URLBatch(s *ShortenerHandler) http.HandlerFunc {
 			return
 		}
 
-		respURLs, err = ShortURLs(s.storage, userID, reqURLs)
-		if err != nil {
+		respURLs, err = ShortURLs(s.storage, requestActor(r, userID), reqURLs)
+		if errors.Is(err, usecase.ErrQuotaExceeded) {
+			http.Error(w, err.Error(), http.StatusTooManyRequests)
+			return
+		}
+		if errors.Is(err, usecase.ErrBlockedURL) {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		if errors.Is(err, usecase.ErrInvalidURL) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil && !errors.Is(err, storage.ErrExists) {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
@@ -112,35 +125,70 @@ func URLBatch(s *ShortenerHandler) http.HandlerFunc {
 	}
 }
 
-// RecoverAllURL gets history of your urls.
+// RecoverAllURL gets page of history of your urls. It's filtered by tag and q parameters,
+// q is searched in titles and original urls. Page has limit links after cursor in sort order
+// like "clicks" or "-created_at", link to next page is sent in Link header.
 func RecoverAllURL(s *ShortenerHandler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		userCookie, err := r.Cookie("userID")
+		userID, err := getUserID(r)
 		if err != nil {
 			http.Error(w, err.Error(), 400)
 			return
 		}
-		userID := userCookie.Value
 
-		history, err := s.storage.GetURLArrayByUser(userID)
+		query := r.URL.Query()
+		filter := entity.HistoryFilter{Tag: query.Get("tag"), Query: query.Get("q")}
+		filter.Sort, filter.Desc = usecase.ParseSort(query.Get("sort"))
+		if limit := query.Get("limit"); limit != "" {
+			if filter.Limit, err = strconv.Atoi(limit); err != nil || filter.Limit < 1 {
+				http.Error(w, "limit must be positive number", http.StatusBadRequest)
+				return
+			}
+		}
+
+		page, err := s.storage.GetURLArrayByUser(userID, filter, query.Get("cursor"))
+		if errors.Is(err, usecase.ErrInvalidPage) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if query.Get("cursor") != "" {
+			query.Del("cursor")
+			w.Header().Add("Link", pageLink(s.cfg.BaseURL, r.URL.Path, query, "first"))
+		}
+		if page.NextCursor != "" {
+			query.Set("cursor", page.NextCursor)
+			w.Header().Add("Link", pageLink(s.cfg.BaseURL, r.URL.Path, query, "next"))
 		}
-		if len(history) == 0 {
+		if len(page.Links) == 0 {
 			w.WriteHeader(http.StatusNoContent)
 			return
 		}
 
-		data, err := json.Marshal(history)
+		data, err := json.Marshal(page.Links)
 		if err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
 		}
 		w.Header().Add("Content-Type", "application/json")
 		w.Write(data)
 	}
 }
 
-// RecoverOriginalURL sends person to page, which url was shortened.
+// pageLink gets value of Link header with relation to other page.
+func pageLink(baseURL, path string, query url.Values, rel string) string {
+	target := baseURL + path
+	if len(query) != 0 {
+		target += "?" + query.Encode()
+	}
+	return fmt.Sprintf("<%s>; rel=%q", target, rel)
+}
+
+// RecoverOriginalURL sends person to page, which url was shortened, or to url of redirect rule which matches them.
 func RecoverOriginalURL(s *ShortenerHandler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		id := chi.URLParam(r, "id")
@@ -150,21 +198,37 @@ func RecoverOriginalURL(s *ShortenerHandler) http.HandlerFunc {
 		}
 
 		url, err := s.storage.GetOriginal(id)
-		if errors.Is(err, storage.ErrDeleted) {
-			http.Error(w, "url is deleted", http.StatusGone)
-			return
-		}
-		if errors.Is(err, storage.ErrNotFound) {
-			http.Error(w, "not found", http.StatusNotFound)
-			return
-		}
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+		if errors.Is(err, usecase.ErrInterstitial) {
+			previewLink(s, w, id)
 			return
 		}
+		url, err = routeOriginal(s, id, url, err, requestVisitor(r))
+		redirectOriginal(w, url, err, http.StatusTemporaryRedirect)
+	}
+}
 
+// redirectOriginal sends redirect to original url or page of error getting it.
+func redirectOriginal(w http.ResponseWriter, url string, err error, code int) {
+	switch {
+	case errors.Is(err, storage.ErrDeleted):
+		http.Error(w, "url is deleted", http.StatusGone)
+	case errors.Is(err, storage.ErrExhausted):
+		http.Error(w, "url clicks are exhausted", http.StatusGone)
+	case errors.Is(err, storage.ErrNotFound):
+		http.Error(w, "not found", http.StatusNotFound)
+	case errors.Is(err, usecase.ErrBlockedURL):
+		blockedWarning(w, url)
+	case errors.Is(err, usecase.ErrPasswordRequired):
+		passwordForm(w, http.StatusUnauthorized, "")
+	case errors.Is(err, usecase.ErrWrongPassword):
+		passwordForm(w, http.StatusUnauthorized, "Wrong password, try again.")
+	case errors.Is(err, usecase.ErrTooManyAttempts):
+		passwordForm(w, http.StatusTooManyRequests, "Too many attempts, try again later.")
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	default:
 		w.Header().Set("Location", url)
-		w.WriteHeader(http.StatusTemporaryRedirect)
+		w.WriteHeader(code)
 	}
 }
 
@@ -173,14 +237,12 @@ func RecoverOriginalURLPost(s *ShortenerHandler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var reqJSON entity.ReqJSON
 
-		userCookie, err := r.Cookie("userID")
+		userID, err := getUserID(r)
 		if err != nil {
 			http.Error(w, err.Error(), 400)
 			return
 		}
 
-		userID := userCookie.Value
-
 		resBody, err := io.ReadAll(r.Body)
 		defer r.Body.Close()
 		if err != nil || string(resBody) == "" {
@@ -195,13 +257,31 @@ func RecoverOriginalURLPost(s *ShortenerHandler) http.HandlerFunc {
 					return
 				}
 
-				res, errCreate := s.storage.CreateShort(userID, reqJSON.URL)
+				options := entity.LinkOptions{
+					Password:  reqJSON.Password,
+					MaxClicks: reqJSON.MaxClicks,
+					Meta: entity.LinkMeta{
+						Title:        reqJSON.Title,
+						Tags:         reqJSON.Tags,
+						Note:         reqJSON.Note,
+						Interstitial: reqJSON.Interstitial,
+					},
+				}
+				id, errCreate := s.storage.CreateShortWithOptions(requestActor(r, userID), reqJSON.URL, options)
+				if errors.Is(errCreate, usecase.ErrQuotaExceeded) {
+					http.Error(w, errCreate.Error(), http.StatusTooManyRequests)
+					return
+				}
+				if errors.Is(errCreate, usecase.ErrBlockedURL) {
+					http.Error(w, errCreate.Error(), http.StatusUnprocessableEntity)
+					return
+				}
 				if errCreate != nil && !errors.Is(errCreate, storage.ErrExists) {
 					http.Error(w, errCreate.Error(), http.StatusBadRequest)
 					return
 				}
 
-				respJSON, err := json.Marshal(entity.RespJSON{Result: s.cfg.BaseURL + "/" + res[0]})
+				respJSON, err := json.Marshal(entity.RespJSON{Result: s.cfg.BaseURL + "/" + id})
 				if err != nil {
 					http.Error(w, err.Error(), http.StatusInternalServerError)
 					return
@@ -217,7 +297,15 @@ func RecoverOriginalURLPost(s *ShortenerHandler) http.HandlerFunc {
 			}
 		default:
 			{
-				res, errCreate := s.storage.CreateShort(userID, string(resBody))
+				res, errCreate := s.storage.CreateShort(requestActor(r, userID), string(resBody))
+				if errors.Is(errCreate, usecase.ErrQuotaExceeded) {
+					http.Error(w, errCreate.Error(), http.StatusTooManyRequests)
+					return
+				}
+				if errors.Is(errCreate, usecase.ErrBlockedURL) {
+					http.Error(w, errCreate.Error(), http.StatusUnprocessableEntity)
+					return
+				}
 				if errCreate != nil && !errors.Is(errCreate, storage.ErrExists) {
 					http.Error(w, errCreate.Error(), 400)
 					return
@@ -234,6 +322,79 @@ func RecoverOriginalURLPost(s *ShortenerHandler) http.HandlerFunc {
 	}
 }
 
+// GetQuota gets usage of link quotas by caller.
+func GetQuota(s *ShortenerHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := getUserID(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		usage, err := s.storage.GetQuota(userID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, usage)
+	}
+}
+
+// UpdateURL changes original url, title, tags or note of link of user.
+func UpdateURL(s *ShortenerHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var patch entity.LinkPatch
+
+		userID, err := getUserID(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err = json.NewDecoder(r.Body).Decode(&patch); err != nil {
+			http.Error(w, "wrong body", http.StatusBadRequest)
+			return
+		}
+
+		link, err := s.storage.EditLink(requestActor(r, userID), chi.URLParam(r, "id"), patch)
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
+			http.Error(w, "not found", http.StatusNotFound)
+		case errors.Is(err, storage.ErrDeleted):
+			http.Error(w, "url is deleted", http.StatusGone)
+		case errors.Is(err, usecase.ErrBlockedURL):
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		case errors.Is(err, usecase.ErrInvalidURL), errors.Is(err, usecase.ErrInvalidMeta):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		default:
+			writeJSON(w, http.StatusOK, link)
+		}
+	}
+}
+
+// URLVersions gets past original urls of link of user.
+func URLVersions(s *ShortenerHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := getUserID(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		versions, err := s.storage.GetLinkVersions(userID, chi.URLParam(r, "id"))
+		if errors.Is(err, storage.ErrNotFound) {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, versions)
+	}
+}
+
 // StatisticHandler returns total urls and users.
 func StatisticHandler(s *ShortenerHandler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -255,8 +416,8 @@ func StatisticHandler(s *ShortenerHandler) http.HandlerFunc {
 	}
 }
 
-// ShortURLs shorts many urls.
-func ShortURLs(s *usecase.ShortenerService, userID string, urlsJSON []entity.URLBatch) ([]entity.URLBatch, error) {
+// ShortURLs shorts many urls of actor.
+func ShortURLs(s *usecase.ShortenerService, actor entity.Actor, urlsJSON []entity.URLBatch) ([]entity.URLBatch, error) {
 	urls := make([]string, len(urlsJSON))
 	resultJSON := make([]entity.URLBatch, len(urlsJSON))
 
@@ -264,7 +425,7 @@ func ShortURLs(s *usecase.ShortenerService, userID string, urlsJSON []entity.URL
 		urls[i] = urlsJSON[i].OriginalURL
 	}
 
-	result, err := s.CreateShort(userID, urls...)
+	result, err := s.CreateShort(actor, urls...)
 	if err != nil && err != storage.ErrExists {
 		return nil, err
 	}
@@ -279,9 +440,9 @@ func ShortURLs(s *usecase.ShortenerService, userID string, urlsJSON []entity.URL
 	return resultJSON, err
 }
 
-// ShortSingleURL shorts single url.
-func ShortSingleURL(s *usecase.ShortenerService, userID string, url string) (string, error) {
-	result, err := s.CreateShort(userID, url)
+// ShortSingleURL shorts single url of actor.
+func ShortSingleURL(s *usecase.ShortenerService, actor entity.Actor, url string) (string, error) {
+	result, err := s.CreateShort(actor, url)
 	if len(result) == 0 {
 		return "", err
 	}
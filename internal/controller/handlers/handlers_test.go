@@ -19,8 +19,78 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
+// testAPI is handler on map storage with router of HTTP API for tests.
+type testAPI struct {
+	t       *testing.T
+	s       *storage.MapStorage
+	service *usecase.ShortenerService
+	h       *ShortenerHandler
+	router  chi.Router
+}
+
+// requestOption changes request of test before it is served.
+type requestOption func(r *http.Request)
+
+// newTestAPI gets handler with router which uses middlewares and then CookieMiddleware, tests add own routes.
+func newTestAPI(t *testing.T, cfg config.Config, middlewares ...func(*ShortenerHandler) func(http.Handler) http.Handler) *testAPI {
+	s, err := storage.NewMapStorage(cfg)
+	require.NoError(t, err)
+	service := usecase.NewShortenerService(cfg, s)
+	h := NewShortenerHandler(cfg, service)
+
+	router := chi.NewRouter()
+	for _, middleware := range middlewares {
+		router.Use(middleware(h))
+	}
+	router.Use(CookieMiddleware)
+	return &testAPI{t: t, s: s, service: service, h: h, router: router}
+}
+
+// do serves request with body, valid JSON body is sent as JSON unless options change it.
+func (api *testAPI) do(method, target, body string, options ...requestOption) *http.Response {
+	request := httptest.NewRequest(method, target, strings.NewReader(body))
+	if json.Valid([]byte(body)) {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	for _, option := range options {
+		option(request)
+	}
+	w := httptest.NewRecorder()
+	api.router.ServeHTTP(w, request)
+	return w.Result()
+}
+
+// text serves request like do and gets status and body of response.
+func (api *testAPI) text(method, target, body string, options ...requestOption) (int, string) {
+	res := api.do(method, target, body, options...)
+	defer res.Body.Close()
+	resBody, err := io.ReadAll(res.Body)
+	require.NoError(api.t, err)
+	return res.StatusCode, string(resBody)
+}
+
+// withCookie adds cookies to request.
+func withCookie(cookies ...*http.Cookie) requestOption {
+	return func(r *http.Request) {
+		for _, cookie := range cookies {
+			r.AddCookie(cookie)
+		}
+	}
+}
+
+// withHeader sets header of request.
+func withHeader(name, value string) requestOption {
+	return func(r *http.Request) { r.Header.Set(name, value) }
+}
+
+// fromIP sets real IP of request.
+func fromIP(ip string) requestOption {
+	return withHeader("X-Real-IP", ip)
+}
+
 func TestURLPostHandler(t *testing.T) {
 	type want struct {
 		code     int
@@ -494,3 +564,50 @@ func TestService_GetStatistic(t *testing.T) {
 	}, check)
 
 }
+
+func TestURLNormalization(t *testing.T) {
+	cfg := config.GetTestConfig()
+	cfg.StoragePath = t.TempDir() + "/file_storage.db"
+	s, err := storage.NewStorage(cfg)
+	assert.NoError(t, err)
+	h := NewShortenerHandler(cfg, usecase.NewShortenerService(cfg, s))
+
+	router := chi.NewRouter()
+	router.Use(CookieMiddleware)
+	router.Post("/", RecoverOriginalURLPost(h))
+	router.Post("/api/shorten/batch", URLBatch(h))
+
+	var cookies []*http.Cookie
+	do := func(target, body string) (int, string) {
+		request := httptest.NewRequest(http.MethodPost, target, strings.NewReader(body))
+		for _, cookie := range cookies {
+			request.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+		if len(cookies) == 0 {
+			cookies = res.Cookies()
+		}
+		resBody, err := io.ReadAll(res.Body)
+		assert.NoError(t, err)
+		return res.StatusCode, string(resBody)
+	}
+
+	code, _ := do("/", "javascript:alert(1)")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, first := do("/", "http://example.com/")
+	assert.Equal(t, http.StatusCreated, code)
+	code, same := do("/", "HTTP://Example.com:80")
+	assert.Equal(t, http.StatusConflict, code)
+	assert.Equal(t, first, same)
+
+	code, _ = do("/api/shorten/batch", `[{"correlation_id":"1","original_url":"ftp://example.com"}]`)
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, body := do("/api/shorten/batch", `[{"correlation_id":"1","original_url":"http://EXAMPLE.com"}]`)
+	assert.Equal(t, http.StatusCreated, code)
+	assert.Contains(t, body, first)
+}
@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/bbt-t/lets-go-shortener/internal/adapter/storage"
+	"github.com/bbt-t/lets-go-shortener/internal/config"
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+	"github.com/bbt-t/lets-go-shortener/internal/usecase"
+	pb "github.com/bbt-t/lets-go-shortener/pkg/grpc"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestHistoryPages(t *testing.T) {
+	cfg := config.GetDefaultConfig()
+	s, err := storage.NewMapStorage(cfg)
+	require.NoError(t, err)
+	service := usecase.NewShortenerService(cfg, s)
+	h := NewShortenerHandler(cfg, service)
+
+	router := chi.NewRouter()
+	router.Use(CookieMiddleware)
+	router.Get("/api/user/urls", RecoverAllURL(h))
+
+	userID, err := newUserID()
+	require.NoError(t, err)
+	for i := 1; i <= 5; i++ {
+		_, err = s.CreateShort(userID, fmt.Sprintf("https://example.com/%d", i))
+		require.NoError(t, err)
+	}
+	for i := 0; i < 3; i++ {
+		require.NoError(t, s.TakeClick("4"))
+	}
+	require.NoError(t, s.TakeClick("2"))
+
+	get := func(target string) (*http.Response, []entity.URLs) {
+		request := httptest.NewRequest(http.MethodGet, target, nil)
+		request.AddCookie(&http.Cookie{Name: "userID", Value: userID})
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, request)
+		res := w.Result()
+		defer res.Body.Close()
+
+		var links []entity.URLs
+		if res.StatusCode == http.StatusOK {
+			require.NoError(t, json.NewDecoder(res.Body).Decode(&links))
+		}
+		return res, links
+	}
+	next := func(res *http.Response) string {
+		for _, link := range res.Header.Values("Link") {
+			if target, ok := strings.CutSuffix(link, `; rel="next"`); ok {
+				u, err := url.Parse(strings.Trim(target, "<>"))
+				require.NoError(t, err)
+				assert.True(t, strings.HasPrefix(target, "<"+cfg.BaseURL+"/api/user/urls?"))
+				return u.RequestURI()
+			}
+		}
+		return ""
+	}
+
+	var (
+		originals []string
+		pages     int
+	)
+	for target := "/api/user/urls?limit=2&sort=-clicks"; target != ""; pages++ {
+		res, links := get(target)
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		for _, link := range links {
+			originals = append(originals, link.OriginalURL)
+		}
+		target = next(res)
+	}
+	assert.Equal(t, 3, pages)
+	assert.Equal(t, []string{
+		"https://example.com/4",
+		"https://example.com/2",
+		"https://example.com/5",
+		"https://example.com/3",
+		"https://example.com/1",
+	}, originals, "links with equal clicks are in the same order by id")
+
+	res, links := get("/api/user/urls")
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Len(t, links, 5)
+	assert.Empty(t, res.Header.Values("Link"))
+	assert.Equal(t, 3, links[3].Clicks)
+
+	res, _ = get("/api/user/urls?limit=2")
+	cursor, err := url.Parse(next(res))
+	require.NoError(t, err)
+	res, _ = get("/api/user/urls?limit=2&sort=url&cursor=" + cursor.Query().Get("cursor"))
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode, "cursor of other sort order")
+
+	for _, query := range []string{"limit=0", "limit=abc", "limit=5000", "sort=title", "cursor=%21%21"} {
+		res, _ = get("/api/user/urls?" + query)
+		assert.Equal(t, http.StatusBadRequest, res.StatusCode, query)
+	}
+
+	server := NewShortenerServer(cfg, service)
+	ctx := WithIdentity(context.Background(), entity.Identity{UserID: userID, Scopes: entity.AllScopes})
+	batch, err := server.GetHistory(ctx, &pb.HistoryFilter{Limit: 3, Sort: "url"})
+	require.NoError(t, err)
+	require.Len(t, batch.Result, 3)
+	require.NotEmpty(t, batch.NextCursor)
+
+	batch, err = server.GetHistory(ctx, &pb.HistoryFilter{Limit: 3, Sort: "url", Cursor: batch.NextCursor})
+	require.NoError(t, err)
+	require.Len(t, batch.Result, 2)
+	assert.Equal(t, "https://example.com/5", batch.Result[1].LongUrl)
+	assert.Empty(t, batch.NextCursor)
+
+	_, err = server.GetHistory(ctx, &pb.HistoryFilter{Sort: "title"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// history without limit and cursor isn't truncated by default limit.
+	for i := 6; i <= 105; i++ {
+		_, err = s.CreateShort(userID, fmt.Sprintf("https://example.com/%d", i))
+		require.NoError(t, err)
+	}
+	res, links = get("/api/user/urls")
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Len(t, links, 105)
+	assert.Empty(t, res.Header.Values("Link"))
+
+	batch, err = server.GetHistory(ctx, &pb.HistoryFilter{})
+	require.NoError(t, err)
+	assert.Len(t, batch.Result, 105)
+	assert.Empty(t, batch.NextCursor)
+}
@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/bbt-t/lets-go-shortener/internal/config"
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+)
+
+// maxCookieKeys is number of keys kept in ring after rotation, older keys are dropped.
+const maxCookieKeys = 3
+
+// cookieKeys signs and verifies userID. It's replaced by configured ring on start.
+var cookieKeys = newRandomKeyRing()
+
+// SetCookieKeys sets key ring used for userID cookies and metadata.
+func SetCookieKeys(k *KeyRing) {
+	cookieKeys = k
+}
+
+// signingKey is key of ring.
+type signingKey struct {
+	ID     string `json:"id"`
+	Secret string `json:"secret"`
+}
+
+// keyRingFile is content of key ring file.
+type keyRingFile struct {
+	Active string       `json:"active"`
+	Keys   []signingKey `json:"keys"`
+}
+
+// KeyRing keeps keys for signing userID.
+// New values are signed by active key, values signed by any key of ring are valid.
+type KeyRing struct {
+	mu     sync.RWMutex
+	path   string
+	active string
+	keys   []signingKey
+}
+
+// NewKeyRing gets key ring from config.
+// Ring is read from CookieKeysFile and saved there on rotation, file is created if it doesn't exist.
+// Without file ring has CookieSecret only, without both it has random key which isn't kept after restart.
+func NewKeyRing(cfg config.Config) (*KeyRing, error) {
+	k := &KeyRing{path: cfg.CookieKeysFile}
+
+	if cfg.CookieKeysFile != "" {
+		var file keyRingFile
+
+		data, err := os.ReadFile(cfg.CookieKeysFile)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+		if err == nil {
+			if err = json.Unmarshal(data, &file); err != nil {
+				return nil, err
+			}
+			k.active, k.keys = file.Active, file.Keys
+		}
+	}
+
+	if len(k.keys) == 0 {
+		key, err := newSigningKey(cfg.CookieSecret)
+		if err != nil {
+			return nil, err
+		}
+		if cfg.CookieSecret == "" && cfg.CookieKeysFile == "" {
+			log.Println("! Cookie signing key isn't set, cookies won't be valid after restart !")
+		}
+		k.active, k.keys = key.ID, []signingKey{key}
+
+		if err = k.save(); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, ok := k.find(k.active); !ok {
+		return nil, errors.New("active cookie key isn't found in key ring")
+	}
+	return k, nil
+}
+
+// newRandomKeyRing gets ring with random key.
+func newRandomKeyRing() *KeyRing {
+	key, err := newSigningKey("")
+	if err != nil {
+		log.Fatalln("Failed generate cookie key:", err)
+	}
+	return &KeyRing{active: key.ID, keys: []signingKey{key}}
+}
+
+// newSigningKey gets key with secret. Random secret is generated if secret is empty.
+// ID of key with given secret is derived from it, so it's the same on every instance.
+func newSigningKey(secret string) (signingKey, error) {
+	if secret == "" {
+		b, err := generateRandom(32)
+		if err != nil {
+			return signingKey{}, err
+		}
+		secret = hex.EncodeToString(b)
+	}
+	sum := sha256.Sum256([]byte(secret))
+	return signingKey{ID: hex.EncodeToString(sum[:4]), Secret: secret}, nil
+}
+
+// find gets key by id.
+func (k *KeyRing) find(id string) (signingKey, bool) {
+	for _, key := range k.keys {
+		if key.ID == id {
+			return key, true
+		}
+	}
+	return signingKey{}, false
+}
+
+// save writes ring to file if it's set.
+func (k *KeyRing) save() error {
+	if k.path == "" {
+		return nil
+	}
+	data, err := json.Marshal(keyRingFile{Active: k.active, Keys: k.keys})
+	if err != nil {
+		return err
+	}
+	tmp := k.path + ".tmp"
+	if err = os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, k.path)
+}
+
+// ActiveID gets id of active key.
+func (k *KeyRing) ActiveID() string {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	return k.active
+}
+
+// Sign gets value signed by active key: "<key id>.<hex of signature and id>".
+func (k *KeyRing) Sign(id []byte) string {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	key, _ := k.find(k.active)
+	return key.ID + "." + hex.EncodeToString(append(sign(key, id), id...))
+}
+
+// Verify checks if value is signed by any key of ring.
+func (k *KeyRing) Verify(value string) bool {
+	keyID, signed, ok := strings.Cut(value, ".")
+	if !ok {
+		return false
+	}
+	data, err := hex.DecodeString(signed)
+	if err != nil || len(data) <= sha256.Size {
+		return false
+	}
+
+	k.mu.RLock()
+	key, ok := k.find(keyID)
+	k.mu.RUnlock()
+	if !ok {
+		return false
+	}
+
+	return hmac.Equal(data[:sha256.Size], sign(key, data[sha256.Size:]))
+}
+
+// Rotate makes new random key active. Previous keys are still valid until they are dropped from ring.
+func (k *KeyRing) Rotate() (string, error) {
+	key, err := newSigningKey("")
+	if err != nil {
+		return "", err
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	prevActive, prevKeys := k.active, k.keys
+
+	k.keys = append([]signingKey{key}, k.keys...)
+	if len(k.keys) > maxCookieKeys {
+		k.keys = k.keys[:maxCookieKeys]
+	}
+	k.active = key.ID
+
+	if err = k.save(); err != nil {
+		k.active, k.keys = prevActive, prevKeys
+		return "", err
+	}
+	return key.ID, nil
+}
+
+// sign gets HMAC of id.
+func sign(key signingKey, id []byte) []byte {
+	h := hmac.New(sha256.New, []byte(key.Secret))
+	h.Write(id)
+	return h.Sum(nil)
+}
+
+// RotateCookieKey makes new cookie signing key active.
+func RotateCookieKey(s *ShortenerHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		prevActive := cookieKeys.ActiveID()
+		active, err := cookieKeys.Rotate()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		log.Println("Cookie signing key is rotated, active key:", active)
+		s.storage.RecordCookieKeyRotation(entity.Actor{IP: clientIP(r)}, prevActive, active)
+
+		data, err := json.Marshal(entity.KeyRotationResponse{Active: active})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(data)
+	}
+}
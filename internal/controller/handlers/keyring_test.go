@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bbt-t/lets-go-shortener/internal/adapter/storage"
+	"github.com/bbt-t/lets-go-shortener/internal/config"
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+	"github.com/bbt-t/lets-go-shortener/internal/usecase"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyRing(t *testing.T) {
+	cfg := config.GetTestConfig()
+	cfg.CookieKeysFile = filepath.Join(t.TempDir(), "cookie_keys.json")
+
+	k, err := NewKeyRing(cfg)
+	require.NoError(t, err)
+
+	first := k.Sign([]byte("user1"))
+	assert.True(t, strings.HasPrefix(first, k.ActiveID()+"."))
+	assert.True(t, k.Verify(first))
+
+	tampered := first[:len(first)-2] + "00"
+	assert.False(t, k.Verify(tampered))
+	assert.False(t, k.Verify("unknown."+strings.SplitN(first, ".", 2)[1]))
+	assert.False(t, k.Verify(strings.SplitN(first, ".", 2)[1]), "value without key id")
+
+	firstKey := k.ActiveID()
+	active, err := k.Rotate()
+	require.NoError(t, err)
+	assert.NotEqual(t, firstKey, active)
+	assert.True(t, k.Verify(first), "old key is valid during rotation")
+	assert.True(t, strings.HasPrefix(k.Sign([]byte("user2")), active+"."))
+
+	// ring is restored from file.
+	restored, err := NewKeyRing(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, active, restored.ActiveID())
+	assert.True(t, restored.Verify(first))
+
+	// oldest keys are dropped.
+	for i := 1; i < maxCookieKeys; i++ {
+		_, err = k.Rotate()
+		require.NoError(t, err)
+	}
+	assert.False(t, k.Verify(first))
+}
+
+func TestKeyRing_Secret(t *testing.T) {
+	cfg := config.GetTestConfig()
+	cfg.CookieSecret = "shared secret"
+
+	first, err := NewKeyRing(cfg)
+	require.NoError(t, err)
+	second, err := NewKeyRing(cfg)
+	require.NoError(t, err)
+
+	assert.Equal(t, first.ActiveID(), second.ActiveID())
+	assert.True(t, second.Verify(first.Sign([]byte("user1"))))
+
+	cfg.CookieSecret = "other secret"
+	other, err := NewKeyRing(cfg)
+	require.NoError(t, err)
+	assert.False(t, other.Verify(first.Sign([]byte("user1"))))
+}
+
+func TestRotateCookieKey(t *testing.T) {
+	prev := cookieKeys
+	t.Cleanup(func() { SetCookieKeys(prev) })
+	SetCookieKeys(newRandomKeyRing())
+	prevActive := cookieKeys.ActiveID()
+
+	cfg := config.GetDefaultConfig()
+	cfg.AuditLogPath = filepath.Join(t.TempDir(), "audit.jsonl")
+	s, err := storage.NewMapStorage(cfg)
+	require.NoError(t, err)
+	auditLog, err := storage.NewAuditLog(cfg, s)
+	require.NoError(t, err)
+	service := usecase.NewShortenerService(cfg, s)
+	service.SetAuditLog(auditLog)
+
+	userID, err := newUserID()
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	RotateCookieKey(NewShortenerHandler(cfg, service)).ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/internal/cookie-keys/rotate", nil))
+	res := w.Result()
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	var rotation entity.KeyRotationResponse
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&rotation))
+	assert.Equal(t, cookieKeys.ActiveID(), rotation.Active)
+
+	events, err := service.GetAuditEvents(entity.AuditFilter{Action: "cookie_key.rotate"})
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.JSONEq(t, `{"active":"`+prevActive+`"}`, string(events[0].Before))
+	assert.JSONEq(t, `{"active":"`+rotation.Active+`"}`, string(events[0].After))
+
+	// cookie signed by previous key is kept.
+	request := httptest.NewRequest(http.MethodGet, "/ping", nil)
+	request.AddCookie(&http.Cookie{Name: "userID", Value: userID})
+	w = httptest.NewRecorder()
+	CookieMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity, ok := IdentityFromContext(r.Context())
+		assert.True(t, ok)
+		assert.Equal(t, userID, identity.UserID)
+	})).ServeHTTP(w, request)
+	res = w.Result()
+	defer res.Body.Close()
+	assert.Empty(t, res.Cookies())
+}
+
+func TestCookieMiddleware_Attributes(t *testing.T) {
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	w := httptest.NewRecorder()
+	CookieMiddleware(next).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ping", nil))
+	res := w.Result()
+	defer res.Body.Close()
+	require.Len(t, res.Cookies(), 1)
+	cookie := res.Cookies()[0]
+	assert.True(t, cookie.HttpOnly)
+	assert.False(t, cookie.Secure)
+	assert.Equal(t, http.SameSiteLaxMode, cookie.SameSite)
+
+	request := httptest.NewRequest(http.MethodGet, "/ping", nil)
+	request.TLS = &tls.ConnectionState{}
+	w = httptest.NewRecorder()
+	CookieMiddleware(next).ServeHTTP(w, request)
+	res = w.Result()
+	defer res.Body.Close()
+	require.Len(t, res.Cookies(), 1)
+	assert.True(t, res.Cookies()[0].Secure)
+}
+
+func TestAuditLog_SignedUserID(t *testing.T) {
+	userID, err := newUserID()
+	require.NoError(t, err)
+	// CN of certificate is up to 64 characters.
+	actors := []string{userID, "cert:" + strings.Repeat("a", 64)}
+
+	cfg := config.GetDefaultConfig()
+	cfg.AuditLogPath = filepath.Join(t.TempDir(), "audit.jsonl")
+	s, err := storage.NewMapStorage(cfg)
+	require.NoError(t, err)
+	fileLog, err := storage.NewAuditLog(cfg, s)
+	require.NoError(t, err)
+	auditLogs := map[string]storage.AuditLog{"file": fileLog}
+
+	// audit log of db is checked if it's set, actors must fit its columns.
+	if dsn := os.Getenv("DATABASE_DSN"); dsn != "" {
+		dbCfg := config.GetDefaultConfig()
+		dbCfg.BasePath = dsn
+		dbCfg.DBMigrationPath = "file://../../../migrations"
+		db, err := storage.NewStorage(dbCfg)
+		require.NoError(t, err)
+		dbLog, err := storage.NewAuditLog(dbCfg, db)
+		require.NoError(t, err)
+		auditLogs["db"] = dbLog
+	}
+
+	for name, auditLog := range auditLogs {
+		for _, actor := range actors {
+			event := entity.AuditEvent{Time: time.Now().UTC(), Actor: entity.Actor{UserID: actor}, Action: "link.create"}
+			require.NoError(t, auditLog.AddAuditEvent(event), name)
+
+			events, err := auditLog.GetAuditEvents(entity.AuditFilter{UserID: actor})
+			require.NoError(t, err, name)
+			require.NotEmpty(t, events, name)
+			assert.Equal(t, actor, events[0].UserID, name)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/bbt-t/lets-go-shortener/internal/config"
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+	pb "github.com/bbt-t/lets-go-shortener/pkg/grpc"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestLinkMeta(t *testing.T) {
+	cfg := config.GetDefaultConfig()
+	api := newTestAPI(t, cfg)
+	service, h := api.service, api.h
+	api.router.Post("/api/shorten", RecoverOriginalURLPost(h))
+	api.router.Get("/api/user/urls", RecoverAllURL(h))
+	api.router.Patch("/api/user/urls/{id}", UpdateURL(h))
+
+	history := func(query string, owner *http.Cookie) []entity.URLs {
+		res := api.do(http.MethodGet, "/api/user/urls"+query, "", withCookie(owner))
+		defer res.Body.Close()
+		if res.StatusCode == http.StatusNoContent {
+			return nil
+		}
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		var urls []entity.URLs
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&urls))
+		return urls
+	}
+
+	res := api.do(http.MethodPost, "/api/shorten", `{"url":"https://go.dev","title":" Go ","tags":["Lang"," go ","lang"],"note":"home"}`)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+	owner := res.Cookies()[0]
+	var created entity.RespJSON
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&created))
+	id := created.Result[strings.LastIndex(created.Result, "/")+1:]
+
+	res = api.do(http.MethodPost, "/api/shorten", `{"url":"https://yandex.ru","title":"Search"}`, withCookie(owner))
+	defer res.Body.Close()
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+
+	res = api.do(http.MethodPost, "/api/shorten", `{"url":"https://example.com","tags":["`+strings.Repeat("a", 65)+`"]}`, withCookie(owner))
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+	urls := history("?tag=LANG", owner)
+	require.Len(t, urls, 1)
+	assert.Equal(t, "Go", urls[0].Title)
+	assert.Equal(t, []string{"go", "lang"}, urls[0].Tags)
+	assert.Equal(t, "home", urls[0].Note)
+	assert.Len(t, history("", owner), 2)
+	assert.Len(t, history("?q=search", owner), 1)
+	assert.Empty(t, history("?tag=lang&q=yandex", owner))
+
+	res = api.do(http.MethodPatch, "/api/user/urls/"+id, `{"tags":["docs"]}`)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusNotFound, res.StatusCode, "other user can't change metadata")
+
+	res = api.do(http.MethodPatch, "/api/user/urls/"+id, `{}`, withCookie(owner))
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+	res = api.do(http.MethodPatch, "/api/user/urls/"+id, `{"url":"https://evil.com","title":"`+strings.Repeat("a", 257)+`"}`, withCookie(owner))
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+	res = api.do(http.MethodPatch, "/api/user/urls/"+id, `{"tags":["docs"]}`, withCookie(owner))
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	var updated entity.URLs
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&updated))
+	assert.Equal(t, "https://go.dev", updated.OriginalURL, "rejected patch changes nothing")
+	assert.Equal(t, "Go", updated.Title, "missing fields are unchanged")
+	assert.Equal(t, []string{"docs"}, updated.Tags)
+	assert.Empty(t, history("?tag=lang", owner))
+
+	server := NewShortenerServer(cfg, service)
+	ctx := WithIdentity(context.Background(), entity.Identity{UserID: owner.Value, Scopes: entity.AllScopes})
+	batch, err := server.GetHistory(ctx, &pb.HistoryFilter{Tag: "docs"})
+	require.NoError(t, err)
+	require.Len(t, batch.Result, 1)
+	assert.Equal(t, id, batch.Result[0].Id)
+	assert.Equal(t, "Go", batch.Result[0].Meta.GetTitle())
+
+	link, err := server.UpdateLink(ctx, &pb.Link{Id: id, Meta: &pb.LinkMeta{Title: "Golang"}})
+	require.NoError(t, err)
+	assert.Equal(t, "https://go.dev", link.LongUrl)
+	assert.Equal(t, "Golang", link.Meta.GetTitle())
+	assert.Empty(t, link.Meta.GetTags(), "meta is replaced")
+
+	batch, err = server.GetHistory(ctx, &pb.HistoryFilter{Query: "golang"})
+	require.NoError(t, err)
+	assert.Len(t, batch.Result, 1)
+
+	_, err = server.UpdateLink(ctx, &pb.Link{Id: id})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
@@ -2,10 +2,7 @@ package handlers
 
 import (
 	"compress/gzip"
-	"crypto/hmac"
 	"crypto/rand"
-	"crypto/sha256"
-	"encoding/hex"
 	"errors"
 	"io"
 	"log"
@@ -13,15 +10,12 @@ import (
 	"net/http"
 	"strings"
 	"time"
+
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
 )
 
 // compress response.
 
-// secretKey is key for cookie auth.
-var secretKey = []byte("super secret key")
-
-// [32 bytes signature][8 bytes userID].
-
 // gzipWriter struct for sending gzip packed response.
 type gzipWriter struct {
 	http.ResponseWriter
@@ -34,6 +28,16 @@ func (w gzipWriter) Write(b []byte) (int, error) {
 	return w.Writer.Write(b)
 }
 
+// Flush sends data packed so far, streamed responses need it.
+func (w gzipWriter) Flush() {
+	if gz, ok := w.Writer.(*gzip.Writer); ok {
+		gz.Flush()
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
 // generateRandom generates random bytes for cookie authentication.
 func generateRandom(size int) ([]byte, error) {
 	b := make([]byte, size)
@@ -44,45 +48,61 @@ func generateRandom(size int) ([]byte, error) {
 	return b, nil
 }
 
+// newUserID generates random userID signed with active cookie key.
+func newUserID() (string, error) {
+	randomID, err := generateRandom(8)
+	if err != nil {
+		return "", err
+	}
+	return cookieKeys.Sign(randomID), nil
+}
+
+// verifyUserID checks signature of userID.
+func verifyUserID(userID string) bool {
+	return cookieKeys.Verify(userID)
+}
+
+// isHTTPS checks if request came over HTTPS directly or through proxy.
+func isHTTPS(r *http.Request) bool {
+	return r.TLS != nil || strings.EqualFold(r.Header.Get("X-Forwarded-Proto"), "https")
+}
+
 // CookieMiddleware check if user is authorized.
+// Requests which are already authenticated by API key are passed as is.
 func CookieMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, authorized := IdentityFromContext(r.Context()); authorized {
+			next.ServeHTTP(w, r)
+			return
+		}
+
 		userID, ok := r.Cookie("userID")
-		if ok == nil {
-			id, err := hex.DecodeString(userID.Value)
-			if err != nil || len(id) != 40 {
-				http.Error(w, err.Error(), 400)
-				return
-			}
-			signSrc := id[:32]
-			id = id[32:]
-			h := hmac.New(sha256.New, secretKey)
-			h.Write(id)
-			sign := h.Sum(nil)
-			if !hmac.Equal(signSrc, sign) {
-				log.Println("failed to verify")
-				ok = errors.New("failed to verify")
-			}
+		if ok == nil && !verifyUserID(userID.Value) {
+			log.Println("failed to verify")
+			ok = errors.New("failed to verify")
 		}
 		if ok != nil {
-			randomID, err := generateRandom(8)
-			h := hmac.New(sha256.New, secretKey)
-			h.Write(randomID)
-			sign := h.Sum(nil)
+			newID, err := newUserID()
 			if err != nil {
 				http.Error(w, err.Error(), 400)
+				return
 			}
 
 			cookie := http.Cookie{
-				Name:    "userID",
-				Value:   hex.EncodeToString(append(sign, randomID...)),
-				Expires: time.Now().Add(24 * time.Hour),
-				Path:    "/",
+				Name:     "userID",
+				Value:    newID,
+				Expires:  time.Now().Add(24 * time.Hour),
+				Path:     "/",
+				HttpOnly: true,
+				Secure:   isHTTPS(r),
+				SameSite: http.SameSiteLaxMode,
 			}
 			http.SetCookie(w, &cookie)
 			r.AddCookie(&cookie)
+			userID = &cookie
 		}
-		next.ServeHTTP(w, r)
+		identity := entity.Identity{UserID: userID.Value, Scopes: entity.AllScopes}
+		next.ServeHTTP(w, r.WithContext(WithIdentity(r.Context(), identity)))
 	})
 }
 
@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/hex"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bbt-t/lets-go-shortener/internal/config"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// OIDC login state is kept in cookie between redirects.
+const (
+	oidcStateCookie = "oidc_state"
+	oidcStateTTL    = 10 * time.Minute
+)
+
+// OIDCAuth signs users in with OpenID Connect authorization code flow.
+type OIDCAuth struct {
+	cfg     config.Config
+	handler *ShortenerHandler
+	client  *http.Client
+
+	mu       sync.Mutex
+	oauth    *oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+// NewOIDCAuth gets OIDC authentication by issuer from config.
+func NewOIDCAuth(cfg config.Config, s *ShortenerHandler) *OIDCAuth {
+	if cfg.OIDCRedirectURL == "" {
+		cfg.OIDCRedirectURL = cfg.BaseURL + "/api/user/oidc/callback"
+	}
+	return &OIDCAuth{
+		cfg:     cfg,
+		handler: s,
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// discover gets OAuth2 config and ID token verifier.
+// Discovery document of issuer is fetched on first use, so service starts when issuer is unavailable.
+func (a *OIDCAuth) discover() (*oauth2.Config, *oidc.IDTokenVerifier, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.oauth != nil {
+		return a.oauth, a.verifier, nil
+	}
+
+	// context is kept by provider to fetch JWKS later, so it mustn't be canceled.
+	ctx := oidc.ClientContext(context.Background(), a.client)
+	provider, err := oidc.NewProvider(ctx, a.cfg.OIDCIssuer)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	a.oauth = &oauth2.Config{
+		ClientID:     a.cfg.OIDCClientID,
+		ClientSecret: a.cfg.OIDCSecret,
+		RedirectURL:  a.cfg.OIDCRedirectURL,
+		Endpoint:     provider.Endpoint(),
+		Scopes:       []string{oidc.ScopeOpenID},
+	}
+	a.verifier = provider.Verifier(&oidc.Config{ClientID: a.cfg.OIDCClientID})
+	return a.oauth, a.verifier, nil
+}
+
+// randomToken gets random hex token.
+func randomToken() (string, error) {
+	b, err := generateRandom(16)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// setOIDCStateCookie sets cookie with state and nonce of login, empty value removes it.
+func setOIDCStateCookie(w http.ResponseWriter, r *http.Request, value string) {
+	cookie := &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    value,
+		Path:     "/api/user/oidc",
+		MaxAge:   int(oidcStateTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   isHTTPS(r),
+		SameSite: http.SameSiteLaxMode,
+	}
+	if value == "" {
+		cookie.MaxAge = -1
+	}
+	http.SetCookie(w, cookie)
+}
+
+// Login redirects user to identity provider.
+func (a *OIDCAuth) Login() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		oauth, _, err := a.discover()
+		if err != nil {
+			log.Println("Failed discover OIDC issuer:", err)
+			http.Error(w, "identity provider is unavailable", http.StatusBadGateway)
+			return
+		}
+
+		state, err := randomToken()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		nonce, err := randomToken()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		setOIDCStateCookie(w, r, state+"."+nonce)
+		http.Redirect(w, r, oauth.AuthCodeURL(state, oidc.Nonce(nonce)), http.StatusFound)
+	}
+}
+
+// Callback checks code from identity provider and starts session of user mapped to subject.
+func (a *OIDCAuth) Callback() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		oauth, verifier, err := a.discover()
+		if err != nil {
+			log.Println("Failed discover OIDC issuer:", err)
+			http.Error(w, "identity provider is unavailable", http.StatusBadGateway)
+			return
+		}
+
+		cookie, err := r.Cookie(oidcStateCookie)
+		if err != nil {
+			http.Error(w, "login isn't started", http.StatusBadRequest)
+			return
+		}
+		setOIDCStateCookie(w, r, "")
+
+		state, nonce, _ := strings.Cut(cookie.Value, ".")
+		query := r.URL.Query()
+		if state == "" || subtle.ConstantTimeCompare([]byte(state), []byte(query.Get("state"))) != 1 {
+			http.Error(w, "wrong state", http.StatusBadRequest)
+			return
+		}
+		if e := query.Get("error"); e != "" {
+			http.Error(w, e+": "+query.Get("error_description"), http.StatusUnauthorized)
+			return
+		}
+
+		ctx := oidc.ClientContext(r.Context(), a.client)
+		token, err := oauth.Exchange(ctx, query.Get("code"))
+		if err != nil {
+			log.Println("Failed exchange OIDC code:", err)
+			http.Error(w, "wrong code", http.StatusUnauthorized)
+			return
+		}
+		rawIDToken, ok := token.Extra("id_token").(string)
+		if !ok {
+			http.Error(w, "no id_token in response", http.StatusUnauthorized)
+			return
+		}
+		idToken, err := verifier.Verify(ctx, rawIDToken)
+		if err != nil {
+			log.Println("Failed verify ID token:", err)
+			http.Error(w, "wrong id_token", http.StatusUnauthorized)
+			return
+		}
+		if subtle.ConstantTimeCompare([]byte(idToken.Nonce), []byte(nonce)) != 1 {
+			http.Error(w, "wrong nonce", http.StatusUnauthorized)
+			return
+		}
+
+		user, err := a.handler.storage.LoginExternal(idToken.Issuer, idToken.Subject)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		startSession(a.handler, w, r, user, http.StatusOK)
+	}
+}
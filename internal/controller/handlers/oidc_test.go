@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bbt-t/lets-go-shortener/internal/adapter/storage"
+	"github.com/bbt-t/lets-go-shortener/internal/config"
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+	"github.com/bbt-t/lets-go-shortener/internal/usecase"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-jose/go-jose/v3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testIssuer is in-process OIDC identity provider.
+type testIssuer struct {
+	*httptest.Server
+	clientID string
+	key      *rsa.PrivateKey
+
+	mu    sync.Mutex
+	codes map[string]map[string]interface{}
+}
+
+// newTestIssuer starts OIDC identity provider with discovery, JWKS and token endpoints.
+func newTestIssuer(t *testing.T, clientID string) *testIssuer {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	issuer := &testIssuer{
+		clientID: clientID,
+		key:      key,
+		codes:    make(map[string]map[string]interface{}),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"issuer":                                issuer.URL,
+			"authorization_endpoint":                issuer.URL + "/authorize",
+			"token_endpoint":                        issuer.URL + "/token",
+			"jwks_uri":                              issuer.URL + "/jwks",
+			"id_token_signing_alg_values_supported": []string{"RS256"},
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(jose.JSONWebKeySet{Keys: []jose.JSONWebKey{
+			{Key: &key.PublicKey, KeyID: "key1", Algorithm: "RS256", Use: "sig"},
+		}})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		issuer.mu.Lock()
+		claims, ok := issuer.codes[r.FormValue("code")]
+		delete(issuer.codes, r.FormValue("code"))
+		issuer.mu.Unlock()
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "access",
+			"token_type":   "Bearer",
+			"id_token":     issuer.sign(t, issuer.key, claims),
+		})
+	})
+
+	issuer.Server = httptest.NewServer(mux)
+	t.Cleanup(issuer.Close)
+	return issuer
+}
+
+// claims gets valid ID token claims of subject.
+func (i *testIssuer) claims(subject, nonce string) map[string]interface{} {
+	return map[string]interface{}{
+		"iss":   i.URL,
+		"aud":   i.clientID,
+		"sub":   subject,
+		"nonce": nonce,
+		"iat":   time.Now().Unix(),
+		"exp":   time.Now().Add(time.Hour).Unix(),
+	}
+}
+
+// issueCode makes code which is exchanged for ID token with claims.
+func (i *testIssuer) issueCode(code string, claims map[string]interface{}) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.codes[code] = claims
+}
+
+// sign gets signed JWT.
+func (i *testIssuer) sign(t *testing.T, key *rsa.PrivateKey, claims map[string]interface{}) string {
+	signer, err := jose.NewSigner(
+		jose.SigningKey{Algorithm: jose.RS256, Key: jose.JSONWebKey{Key: key, KeyID: "key1"}},
+		(&jose.SignerOptions{}).WithType("JWT"),
+	)
+	require.NoError(t, err)
+
+	payload, err := json.Marshal(claims)
+	require.NoError(t, err)
+	jws, err := signer.Sign(payload)
+	require.NoError(t, err)
+	raw, err := jws.CompactSerialize()
+	require.NoError(t, err)
+	return raw
+}
+
+func TestOIDCAuth(t *testing.T) {
+	issuer := newTestIssuer(t, "shortener")
+
+	cfg := config.GetDefaultConfig()
+	cfg.OIDCIssuer, cfg.OIDCClientID, cfg.OIDCSecret = issuer.URL, "shortener", "secret"
+	s, err := storage.NewMapStorage(cfg)
+	require.NoError(t, err)
+	h := NewShortenerHandler(cfg, usecase.NewShortenerService(cfg, s))
+	oidcAuth := NewOIDCAuth(cfg, h)
+
+	router := chi.NewRouter()
+	router.Use(NewSessionMiddleware(h), CookieMiddleware)
+	router.Get("/api/user/oidc/login", oidcAuth.Login())
+	router.Get("/api/user/oidc/callback", oidcAuth.Callback())
+	router.Get("/api/user/urls", RecoverAllURL(h))
+
+	do := func(target string, cookies ...*http.Cookie) *http.Response {
+		request := httptest.NewRequest(http.MethodGet, target, nil)
+		for _, cookie := range cookies {
+			request.AddCookie(cookie)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, request)
+		return w.Result()
+	}
+	cookie := func(res *http.Response, name string) *http.Cookie {
+		for _, c := range res.Cookies() {
+			if c.Name == name {
+				return c
+			}
+		}
+		return nil
+	}
+	// login starts flow and gets state cookie, state and nonce sent to issuer.
+	login := func() (*http.Cookie, string, string) {
+		res := do("/api/user/oidc/login")
+		defer res.Body.Close()
+		require.Equal(t, http.StatusFound, res.StatusCode)
+
+		location, err := url.Parse(res.Header.Get("Location"))
+		require.NoError(t, err)
+		assert.Equal(t, issuer.URL+"/authorize", location.Scheme+"://"+location.Host+location.Path)
+		assert.Equal(t, "shortener", location.Query().Get("client_id"))
+		assert.Equal(t, cfg.BaseURL+"/api/user/oidc/callback", location.Query().Get("redirect_uri"))
+
+		state := cookie(res, oidcStateCookie)
+		require.NotNil(t, state)
+		assert.True(t, state.HttpOnly)
+		return state, location.Query().Get("state"), location.Query().Get("nonce")
+	}
+
+	stateCookie, state, nonce := login()
+	issuer.issueCode("code1", issuer.claims("employee1", nonce))
+
+	res := do("/api/user/oidc/callback?code=code1&state=wrong", stateCookie)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+	res = do("/api/user/oidc/callback?code=code1&state="+state, stateCookie)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	session := cookie(res, sessionCookie)
+	require.NotNil(t, session)
+
+	var user entity.User
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&user))
+
+	res = do("/api/user/urls", session)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusNoContent, res.StatusCode)
+	assert.Nil(t, cookie(res, "userID"), "session is used instead of anonymous cookie")
+
+	// same subject is mapped to same user.
+	stateCookie, state, nonce = login()
+	issuer.issueCode("code2", issuer.claims("employee1", nonce))
+	res = do("/api/user/oidc/callback?code=code2&state="+state, stateCookie)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	var again entity.User
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&again))
+	assert.Equal(t, user.ID, again.ID)
+
+	// ID token of other login is refused.
+	stateCookie, state, _ = login()
+	issuer.issueCode("code3", issuer.claims("employee1", "other nonce"))
+	res = do("/api/user/oidc/callback?code=code3&state="+state, stateCookie)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+
+	// ID token for other client is refused.
+	stateCookie, state, nonce = login()
+	claims := issuer.claims("employee1", nonce)
+	claims["aud"] = "other client"
+	issuer.issueCode("code4", claims)
+	res = do("/api/user/oidc/callback?code=code4&state="+state, stateCookie)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+
+	// ID token signed by unknown key is refused.
+	foreign, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	stateCookie, state, nonce = login()
+	issuer.issueCode("code5", issuer.claims("employee1", nonce))
+	issuer.key, foreign = foreign, issuer.key
+	res = do("/api/user/oidc/callback?code=code5&state="+state, stateCookie)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	issuer.key = foreign
+
+	res = do("/api/user/oidc/callback?code=unknown&state=" + state)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode, "login isn't started")
+}
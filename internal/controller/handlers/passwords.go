@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// passwordPage is form of password of protected link, it's posted to the same short url.
+var passwordPage = template.Must(template.New("password").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Link is protected</title></head>
+<body>
+<h1>Link is protected by password</h1>
+{{if .}}<p>{{.}}</p>{{end}}
+<form method="post">
+<input type="password" name="password" autofocus required>
+<button type="submit">Open</button>
+</form>
+</body>
+</html>
+`))
+
+// passwordForm sends form of password with message.
+func passwordForm(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	if err := passwordPage.Execute(w, message); err != nil {
+		log.Printf("failed to write password page: %v", err)
+	}
+}
+
+// UnlockURL sends person to page of protected link after correct password.
+// See Other makes browser get original url instead of posting password to it.
+func UnlockURL(s *ShortenerHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+		if id == "" {
+			http.Error(w, "missing id parameter", http.StatusBadRequest)
+			return
+		}
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, "wrong form", http.StatusBadRequest)
+			return
+		}
+
+		url, err := s.storage.UnlockOriginal(requestActor(r, ""), id, r.PostForm.Get("password"))
+		url, err = routeOriginal(s, id, url, err, requestVisitor(r))
+		redirectOriginal(w, url, err, http.StatusSeeOther)
+	}
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/bbt-t/lets-go-shortener/internal/adapter/storage"
+	"github.com/bbt-t/lets-go-shortener/internal/config"
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+	"github.com/bbt-t/lets-go-shortener/internal/usecase"
+	pb "github.com/bbt-t/lets-go-shortener/pkg/grpc"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestProtectedLink(t *testing.T) {
+	cfg := config.GetDefaultConfig()
+	s, err := storage.NewMapStorage(cfg)
+	require.NoError(t, err)
+	limiter, err := storage.NewLimiter(cfg, s)
+	require.NoError(t, err)
+	service := usecase.NewShortenerService(cfg, s)
+	service.SetLimiter(limiter)
+	h := NewShortenerHandler(cfg, service)
+
+	router := chi.NewRouter()
+	router.Use(CookieMiddleware)
+	router.Post("/api/shorten", RecoverOriginalURLPost(h))
+	router.Get("/{id}", RecoverOriginalURL(h))
+	router.Post("/{id}", UnlockURL(h))
+
+	do := func(request *http.Request) *http.Response {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, request)
+		return w.Result()
+	}
+	unlock := func(id, password, ip string) *http.Response {
+		form := url.Values{"password": {password}}
+		request := httptest.NewRequest(http.MethodPost, "/"+id, strings.NewReader(form.Encode()))
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		request.Header.Set("X-Real-IP", ip)
+		return do(request)
+	}
+
+	request := httptest.NewRequest(http.MethodPost, "/api/shorten", strings.NewReader(`{"url":"https://yandex.ru","password":"secret"}`))
+	request.Header.Set("Content-Type", "application/json")
+	res := do(request)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+	var created entity.RespJSON
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&created))
+	id := created.Result[strings.LastIndex(created.Result, "/")+1:]
+
+	res = do(httptest.NewRequest(http.MethodGet, "/"+id, nil))
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	assert.Contains(t, res.Header.Get("Content-Type"), "text/html")
+	assert.Empty(t, res.Header.Get("Location"))
+
+	res = unlock(id, "wrong", "10.0.0.1")
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+
+	res = unlock(id, "secret", "10.0.0.1")
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusSeeOther, res.StatusCode)
+	assert.Equal(t, "https://yandex.ru", res.Header.Get("Location"))
+
+	// attempts are limited, correct password doesn't help after limit.
+	for i := 0; i < 5; i++ {
+		res = unlock(id, "wrong", "10.0.0.2")
+		res.Body.Close()
+		assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	}
+	res = unlock(id, "secret", "10.0.0.2")
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+
+	server := NewShortenerServer(cfg, service)
+	_, err = server.GetLong(context.Background(), &pb.Link{Id: id})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(linkPasswordMetadata, "wrong"))
+	_, err = server.GetLong(ctx, &pb.Link{Id: id})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs(linkPasswordMetadata, "secret"))
+	link, err := server.GetLong(ctx, &pb.Link{Id: id})
+	require.NoError(t, err)
+	assert.Equal(t, "https://yandex.ru", link.LongUrl)
+
+	ctx = WithIdentity(context.Background(), entity.Identity{UserID: "user1", Scopes: entity.AllScopes})
+	link, err = server.CreateShort(ctx, &pb.Link{LongUrl: "https://yandex.ru", Password: "other"})
+	require.NoError(t, err)
+	assert.NotEqual(t, id, link.Id)
+	assert.Empty(t, link.Password)
+}
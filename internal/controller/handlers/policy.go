@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+)
+
+// blockedPage is warning shown instead of redirect to blocked domain.
+// Original url is shown as text, so it can't be opened by one click.
+var blockedPage = template.Must(template.New("blocked").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Link is blocked</title></head>
+<body>
+<h1>Warning: link is blocked</h1>
+<p>This short link leads to domain which is blocked as unsafe, so you aren't redirected.</p>
+<p>Original url: <code>{{.}}</code></p>
+</body>
+</html>
+`))
+
+// blockedWarning sends warning page instead of redirect to url.
+func blockedWarning(w http.ResponseWriter, url string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusForbidden)
+	if err := blockedPage.Execute(w, url); err != nil {
+		log.Printf("failed to write blocked page: %v", err)
+	}
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/bbt-t/lets-go-shortener/internal/config"
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+	"github.com/bbt-t/lets-go-shortener/internal/usecase"
+	pb "github.com/bbt-t/lets-go-shortener/pkg/grpc"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestDomainPolicy(t *testing.T) {
+	cfg := config.GetDefaultConfig()
+	cfg.DomainDenylist = "evil.com"
+	api := newTestAPI(t, cfg)
+	service, h := api.service, api.h
+	policy, err := usecase.NewPolicy(cfg)
+	require.NoError(t, err)
+	service.SetPolicy(policy)
+
+	api.router.Post("/", RecoverOriginalURLPost(h))
+	api.router.Post("/api/shorten/batch", URLBatch(h))
+	api.router.Get("/{id}", RecoverOriginalURL(h))
+
+	code, _ := api.text(http.MethodPost, "/", "https://EVIL.com/login")
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+	code, _ = api.text(http.MethodPost, "/api/shorten/batch", `[{"correlation_id":"1","original_url":"https://ya.ru"},{"correlation_id":"2","original_url":"https://evil.com"}]`)
+	assert.Equal(t, http.StatusUnprocessableEntity, code)
+
+	code, short := api.text(http.MethodPost, "/", "https://bad.org/login")
+	require.Equal(t, http.StatusCreated, code)
+	id := short[strings.LastIndex(short, "/")+1:]
+	code, _ = api.text(http.MethodGet, "/"+id, "")
+	assert.Equal(t, http.StatusTemporaryRedirect, code)
+
+	server := NewShortenerServer(cfg, service)
+	ctx := WithIdentity(context.Background(), entity.Identity{UserID: "user1", Scopes: entity.AllScopes})
+	_, err = server.CreateShort(ctx, &pb.Link{LongUrl: "https://evil.com"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = server.BatchShort(ctx, &pb.Batch{Result: []*pb.Link{{CorrelationId: "1", LongUrl: "https://evil.com"}}})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// domain is blocked after link was created.
+	cfg.DomainDenylist = "evil.com,bad.org"
+	policy, err = usecase.NewPolicy(cfg)
+	require.NoError(t, err)
+	service.SetPolicy(policy)
+
+	code, body := api.text(http.MethodGet, "/"+id, "")
+	assert.Equal(t, http.StatusForbidden, code)
+	assert.Contains(t, body, "https://bad.org/login")
+
+	_, err = server.GetLong(ctx, &pb.Link{Id: id})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// previewPage shows destination of link, continue button posts to short url which redirects and takes click.
+// Original url is shown as text, so person sees where link leads before following it.
+var previewPage = template.Must(template.New("preview").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Link preview</title></head>
+<body>
+<h1>This link leads to {{.Domain}}</h1>
+{{if .Title}}<p>Page title: <strong>{{.Title}}</strong></p>{{end}}
+<p>Original url: <code>{{.OriginalURL}}</code></p>
+<form method="post" action="{{.ShortURL}}">
+<button type="submit" autofocus>Continue</button>
+</form>
+</body>
+</html>
+`))
+
+// previewLink sends preview page of link or page of error getting it.
+func previewLink(s *ShortenerHandler, w http.ResponseWriter, id string) {
+	preview, err := s.storage.PreviewLink(id)
+	if err != nil {
+		redirectOriginal(w, preview.OriginalURL, err, http.StatusTemporaryRedirect)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	if err = previewPage.Execute(w, preview); err != nil {
+		log.Printf("failed to write preview page: %v", err)
+	}
+}
+
+// PreviewURL sends preview page of link instead of redirect, click isn't taken until person continues.
+func PreviewURL(s *ShortenerHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+		if id == "" {
+			http.Error(w, "missing id parameter", http.StatusBadRequest)
+			return
+		}
+		previewLink(s, w, id)
+	}
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bbt-t/lets-go-shortener/internal/adapter/storage"
+	"github.com/bbt-t/lets-go-shortener/internal/config"
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+	"github.com/bbt-t/lets-go-shortener/internal/usecase"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLinkPreview(t *testing.T) {
+	destination := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		io.WriteString(w, "<html><head><title>\n  Example   page </title></head><body></body></html>")
+	}))
+	defer destination.Close()
+
+	cfg := config.GetDefaultConfig()
+	s, err := storage.NewMapStorage(cfg)
+	require.NoError(t, err)
+	service := usecase.NewShortenerService(cfg, s)
+	titles := usecase.NewTitleFetcher(destination.Client())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go titles.Run(ctx, 1)
+	service.SetTitleFetcher(titles)
+	h := NewShortenerHandler(cfg, service)
+
+	router := chi.NewRouter()
+	router.Get("/{id}", RecoverOriginalURL(h))
+	router.Get("/{id}+", PreviewURL(h))
+	router.Post("/{id}", UnlockURL(h))
+
+	do := func(method, target string) (*http.Response, string) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+		res := w.Result()
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		return res, string(body)
+	}
+	clicks := func(id string) int {
+		link, err := service.GetUserLink("user", id)
+		require.NoError(t, err)
+		return link.Clicks
+	}
+
+	actor := entity.Actor{UserID: "user"}
+	id, err := service.CreateShortWithOptions(actor, destination.URL+"/page", entity.LinkOptions{})
+	require.NoError(t, err)
+
+	res, body := do(http.MethodGet, "/"+id+"+")
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Equal(t, "text/html; charset=utf-8", res.Header.Get("Content-Type"))
+	assert.Contains(t, body, "127.0.0.1")
+	assert.Contains(t, body, destination.URL+"/page")
+	assert.Contains(t, body, `action="`+cfg.BaseURL+"/"+id+`"`)
+	assert.Equal(t, 0, clicks(id))
+
+	// title is fetched in background and cached.
+	assert.Eventually(t, func() bool {
+		_, body = do(http.MethodGet, "/"+id+"+")
+		return strings.Contains(body, "Example page")
+	}, 2*time.Second, 10*time.Millisecond)
+
+	res, _ = do(http.MethodGet, "/"+id)
+	assert.Equal(t, http.StatusTemporaryRedirect, res.StatusCode)
+	assert.Equal(t, 1, clicks(id))
+
+	// interstitial link shows preview instead of redirect, continue button redirects.
+	interstitial := true
+	_, err = service.EditLink(actor, id, entity.LinkPatch{Interstitial: &interstitial})
+	require.NoError(t, err)
+	res, body = do(http.MethodGet, "/"+id)
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	assert.Contains(t, body, "Continue")
+	assert.Equal(t, 1, clicks(id))
+	res, _ = do(http.MethodPost, "/"+id)
+	assert.Equal(t, http.StatusSeeOther, res.StatusCode)
+	assert.Equal(t, destination.URL+"/page", res.Header.Get("Location"))
+	assert.Equal(t, 2, clicks(id))
+
+	link, err := service.GetUserLink("user", id)
+	require.NoError(t, err)
+	assert.True(t, link.Interstitial)
+	interstitial = false
+	_, err = service.EditLink(actor, id, entity.LinkPatch{Interstitial: &interstitial})
+	require.NoError(t, err)
+	res, _ = do(http.MethodGet, "/"+id)
+	assert.Equal(t, http.StatusTemporaryRedirect, res.StatusCode)
+
+	// destination of protected link is shown only after password.
+	protected, err := service.CreateShortWithOptions(actor, destination.URL+"/secret", entity.LinkOptions{
+		Password: "secret",
+		Meta:     entity.LinkMeta{Interstitial: true},
+	})
+	require.NoError(t, err)
+	for _, target := range []string{"/" + protected, "/" + protected + "+"} {
+		res, body = do(http.MethodGet, target)
+		assert.Equal(t, http.StatusUnauthorized, res.StatusCode, target)
+		assert.NotContains(t, body, "/secret", target)
+	}
+
+	require.NoError(t, service.MarkAsDeleted(actor, id))
+	res, _ = do(http.MethodGet, "/"+id+"+")
+	assert.Equal(t, http.StatusGone, res.StatusCode)
+	res, _ = do(http.MethodGet, "/unknown+")
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+}
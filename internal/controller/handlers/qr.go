@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/bbt-t/lets-go-shortener/internal/adapter/storage"
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+	"github.com/bbt-t/lets-go-shortener/internal/usecase"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// qrCacheControl lets clients and proxies cache QR codes for a day, code of short url never changes.
+const qrCacheControl = "public, max-age=86400"
+
+// QRCode sends QR code of short url, options are taken from query: size, format, level and logo.
+// Errors of link are the same as errors of redirect.
+func QRCode(s *ShortenerHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := chi.URLParam(r, "id")
+		if id == "" {
+			http.Error(w, "missing id parameter", http.StatusBadRequest)
+			return
+		}
+
+		query := r.URL.Query()
+		options := entity.QROptions{Format: query.Get("format"), Level: query.Get("level")}
+		var err error
+		if value := query.Get("size"); value != "" {
+			if options.Size, err = strconv.Atoi(value); err != nil {
+				http.Error(w, "wrong size", http.StatusBadRequest)
+				return
+			}
+		}
+		if value := query.Get("logo"); value != "" {
+			if options.Logo, err = strconv.ParseBool(value); err != nil {
+				http.Error(w, "wrong logo", http.StatusBadRequest)
+				return
+			}
+		}
+
+		content, contentType, err := s.storage.RenderQR(id, options)
+		switch {
+		case errors.Is(err, usecase.ErrInvalidQR):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		case errors.Is(err, storage.ErrDeleted), errors.Is(err, storage.ErrExhausted), errors.Is(err, storage.ErrNotFound):
+			redirectOriginal(w, "", err, http.StatusTemporaryRedirect)
+			return
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		sum := sha256.Sum256(content)
+		etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", qrCacheControl)
+		if match := r.Header.Get("If-None-Match"); match == "*" || strings.Contains(match, etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("Content-Type", contentType)
+		if _, err = w.Write(content); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bbt-t/lets-go-shortener/internal/adapter/storage"
+	"github.com/bbt-t/lets-go-shortener/internal/config"
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+	"github.com/bbt-t/lets-go-shortener/internal/usecase"
+	pb "github.com/bbt-t/lets-go-shortener/pkg/grpc"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestQRCode(t *testing.T) {
+	cfg := config.GetDefaultConfig()
+	s, err := storage.NewMapStorage(cfg)
+	require.NoError(t, err)
+	service := usecase.NewShortenerService(cfg, s)
+	h := NewShortenerHandler(cfg, service)
+
+	router := chi.NewRouter()
+	router.Get("/{id}/qr", QRCode(h))
+
+	do := func(request *http.Request) *http.Response {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, request)
+		return w.Result()
+	}
+	get := func(target string) (int, http.Header, []byte) {
+		res := do(httptest.NewRequest(http.MethodGet, target, nil))
+		defer res.Body.Close()
+		body, err := io.ReadAll(res.Body)
+		require.NoError(t, err)
+		return res.StatusCode, res.Header, body
+	}
+
+	actor := entity.Actor{UserID: "user"}
+	id, err := service.CreateShortWithOptions(actor, "https://yandex.ru/qr", entity.LinkOptions{})
+	require.NoError(t, err)
+
+	code, header, body := get("/" + id + "/qr?size=128")
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "image/png", header.Get("Content-Type"))
+	assert.Equal(t, "public, max-age=86400", header.Get("Cache-Control"))
+	img, err := png.Decode(bytes.NewReader(body))
+	require.NoError(t, err)
+	assert.Equal(t, 128, img.Bounds().Dx())
+
+	// client with cached code gets nothing.
+	request := httptest.NewRequest(http.MethodGet, "/"+id+"/qr?size=128", nil)
+	request.Header.Set("If-None-Match", header.Get("ETag"))
+	res := do(request)
+	res.Body.Close()
+	assert.Equal(t, http.StatusNotModified, res.StatusCode)
+
+	code, header, body = get("/" + id + "/qr?format=svg&level=H")
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "image/svg+xml", header.Get("Content-Type"))
+	assert.True(t, strings.HasPrefix(string(body), "<svg"))
+	assert.NotContains(t, string(body), "<image")
+
+	for _, query := range []string{"size=8", "size=big", "format=gif", "level=X", "logo=true"} {
+		code, _, _ = get("/" + id + "/qr?" + query)
+		assert.Equal(t, http.StatusBadRequest, code, query)
+	}
+
+	logo := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	for i := range logo.Pix {
+		logo.Pix[i] = 0xff
+		if i%4 == 1 || i%4 == 2 {
+			logo.Pix[i] = 0
+		}
+	}
+	service.SetQRLogo(logo)
+	code, _, body = get("/" + id + "/qr?logo=true")
+	require.Equal(t, http.StatusOK, code)
+	img, err = png.Decode(bytes.NewReader(body))
+	require.NoError(t, err)
+	assert.Equal(t, color.RGBAModel.Convert(color.RGBA{R: 0xff, A: 0xff}), color.RGBAModel.Convert(img.At(128, 128)))
+	code, _, body = get("/" + id + "/qr?logo=true&format=svg")
+	require.Equal(t, http.StatusOK, code)
+	assert.Contains(t, string(body), `<image`)
+
+	// QR code doesn't take click of limited link.
+	limited, err := service.CreateShortWithOptions(actor, "https://yandex.ru/once", entity.LinkOptions{MaxClicks: 1})
+	require.NoError(t, err)
+	code, _, _ = get("/" + limited + "/qr")
+	assert.Equal(t, http.StatusOK, code)
+	_, err = service.GetOriginal(limited)
+	require.NoError(t, err)
+	code, _, _ = get("/" + limited + "/qr")
+	assert.Equal(t, http.StatusGone, code)
+
+	require.NoError(t, service.MarkAsDeleted(actor, id))
+	code, _, _ = get("/" + id + "/qr")
+	assert.Equal(t, http.StatusGone, code)
+	code, _, _ = get("/unknown/qr")
+	assert.Equal(t, http.StatusNotFound, code)
+
+	server := NewShortenerServer(cfg, service)
+	_, err = server.GetQRCode(context.Background(), &pb.QRRequest{Id: id})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	_, err = server.GetQRCode(context.Background(), &pb.QRRequest{Id: limited, Format: "gif"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	other, err := service.CreateShortWithOptions(actor, "https://yandex.ru/grpc", entity.LinkOptions{})
+	require.NoError(t, err)
+	qr, err := server.GetQRCode(context.Background(), &pb.QRRequest{Id: other, Format: "svg"})
+	require.NoError(t, err)
+	assert.Equal(t, "image/svg+xml", qr.ContentType)
+	assert.Contains(t, string(qr.Content), "<svg")
+}
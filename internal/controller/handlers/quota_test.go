@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/bbt-t/lets-go-shortener/internal/config"
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+	pb "github.com/bbt-t/lets-go-shortener/pkg/grpc"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestQuota(t *testing.T) {
+	cfg := config.GetDefaultConfig()
+	cfg.QuotaActive = 3
+	cfg.QuotaPerDay = 4
+	api := newTestAPI(t, cfg)
+	h := api.h
+	api.router.Post("/api/shorten", RecoverOriginalURLPost(h))
+	api.router.Post("/api/shorten/batch", URLBatch(h))
+	api.router.Delete("/api/user/urls", DeleteURL(h))
+	api.router.Get("/api/user/quota", GetQuota(h))
+
+	quota := func(user *http.Cookie) entity.QuotaUsage {
+		res := api.do(http.MethodGet, "/api/user/quota", "", withCookie(user))
+		defer res.Body.Close()
+		require.Equal(t, http.StatusOK, res.StatusCode)
+		var usage entity.QuotaUsage
+		require.NoError(t, json.NewDecoder(res.Body).Decode(&usage))
+		return usage
+	}
+
+	res := api.do(http.MethodPost, "/api/shorten", `{"url":"https://yandex.ru"}`)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+	user := res.Cookies()[0]
+
+	res = api.do(http.MethodPost, "/api/shorten/batch", `[{"correlation_id":"1","original_url":"https://google.com"},{"correlation_id":"2","original_url":"https://ya.ru"},{"correlation_id":"3","original_url":"https://go.dev"}]`, withCookie(user))
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusTooManyRequests, res.StatusCode, "batch is over quota of active links")
+
+	res = api.do(http.MethodPost, "/api/shorten/batch", `[{"correlation_id":"1","original_url":"https://google.com"},{"correlation_id":"2","original_url":"https://ya.ru"}]`, withCookie(user))
+	defer res.Body.Close()
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+
+	res = api.do(http.MethodPost, "/api/shorten", `{"url":"https://go.dev"}`, withCookie(user))
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+
+	// links which user already has don't need quota.
+	res = api.do(http.MethodPost, "/api/shorten", `{"url":"https://yandex.ru"}`, withCookie(user))
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusConflict, res.StatusCode)
+
+	usage := quota(user)
+	assert.Equal(t, 3, usage.ActiveLinks)
+	assert.Equal(t, 3, usage.MaxActiveLinks)
+	assert.Equal(t, 3, usage.LinksToday)
+	assert.Equal(t, 4, usage.MaxLinksPerDay)
+	assert.True(t, usage.ResetsAt.After(time.Now()))
+	assert.True(t, usage.ResetsAt.Before(time.Now().Add(24*time.Hour)))
+
+	// deleted link frees quota of active links, but not of links per day.
+	res = api.do(http.MethodDelete, "/api/user/urls", `["1"]`, withCookie(user))
+	defer res.Body.Close()
+	require.Equal(t, http.StatusAccepted, res.StatusCode)
+
+	res = api.do(http.MethodPost, "/api/shorten", `{"url":"https://go.dev"}`, withCookie(user))
+	defer res.Body.Close()
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+
+	res = api.do(http.MethodDelete, "/api/user/urls", `["2"]`, withCookie(user))
+	defer res.Body.Close()
+	require.Equal(t, http.StatusAccepted, res.StatusCode)
+
+	res = api.do(http.MethodPost, "/api/shorten", `{"url":"https://pkg.go.dev"}`, withCookie(user))
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+
+	usage = quota(user)
+	assert.Equal(t, 2, usage.ActiveLinks)
+	assert.Equal(t, 4, usage.LinksToday)
+
+	// other users have own quota.
+	res = api.do(http.MethodPost, "/api/shorten", `{"url":"https://pkg.go.dev"}`)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusCreated, res.StatusCode)
+
+	// gRPC shares quota with HTTP.
+	server := NewShortenerServer(cfg, h.storage)
+	ctx := WithIdentity(context.Background(), entity.Identity{UserID: user.Value, Scopes: entity.AllScopes})
+	_, err := server.CreateShort(ctx, &pb.Link{LongUrl: "https://pkg.go.dev/net/http"})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	_, err = server.BatchShort(ctx, &pb.Batch{Result: []*pb.Link{{CorrelationId: "1", LongUrl: "https://pkg.go.dev/net"}}})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
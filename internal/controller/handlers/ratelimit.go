@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bbt-t/lets-go-shortener/internal/adapter/storage"
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+	pb "github.com/bbt-t/lets-go-shortener/pkg/grpc"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Route groups of rate limits.
+const (
+	RateGroupCreate   = "create"
+	RateGroupRedirect = "redirect"
+	RateGroupHistory  = "history"
+	RateGroupInternal = "internal"
+)
+
+// rateGroups are RPCs of route groups.
+var rateGroups = map[string]string{
+	pb.Shortener_CreateShort_FullMethodName:      RateGroupCreate,
+	pb.Shortener_BatchShort_FullMethodName:       RateGroupCreate,
+	pb.Shortener_UpdateLink_FullMethodName:       RateGroupCreate,
+	pb.Shortener_SetRedirectRules_FullMethodName: RateGroupCreate,
+	pb.Shortener_GetLong_FullMethodName:          RateGroupRedirect,
+	pb.Shortener_GetQRCode_FullMethodName:        RateGroupRedirect,
+	pb.Shortener_GetHistory_FullMethodName:       RateGroupHistory,
+	pb.Shortener_WatchClicks_FullMethodName:      RateGroupHistory,
+	pb.Shortener_GetStatistics_FullMethodName:    RateGroupInternal,
+}
+
+// ParseRateLimits parses limits like "create=20/s,redirect=100/1m".
+// Period is s, m, h or Go duration, burst of group is its count of requests.
+func ParseRateLimits(value string) (map[string]entity.RateLimit, error) {
+	limits := make(map[string]entity.RateLimit)
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		group, rate, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("wrong rate limit %q: group=count/period is expected", part)
+		}
+		count, period, ok := strings.Cut(rate, "/")
+		if !ok {
+			return nil, fmt.Errorf("wrong rate limit %q: count/period is expected", part)
+		}
+
+		burst, err := strconv.Atoi(count)
+		if err != nil || burst < 1 {
+			return nil, fmt.Errorf("wrong rate limit %q: count must be positive number", part)
+		}
+		if period != "" && strings.IndexAny(period[:1], "0123456789") < 0 {
+			period = "1" + period
+		}
+		duration, err := time.ParseDuration(period)
+		if err != nil || duration <= 0 {
+			return nil, fmt.Errorf("wrong rate limit %q: period must be positive duration", part)
+		}
+
+		switch group {
+		case RateGroupCreate, RateGroupRedirect, RateGroupHistory, RateGroupInternal:
+			limits[group] = entity.RateLimit{Burst: burst, Period: duration}
+		default:
+			return nil, fmt.Errorf("wrong rate limit %q: unknown group %s", part, group)
+		}
+	}
+	return limits, nil
+}
+
+// RateLimiter limits requests of route groups by caller.
+type RateLimiter struct {
+	limiter storage.Limiter
+	limits  map[string]entity.RateLimit
+}
+
+// NewRateLimiter gets rate limiter of route groups. Group without limit isn't limited.
+func NewRateLimiter(limiter storage.Limiter, limits map[string]entity.RateLimit) *RateLimiter {
+	return &RateLimiter{limiter: limiter, limits: limits}
+}
+
+// rateKey gets key of caller. Users authenticated by API key or session are limited by user ID,
+// others by IP because anonymous userID cookies are free to get.
+func rateKey(ctx context.Context, ip string) string {
+	if identity, ok := IdentityFromContext(ctx); ok && (identity.KeyID != "" || identity.Account) {
+		return "user:" + identity.UserID
+	}
+	return "ip:" + ip
+}
+
+// take takes token of caller in group. Returns time to wait if limit is exceeded.
+// Failed limiter doesn't fail request, error is written to log.
+func (l *RateLimiter) take(group, key string) time.Duration {
+	limit, ok := l.limits[group]
+	if !ok {
+		return 0
+	}
+	wait, err := l.limiter.Take(group+":"+key, limit)
+	if err != nil {
+		log.Printf("rate limiter: %v", err)
+		return 0
+	}
+	return wait
+}
+
+// retryAfter gets seconds to wait, at least one.
+func retryAfter(wait time.Duration) string {
+	return strconv.Itoa(int(math.Max(1, math.Ceil(wait.Seconds()))))
+}
+
+// limitRequest sends 429 response if caller exceeded limit of group.
+func (l *RateLimiter) limitRequest(group string, w http.ResponseWriter, r *http.Request) bool {
+	wait := l.take(group, rateKey(r.Context(), clientIP(r)))
+	if wait <= 0 {
+		return false
+	}
+	w.Header().Set("Retry-After", retryAfter(wait))
+	http.Error(w, "429 Too Many Requests", http.StatusTooManyRequests)
+	return true
+}
+
+// Limit limits requests of route group.
+func (l *RateLimiter) Limit(group string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if l.limitRequest(group, w, r) {
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// gatewayRateGroup gets route group of /v2/ request like of its RPC.
+func gatewayRateGroup(r *http.Request) string {
+	path := strings.TrimSuffix(r.URL.Path, "/")
+	switch {
+	case r.Method == http.MethodPost && (path == "/v2/shorten" || path == "/v2/shorten/batch"):
+		return RateGroupCreate
+	case (r.Method == http.MethodPatch || r.Method == http.MethodPut) && strings.HasPrefix(path, "/v2/user/urls/"):
+		return RateGroupCreate
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/v2/links/"):
+		return RateGroupRedirect
+	case r.Method == http.MethodGet && path == "/v2/user/urls":
+		return RateGroupHistory
+	case strings.HasPrefix(path, "/v2/internal/"):
+		return RateGroupInternal
+	}
+	return ""
+}
+
+// LimitGateway limits requests of /v2/ API, gateway calls RPCs without interceptors.
+func (l *RateLimiter) LimitGateway(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if l.limitRequest(gatewayRateGroup(r), w, r) {
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// NewRateLimitInterceptor limits calls of route groups.
+// It must run after API key interceptor to limit authenticated callers by user ID.
+func NewRateLimitInterceptor(l *RateLimiter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		wait := l.take(rateGroups[info.FullMethod], rateKey(ctx, peerIP(ctx)))
+		if wait <= 0 {
+			return handler(ctx, req)
+		}
+		grpc.SetHeader(ctx, metadata.Pairs("retry-after", retryAfter(wait)))
+		return nil, status.Error(codes.ResourceExhausted, "rate limit exceeded, retry after "+retryAfter(wait)+"s")
+	}
+}
+
+// NewRateLimitStreamInterceptor limits opening of streams like NewRateLimitInterceptor limits calls.
+func NewRateLimitStreamInterceptor(l *RateLimiter) grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := stream.Context()
+		wait := l.take(rateGroups[info.FullMethod], rateKey(ctx, peerIP(ctx)))
+		if wait <= 0 {
+			return handler(srv, stream)
+		}
+		stream.SetHeader(metadata.Pairs("retry-after", retryAfter(wait)))
+		return status.Error(codes.ResourceExhausted, "rate limit exceeded, retry after "+retryAfter(wait)+"s")
+	}
+}
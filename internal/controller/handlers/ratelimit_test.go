@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+	pb "github.com/bbt-t/lets-go-shortener/pkg/grpc"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// fakeLimiter allows burst of every key once.
+type fakeLimiter map[string]int
+
+// Take takes token from bucket of key.
+func (l fakeLimiter) Take(key string, limit entity.RateLimit) (time.Duration, error) {
+	if l[key] >= limit.Burst {
+		return 1500 * time.Millisecond, nil
+	}
+	l[key]++
+	return 0, nil
+}
+
+func TestParseRateLimits(t *testing.T) {
+	limits, err := ParseRateLimits("create=20/s, redirect=100/1m,history=5/h")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]entity.RateLimit{
+		RateGroupCreate:   {Burst: 20, Period: time.Second},
+		RateGroupRedirect: {Burst: 100, Period: time.Minute},
+		RateGroupHistory:  {Burst: 5, Period: time.Hour},
+	}, limits)
+
+	limits, err = ParseRateLimits("")
+	require.NoError(t, err)
+	assert.Empty(t, limits)
+
+	for _, value := range []string{"create", "create=20", "create=0/s", "create=20/", "create=20/0s", "delete=20/s"} {
+		_, err = ParseRateLimits(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	limiter := fakeLimiter{}
+	limits := NewRateLimiter(limiter, map[string]entity.RateLimit{
+		RateGroupCreate: {Burst: 2, Period: time.Second},
+	})
+
+	var handled int
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { handled++ })
+	do := func(handler http.Handler, method, target, ip string, identity *entity.Identity) *http.Response {
+		request := httptest.NewRequest(method, target, nil)
+		request.Header.Set("X-Real-IP", ip)
+		if identity != nil {
+			request = request.WithContext(WithIdentity(request.Context(), *identity))
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, request)
+		return w.Result()
+	}
+
+	create := limits.Limit(RateGroupCreate)(ok)
+	for i := 0; i < 2; i++ {
+		res := do(create, http.MethodPost, "/api/shorten", "192.168.1.1", &entity.Identity{UserID: "anonymous"})
+		res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	}
+
+	// new anonymous cookie doesn't reset limit of IP.
+	res := do(create, http.MethodPost, "/api/shorten", "192.168.1.1", &entity.Identity{UserID: "other"})
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+	assert.Equal(t, "2", res.Header.Get("Retry-After"))
+
+	res = do(create, http.MethodPost, "/api/shorten", "192.168.1.2", nil)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	// users with API key are limited by user ID from any IP.
+	key := &entity.Identity{UserID: "user1", KeyID: "key1"}
+	res = do(create, http.MethodPost, "/api/shorten", "192.168.1.1", key)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	res = do(create, http.MethodPost, "/api/shorten", "192.168.1.3", key)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	res = do(create, http.MethodPost, "/api/shorten", "192.168.1.4", key)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+
+	// group without limit isn't limited.
+	redirect := limits.Limit(RateGroupRedirect)(ok)
+	for i := 0; i < 5; i++ {
+		res = do(redirect, http.MethodGet, "/1", "192.168.1.1", nil)
+		res.Body.Close()
+		assert.Equal(t, http.StatusOK, res.StatusCode)
+	}
+
+	// gateway requests share limits of their RPCs.
+	gateway := limits.LimitGateway(ok)
+	res = do(gateway, http.MethodGet, "/v2/links/1", "192.168.1.2", nil)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	res = do(gateway, http.MethodPost, "/v2/shorten", "192.168.1.2", nil)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+	res = do(gateway, http.MethodPost, "/v2/shorten/batch", "192.168.1.2", nil)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusTooManyRequests, res.StatusCode)
+
+	assert.Equal(t, 12, handled)
+}
+
+func TestRateLimitInterceptor(t *testing.T) {
+	interceptor := NewRateLimitInterceptor(NewRateLimiter(fakeLimiter{}, map[string]entity.RateLimit{
+		RateGroupRedirect: {Burst: 1, Period: time.Second},
+	}))
+
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("192.168.1.1"), Port: 5000}})
+	call := func(method string) error {
+		_, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, nil
+		})
+		return err
+	}
+
+	assert.NoError(t, call(pb.Shortener_GetLong_FullMethodName))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(call(pb.Shortener_GetLong_FullMethodName)))
+	assert.NoError(t, call(pb.Shortener_CreateShort_FullMethodName))
+	assert.NoError(t, call(pb.Shortener_Ping_FullMethodName))
+}
@@ -0,0 +1,160 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/bbt-t/lets-go-shortener/internal/adapter/storage"
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+	"github.com/bbt-t/lets-go-shortener/internal/usecase"
+	pb "github.com/bbt-t/lets-go-shortener/pkg/grpc"
+
+	"github.com/go-chi/chi/v5"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// requestVisitor gets visitor of link from redirect request.
+func requestVisitor(r *http.Request) entity.Visitor {
+	return entity.Visitor{
+		UserAgent:      r.UserAgent(),
+		AcceptLanguage: r.Header.Get("Accept-Language"),
+		IP:             clientIP(r),
+	}
+}
+
+// routeOriginal gets url of link for visitor by redirect rules if original url was got.
+func routeOriginal(s *ShortenerHandler, id, original string, err error, visitor entity.Visitor) (string, error) {
+	if err != nil {
+		return original, err
+	}
+	return s.storage.RouteOriginal(id, original, visitor)
+}
+
+// GetRedirectRules gets redirect rules of link of caller.
+func GetRedirectRules(s *ShortenerHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := getUserID(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		rules, err := s.storage.GetRedirectRules(userID, chi.URLParam(r, "id"))
+		if errors.Is(err, storage.ErrNotFound) {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, rules)
+	}
+}
+
+// SetRedirectRules replaces redirect rules of link of caller by JSON array of rules.
+func SetRedirectRules(s *ShortenerHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var rules []entity.RedirectRule
+
+		userID, err := getUserID(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err = json.NewDecoder(r.Body).Decode(&rules); err != nil {
+			http.Error(w, "wrong body", http.StatusBadRequest)
+			return
+		}
+
+		rules, err = s.storage.SetRedirectRules(requestActor(r, userID), chi.URLParam(r, "id"), rules)
+		switch {
+		case errors.Is(err, storage.ErrNotFound):
+			http.Error(w, "not found", http.StatusNotFound)
+		case errors.Is(err, storage.ErrDeleted):
+			http.Error(w, "url is deleted", http.StatusGone)
+		case errors.Is(err, usecase.ErrBlockedURL):
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		case errors.Is(err, usecase.ErrInvalidURL), errors.Is(err, usecase.ErrInvalidRule):
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		default:
+			writeJSON(w, http.StatusOK, rules)
+		}
+	}
+}
+
+// GetRedirectRules gets redirect rules of link of caller.
+func (server *ShortenerServer) GetRedirectRules(ctx context.Context, in *pb.Link) (*pb.RedirectRules, error) {
+	userID, err := authorize(ctx, entity.ScopeRead)
+	if err != nil {
+		return nil, err
+	}
+
+	rules, err := server.service.GetRedirectRules(userID, in.Id)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, statusWithReason(codes.NotFound, "Link not in storage", pb.ReasonNotFound)
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return rulesToProto(in.Id, rules), nil
+}
+
+// SetRedirectRules replaces redirect rules of link of caller, empty rules remove them.
+func (server *ShortenerServer) SetRedirectRules(ctx context.Context, in *pb.RedirectRules) (*pb.RedirectRules, error) {
+	userID, err := authorize(ctx, entity.ScopeWrite)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := make([]entity.RedirectRule, 0, len(in.Rules))
+	for _, rule := range in.Rules {
+		rules = append(rules, entity.RedirectRule{
+			Platform: rule.Platform,
+			Language: rule.Language,
+			Country:  rule.Country,
+			From:     rule.From,
+			To:       rule.To,
+			URL:      rule.Url,
+		})
+	}
+
+	rules, err = server.service.SetRedirectRules(callerActor(ctx, userID), in.Id, rules)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, statusWithReason(codes.NotFound, "Link not in storage", pb.ReasonNotFound)
+	}
+	if errors.Is(err, storage.ErrDeleted) {
+		return nil, statusWithReason(codes.NotFound, "Link is deleted", pb.ReasonDeleted)
+	}
+	if errors.Is(err, usecase.ErrBlockedURL) {
+		return nil, statusWithReason(codes.InvalidArgument, err.Error(), pb.ReasonBlocked)
+	}
+	if errors.Is(err, usecase.ErrInvalidURL) || errors.Is(err, usecase.ErrInvalidRule) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return rulesToProto(in.Id, rules), nil
+}
+
+// rulesToProto converts redirect rules of link to proto message.
+func rulesToProto(id string, rules []entity.RedirectRule) *pb.RedirectRules {
+	result := &pb.RedirectRules{Id: id, Rules: make([]*pb.RedirectRule, 0, len(rules))}
+	for _, rule := range rules {
+		result.Rules = append(result.Rules, &pb.RedirectRule{
+			Platform: rule.Platform,
+			Language: rule.Language,
+			Country:  rule.Country,
+			From:     rule.From,
+			To:       rule.To,
+			Url:      rule.URL,
+		})
+	}
+	return result
+}
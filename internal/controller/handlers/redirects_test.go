@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/bbt-t/lets-go-shortener/internal/config"
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+	pb "github.com/bbt-t/lets-go-shortener/pkg/grpc"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	appStoreURL = "https://apps.apple.com/app/id1"
+	playURL     = "https://play.google.com/store/apps/details?id=app"
+	iPhoneAgent = "Mozilla/5.0 (iPhone; CPU iPhone OS 16_5 like Mac OS X) AppleWebKit/605.1.15 Mobile/15E148"
+)
+
+func TestRedirectRulesAPI(t *testing.T) {
+	cfg := config.GetDefaultConfig()
+	api := newTestAPI(t, cfg)
+	service, h := api.service, api.h
+	api.router.Get("/{id}", RecoverOriginalURL(h))
+	api.router.Post("/{id}", UnlockURL(h))
+	api.router.Get("/api/user/urls/{id}/rules", GetRedirectRules(h))
+	api.router.Put("/api/user/urls/{id}/rules", SetRedirectRules(h))
+
+	userID, err := newUserID()
+	require.NoError(t, err)
+	ids, err := service.CreateShort(entity.Actor{UserID: userID}, "https://example.com")
+	require.NoError(t, err)
+	protected, err := service.CreateShortWithOptions(entity.Actor{UserID: userID}, "https://example.com/secret", entity.LinkOptions{Password: "open sesame"})
+	require.NoError(t, err)
+	asOwner := withCookie(&http.Cookie{Name: "userID", Value: userID})
+
+	rules := `[{"platform":"ios","url":"` + appStoreURL + `"},{"platform":"android","url":"` + playURL + `"}]`
+	res := api.do(http.MethodPut, "/api/user/urls/"+ids[0]+"/rules", `[{"platform":"symbian","url":"https://example.com"}]`, asOwner)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	res = api.do(http.MethodPut, "/api/user/urls/100/rules", rules, asOwner)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+
+	res = api.do(http.MethodPut, "/api/user/urls/"+ids[0]+"/rules", rules, asOwner)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	res = api.do(http.MethodPut, "/api/user/urls/"+protected+"/rules", rules, asOwner)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+
+	res = api.do(http.MethodGet, "/api/user/urls/"+ids[0]+"/rules", "", asOwner)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	var got []entity.RedirectRule
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&got))
+	require.Len(t, got, 2)
+	assert.Equal(t, appStoreURL, got[0].URL)
+
+	for agent, location := range map[string]string{
+		iPhoneAgent: appStoreURL,
+		"Mozilla/5.0 (Linux; Android 13; Pixel 7) Chrome/114.0 Mobile": playURL,
+		"Mozilla/5.0 (Windows NT 10.0; Win64; x64) Chrome/114.0":       "https://example.com",
+	} {
+		res = api.do(http.MethodGet, "/"+ids[0], "", withHeader("User-Agent", agent), asOwner)
+		defer res.Body.Close()
+		assert.Equal(t, http.StatusTemporaryRedirect, res.StatusCode, agent)
+		assert.Equal(t, location, res.Header.Get("Location"), agent)
+	}
+
+	// rules are evaluated after password of protected link.
+	res = api.do(http.MethodPost, "/"+protected, "password=open+sesame", asOwner,
+		withHeader("User-Agent", iPhoneAgent), withHeader("Content-Type", "application/x-www-form-urlencoded"))
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusSeeOther, res.StatusCode)
+	assert.Equal(t, appStoreURL, res.Header.Get("Location"))
+
+	res = api.do(http.MethodPut, "/api/user/urls/"+ids[0]+"/rules", `[]`, asOwner)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	res = api.do(http.MethodGet, "/"+ids[0], "", withHeader("User-Agent", iPhoneAgent), asOwner)
+	defer res.Body.Close()
+	assert.Equal(t, "https://example.com", res.Header.Get("Location"))
+
+	// gRPC API edits rules and GetLong evaluates them for visitor.
+	server := NewShortenerServer(cfg, service)
+	owner := WithIdentity(context.Background(), entity.Identity{UserID: userID, Scopes: entity.AllScopes})
+	_, err = server.SetRedirectRules(owner, &pb.RedirectRules{Id: ids[0], Rules: []*pb.RedirectRule{{Country: "Deutschland", Url: "https://example.de"}}})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	set, err := server.SetRedirectRules(owner, &pb.RedirectRules{Id: ids[0], Rules: []*pb.RedirectRule{
+		{Language: "de", Url: "https://example.de"},
+		{Platform: "IOS", Url: appStoreURL},
+	}})
+	require.NoError(t, err)
+	require.Len(t, set.Rules, 2)
+	assert.Equal(t, entity.PlatformIOS, set.Rules[1].Platform)
+
+	reader := WithIdentity(context.Background(), entity.Identity{UserID: userID, Scopes: []string{entity.ScopeRead}})
+	_, err = server.SetRedirectRules(reader, &pb.RedirectRules{Id: ids[0]})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	list, err := server.GetRedirectRules(reader, &pb.Link{Id: ids[0]})
+	require.NoError(t, err)
+	assert.Equal(t, set.Rules[0].Url, list.Rules[0].Url)
+	_, err = server.GetRedirectRules(reader, &pb.Link{Id: "100"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	for visitor, long := range map[*pb.Visitor]string{
+		nil:                      "https://example.com",
+		{UserAgent: iPhoneAgent}: appStoreURL,
+		{UserAgent: iPhoneAgent, AcceptLanguage: "de-DE"}: "https://example.de",
+	} {
+		link, err := server.GetLong(context.Background(), &pb.Link{Id: ids[0], Visitor: visitor})
+		require.NoError(t, err)
+		assert.Equal(t, long, link.LongUrl, visitor)
+	}
+}
@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+	"github.com/bbt-t/lets-go-shortener/internal/usecase"
+)
+
+// sessionCookie is name of cookie with session token.
+const sessionCookie = "session"
+
+// NewSessionMiddleware authenticates requests with session cookie of logged in user.
+// Requests without valid session are passed to cookie authentication.
+func NewSessionMiddleware(s *ShortenerHandler) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, authorized := IdentityFromContext(r.Context()); authorized {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cookie, err := r.Cookie(sessionCookie)
+			if err != nil {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			identity, err := s.storage.AuthenticateSession(cookie.Value)
+			if errors.Is(err, usecase.ErrInvalidSession) {
+				setSessionCookie(w, r, "", time.Unix(0, 0))
+				next.ServeHTTP(w, r)
+				return
+			}
+			if err != nil {
+				log.Println("Failed authenticate session:", err)
+				http.Error(w, "internal server error", http.StatusInternalServerError)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(WithIdentity(r.Context(), identity)))
+		})
+	}
+}
+
+// setSessionCookie sets session cookie, expired cookie is removed by browser.
+func setSessionCookie(w http.ResponseWriter, r *http.Request, token string, expires time.Time) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    token,
+		Expires:  expires,
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   isHTTPS(r),
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// readCredentials reads login and password from body.
+func readCredentials(r *http.Request) (entity.Credentials, error) {
+	var credentials entity.Credentials
+
+	resBody, err := io.ReadAll(r.Body)
+	defer r.Body.Close()
+	if err != nil || string(resBody) == "" {
+		return credentials, errors.New("wrong body")
+	}
+	err = json.Unmarshal(resBody, &credentials)
+	return credentials, err
+}
+
+// startSession creates session of user and sends user with session cookie.
+func startSession(s *ShortenerHandler, w http.ResponseWriter, r *http.Request, user entity.User, status int) {
+	token, expires, err := s.storage.CreateSession(user)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	data, err := json.Marshal(user)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	setSessionCookie(w, r, token, expires)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(data)
+}
+
+// Register creates account and logs it in.
+func Register(s *ShortenerHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		credentials, err := readCredentials(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		user, err := s.storage.Register(credentials.Login, credentials.Password)
+		if errors.Is(err, usecase.ErrLoginTaken) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
+		if errors.Is(err, usecase.ErrInvalidLogin) || errors.Is(err, usecase.ErrWeakPassword) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		startSession(s, w, r, user, http.StatusCreated)
+	}
+}
+
+// Login checks password and starts session.
+func Login(s *ShortenerHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		credentials, err := readCredentials(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		user, err := s.storage.Login(credentials.Login, credentials.Password)
+		if errors.Is(err, usecase.ErrInvalidCredentials) {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		startSession(s, w, r, user, http.StatusOK)
+	}
+}
+
+// Logout ends session.
+func Logout(s *ShortenerHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if cookie, err := r.Cookie(sessionCookie); err == nil {
+			if err = s.storage.Logout(cookie.Value); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		setSessionCookie(w, r, "", time.Unix(0, 0))
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// ClaimURLs moves urls of anonymous userID cookie to logged in account.
+func ClaimURLs(s *ShortenerHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		identity, ok := IdentityFromContext(r.Context())
+		if !ok || !identity.Account {
+			unauthorized(w)
+			return
+		}
+
+		var claimed int
+
+		if anonymous, err := r.Cookie("userID"); err == nil {
+			if !verifyUserID(anonymous.Value) {
+				http.Error(w, "wrong userID cookie", http.StatusBadRequest)
+				return
+			}
+
+			claimed, err = s.storage.ClaimURLs(requestActor(r, identity.UserID), anonymous.Value)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		data, err := json.Marshal(entity.ClaimResponse{Claimed: claimed})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(data)
+	}
+}
@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/bbt-t/lets-go-shortener/internal/config"
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccounts(t *testing.T) {
+	cfg := config.GetDefaultConfig()
+	api := newTestAPI(t, cfg, NewSessionMiddleware)
+	h := api.h
+	api.router.Post("/api/user/register", Register(h))
+	api.router.Post("/api/user/login", Login(h))
+	api.router.Post("/api/user/logout", Logout(h))
+	api.router.Post("/api/user/claim", ClaimURLs(h))
+	api.router.Post("/api/shorten", RecoverOriginalURLPost(h))
+	api.router.Get("/api/user/urls", RecoverAllURL(h))
+
+	cookie := func(res *http.Response, name string) *http.Cookie {
+		for _, c := range res.Cookies() {
+			if c.Name == name {
+				return c
+			}
+		}
+		return nil
+	}
+
+	// anonymous user shortens url.
+	res := api.do(http.MethodPost, "/api/shorten", `{"url":"https://yandex.ru"}`)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+	anonymous := cookie(res, "userID")
+	require.NotNil(t, anonymous)
+
+	res = api.do(http.MethodPost, "/api/user/register", `{"login":"Alice","password":"short"}`)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+	res = api.do(http.MethodPost, "/api/user/register", `{"login":"ext:alice","password":"correct horse"}`)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode, "logins with ':' are reserved")
+
+	res = api.do(http.MethodPost, "/api/user/register", `{"login":"Alice","password":"correct horse"}`)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+	session := cookie(res, sessionCookie)
+	require.NotNil(t, session)
+	assert.True(t, session.HttpOnly)
+
+	var user entity.User
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&user))
+	assert.Equal(t, "alice", user.Login)
+
+	res = api.do(http.MethodPost, "/api/user/register", `{"login":"alice","password":"other password"}`)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusConflict, res.StatusCode)
+
+	res = api.do(http.MethodPost, "/api/user/claim", "", withCookie(anonymous))
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+
+	res = api.do(http.MethodPost, "/api/user/claim", "", withCookie(session, anonymous))
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	var claim entity.ClaimResponse
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&claim))
+	assert.Equal(t, 1, claim.Claimed)
+
+	res = api.do(http.MethodPost, "/api/user/login", `{"login":"alice","password":"wrong password"}`)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+
+	// links are kept by new session of account.
+	res = api.do(http.MethodPost, "/api/user/login", `{"login":"alice","password":"correct horse"}`)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	second := cookie(res, sessionCookie)
+	require.NotNil(t, second)
+
+	res = api.do(http.MethodGet, "/api/user/urls", "", withCookie(second))
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	var history []entity.URLs
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&history))
+	require.Len(t, history, 1)
+	assert.Equal(t, "https://yandex.ru", history[0].OriginalURL)
+
+	res = api.do(http.MethodPost, "/api/user/logout", "", withCookie(second))
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusNoContent, res.StatusCode)
+
+	// session is ended, request is passed to anonymous cookie.
+	res = api.do(http.MethodGet, "/api/user/urls", "", withCookie(second))
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusNoContent, res.StatusCode)
+	assert.NotNil(t, cookie(res, "userID"))
+}
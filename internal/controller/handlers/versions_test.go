@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/bbt-t/lets-go-shortener/internal/config"
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+	pb "github.com/bbt-t/lets-go-shortener/pkg/grpc"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestUpdateURL(t *testing.T) {
+	cfg := config.GetDefaultConfig()
+	api := newTestAPI(t, cfg)
+	service, h := api.service, api.h
+	api.router.Post("/", RecoverOriginalURLPost(h))
+	api.router.Get("/{id}", RecoverOriginalURL(h))
+	api.router.Patch("/api/user/urls/{id}", UpdateURL(h))
+	api.router.Get("/api/user/urls/{id}/versions", URLVersions(h))
+
+	res := api.do(http.MethodPost, "/", "https://yandex.ru")
+	defer res.Body.Close()
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+	owner := res.Cookies()[0]
+	short, err := io.ReadAll(res.Body)
+	require.NoError(t, err)
+	id := string(short[strings.LastIndex(string(short), "/")+1:])
+
+	res = api.do(http.MethodPatch, "/api/user/urls/"+id, `{"url":"https://go.dev"}`)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusNotFound, res.StatusCode, "other user can't update link")
+
+	res = api.do(http.MethodPatch, "/api/user/urls/"+id, `{"url":"javascript:alert(1)"}`, withCookie(owner))
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+	res = api.do(http.MethodPatch, "/api/user/urls/"+id, `{"url":"HTTPS://Go.dev"}`, withCookie(owner))
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	var updated entity.URLs
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&updated))
+	assert.Equal(t, "https://go.dev", updated.OriginalURL)
+
+	res = api.do(http.MethodGet, "/"+id, "")
+	defer res.Body.Close()
+	assert.Equal(t, "https://go.dev", res.Header.Get("Location"))
+
+	res = api.do(http.MethodGet, "/api/user/urls/"+id+"/versions", "", withCookie(owner))
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	var versions []entity.LinkVersion
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&versions))
+	require.Len(t, versions, 1)
+	assert.Equal(t, "https://yandex.ru", versions[0].URL)
+
+	res = api.do(http.MethodGet, "/api/user/urls/"+id+"/versions", "")
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+
+	server := NewShortenerServer(cfg, service)
+	ctx := WithIdentity(context.Background(), entity.Identity{UserID: owner.Value, Scopes: entity.AllScopes})
+	link, err := server.UpdateLink(ctx, &pb.Link{Id: id, LongUrl: "https://golang.org"})
+	require.NoError(t, err)
+	assert.Equal(t, "https://golang.org", link.LongUrl)
+
+	other := WithIdentity(context.Background(), entity.Identity{UserID: "other", Scopes: entity.AllScopes})
+	_, err = server.UpdateLink(other, &pb.Link{Id: id, LongUrl: "https://evil.com"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	readOnly := WithIdentity(context.Background(), entity.Identity{UserID: owner.Value, Scopes: []string{entity.ScopeRead}})
+	_, err = server.UpdateLink(readOnly, &pb.Link{Id: id, LongUrl: "https://evil.com"})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
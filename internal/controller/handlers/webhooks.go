@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/bbt-t/lets-go-shortener/internal/adapter/storage"
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+	"github.com/bbt-t/lets-go-shortener/internal/usecase"
+	pb "github.com/bbt-t/lets-go-shortener/pkg/grpc"
+
+	"github.com/go-chi/chi/v5"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// CreateWebhook creates webhook for caller.
+func CreateWebhook(s *ShortenerHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req entity.WebhookRequest
+
+		userID, err := getUserID(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		resBody, err := io.ReadAll(r.Body)
+		defer r.Body.Close()
+		if err != nil || string(resBody) == "" {
+			http.Error(w, "wrong body", http.StatusBadRequest)
+			return
+		}
+		if err = json.Unmarshal(resBody, &req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		hook, secret, err := s.storage.CreateWebhook(requestActor(r, userID), req.URL, req.Events)
+		if errors.Is(err, usecase.ErrInvalidURL) || errors.Is(err, usecase.ErrUnknownEvent) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusCreated, entity.WebhookResponse{Webhook: hook, Secret: secret})
+	}
+}
+
+// ListWebhooks gets webhooks of caller.
+func ListWebhooks(s *ShortenerHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := getUserID(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		hooks, err := s.storage.GetWebhooksByUser(userID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if len(hooks) == 0 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		writeJSON(w, http.StatusOK, hooks)
+	}
+}
+
+// DeleteWebhook deletes webhook of caller.
+func DeleteWebhook(s *ShortenerHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := getUserID(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		err = s.storage.DeleteWebhook(requestActor(r, userID), chi.URLParam(r, "id"))
+		if errors.Is(err, storage.ErrNotFound) {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// DeadDeliveries gets dead-letter list of webhooks of caller.
+func DeadDeliveries(s *ShortenerHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := getUserID(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		deliveries, err := s.storage.GetDeadDeliveries(userID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, http.StatusOK, deliveries)
+	}
+}
+
+// RetryDelivery queues dead delivery of caller again.
+func RetryDelivery(s *ShortenerHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := getUserID(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+
+		id, err := strconv.ParseInt(chi.URLParam(r, "id"), 10, 64)
+		if err != nil {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		err = s.storage.RetryDelivery(requestActor(r, userID), id)
+		if errors.Is(err, storage.ErrNotFound) {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+// CreateWebhook creates webhook for caller.
+func (server *ShortenerServer) CreateWebhook(ctx context.Context, in *pb.Webhook) (*pb.Webhook, error) {
+	userID, err := authorize(ctx, entity.ScopeAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	hook, secret, err := server.service.CreateWebhook(callerActor(ctx, userID), in.Url, in.Events)
+	if errors.Is(err, usecase.ErrInvalidURL) || errors.Is(err, usecase.ErrUnknownEvent) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	result := webhookToProto(hook)
+	result.Secret = secret
+	return result, nil
+}
+
+// ListWebhooks gets webhooks of caller.
+func (server *ShortenerServer) ListWebhooks(ctx context.Context, _ *emptypb.Empty) (*pb.WebhookList, error) {
+	userID, err := authorize(ctx, entity.ScopeAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	hooks, err := server.service.GetWebhooksByUser(userID)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	result := &pb.WebhookList{Webhooks: make([]*pb.Webhook, 0, len(hooks))}
+	for _, hook := range hooks {
+		result.Webhooks = append(result.Webhooks, webhookToProto(hook))
+	}
+	return result, nil
+}
+
+// DeleteWebhook deletes webhook of caller.
+func (server *ShortenerServer) DeleteWebhook(ctx context.Context, in *pb.Webhook) (*emptypb.Empty, error) {
+	userID, err := authorize(ctx, entity.ScopeAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	err = server.service.DeleteWebhook(callerActor(ctx, userID), in.Id)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, status.Error(codes.NotFound, "webhook not found")
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// ListDeadDeliveries gets dead-letter list of webhooks of caller.
+func (server *ShortenerServer) ListDeadDeliveries(ctx context.Context, _ *emptypb.Empty) (*pb.WebhookDeliveryList, error) {
+	userID, err := authorize(ctx, entity.ScopeAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	deliveries, err := server.service.GetDeadDeliveries(userID)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	result := &pb.WebhookDeliveryList{Deliveries: make([]*pb.WebhookDelivery, 0, len(deliveries))}
+	for _, delivery := range deliveries {
+		result.Deliveries = append(result.Deliveries, &pb.WebhookDelivery{
+			Id:        delivery.ID,
+			WebhookId: delivery.WebhookID,
+			Event:     delivery.Event,
+			Payload:   string(delivery.Payload),
+			Attempts:  uint32(delivery.Attempts),
+			LastError: delivery.LastError,
+			CreatedAt: timestamppb.New(delivery.CreatedAt),
+		})
+	}
+	return result, nil
+}
+
+// RetryDelivery queues dead delivery of caller again.
+func (server *ShortenerServer) RetryDelivery(ctx context.Context, in *pb.WebhookDelivery) (*emptypb.Empty, error) {
+	userID, err := authorize(ctx, entity.ScopeAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	err = server.service.RetryDelivery(callerActor(ctx, userID), in.Id)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, status.Error(codes.NotFound, "dead delivery not found")
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// webhookToProto converts webhook to proto message.
+func webhookToProto(hook entity.Webhook) *pb.Webhook {
+	return &pb.Webhook{
+		Id:        hook.ID,
+		Url:       hook.URL,
+		Events:    hook.Events,
+		CreatedAt: timestamppb.New(hook.CreatedAt),
+	}
+}
@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bbt-t/lets-go-shortener/internal/config"
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+	"github.com/bbt-t/lets-go-shortener/internal/usecase"
+	pb "github.com/bbt-t/lets-go-shortener/pkg/grpc"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func TestWebhooksAPI(t *testing.T) {
+	events := make(chan string, 10)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		events <- r.Header.Get(usecase.HeaderWebhookEvent)
+	}))
+	defer receiver.Close()
+
+	cfg := config.GetDefaultConfig()
+	api := newTestAPI(t, cfg, NewAPIKeyMiddleware)
+	s, service, h := api.s, api.service, api.h
+	webhooks := usecase.NewWebhookDispatcher(s, receiver.Client())
+	service.SetWebhooks(webhooks)
+	server := NewShortenerServer(cfg, service)
+
+	api.router.With(RequireScope(entity.ScopeWrite)).Post("/api/shorten", RecoverOriginalURLPost(h))
+	api.router.Group(func(r chi.Router) {
+		r.Use(RequireScope(entity.ScopeAdmin))
+		r.Post("/api/user/webhooks", CreateWebhook(h))
+		r.Get("/api/user/webhooks", ListWebhooks(h))
+		r.Delete("/api/user/webhooks/{id}", DeleteWebhook(h))
+		r.Get("/api/user/webhooks/dead-letters", DeadDeliveries(h))
+		r.Post("/api/user/webhooks/dead-letters/{id}/retry", RetryDelivery(h))
+	})
+
+	userID, err := newUserID()
+	require.NoError(t, err)
+	asOwner := withCookie(&http.Cookie{Name: "userID", Value: userID})
+
+	res := api.do(http.MethodGet, "/api/user/webhooks", "", asOwner)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusNoContent, res.StatusCode)
+
+	res = api.do(http.MethodPost, "/api/user/webhooks", `{"url":"`+receiver.URL+`","events":["link.deleted"]}`, asOwner)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+	var created entity.WebhookResponse
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&created))
+	assert.NotEmpty(t, created.Secret)
+	assert.Equal(t, []string{entity.EventLinkDeleted}, created.Events)
+
+	res = api.do(http.MethodPost, "/api/user/webhooks", `{"url":"`+receiver.URL+`","events":["link.moved"]}`, asOwner)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+	res = api.do(http.MethodPost, "/api/user/webhooks", `{"url":"mailto:admin@example.com"}`, asOwner)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, res.StatusCode)
+
+	res = api.do(http.MethodPost, "/api/user/webhooks", `{"url":"`+receiver.URL+`/all"}`, asOwner)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+
+	res = api.do(http.MethodGet, "/api/user/webhooks", "", asOwner)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	var hooks []map[string]interface{}
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&hooks))
+	require.Len(t, hooks, 2)
+	assert.NotContains(t, hooks[0], "secret", "secret is shown only on creation")
+
+	// link created by HTTP API is sent to webhook.
+	res = api.do(http.MethodPost, "/api/shorten", `{"url":"https://example.com"}`, asOwner)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusCreated, res.StatusCode)
+	delivered, err := webhooks.Deliver(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, delivered)
+	select {
+	case event := <-events:
+		assert.Equal(t, entity.EventLinkCreated, event)
+	case <-time.After(time.Second):
+		t.Fatal("event is not received")
+	}
+
+	res = api.do(http.MethodGet, "/api/user/webhooks/dead-letters", "", asOwner)
+	defer res.Body.Close()
+	require.Equal(t, http.StatusOK, res.StatusCode)
+	var dead []entity.WebhookDelivery
+	require.NoError(t, json.NewDecoder(res.Body).Decode(&dead))
+	assert.Empty(t, dead)
+
+	res = api.do(http.MethodPost, "/api/user/webhooks/dead-letters/100/retry", "", asOwner)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+	res = api.do(http.MethodPost, "/api/user/webhooks/dead-letters/first/retry", "", asOwner)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+
+	res = api.do(http.MethodDelete, "/api/user/webhooks/"+created.ID, "", asOwner)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusNoContent, res.StatusCode)
+	res = api.do(http.MethodDelete, "/api/user/webhooks/"+created.ID, "", asOwner)
+	defer res.Body.Close()
+	assert.Equal(t, http.StatusNotFound, res.StatusCode)
+
+	// gRPC API manages webhooks of caller and requires admin scope.
+	owner := WithIdentity(context.Background(), entity.Identity{UserID: "grpc-user", Scopes: entity.AllScopes})
+	hook, err := server.CreateWebhook(owner, &pb.Webhook{Url: receiver.URL, Events: []string{entity.EventLinkClicked}})
+	require.NoError(t, err)
+	assert.NotEmpty(t, hook.Secret)
+	_, err = server.CreateWebhook(owner, &pb.Webhook{Url: "not a url"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	list, err := server.ListWebhooks(owner, &emptypb.Empty{})
+	require.NoError(t, err)
+	require.Len(t, list.Webhooks, 1)
+	assert.Empty(t, list.Webhooks[0].Secret)
+
+	reader := WithIdentity(context.Background(), entity.Identity{UserID: "grpc-user", Scopes: []string{entity.ScopeRead}})
+	_, err = server.ListWebhooks(reader, &emptypb.Empty{})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	deadList, err := server.ListDeadDeliveries(owner, &emptypb.Empty{})
+	require.NoError(t, err)
+	assert.Empty(t, deadList.Deliveries)
+	_, err = server.RetryDelivery(owner, &pb.WebhookDelivery{Id: 1})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = server.DeleteWebhook(owner, &pb.Webhook{Id: hook.Id})
+	require.NoError(t, err)
+	_, err = server.DeleteWebhook(owner, &pb.Webhook{Id: hook.Id})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
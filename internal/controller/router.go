@@ -8,6 +8,7 @@ import (
 
 	"github.com/bbt-t/lets-go-shortener/internal/config"
 	"github.com/bbt-t/lets-go-shortener/internal/controller/handlers"
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
@@ -20,30 +21,103 @@ type server struct {
 }
 
 // newHTTPServer Initializing a new router.
-func newHTTPServer(cfg config.Config, s *handlers.ShortenerHandler, manager *autocert.Manager) *server {
+func newHTTPServer(cfg config.Config, s *handlers.ShortenerHandler, gateway http.Handler, limits *handlers.RateLimiter, manager *autocert.Manager) *server {
+	gateway = limits.LimitGateway(gateway)
+
 	router := chi.NewRouter()
 	router.Use(
 		middleware.RealIP, // <- (!) Only if a reverse proxy is used (e.g. nginx) (!)
 		middleware.Logger,
 		middleware.Recoverer,
+		handlers.NewAPIKeyMiddleware(s),
+		handlers.NewSessionMiddleware(s),
 		handlers.CookieMiddleware,
 		handlers.GzipHandle,
 		handlers.GzipRequest,
 	)
 
 	router.Get("/ping", handlers.Ping(s))
-	router.Get("/{id}", handlers.RecoverOriginalURL(s))
-	router.Get("/api/user/urls", handlers.RecoverAllURL(s))
+	router.With(limits.Limit(handlers.RateGroupRedirect)).Get("/{id}", handlers.RecoverOriginalURL(s))
+	router.With(limits.Limit(handlers.RateGroupRedirect)).Get("/{id}+", handlers.PreviewURL(s))
+	router.With(limits.Limit(handlers.RateGroupRedirect)).Post("/{id}", handlers.UnlockURL(s))
+	router.With(limits.Limit(handlers.RateGroupRedirect)).Get("/{id}/qr", handlers.QRCode(s))
+
+	router.Post("/api/user/register", handlers.Register(s))
+	router.Post("/api/user/login", handlers.Login(s))
+	router.Post("/api/user/logout", handlers.Logout(s))
+	router.Post("/api/user/claim", handlers.ClaimURLs(s))
+
+	if cfg.OIDCIssuer != "" {
+		oidcAuth := handlers.NewOIDCAuth(cfg, s)
+		router.Get("/api/user/oidc/login", oidcAuth.Login())
+		router.Get("/api/user/oidc/callback", oidcAuth.Callback())
+	}
+
+	router.With(
+		limits.Limit(handlers.RateGroupHistory),
+		handlers.RequireScope(entity.ScopeRead),
+	).Get("/api/user/urls", handlers.RecoverAllURL(s))
+	router.With(
+		limits.Limit(handlers.RateGroupHistory),
+		handlers.RequireScope(entity.ScopeRead),
+	).Get("/api/user/urls/{id}/versions", handlers.URLVersions(s))
+	router.With(
+		limits.Limit(handlers.RateGroupHistory),
+		handlers.RequireScope(entity.ScopeRead),
+	).Get("/api/user/urls/{id}/events", handlers.ClickEvents(s))
+	router.With(handlers.RequireScope(entity.ScopeRead)).Get("/api/user/urls/{id}/rules", handlers.GetRedirectRules(s))
+	router.With(handlers.RequireScope(entity.ScopeRead)).Get("/api/user/quota", handlers.GetQuota(s))
+
+	router.With(handlers.RequireScope(entity.ScopeDelete)).Delete("/api/user/urls", handlers.DeleteURL(s))
+	router.With(
+		limits.Limit(handlers.RateGroupCreate),
+		handlers.RequireScope(entity.ScopeWrite),
+	).Patch("/api/user/urls/{id}", handlers.UpdateURL(s))
+	router.With(
+		limits.Limit(handlers.RateGroupCreate),
+		handlers.RequireScope(entity.ScopeWrite),
+	).Put("/api/user/urls/{id}/rules", handlers.SetRedirectRules(s))
+
+	router.Group(func(r chi.Router) {
+		r.Use(limits.Limit(handlers.RateGroupCreate), handlers.RequireScope(entity.ScopeWrite))
+		r.Post("/", handlers.RecoverOriginalURLPost(s))
+		r.Post("/api/shorten", handlers.RecoverOriginalURLPost(s))
+		r.Post("/api/shorten/batch", handlers.URLBatch(s))
+	})
 
-	router.Delete("/api/user/urls", handlers.DeleteURL(s))
+	router.Group(func(r chi.Router) {
+		r.Use(handlers.RequireScope(entity.ScopeAdmin))
+		r.Post("/api/user/keys", handlers.CreateAPIKey(s))
+		r.Get("/api/user/keys", handlers.ListAPIKeys(s))
+		r.Delete("/api/user/keys/{id}", handlers.RevokeAPIKey(s))
+		r.Post("/api/user/webhooks", handlers.CreateWebhook(s))
+		r.Get("/api/user/webhooks", handlers.ListWebhooks(s))
+		r.Delete("/api/user/webhooks/{id}", handlers.DeleteWebhook(s))
+		r.Get("/api/user/webhooks/dead-letters", handlers.DeadDeliveries(s))
+		r.Post("/api/user/webhooks/dead-letters/{id}/retry", handlers.RetryDelivery(s))
+	})
+
+	router.Route("/api/admin", func(r chi.Router) {
+		r.Use(handlers.RequireAdmin(cfg.TrustedSubnet))
+		r.Get("/links", handlers.SearchLinks(s))
+		r.Get("/links/{id}", handlers.GetLinkInfo(s))
+		r.Post("/links/{id}/disable", handlers.DisableLink(s))
+		r.Post("/links/{id}/restore", handlers.RestoreLink(s))
+		r.Put("/links/{id}/owner", handlers.ReassignLink(s))
+		r.Get("/users/{userID}/links", handlers.UserLinks(s))
+		r.Get("/audit", handlers.AuditEvents(s))
+		r.Get("/health", handlers.HealthReport(s))
+	})
+	router.With(handlers.RequireAdmin(cfg.TrustedSubnet)).Handle("/v2/admin/*", gateway)
 
-	router.Post("/", handlers.RecoverOriginalURLPost(s))
-	router.Post("/api/shorten", handlers.RecoverOriginalURLPost(s))
-	router.Post("/api/shorten/batch", handlers.URLBatch(s))
+	// v2 API generated from service.proto.
+	router.Mount("/v2", gateway)
 
 	router.Group(func(r chi.Router) {
-		r.Use(handlers.NewIPPermissionsChecker(cfg.TrustedSubnet))
+		r.Use(limits.Limit(handlers.RateGroupInternal), handlers.NewIPPermissionsChecker(cfg.TrustedSubnet))
 		r.Get("/api/internal/stats", handlers.StatisticHandler(s))
+		r.Post("/api/internal/cookie-keys/rotate", handlers.RotateCookieKey(s))
+		r.Handle("/v2/internal/*", gateway)
 	})
 
 	return &server{
@@ -78,6 +152,6 @@ type HTTPServer interface {
 }
 
 // NewRouter - make router.
-func NewRouter(cfg config.Config, s *handlers.ShortenerHandler, manager *autocert.Manager) HTTPServer {
-	return newHTTPServer(cfg, s, manager)
+func NewRouter(cfg config.Config, s *handlers.ShortenerHandler, gateway http.Handler, limits *handlers.RateLimiter, manager *autocert.Manager) HTTPServer {
+	return newHTTPServer(cfg, s, gateway, limits, manager)
 }
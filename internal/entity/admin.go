@@ -0,0 +1,25 @@
+package entity
+
+// LinkInfo struct for link with owner and state, used by admin API.
+type LinkInfo struct {
+	ID          string `json:"id"`
+	ShortURL    string `json:"short_url"`
+	OriginalURL string `json:"original_url"`
+	UserID      string `json:"user_id"`
+	Deleted     bool   `json:"deleted"`
+	Disabled    bool   `json:"disabled"`
+}
+
+// LinkFilter struct for admin search of links. Empty fields match all links.
+type LinkFilter struct {
+	Domain string
+	UserID string
+	Query  string
+	Limit  int
+	Offset int
+}
+
+// ReassignRequest struct for request to change owner of link.
+type ReassignRequest struct {
+	UserID string `json:"user_id"`
+}
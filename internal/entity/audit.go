@@ -0,0 +1,35 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Actor struct for caller of audited action. UserID is empty for callers from trusted subnet.
+type Actor struct {
+	UserID string `json:"user_id,omitempty"`
+	IP     string `json:"ip,omitempty"`
+}
+
+// AuditEvent struct for record of audit log. Before and after keep state of target as JSON.
+type AuditEvent struct {
+	ID   int64     `json:"id"`
+	Time time.Time `json:"time"`
+	Actor
+	Action string          `json:"action"`
+	Target string          `json:"target,omitempty"`
+	Before json.RawMessage `json:"before,omitempty"`
+	After  json.RawMessage `json:"after,omitempty"`
+}
+
+// AuditFilter struct for query of audit log. Empty fields match all events.
+type AuditFilter struct {
+	UserID string
+	IP     string
+	Action string
+	Target string
+	Since  time.Time
+	Until  time.Time
+	Limit  int
+	Offset int
+}
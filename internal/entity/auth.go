@@ -0,0 +1,94 @@
+package entity
+
+import "time"
+
+// Scopes of API keys.
+const (
+	ScopeRead   = "read"
+	ScopeWrite  = "write"
+	ScopeDelete = "delete"
+	ScopeAdmin  = "admin"
+)
+
+// AllScopes are all known scopes. Owner of cookie has all of them.
+var AllScopes = []string{ScopeRead, ScopeWrite, ScopeDelete, ScopeAdmin}
+
+// ScopeModerate allows admin API. It's given only to users with admin role.
+const ScopeModerate = "moderate"
+
+// RoleAdmin is role of users who moderate links.
+const RoleAdmin = "admin"
+
+// APIKey struct for server-to-server authentication. Only hash of secret is stored.
+type APIKey struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"-"`
+	Name      string    `json:"name,omitempty"`
+	Scopes    []string  `json:"scopes"`
+	Hash      string    `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+	Revoked   bool      `json:"revoked"`
+}
+
+// Identity struct for authenticated caller.
+type Identity struct {
+	UserID  string
+	Scopes  []string
+	KeyID   string
+	Account bool
+}
+
+// HasScope checks if identity has scope.
+func (i Identity) HasScope(scope string) bool {
+	for _, s := range i.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// APIKeyRequest struct for API key creation request.
+type APIKeyRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+// APIKeyResponse struct for created API key, plain key is shown only once.
+type APIKeyResponse struct {
+	APIKey
+	Key string `json:"key"`
+}
+
+// User struct for registered account. Only bcrypt hash of password is stored.
+type User struct {
+	ID           string    `json:"id"`
+	Login        string    `json:"login"`
+	PasswordHash string    `json:"-"`
+	CreatedAt    time.Time `json:"created_at"`
+	Role         string    `json:"role,omitempty"`
+}
+
+// Session struct for logged in user. Only hash of token is stored.
+type Session struct {
+	Hash      string    `json:"hash"`
+	UserID    string    `json:"user_id"`
+	ExpiresAt time.Time `json:"expires_at"`
+	Role      string    `json:"role,omitempty"`
+}
+
+// Credentials struct for registration and login request.
+type Credentials struct {
+	Login    string `json:"login"`
+	Password string `json:"password"`
+}
+
+// ClaimResponse struct for claimed anonymous links.
+type ClaimResponse struct {
+	Claimed int `json:"claimed"`
+}
+
+// KeyRotationResponse struct for rotated cookie signing key.
+type KeyRotationResponse struct {
+	Active string `json:"active"`
+}
@@ -0,0 +1,10 @@
+package entity
+
+import "time"
+
+// ClickEvent struct for click of link pushed to watchers. Clicks counts clicks of link since its creation.
+type ClickEvent struct {
+	LinkID string    `json:"link_id"`
+	Time   time.Time `json:"time"`
+	Clicks int       `json:"clicks"`
+}
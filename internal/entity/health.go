@@ -0,0 +1,41 @@
+package entity
+
+import "time"
+
+// LinkHealth struct for result of last check of original url.
+// Status is zero if request failed, Error tells why. Failures counts failed checks in a row.
+type LinkHealth struct {
+	Status      int       `json:"status"`
+	LatencyMS   int64     `json:"latency_ms"`
+	Error       string    `json:"error,omitempty"`
+	Failures    int       `json:"failures,omitempty"`
+	CheckedAt   time.Time `json:"checked_at"`
+	NextCheckAt time.Time `json:"next_check_at"`
+}
+
+// Failed checks if original url didn't respond or responded with client or server error.
+func (h LinkHealth) Failed() bool {
+	return h.Error != "" || h.Status >= 400
+}
+
+// HealthTarget struct for link which is due to check. Health is zero if link was never checked.
+type HealthTarget struct {
+	ID     string
+	URL    string
+	Health LinkHealth
+}
+
+// LinkHealthInfo struct for link with health, used by admin report.
+type LinkHealthInfo struct {
+	LinkInfo
+	Health LinkHealth `json:"health"`
+}
+
+// HealthFilter struct for admin report of checked links. Failing matches only links whose last check failed,
+// domain matches host of original url and its subdomains.
+type HealthFilter struct {
+	Failing bool
+	Domain  string
+	Limit   int
+	Offset  int
+}
@@ -0,0 +1,12 @@
+package entity
+
+import "time"
+
+// QuotaUsage struct for links of user counted against quotas. Zero max is unlimited.
+type QuotaUsage struct {
+	ActiveLinks    int       `json:"active_links"`
+	MaxActiveLinks int       `json:"max_active_links,omitempty"`
+	LinksToday     int       `json:"links_today"`
+	MaxLinksPerDay int       `json:"max_links_per_day,omitempty"`
+	ResetsAt       time.Time `json:"resets_at"`
+}
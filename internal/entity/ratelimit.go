@@ -0,0 +1,14 @@
+package entity
+
+import "time"
+
+// RateLimit struct for token bucket: Burst requests are allowed per Period, tokens are refilled evenly.
+type RateLimit struct {
+	Burst  int
+	Period time.Duration
+}
+
+// PerSecond gets refill rate of bucket in tokens per second.
+func (l RateLimit) PerSecond() float64 {
+	return float64(l.Burst) / l.Period.Seconds()
+}
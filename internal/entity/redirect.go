@@ -0,0 +1,36 @@
+package entity
+
+import "time"
+
+// Platforms of visitors matched by redirect rules.
+const (
+	PlatformIOS     = "ios"
+	PlatformAndroid = "android"
+	PlatformWindows = "windows"
+	PlatformMacOS   = "macos"
+	PlatformLinux   = "linux"
+)
+
+// AllPlatforms are platforms known to redirect rules.
+var AllPlatforms = []string{PlatformIOS, PlatformAndroid, PlatformWindows, PlatformMacOS, PlatformLinux}
+
+// RedirectRule struct for conditional redirect of link. Empty conditions match all visitors.
+// Language matches preferred language of visitor by prefix, e.g. "pt" matches "pt-BR".
+// Country is ISO 3166 code, From and To are "15:04" time window in UTC, it may pass midnight.
+type RedirectRule struct {
+	Platform string `json:"platform,omitempty"`
+	Language string `json:"language,omitempty"`
+	Country  string `json:"country,omitempty"`
+	From     string `json:"from,omitempty"`
+	To       string `json:"to,omitempty"`
+	URL      string `json:"url"`
+}
+
+// Visitor struct for request of redirect matched by redirect rules. Country is looked up by IP if it's empty.
+type Visitor struct {
+	UserAgent      string
+	AcceptLanguage string
+	IP             string
+	Country        string
+	Time           time.Time
+}
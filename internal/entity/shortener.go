@@ -2,10 +2,19 @@
 
 package entity
 
+import "time"
+
 // URLs struct for history response.
 type URLs struct {
-	ShortURL    string `json:"short_url"`
-	OriginalURL string `json:"original_url"`
+	ShortURL     string      `json:"short_url"`
+	OriginalURL  string      `json:"original_url"`
+	Title        string      `json:"title,omitempty"`
+	Tags         []string    `json:"tags,omitempty"`
+	Note         string      `json:"note,omitempty"`
+	Interstitial bool        `json:"interstitial,omitempty"`
+	Clicks       int         `json:"clicks"`
+	CreatedAt    time.Time   `json:"created_at"`
+	Health       *LinkHealth `json:"health,omitempty"`
 }
 
 // URLBatch struct for batch request.
@@ -15,9 +24,102 @@ type URLBatch struct {
 	ShortURL      string `json:"short_url,omitempty"`
 }
 
-// ReqJSON struct for single application/json request.
+// ReqJSON struct for single application/json request. Interstitial link shows preview page before redirect.
 type ReqJSON struct {
-	URL string `json:"url"`
+	URL          string   `json:"url"`
+	Password     string   `json:"password,omitempty"`
+	MaxClicks    int      `json:"max_clicks,omitempty"`
+	Title        string   `json:"title,omitempty"`
+	Tags         []string `json:"tags,omitempty"`
+	Note         string   `json:"note,omitempty"`
+	Interstitial bool     `json:"interstitial,omitempty"`
+}
+
+// LinkOptions struct for options of new link. Zero MaxClicks is unlimited.
+type LinkOptions struct {
+	Password  string
+	MaxClicks int
+	Meta      LinkMeta
+}
+
+// QR code formats.
+const (
+	QRFormatPNG = "png"
+	QRFormatSVG = "svg"
+)
+
+// QROptions struct for options of QR code of link. Zero values are defaults of service.
+// Level is error-correction level L, M, Q or H. Logo embeds logo of service in the middle of code.
+type QROptions struct {
+	Size   int
+	Format string
+	Level  string
+	Logo   bool
+}
+
+// LinkMeta struct for user-defined title, tags and note of link.
+// Interstitial link shows preview page before redirect.
+type LinkMeta struct {
+	Title        string   `json:"title,omitempty"`
+	Tags         []string `json:"tags,omitempty"`
+	Note         string   `json:"note,omitempty"`
+	Interstitial bool     `json:"interstitial,omitempty"`
+}
+
+// LinkPreview struct for preview page of link. Title is title of destination page if it was fetched.
+type LinkPreview struct {
+	ID          string
+	ShortURL    string
+	OriginalURL string
+	Domain      string
+	Title       string
+}
+
+// LinkPatch struct for PATCH request of link. Empty URL and nil fields are unchanged.
+type LinkPatch struct {
+	URL          string    `json:"url,omitempty"`
+	Title        *string   `json:"title,omitempty"`
+	Tags         *[]string `json:"tags,omitempty"`
+	Note         *string   `json:"note,omitempty"`
+	Interstitial *bool     `json:"interstitial,omitempty"`
+}
+
+// Sort orders of history.
+const (
+	SortCreated = "created_at"
+	SortClicks  = "clicks"
+	SortURL     = "url"
+)
+
+// HistoryFilter struct for filter and page of user history. Query is searched in title and original url,
+// empty fields match all links. Page has Limit links after link with key After in Sort order, zero Limit is unlimited.
+type HistoryFilter struct {
+	Tag   string
+	Query string
+	Sort  string
+	Desc  bool
+	Limit int
+	After *HistoryKey
+}
+
+// HistoryKey struct for position of link in sorted history. Links with equal sort value are ordered by ID.
+type HistoryKey struct {
+	ID        string    `json:"id"`
+	CreatedAt time.Time `json:"created_at,omitempty"`
+	Clicks    int       `json:"clicks,omitempty"`
+	URL       string    `json:"url,omitempty"`
+}
+
+// HistoryPage struct for page of user history. NextCursor is empty on last page.
+type HistoryPage struct {
+	Links      []URLs
+	NextCursor string
+}
+
+// LinkVersion struct for past original url of link.
+type LinkVersion struct {
+	URL        string    `json:"url"`
+	ReplacedAt time.Time `json:"replaced_at"`
 }
 
 // RespJSON struct for single application/json response.
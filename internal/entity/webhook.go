@@ -0,0 +1,79 @@
+package entity
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Events of links sent to webhooks.
+const (
+	EventLinkCreated = "link.created"
+	EventLinkDeleted = "link.deleted"
+	EventLinkClicked = "link.clicked"
+	EventLinkExpired = "link.expired"
+)
+
+// AllEvents are all events of links. Webhook without events receives all of them.
+var AllEvents = []string{EventLinkCreated, EventLinkDeleted, EventLinkClicked, EventLinkExpired}
+
+// Webhook struct for endpoint of user which receives events of his links.
+// Secret signs payloads, it's shown only on creation.
+type Webhook struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"-"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"-"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Receives checks if webhook receives event.
+func (w Webhook) Receives(event string) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, e := range w.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookRequest struct for webhook creation request. Empty events are all events.
+type WebhookRequest struct {
+	URL    string   `json:"url"`
+	Events []string `json:"events"`
+}
+
+// WebhookResponse struct for created webhook, secret is shown only once.
+type WebhookResponse struct {
+	Webhook
+	Secret string `json:"secret"`
+}
+
+// WebhookEvent struct for payload of webhook. Clicks is count of clicks aggregated by clicked event.
+type WebhookEvent struct {
+	ID          string    `json:"id"`
+	Type        string    `json:"type"`
+	Time        time.Time `json:"time"`
+	LinkID      string    `json:"link_id"`
+	ShortURL    string    `json:"short_url"`
+	OriginalURL string    `json:"original_url"`
+	Clicks      int       `json:"clicks,omitempty"`
+}
+
+// WebhookDelivery struct for queued delivery of event to webhook.
+// Failed delivery is retried at NextAttemptAt, dead delivery isn't retried and stays in dead-letter list.
+type WebhookDelivery struct {
+	ID            int64           `json:"id"`
+	WebhookID     string          `json:"webhook_id"`
+	UserID        string          `json:"-"`
+	Event         string          `json:"event"`
+	Payload       json.RawMessage `json:"payload"`
+	Attempts      int             `json:"attempts"`
+	LastError     string          `json:"last_error,omitempty"`
+	CreatedAt     time.Time       `json:"created_at"`
+	NextAttemptAt time.Time       `json:"next_attempt_at"`
+	Dead          bool            `json:"dead,omitempty"`
+}
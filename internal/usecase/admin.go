@@ -0,0 +1,87 @@
+package usecase
+
+import (
+	"errors"
+
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+)
+
+// Limits of admin search page.
+const (
+	defaultSearchLimit = 100
+	maxSearchLimit     = 1000
+)
+
+// ErrEmptyOwner is returned if link is reassigned to nobody.
+var ErrEmptyOwner = errors.New("empty owner")
+
+// SearchURLs gets links matching filter for admin.
+func (s ShortenerService) SearchURLs(actor entity.Actor, filter entity.LinkFilter) ([]entity.LinkInfo, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultSearchLimit
+	}
+	if filter.Limit > maxSearchLimit {
+		filter.Limit = maxSearchLimit
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	links, err := s.storage.SearchURLs(filter)
+	if err != nil {
+		return nil, err
+	}
+	s.audit(actor, "link.search", "", filter, len(links))
+	return links, nil
+}
+
+// GetURLInfo gets link with owner and state for admin.
+func (s ShortenerService) GetURLInfo(actor entity.Actor, id string) (entity.LinkInfo, error) {
+	link, err := s.storage.GetURLInfo(id)
+	if err != nil {
+		return link, err
+	}
+	s.audit(actor, "link.view", id, nil, nil)
+	return link, nil
+}
+
+// DisableURL disables link for all users.
+func (s ShortenerService) DisableURL(actor entity.Actor, id string) (entity.LinkInfo, error) {
+	return s.changeURL(actor, "link.disable", id, func() error {
+		return s.storage.SetDisabled(id, true)
+	})
+}
+
+// RestoreURL restores link disabled by admin.
+func (s ShortenerService) RestoreURL(actor entity.Actor, id string) (entity.LinkInfo, error) {
+	return s.changeURL(actor, "link.restore", id, func() error {
+		return s.storage.SetDisabled(id, false)
+	})
+}
+
+// ReassignURL changes owner of link.
+func (s ShortenerService) ReassignURL(actor entity.Actor, id, userID string) (entity.LinkInfo, error) {
+	if userID == "" {
+		return entity.LinkInfo{}, ErrEmptyOwner
+	}
+	return s.changeURL(actor, "link.reassign", id, func() error {
+		return s.storage.ReassignURL(id, userID)
+	})
+}
+
+// changeURL runs change of link and writes link before and after it to audit log.
+func (s ShortenerService) changeURL(actor entity.Actor, action, id string, change func() error) (entity.LinkInfo, error) {
+	before, err := s.storage.GetURLInfo(id)
+	if err != nil {
+		return before, err
+	}
+	if err = change(); err != nil {
+		return before, err
+	}
+	after, err := s.storage.GetURLInfo(id)
+	if err != nil {
+		return after, err
+	}
+	s.audit(actor, action, id, before, after)
+	return after, nil
+}
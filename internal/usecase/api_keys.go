@@ -0,0 +1,120 @@
+package usecase
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bbt-t/lets-go-shortener/internal/adapter/storage"
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+)
+
+// apiKeyPrefix is prefix of API keys, key looks like "sk_<id>.<secret>".
+const apiKeyPrefix = "sk_"
+
+// Errors of API keys.
+var (
+	ErrInvalidAPIKey = errors.New("invalid api key")
+	ErrUnknownScope  = errors.New("unknown scope")
+)
+
+// CreateAPIKey creates API key of actor. Plain key is returned only here, storage keeps its hash.
+func (s ShortenerService) CreateAPIKey(actor entity.Actor, name string, scopes []string) (entity.APIKey, string, error) {
+	if len(scopes) == 0 {
+		return entity.APIKey{}, "", fmt.Errorf("%w: no scopes", ErrUnknownScope)
+	}
+	for _, scope := range scopes {
+		if !(entity.Identity{Scopes: entity.AllScopes}).HasScope(scope) {
+			return entity.APIKey{}, "", fmt.Errorf("%w: %s", ErrUnknownScope, scope)
+		}
+	}
+
+	id, err := randomHex(8)
+	if err != nil {
+		return entity.APIKey{}, "", err
+	}
+	secret, err := randomHex(24)
+	if err != nil {
+		return entity.APIKey{}, "", err
+	}
+
+	key := entity.APIKey{
+		ID:        id,
+		UserID:    actor.UserID,
+		Name:      name,
+		Scopes:    scopes,
+		Hash:      hashSecret(secret),
+		CreatedAt: time.Now().UTC(),
+	}
+	if err = s.storage.AddAPIKey(key); err != nil {
+		return entity.APIKey{}, "", err
+	}
+	s.audit(actor, "key.create", id, nil, key)
+	return key, apiKeyPrefix + id + "." + secret, nil
+}
+
+// GetAPIKeysByUser gets API keys of user.
+func (s ShortenerService) GetAPIKeysByUser(userID string) ([]entity.APIKey, error) {
+	return s.storage.GetAPIKeysByUser(userID)
+}
+
+// RevokeAPIKey revokes API key of actor.
+func (s ShortenerService) RevokeAPIKey(actor entity.Actor, id string) error {
+	before, err := s.storage.GetAPIKey(id)
+	if err != nil {
+		return err
+	}
+	if err = s.storage.RevokeAPIKey(actor.UserID, id); err != nil {
+		return err
+	}
+	after := before
+	after.Revoked = true
+	s.audit(actor, "key.revoke", id, before, after)
+	return nil
+}
+
+// AuthenticateAPIKey gets identity of API key owner.
+func (s ShortenerService) AuthenticateAPIKey(plain string) (entity.Identity, error) {
+	id, secret, ok := strings.Cut(strings.TrimPrefix(plain, apiKeyPrefix), ".")
+	if !ok || !strings.HasPrefix(plain, apiKeyPrefix) {
+		return entity.Identity{}, ErrInvalidAPIKey
+	}
+
+	key, err := s.storage.GetAPIKey(id)
+	if errors.Is(err, storage.ErrNotFound) {
+		return entity.Identity{}, ErrInvalidAPIKey
+	}
+	if err != nil {
+		return entity.Identity{}, err
+	}
+
+	if key.Revoked || subtle.ConstantTimeCompare([]byte(key.Hash), []byte(hashSecret(secret))) != 1 {
+		return entity.Identity{}, ErrInvalidAPIKey
+	}
+
+	return entity.Identity{
+		UserID: key.UserID,
+		Scopes: key.Scopes,
+		KeyID:  key.ID,
+	}, nil
+}
+
+// hashSecret gets hash of API key secret.
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// randomHex gets hex string of random bytes.
+func randomHex(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,76 @@
+package usecase
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+)
+
+// AuditLog is an interface that describes storage of audit events.
+type AuditLog interface {
+	AddAuditEvent(event entity.AuditEvent) error
+	GetAuditEvents(filter entity.AuditFilter) ([]entity.AuditEvent, error)
+}
+
+// SetAuditLog sets storage of audit events. Without it events are only written to log.
+func (s *ShortenerService) SetAuditLog(auditLog AuditLog) {
+	s.auditLog = auditLog
+}
+
+// marshalState gets JSON of state, nil state stays empty.
+func marshalState(state interface{}) json.RawMessage {
+	if state == nil {
+		return nil
+	}
+	data, err := json.Marshal(state)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// audit records action of actor on target with its state before and after.
+// Failed record doesn't fail action, it's written to log.
+func (s ShortenerService) audit(actor entity.Actor, action, target string, before, after interface{}) {
+	event := entity.AuditEvent{
+		Time:   time.Now().UTC(),
+		Actor:  actor,
+		Action: action,
+		Target: target,
+		Before: marshalState(before),
+		After:  marshalState(after),
+	}
+
+	if s.auditLog == nil {
+		log.Printf("audit: user=%q ip=%q action=%s target=%q before=%s after=%s",
+			actor.UserID, actor.IP, action, target, event.Before, event.After)
+		return
+	}
+	if err := s.auditLog.AddAuditEvent(event); err != nil {
+		log.Printf("audit: failed to record %s of %q by %q: %v", action, target, actor.UserID, err)
+	}
+}
+
+// GetAuditEvents gets audit events matching filter for admin, newest first.
+func (s ShortenerService) GetAuditEvents(filter entity.AuditFilter) ([]entity.AuditEvent, error) {
+	if s.auditLog == nil {
+		return []entity.AuditEvent{}, nil
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = defaultSearchLimit
+	}
+	if filter.Limit > maxSearchLimit {
+		filter.Limit = maxSearchLimit
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+	return s.auditLog.GetAuditEvents(filter)
+}
+
+// RecordCookieKeyRotation records rotation of cookie signing key.
+func (s ShortenerService) RecordCookieKeyRotation(actor entity.Actor, before, after string) {
+	s.audit(actor, "cookie_key.rotate", "", entity.KeyRotationResponse{Active: before}, entity.KeyRotationResponse{Active: after})
+}
@@ -0,0 +1,164 @@
+package usecase
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+)
+
+// clickBuffer is count of clicks kept for watcher which doesn't read them yet.
+const clickBuffer = 64
+
+var (
+	ErrSlowWatcher   = errors.New("watcher doesn't read clicks in time, it's dropped")
+	ErrClicksStopped = errors.New("click streams are stopped")
+)
+
+// ClickHub publishes clicks of links to their watchers in process. Every watcher has its own buffer,
+// watcher whose buffer is full is dropped with ErrSlowWatcher, so slow watcher never blocks redirects.
+type ClickHub struct {
+	watchers map[string]map[*ClickWatcher]struct{}
+	stopped  bool
+	mu       sync.Mutex
+}
+
+// ClickWatcher receives clicks of one link until it's closed or dropped.
+type ClickWatcher struct {
+	hub    *ClickHub
+	linkID string
+	clicks chan entity.ClickEvent
+	err    error
+}
+
+// NewClickHub creates hub of click streams.
+func NewClickHub() *ClickHub {
+	return &ClickHub{watchers: make(map[string]map[*ClickWatcher]struct{})}
+}
+
+// Watch subscribes to clicks of link, buffer is count of clicks kept for watcher, clickBuffer if it's not positive.
+func (h *ClickHub) Watch(linkID string, buffer int) *ClickWatcher {
+	if buffer <= 0 {
+		buffer = clickBuffer
+	}
+	w := &ClickWatcher{hub: h, linkID: linkID, clicks: make(chan entity.ClickEvent, buffer)}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.stopped {
+		w.err = ErrClicksStopped
+		close(w.clicks)
+		return w
+	}
+	if h.watchers[linkID] == nil {
+		h.watchers[linkID] = make(map[*ClickWatcher]struct{})
+	}
+	h.watchers[linkID][w] = struct{}{}
+	return w
+}
+
+// Watched checks if link has watchers.
+func (h *ClickHub) Watched(linkID string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return len(h.watchers[linkID]) != 0
+}
+
+// Publish sends click to watchers of its link without waiting for them.
+func (h *ClickHub) Publish(event entity.ClickEvent) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for w := range h.watchers[event.LinkID] {
+		select {
+		case w.clicks <- event:
+		default:
+			h.drop(w, ErrSlowWatcher)
+		}
+	}
+}
+
+// Stop drops all watchers with ErrClicksStopped, so streams end before shutdown.
+func (h *ClickHub) Stop() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.stopped = true
+	for _, watchers := range h.watchers {
+		for w := range watchers {
+			h.drop(w, ErrClicksStopped)
+		}
+	}
+}
+
+// drop unsubscribes watcher and closes its clicks with err, hub must be locked.
+func (h *ClickHub) drop(w *ClickWatcher, err error) {
+	watchers := h.watchers[w.linkID]
+	if _, ok := watchers[w]; !ok {
+		return
+	}
+	delete(watchers, w)
+	if len(watchers) == 0 {
+		delete(h.watchers, w.linkID)
+	}
+	w.err = err
+	close(w.clicks)
+}
+
+// Clicks gets clicks of link, channel is closed when watcher is closed or dropped.
+func (w *ClickWatcher) Clicks() <-chan entity.ClickEvent {
+	return w.clicks
+}
+
+// Err gets why watcher was dropped, it's nil while watcher works or if it was closed.
+func (w *ClickWatcher) Err() error {
+	w.hub.mu.Lock()
+	defer w.hub.mu.Unlock()
+
+	return w.err
+}
+
+// Close unsubscribes watcher.
+func (w *ClickWatcher) Close() {
+	w.hub.mu.Lock()
+	defer w.hub.mu.Unlock()
+
+	w.hub.drop(w, nil)
+}
+
+// SetClickHub sets hub of click streams. Without it clicks can't be watched.
+func (s *ShortenerService) SetClickHub(hub *ClickHub) {
+	s.clickHub = hub
+}
+
+// WatchClicks subscribes user to clicks of their link.
+func (s ShortenerService) WatchClicks(userID, id string) (*ClickWatcher, error) {
+	if s.clickHub == nil {
+		return nil, ErrClicksStopped
+	}
+	if _, err := s.storage.GetUserURL(userID, id); err != nil {
+		return nil, err
+	}
+	return s.clickHub.Watch(id, clickBuffer), nil
+}
+
+// click counts click of link for webhooks and sends it to watchers of link.
+func (s ShortenerService) click(id string) {
+	if s.webhooks != nil {
+		s.webhooks.Click(id)
+	}
+	if s.clickHub == nil || !s.clickHub.Watched(id) {
+		return
+	}
+
+	event := entity.ClickEvent{LinkID: id, Time: time.Now().UTC()}
+	if link, err := s.storage.GetURLInfo(id); err == nil {
+		if owned, err := s.storage.GetUserURL(link.UserID, id); err == nil {
+			event.Clicks = owned.Clicks
+		}
+	}
+	s.clickHub.Publish(event)
+}
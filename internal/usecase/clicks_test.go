@@ -0,0 +1,80 @@
+package usecase
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bbt-t/lets-go-shortener/internal/adapter/storage"
+	"github.com/bbt-t/lets-go-shortener/internal/config"
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClickHub(t *testing.T) {
+	hub := NewClickHub()
+	fast := hub.Watch("a", 2)
+	slow := hub.Watch("a", 1)
+	other := hub.Watch("b", 0)
+	assert.True(t, hub.Watched("a"))
+	assert.False(t, hub.Watched("c"))
+
+	hub.Publish(entity.ClickEvent{LinkID: "a", Clicks: 1})
+	assert.Equal(t, 1, (<-fast.Clicks()).Clicks)
+	hub.Publish(entity.ClickEvent{LinkID: "a", Clicks: 2})
+	assert.Equal(t, 2, (<-fast.Clicks()).Clicks)
+	assert.Len(t, other.Clicks(), 0, "clicks of other links aren't received")
+
+	// slow watcher is dropped after its buffer is full, it doesn't hold others.
+	assert.Equal(t, 1, (<-slow.Clicks()).Clicks)
+	_, ok := <-slow.Clicks()
+	assert.False(t, ok)
+	assert.ErrorIs(t, slow.Err(), ErrSlowWatcher)
+	assert.NoError(t, fast.Err())
+
+	fast.Close()
+	fast.Close()
+	_, ok = <-fast.Clicks()
+	assert.False(t, ok)
+	assert.NoError(t, fast.Err())
+	assert.False(t, hub.Watched("a"))
+
+	hub.Stop()
+	_, ok = <-other.Clicks()
+	assert.False(t, ok)
+	assert.ErrorIs(t, other.Err(), ErrClicksStopped)
+	late := hub.Watch("b", 0)
+	_, ok = <-late.Clicks()
+	assert.False(t, ok)
+	assert.ErrorIs(t, late.Err(), ErrClicksStopped)
+}
+
+func TestWatchClicks(t *testing.T) {
+	cfg := config.GetDefaultConfig()
+	s, err := storage.NewMapStorage(cfg)
+	require.NoError(t, err)
+	service := NewShortenerService(cfg, s)
+	actor := entity.Actor{UserID: "user1"}
+
+	ids, err := service.CreateShort(actor, "https://example.com")
+	require.NoError(t, err)
+	_, err = service.WatchClicks("user1", ids[0])
+	assert.ErrorIs(t, err, ErrClicksStopped, "hub isn't set")
+
+	service.SetClickHub(NewClickHub())
+	_, err = service.WatchClicks("user2", ids[0])
+	assert.ErrorIs(t, err, storage.ErrNotFound, "link of other user can't be watched")
+	watcher, err := service.WatchClicks("user1", ids[0])
+	require.NoError(t, err)
+	defer watcher.Close()
+
+	for i := 1; i <= 2; i++ {
+		_, err = service.GetOriginal(ids[0])
+		require.NoError(t, err)
+		event := <-watcher.Clicks()
+		assert.Equal(t, ids[0], event.LinkID)
+		assert.Equal(t, i, event.Clicks)
+		assert.WithinDuration(t, time.Now(), event.Time, time.Second)
+	}
+}
@@ -0,0 +1,156 @@
+package usecase
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/bbt-t/lets-go-shortener/internal/config"
+)
+
+// geoRange is range of IP addresses of country.
+type geoRange struct {
+	first   netip.Addr
+	last    netip.Addr
+	country string
+}
+
+// GeoIP finds countries of IP addresses in local database.
+type GeoIP struct {
+	ranges []geoRange
+}
+
+// LoadGeoIP loads GeoIP database from file of config, it's nil if file isn't set.
+// File is CSV of "network,country" or "first IP,last IP,country" lines like free country databases,
+// header line and lines starting with # are skipped.
+func LoadGeoIP(cfg config.Config) (*GeoIP, error) {
+	if cfg.GeoIPFile == "" {
+		return nil, nil
+	}
+	file, err := os.Open(cfg.GeoIPFile)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	geo, err := parseGeoIP(file)
+	if err != nil {
+		return nil, fmt.Errorf("wrong GeoIP database %q: %w", cfg.GeoIPFile, err)
+	}
+	return geo, nil
+}
+
+// parseGeoIP parses GeoIP database, ranges are sorted by first address.
+func parseGeoIP(r io.Reader) (*GeoIP, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	geo := &GeoIP{}
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		item, err := parseGeoRange(record)
+		if err != nil && line == 1 {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		geo.ranges = append(geo.ranges, item)
+	}
+
+	sort.Slice(geo.ranges, func(i, j int) bool {
+		return geo.ranges[i].first.Less(geo.ranges[j].first)
+	})
+	return geo, nil
+}
+
+// parseGeoRange parses range of country from CSV record.
+func parseGeoRange(record []string) (geoRange, error) {
+	var item geoRange
+
+	switch len(record) {
+	case 2:
+		prefix, err := netip.ParsePrefix(strings.TrimSpace(record[0]))
+		if err != nil {
+			return item, err
+		}
+		prefix = prefix.Masked()
+		item.first, item.last = prefix.Addr().Unmap(), lastAddr(prefix).Unmap()
+	case 3:
+		first, err := netip.ParseAddr(strings.TrimSpace(record[0]))
+		if err != nil {
+			return item, err
+		}
+		last, err := netip.ParseAddr(strings.TrimSpace(record[1]))
+		if err != nil {
+			return item, err
+		}
+		item.first, item.last = first.Unmap(), last.Unmap()
+		if item.last.Less(item.first) || item.first.Is4() != item.last.Is4() {
+			return item, fmt.Errorf("wrong range %s-%s", first, last)
+		}
+	default:
+		return item, fmt.Errorf("2 or 3 fields are expected, got %d", len(record))
+	}
+
+	item.country = strings.ToUpper(strings.TrimSpace(record[len(record)-1]))
+	if !validCountry(item.country) {
+		return item, fmt.Errorf("wrong country %q", item.country)
+	}
+	return item, nil
+}
+
+// lastAddr gets last address of network.
+func lastAddr(prefix netip.Prefix) netip.Addr {
+	addr := prefix.Addr().AsSlice()
+	bits := prefix.Bits()
+	for i := range addr {
+		if bits >= 8 {
+			bits -= 8
+			continue
+		}
+		addr[i] |= 0xff >> bits
+		bits = 0
+	}
+	last, _ := netip.AddrFromSlice(addr)
+	return last
+}
+
+// validCountry checks if country is two letters ISO 3166 code in upper case.
+func validCountry(country string) bool {
+	return len(country) == 2 && country[0] >= 'A' && country[0] <= 'Z' && country[1] >= 'A' && country[1] <= 'Z'
+}
+
+// Country gets ISO 3166 code of country of IP, it's empty if IP isn't found or database isn't loaded.
+func (g *GeoIP) Country(ip string) string {
+	if g == nil {
+		return ""
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return ""
+	}
+	addr = addr.Unmap()
+
+	i := sort.Search(len(g.ranges), func(i int) bool {
+		return addr.Less(g.ranges[i].first)
+	})
+	if i == 0 || g.ranges[i-1].last.Less(addr) {
+		return ""
+	}
+	return g.ranges[i-1].country
+}
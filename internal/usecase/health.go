@@ -0,0 +1,287 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bbt-t/lets-go-shortener/internal/config"
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+)
+
+// Defaults and limits of health checker.
+const (
+	defaultHealthWorkers   = 4
+	defaultHealthHostDelay = time.Second
+	healthBatchSize        = 100
+	healthTick             = time.Minute
+	maxLinkBackoff         = 6
+	maxHostBackoff         = 5 * time.Minute
+	maxHealthBody          = 64 << 10
+	healthUserAgent        = "lets-go-shortener health checker"
+)
+
+// HealthStorage is an interface that describes storage of health of links.
+type HealthStorage interface {
+	GetHealthTargets(now time.Time, limit int) ([]entity.HealthTarget, error)
+	SetURLHealth(id string, health entity.LinkHealth) error
+}
+
+// hostState is state of host of checked urls. Token is held by check of host, next check starts after next.
+type hostState struct {
+	token    chan struct{}
+	next     time.Time
+	failures int
+}
+
+// HealthChecker checks original urls of links in background and records their status and latency.
+// Checks are run by bounded count of workers, one host is checked by one worker at a time
+// with delay between requests. Delay of overloaded or unreachable host and interval of failing link grow twice
+// after every failure.
+type HealthChecker struct {
+	storage   HealthStorage
+	client    *http.Client
+	interval  time.Duration
+	hostDelay time.Duration
+	workers   int
+	hosts     map[string]*hostState
+	mu        sync.Mutex
+}
+
+// NewHealthChecker creates checker of links by config, it's nil if interval of checks isn't set.
+func NewHealthChecker(cfg config.Config, s HealthStorage, client *http.Client) (*HealthChecker, error) {
+	if cfg.HealthInterval == "" {
+		return nil, nil
+	}
+	interval, err := time.ParseDuration(cfg.HealthInterval)
+	if err != nil || interval <= 0 {
+		return nil, fmt.Errorf("wrong health check interval %q", cfg.HealthInterval)
+	}
+	hostDelay := defaultHealthHostDelay
+	if cfg.HealthHostDelay != "" {
+		if hostDelay, err = time.ParseDuration(cfg.HealthHostDelay); err != nil || hostDelay < 0 {
+			return nil, fmt.Errorf("wrong health host delay %q", cfg.HealthHostDelay)
+		}
+	}
+	workers := cfg.HealthWorkers
+	if workers <= 0 {
+		workers = defaultHealthWorkers
+	}
+
+	return &HealthChecker{
+		storage:   s,
+		client:    client,
+		interval:  interval,
+		hostDelay: hostDelay,
+		workers:   workers,
+		hosts:     make(map[string]*hostState),
+	}, nil
+}
+
+// Run checks due links until context is done. Full batch is followed by next one at once.
+func (c *HealthChecker) Run(ctx context.Context) {
+	tick := healthTick
+	if c.interval < tick {
+		tick = c.interval
+	}
+	for {
+		checked, err := c.CheckDue(ctx)
+		if err != nil {
+			log.Printf("health checker: %v", err)
+		}
+		if checked == healthBatchSize && ctx.Err() == nil {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(tick):
+		}
+	}
+}
+
+// CheckDue checks batch of links which are due to check and gets count of checked links.
+// Passes of checker must not run concurrently.
+func (c *HealthChecker) CheckDue(ctx context.Context) (int, error) {
+	targets, err := c.storage.GetHealthTargets(time.Now(), healthBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	c.forgetHosts()
+
+	var (
+		checked int32
+		wg      sync.WaitGroup
+	)
+	slots := make(chan struct{}, c.workers)
+	for _, target := range targets {
+		wg.Add(1)
+		go func(target entity.HealthTarget) {
+			defer wg.Done()
+
+			host, err := c.acquireHost(ctx, hostOf(target.URL))
+			if err != nil {
+				return
+			}
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				c.releaseHost(host, false)
+				return
+			}
+			health, overloaded := c.check(ctx, target)
+			<-slots
+			c.releaseHost(host, overloaded)
+
+			if ctx.Err() != nil {
+				return
+			}
+			if err = c.storage.SetURLHealth(target.ID, health); err != nil {
+				log.Printf("health checker: %s: %v", target.ID, err)
+				return
+			}
+			atomic.AddInt32(&checked, 1)
+		}(target)
+	}
+	wg.Wait()
+	return int(checked), nil
+}
+
+// hostOf gets host of url, urls which can't be parsed share empty host.
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	return strings.ToLower(u.Hostname())
+}
+
+// forgetHosts forgets healthy hosts which can be checked already, they are like new hosts.
+// Failing hosts are kept until their check succeeds, so their delay keeps growing.
+func (c *HealthChecker) forgetHosts() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for name, host := range c.hosts {
+		if len(host.token) == 0 && host.failures == 0 && now.After(host.next) {
+			delete(c.hosts, name)
+		}
+	}
+}
+
+// acquireHost waits until host is free and its delay passes.
+func (c *HealthChecker) acquireHost(ctx context.Context, name string) (*hostState, error) {
+	c.mu.Lock()
+	host, ok := c.hosts[name]
+	if !ok {
+		host = &hostState{token: make(chan struct{}, 1)}
+		c.hosts[name] = host
+	}
+	c.mu.Unlock()
+
+	select {
+	case host.token <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if wait := time.Until(host.next); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			<-host.token
+			return nil, ctx.Err()
+		}
+	}
+	return host, nil
+}
+
+// releaseHost frees host and sets its next check, delay of overloaded host grows twice.
+func (c *HealthChecker) releaseHost(host *hostState, overloaded bool) {
+	delay := c.hostDelay
+	if overloaded {
+		host.failures++
+		for i := 0; i < host.failures && delay < maxHostBackoff; i++ {
+			delay *= 2
+		}
+		if delay > maxHostBackoff {
+			delay = maxHostBackoff
+		}
+	} else {
+		host.failures = 0
+	}
+	host.next = time.Now().Add(delay)
+	<-host.token
+}
+
+// check checks original url by HEAD request, GET is used if server doesn't support HEAD.
+// Host is overloaded if it's unreachable or responds with 429 or server error.
+func (c *HealthChecker) check(ctx context.Context, target entity.HealthTarget) (entity.LinkHealth, bool) {
+	start := time.Now()
+	status, err := c.request(ctx, http.MethodHead, target.URL)
+	if err == nil && (status == http.StatusMethodNotAllowed || status == http.StatusNotImplemented) {
+		status, err = c.request(ctx, http.MethodGet, target.URL)
+	}
+
+	health := entity.LinkHealth{Status: status, LatencyMS: time.Since(start).Milliseconds(), CheckedAt: start.UTC()}
+	if err != nil {
+		health.Error = err.Error()
+	}
+	backoff := 0
+	if health.Failed() {
+		health.Failures = target.Health.Failures + 1
+		backoff = health.Failures
+		if backoff > maxLinkBackoff {
+			backoff = maxLinkBackoff
+		}
+	}
+	health.NextCheckAt = health.CheckedAt.Add(c.interval << backoff)
+	return health, err != nil || status == http.StatusTooManyRequests || status >= 500
+}
+
+// request sends request to url and gets status of response, redirects are followed.
+func (c *HealthChecker) request(ctx context.Context, method, target string) (int, error) {
+	request, err := http.NewRequestWithContext(ctx, method, target, nil)
+	if err != nil {
+		return 0, err
+	}
+	request.Header.Set("User-Agent", healthUserAgent)
+
+	response, err := c.client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+	// Read rest of small body, so connection is reused.
+	io.Copy(io.Discard, io.LimitReader(response.Body, maxHealthBody))
+	return response.StatusCode, nil
+}
+
+// GetHealthReport gets checked links matching filter for admin.
+func (s ShortenerService) GetHealthReport(actor entity.Actor, filter entity.HealthFilter) ([]entity.LinkHealthInfo, error) {
+	if filter.Limit <= 0 {
+		filter.Limit = defaultSearchLimit
+	}
+	if filter.Limit > maxSearchLimit {
+		filter.Limit = maxSearchLimit
+	}
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	links, err := s.storage.GetHealthReport(filter)
+	if err != nil {
+		return nil, err
+	}
+	s.audit(actor, "link.health", "", filter, len(links))
+	return links, nil
+}
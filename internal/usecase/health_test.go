@@ -0,0 +1,191 @@
+package usecase
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bbt-t/lets-go-shortener/internal/config"
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// healthMock is storage of health of links for tests, every target is due.
+type healthMock struct {
+	targets []entity.HealthTarget
+	results map[string]entity.LinkHealth
+	mu      sync.Mutex
+}
+
+func (m *healthMock) GetHealthTargets(time.Time, int) ([]entity.HealthTarget, error) {
+	return m.targets, nil
+}
+
+func (m *healthMock) SetURLHealth(id string, health entity.LinkHealth) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.results[id] = health
+	return nil
+}
+
+func TestNewHealthChecker(t *testing.T) {
+	cfg := config.GetTestConfig()
+	checker, err := NewHealthChecker(cfg, &healthMock{}, http.DefaultClient)
+	require.NoError(t, err)
+	assert.Nil(t, checker, "checks are off by default")
+
+	cfg.HealthInterval = "1h"
+	checker, err = NewHealthChecker(cfg, &healthMock{}, http.DefaultClient)
+	require.NoError(t, err)
+	assert.Equal(t, defaultHealthWorkers, checker.workers)
+	assert.Equal(t, defaultHealthHostDelay, checker.hostDelay)
+
+	for _, tt := range []struct{ interval, delay string }{{"1x", ""}, {"-1h", ""}, {"1h", "soon"}} {
+		cfg.HealthInterval, cfg.HealthHostDelay = tt.interval, tt.delay
+		_, err = NewHealthChecker(cfg, &healthMock{}, http.DefaultClient)
+		assert.Error(t, err, tt)
+	}
+}
+
+func TestHealthChecker_CheckDue(t *testing.T) {
+	var (
+		active, peak int32
+		methods      sync.Map
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := atomic.AddInt32(&active, 1)
+		defer atomic.AddInt32(&active, -1)
+		for {
+			if old := atomic.LoadInt32(&peak); current <= old || atomic.CompareAndSwapInt32(&peak, old, current) {
+				break
+			}
+		}
+		methods.Store(r.URL.Path+" "+r.Method, true)
+		time.Sleep(10 * time.Millisecond)
+
+		switch r.URL.Path {
+		case "/get-only":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		case "/busy":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	cfg := config.GetTestConfig()
+	cfg.HealthInterval = "1h"
+	cfg.HealthHostDelay = "0s"
+	storage := &healthMock{
+		targets: []entity.HealthTarget{
+			{ID: "1", URL: server.URL + "/ok"},
+			{ID: "2", URL: server.URL + "/get-only"},
+			{ID: "3", URL: server.URL + "/missing", Health: entity.LinkHealth{Status: 404, Failures: 2}},
+			{ID: "4", URL: server.URL + "/busy"},
+			{ID: "5", URL: "http://localhost:1/closed"},
+		},
+		results: make(map[string]entity.LinkHealth),
+	}
+	checker, err := NewHealthChecker(cfg, storage, server.Client())
+	require.NoError(t, err)
+
+	start := time.Now()
+	checked, err := checker.CheckDue(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 5, checked)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&peak), "one host is checked by one worker at a time")
+
+	ok := storage.results["1"]
+	assert.Equal(t, http.StatusOK, ok.Status)
+	assert.False(t, ok.Failed())
+	assert.Zero(t, ok.Failures)
+	assert.GreaterOrEqual(t, ok.LatencyMS, int64(10))
+	assert.WithinDuration(t, start, ok.CheckedAt, time.Second)
+	assert.Equal(t, ok.CheckedAt.Add(time.Hour), ok.NextCheckAt)
+	_, headed := methods.Load("/ok HEAD")
+	assert.True(t, headed)
+	_, got := methods.Load("/ok GET")
+	assert.False(t, got, "GET isn't needed if HEAD is supported")
+
+	assert.Equal(t, http.StatusOK, storage.results["2"].Status)
+	_, got = methods.Load("/get-only GET")
+	assert.True(t, got, "GET is used if HEAD isn't allowed")
+
+	missing := storage.results["3"]
+	assert.True(t, missing.Failed())
+	assert.Equal(t, 3, missing.Failures)
+	assert.Equal(t, missing.CheckedAt.Add(8*time.Hour), missing.NextCheckAt, "interval of failing link grows twice")
+
+	assert.Equal(t, http.StatusServiceUnavailable, storage.results["4"].Status)
+	assert.Equal(t, 1, storage.results["4"].Failures)
+
+	closed := storage.results["5"]
+	assert.Zero(t, closed.Status)
+	assert.NotEmpty(t, closed.Error)
+	assert.True(t, closed.Failed())
+
+	// delay of overloaded host grows and is reset by success.
+	checker.hostDelay = time.Second
+	host, err := checker.acquireHost(context.Background(), "example.com")
+	require.NoError(t, err)
+	checker.releaseHost(host, true)
+	host.next = time.Time{}
+	_, err = checker.acquireHost(context.Background(), "example.com")
+	require.NoError(t, err)
+	checker.releaseHost(host, true)
+	assert.Equal(t, 2, host.failures)
+	assert.WithinDuration(t, time.Now().Add(4*time.Second), host.next, 100*time.Millisecond)
+	for i := 0; i < 20; i++ {
+		host.token <- struct{}{}
+		checker.releaseHost(host, true)
+	}
+	assert.WithinDuration(t, time.Now().Add(maxHostBackoff), host.next, 100*time.Millisecond)
+
+	// failing host isn't forgotten between passes.
+	host.next = time.Time{}
+	checker.forgetHosts()
+	assert.Same(t, host, checker.hosts["example.com"])
+
+	host.token <- struct{}{}
+	checker.releaseHost(host, false)
+	assert.Equal(t, 0, host.failures)
+	assert.WithinDuration(t, time.Now().Add(time.Second), host.next, 100*time.Millisecond)
+
+	host.next = time.Time{}
+	checker.forgetHosts()
+	assert.NotContains(t, checker.hosts, "example.com", "healthy host is forgotten")
+}
+
+func TestHealthChecker_Cancel(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	cfg := config.GetTestConfig()
+	cfg.HealthInterval = "1h"
+	cfg.HealthHostDelay = "1h"
+	storage := &healthMock{
+		targets: []entity.HealthTarget{
+			{ID: "1", URL: server.URL + "/a"},
+			{ID: "2", URL: server.URL + "/b"},
+		},
+		results: make(map[string]entity.LinkHealth),
+	}
+	checker, err := NewHealthChecker(cfg, storage, server.Client())
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	checked, err := checker.CheckDue(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, checked, "second link of host waits for delay of host")
+	assert.Len(t, storage.results, 1)
+}
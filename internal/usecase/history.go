@@ -0,0 +1,116 @@
+package usecase
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+)
+
+// Limits of history page.
+const (
+	defaultHistoryLimit = 100
+	maxHistoryLimit     = 1000
+)
+
+// ErrInvalidPage is returned if sort, limit or cursor of history page are wrong.
+var ErrInvalidPage = errors.New("wrong history page")
+
+// historyCursor is position in history, it's given to client as opaque string.
+// Sort order is kept in cursor, so it can't be used with other order.
+type historyCursor struct {
+	Sort  string            `json:"sort"`
+	Desc  bool              `json:"desc,omitempty"`
+	After entity.HistoryKey `json:"after"`
+}
+
+// encodeCursor gets opaque cursor.
+func encodeCursor(cursor historyCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor gets position from opaque cursor.
+func decodeCursor(value string) (historyCursor, error) {
+	var cursor historyCursor
+
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return cursor, fmt.Errorf("%w: wrong cursor", ErrInvalidPage)
+	}
+	if err = json.Unmarshal(data, &cursor); err != nil || cursor.After.ID == "" {
+		return cursor, fmt.Errorf("%w: wrong cursor", ErrInvalidPage)
+	}
+	return cursor, nil
+}
+
+// ParseSort parses sort order like "clicks" or "-clicks", minus means descending order.
+func ParseSort(value string) (sortBy string, desc bool) {
+	if strings.HasPrefix(value, "-") {
+		return value[1:], true
+	}
+	return value, false
+}
+
+// GetURLArrayByUser gets page of urls of user which match filter, cursor is NextCursor of previous page.
+// Links are sorted by creation time by default. Zero limit gets whole history like before paging,
+// with cursor zero limit is default limit.
+func (s ShortenerService) GetURLArrayByUser(userID string, filter entity.HistoryFilter, cursor string) (entity.HistoryPage, error) {
+	filter.Tag, filter.Query = normalizeTag(filter.Tag), strings.TrimSpace(filter.Query)
+
+	switch filter.Sort {
+	case "":
+		filter.Sort = entity.SortCreated
+	case entity.SortCreated, entity.SortClicks, entity.SortURL:
+	default:
+		return entity.HistoryPage{}, fmt.Errorf("%w: unknown sort %q", ErrInvalidPage, filter.Sort)
+	}
+
+	switch {
+	case filter.Limit < 0 || filter.Limit > maxHistoryLimit:
+		return entity.HistoryPage{}, fmt.Errorf("%w: limit must be from 1 to %d", ErrInvalidPage, maxHistoryLimit)
+	case filter.Limit == 0 && cursor != "":
+		filter.Limit = defaultHistoryLimit
+	}
+
+	if cursor != "" {
+		position, err := decodeCursor(cursor)
+		if err != nil {
+			return entity.HistoryPage{}, err
+		}
+		if position.Sort != filter.Sort || position.Desc != filter.Desc {
+			return entity.HistoryPage{}, fmt.Errorf("%w: cursor of other sort order", ErrInvalidPage)
+		}
+		filter.After = &position.After
+	}
+
+	// one more link tells if there is next page.
+	limit := filter.Limit
+	if limit > 0 {
+		filter.Limit++
+	}
+	links, err := s.storage.GetURLArrayByUser(userID, filter)
+	if err != nil {
+		return entity.HistoryPage{}, err
+	}
+
+	page := entity.HistoryPage{Links: links}
+	if limit > 0 && len(links) > limit {
+		page.Links = links[:limit]
+		last := page.Links[limit-1]
+		page.NextCursor = encodeCursor(historyCursor{
+			Sort: filter.Sort,
+			Desc: filter.Desc,
+			After: entity.HistoryKey{
+				ID:        strings.TrimPrefix(last.ShortURL, s.cfg.BaseURL+"/"),
+				CreatedAt: last.CreatedAt,
+				Clicks:    last.Clicks,
+				URL:       last.OriginalURL,
+			},
+		})
+	}
+	return page, nil
+}
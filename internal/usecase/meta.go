@@ -0,0 +1,116 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+)
+
+// Limits of link metadata, they are sizes of columns in db.
+const (
+	maxTitleLength = 256
+	maxNoteLength  = 1024
+	maxTags        = 20
+	maxTagLength   = 64
+)
+
+// ErrInvalidMeta is returned if title, tags or note of link are wrong.
+var ErrInvalidMeta = errors.New("wrong link metadata")
+
+// normalizeTag gets canonical form of tag, tags are case-insensitive.
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+// normalizeMeta validates metadata and gets its canonical form.
+// Title and note are trimmed, tags are normalized, deduplicated and sorted.
+func normalizeMeta(meta entity.LinkMeta) (entity.LinkMeta, error) {
+	meta.Title, meta.Note = strings.TrimSpace(meta.Title), strings.TrimSpace(meta.Note)
+	if utf8.RuneCountInString(meta.Title) > maxTitleLength {
+		return meta, fmt.Errorf("%w: title is longer than %d characters", ErrInvalidMeta, maxTitleLength)
+	}
+	if utf8.RuneCountInString(meta.Note) > maxNoteLength {
+		return meta, fmt.Errorf("%w: note is longer than %d characters", ErrInvalidMeta, maxNoteLength)
+	}
+
+	seen := make(map[string]bool, len(meta.Tags))
+	tags := make([]string, 0, len(meta.Tags))
+	for _, tag := range meta.Tags {
+		tag = normalizeTag(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if utf8.RuneCountInString(tag) > maxTagLength {
+			return meta, fmt.Errorf("%w: tag %q is longer than %d characters", ErrInvalidMeta, tag, maxTagLength)
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	if len(tags) > maxTags {
+		return meta, fmt.Errorf("%w: more than %d tags", ErrInvalidMeta, maxTags)
+	}
+	sort.Strings(tags)
+	meta.Tags = tags
+	return meta, nil
+}
+
+// emptyMeta checks if link has no metadata.
+func emptyMeta(meta entity.LinkMeta) bool {
+	return meta.Title == "" && len(meta.Tags) == 0 && meta.Note == "" && !meta.Interstitial
+}
+
+// GetUserLink gets link of user with its metadata and counters.
+func (s ShortenerService) GetUserLink(userID, id string) (entity.URLs, error) {
+	return s.storage.GetUserURL(userID, id)
+}
+
+// EditLink changes original url and metadata of link of actor by patch.
+// Whole patch is validated before link is changed.
+func (s ShortenerService) EditLink(actor entity.Actor, id string, patch entity.LinkPatch) (entity.URLs, error) {
+	changesMeta := patch.Title != nil || patch.Tags != nil || patch.Note != nil || patch.Interstitial != nil
+	if patch.URL == "" && !changesMeta {
+		return entity.URLs{}, fmt.Errorf("%w: nothing to update", ErrInvalidMeta)
+	}
+
+	link, err := s.GetUserLink(actor.UserID, id)
+	if err != nil {
+		return entity.URLs{}, err
+	}
+	before := entity.LinkMeta{Title: link.Title, Tags: link.Tags, Note: link.Note, Interstitial: link.Interstitial}
+
+	meta := before
+	if patch.Title != nil {
+		meta.Title = *patch.Title
+	}
+	if patch.Tags != nil {
+		meta.Tags = *patch.Tags
+	}
+	if patch.Note != nil {
+		meta.Note = *patch.Note
+	}
+	if patch.Interstitial != nil {
+		meta.Interstitial = *patch.Interstitial
+	}
+	if meta, err = normalizeMeta(meta); err != nil {
+		return entity.URLs{}, err
+	}
+
+	if patch.URL != "" {
+		if link.OriginalURL, err = s.UpdateLink(actor, id, patch.URL); err != nil {
+			return entity.URLs{}, err
+		}
+	}
+	if changesMeta {
+		if err = s.storage.SetURLMeta(actor.UserID, id, meta); err != nil {
+			return entity.URLs{}, err
+		}
+		s.audit(actor, "link.meta", id, before, meta)
+	}
+
+	link.Title, link.Tags, link.Note, link.Interstitial = meta.Title, meta.Tags, meta.Note, meta.Interstitial
+	return link, nil
+}
@@ -0,0 +1,86 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// passwordAttempts is limit of attempts to unlock protected link from one IP.
+var passwordAttempts = entity.RateLimit{Burst: 5, Period: time.Minute}
+
+// Errors of protected links.
+var (
+	ErrLinkPassword     = fmt.Errorf("link password must be at most %d bytes long", maxPasswordLength)
+	ErrMaxClicks        = errors.New("max clicks must not be negative")
+	ErrPasswordRequired = errors.New("link is protected by password")
+	ErrWrongPassword    = errors.New("wrong password of link")
+	ErrTooManyAttempts  = errors.New("too many password attempts")
+)
+
+// Limiter is an interface that describes token bucket rate limiter.
+type Limiter interface {
+	Take(key string, limit entity.RateLimit) (time.Duration, error)
+}
+
+// SetLimiter sets limiter of password attempts. Without it attempts aren't limited.
+func (s *ShortenerService) SetLimiter(limiter Limiter) {
+	s.limiter = limiter
+}
+
+// takeAttempt takes password attempt of actor on link.
+// Failed limiter doesn't fail attempt, error is written to log.
+func (s ShortenerService) takeAttempt(actor entity.Actor, id string) error {
+	if s.limiter == nil {
+		return nil
+	}
+	wait, err := s.limiter.Take("password:"+id+":"+actor.IP, passwordAttempts)
+	if err != nil {
+		log.Printf("password attempts limiter: %v", err)
+		return nil
+	}
+	if wait > 0 {
+		return fmt.Errorf("%w, retry after %v", ErrTooManyAttempts, wait.Round(time.Second))
+	}
+	return nil
+}
+
+// UnlockOriginal gets original url from short, protected link needs its password.
+// Every attempt with password takes token of limiter, so passwords can't be guessed fast.
+// Redirect takes click of limited link, returns ErrExhausted if no clicks left.
+// Returns url with ErrBlockedURL if its domain was blocked later.
+func (s ShortenerService) UnlockOriginal(actor entity.Actor, id, password string) (string, error) {
+	original, err := s.storage.GetOriginal(id)
+	if err != nil {
+		return original, err
+	}
+
+	hash, err := s.storage.GetPasswordHash(id)
+	if err != nil {
+		return "", err
+	}
+	if hash != "" {
+		if password == "" {
+			return "", ErrPasswordRequired
+		}
+		if err = s.takeAttempt(actor, id); err != nil {
+			return "", err
+		}
+		if bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) != nil {
+			return "", ErrWrongPassword
+		}
+	}
+	if err = s.checkPolicy(original); err != nil {
+		return original, err
+	}
+	if err = s.storage.TakeClick(id); err != nil {
+		return "", err
+	}
+	s.click(id)
+	return original, nil
+}
@@ -0,0 +1,222 @@
+package usecase
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bbt-t/lets-go-shortener/internal/config"
+)
+
+// blocklistCheckInterval is how often blocklist file is checked for changes.
+const blocklistCheckInterval = 5 * time.Second
+
+// ErrBlockedURL is returned if domain of url isn't allowed by policy.
+var ErrBlockedURL = errors.New("url is blocked by policy")
+
+// domainRules are domains, wildcard domains and networks.
+type domainRules struct {
+	hosts     map[string]bool
+	wildcards []string
+	networks  []*net.IPNet
+}
+
+// parseDomainRules parses rules like "example.com", "*.example.com", "10.0.0.0/8" or "127.0.0.1".
+// Wildcard matches subdomains only, networks match hosts which are ip addresses.
+func parseDomainRules(entries []string) (domainRules, error) {
+	rules := domainRules{hosts: make(map[string]bool)}
+	for _, entry := range entries {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		switch {
+		case entry == "":
+			continue
+		case strings.Contains(entry, "/"):
+			_, network, err := net.ParseCIDR(entry)
+			if err != nil {
+				return rules, fmt.Errorf("wrong network %q: %w", entry, err)
+			}
+			rules.networks = append(rules.networks, network)
+		case net.ParseIP(entry) != nil:
+			rules.hosts[net.ParseIP(entry).String()] = true
+		default:
+			domain := strings.TrimPrefix(entry, "*.")
+			ascii, err := hostProfile.ToASCII(strings.TrimSuffix(domain, "."))
+			if err != nil {
+				return rules, fmt.Errorf("wrong domain %q: %w", entry, err)
+			}
+			if domain != entry {
+				rules.wildcards = append(rules.wildcards, "."+ascii)
+			} else {
+				rules.hosts[ascii] = true
+			}
+		}
+	}
+	return rules, nil
+}
+
+// empty checks if there are no rules.
+func (r domainRules) empty() bool {
+	return len(r.hosts) == 0 && len(r.wildcards) == 0 && len(r.networks) == 0
+}
+
+// match checks if host matches one of rules.
+func (r domainRules) match(host string) bool {
+	if ip := net.ParseIP(host); ip != nil {
+		if r.hosts[ip.String()] {
+			return true
+		}
+		for _, network := range r.networks {
+			if network.Contains(ip) {
+				return true
+			}
+		}
+		return false
+	}
+
+	host = strings.TrimSuffix(host, ".")
+	if r.hosts[host] {
+		return true
+	}
+	for _, wildcard := range r.wildcards {
+		if strings.HasSuffix(host, wildcard) {
+			return true
+		}
+	}
+	return false
+}
+
+// Policy decides which domains can be shortened and redirected to.
+// Denied and blocklisted hosts are rejected, with allowlist only its hosts are accepted.
+type Policy struct {
+	allow, deny domainRules
+
+	blocklistPath string
+	blocklist     domainRules
+	modTime       time.Time
+	size          int64
+	checked       time.Time
+	checkInterval time.Duration
+	mu            sync.Mutex
+}
+
+// NewPolicy creates policy from allowlist, denylist and blocklist file of config.
+func NewPolicy(cfg config.Config) (*Policy, error) {
+	var err error
+
+	p := &Policy{blocklistPath: cfg.BlocklistFile, checkInterval: blocklistCheckInterval}
+	if p.allow, err = parseDomainRules(strings.Split(cfg.DomainAllowlist, ",")); err != nil {
+		return nil, fmt.Errorf("allowlist: %w", err)
+	}
+	if p.deny, err = parseDomainRules(strings.Split(cfg.DomainDenylist, ",")); err != nil {
+		return nil, fmt.Errorf("denylist: %w", err)
+	}
+	if p.blocklistPath != "" {
+		if err = p.loadBlocklist(); err != nil {
+			return nil, fmt.Errorf("blocklist: %w", err)
+		}
+	}
+	return p, nil
+}
+
+// readBlocklist reads hosts file or list of domains, one per line. Comments start with #.
+func readBlocklist(path string) (domainRules, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return domainRules{}, err
+	}
+	defer file.Close()
+
+	var entries []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) > 1 && net.ParseIP(fields[0]) != nil {
+			// hosts format: address and its names.
+			fields = fields[1:]
+		}
+		entries = append(entries, fields...)
+	}
+	if err = scanner.Err(); err != nil {
+		return domainRules{}, err
+	}
+	return parseDomainRules(entries)
+}
+
+// loadBlocklist reads blocklist file if it was changed since last load.
+func (p *Policy) loadBlocklist() error {
+	info, err := os.Stat(p.blocklistPath)
+	if err != nil {
+		return err
+	}
+	if info.ModTime().Equal(p.modTime) && info.Size() == p.size {
+		return nil
+	}
+
+	blocklist, err := readBlocklist(p.blocklistPath)
+	if err != nil {
+		return err
+	}
+	p.blocklist, p.modTime, p.size = blocklist, info.ModTime(), info.Size()
+	return nil
+}
+
+// reloadBlocklist reloads changed blocklist file at most once per check interval.
+// Failed reload keeps previous blocklist, error is written to log.
+func (p *Policy) reloadBlocklist() domainRules {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.blocklistPath != "" && time.Since(p.checked) >= p.checkInterval {
+		p.checked = time.Now()
+		if err := p.loadBlocklist(); err != nil {
+			log.Printf("policy: failed to reload blocklist: %v", err)
+		}
+	}
+	return p.blocklist
+}
+
+// Check checks if canonical url can be shortened or redirected to.
+func (p *Policy) Check(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidURL, err)
+	}
+	host, err := canonicalIPv4(strings.ToLower(u.Hostname()))
+	if err != nil {
+		return err
+	}
+
+	switch {
+	case p.deny.match(host), p.reloadBlocklist().match(host):
+		return fmt.Errorf("%w: host %s is denied", ErrBlockedURL, host)
+	case !p.allow.empty() && !p.allow.match(host):
+		return fmt.Errorf("%w: host %s isn't allowed", ErrBlockedURL, host)
+	}
+	return nil
+}
+
+// SetPolicy sets policy of domains. Without it all domains are allowed.
+func (s *ShortenerService) SetPolicy(policy *Policy) {
+	s.policy = policy
+}
+
+// checkPolicy checks urls by policy of service.
+func (s ShortenerService) checkPolicy(urls ...string) error {
+	if s.policy == nil {
+		return nil
+	}
+	for _, original := range urls {
+		if err := s.policy.Check(original); err != nil {
+			return err
+		}
+	}
+	return nil
+}
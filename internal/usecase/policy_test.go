@@ -0,0 +1,92 @@
+package usecase
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bbt-t/lets-go-shortener/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicy_Check(t *testing.T) {
+	cfg := config.GetTestConfig()
+	cfg.DomainDenylist = "evil.com, *.phishing.net, 10.0.0.0/8, 127.0.0.1, *.пример.рф"
+	policy, err := NewPolicy(cfg)
+	require.NoError(t, err)
+
+	tests := []struct {
+		url     string
+		blocked bool
+	}{
+		{url: "https://example.com", blocked: false},
+		{url: "https://evil.com/login", blocked: true},
+		{url: "https://sub.evil.com", blocked: false},
+		{url: "https://phishing.net", blocked: false},
+		{url: "https://bank.phishing.net", blocked: true},
+		{url: "http://10.1.2.3:8080/admin", blocked: true},
+		{url: "http://11.1.2.3", blocked: false},
+		{url: "http://127.0.0.1", blocked: true},
+		{url: "http://2130706433", blocked: true},
+		{url: "http://127.1", blocked: true},
+		{url: "http://10.65536", blocked: true},
+		{url: "https://xn--e1afmkfd.xn--p1ai", blocked: false},
+		{url: "https://sub.xn--e1afmkfd.xn--p1ai", blocked: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			err := policy.Check(tt.url)
+			if tt.blocked {
+				assert.ErrorIs(t, err, ErrBlockedURL)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	cfg.DomainAllowlist = "example.com,*.example.com"
+	policy, err = NewPolicy(cfg)
+	require.NoError(t, err)
+	assert.NoError(t, policy.Check("https://example.com"))
+	assert.NoError(t, policy.Check("https://docs.example.com"))
+	assert.ErrorIs(t, policy.Check("https://example.org"), ErrBlockedURL)
+
+	cfg.DomainDenylist = "10.0.0.0/33"
+	_, err = NewPolicy(cfg)
+	assert.Error(t, err)
+}
+
+func TestPolicy_Blocklist(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "blocklist")
+	hosts := "# blocked hosts\n0.0.0.0 ads.example.com tracker.example.com\n\nmalware.org # plain domain\n"
+	require.NoError(t, os.WriteFile(path, []byte(hosts), 0600))
+
+	cfg := config.GetTestConfig()
+	cfg.BlocklistFile = path
+	policy, err := NewPolicy(cfg)
+	require.NoError(t, err)
+	policy.checkInterval = 0
+
+	assert.ErrorIs(t, policy.Check("https://ads.example.com/banner"), ErrBlockedURL)
+	assert.ErrorIs(t, policy.Check("https://tracker.example.com"), ErrBlockedURL)
+	assert.ErrorIs(t, policy.Check("https://malware.org"), ErrBlockedURL)
+	assert.NoError(t, policy.Check("https://example.com"))
+	assert.NoError(t, policy.Check("http://0.0.0.0"))
+
+	// changed file is reloaded.
+	require.NoError(t, os.WriteFile(path, []byte("example.com\n"), 0600))
+	require.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Minute)))
+	assert.ErrorIs(t, policy.Check("https://example.com"), ErrBlockedURL)
+	assert.NoError(t, policy.Check("https://malware.org"))
+
+	// removed file keeps last blocklist.
+	require.NoError(t, os.Remove(path))
+	assert.ErrorIs(t, policy.Check("https://example.com"), ErrBlockedURL)
+
+	cfg.BlocklistFile = filepath.Join(t.TempDir(), "missing")
+	_, err = NewPolicy(cfg)
+	assert.Error(t, err)
+}
@@ -0,0 +1,238 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+
+	"golang.org/x/net/html"
+)
+
+// Limits of title fetcher.
+const (
+	titleTTL        = 24 * time.Hour
+	failedTitleTTL  = time.Hour
+	maxCachedTitles = 10000
+	maxTitlePage    = 1 << 20
+	titleQueueSize  = 256
+)
+
+// Errors of link previews.
+var (
+	ErrInterstitial   = errors.New("link shows preview before redirect")
+	errPrivateAddress = errors.New("address isn't public")
+)
+
+// NewPublicClient gets HTTP client which connects only to public addresses,
+// so fetching pages of links can't be used to reach internal network.
+func NewPublicClient(timeout time.Duration) *http.Client {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			ip := net.ParseIP(host)
+			if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+				ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsMulticast() {
+				return fmt.Errorf("%w: %s", errPrivateAddress, host)
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// pageTitle is cached title of page.
+type pageTitle struct {
+	title   string
+	expires time.Time
+}
+
+// TitleFetcher fetches titles of pages in background and caches them.
+// Previews never wait for pages, they show title after it was fetched.
+type TitleFetcher struct {
+	client  *http.Client
+	queue   chan string
+	titles  map[string]pageTitle
+	pending map[string]bool
+	mu      sync.Mutex
+}
+
+// NewTitleFetcher gets fetcher of titles which uses client.
+func NewTitleFetcher(client *http.Client) *TitleFetcher {
+	return &TitleFetcher{
+		client:  client,
+		queue:   make(chan string, titleQueueSize),
+		titles:  make(map[string]pageTitle),
+		pending: make(map[string]bool),
+	}
+}
+
+// Title gets cached title of page, missing or expired title is queued for fetching.
+// Request is dropped if queue is full, next preview queues it again.
+func (f *TitleFetcher) Title(page string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	cached, ok := f.titles[page]
+	if (!ok || time.Now().After(cached.expires)) && !f.pending[page] {
+		select {
+		case f.queue <- page:
+			f.pending[page] = true
+		default:
+		}
+	}
+	return cached.title
+}
+
+// Run fetches queued titles by workers until context is done.
+func (f *TitleFetcher) Run(ctx context.Context, workers int) {
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case page := <-f.queue:
+					title, err := f.fetch(ctx, page)
+					if err != nil {
+						log.Printf("title fetcher: %s: %v", page, err)
+					}
+					f.store(page, title, err)
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// store caches fetched title. Failed fetch keeps previous title and is retried sooner.
+// Expired titles are evicted when cache is full, then arbitrary one.
+func (f *TitleFetcher) store(page, title string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.pending, page)
+	entry := pageTitle{title: title, expires: time.Now().Add(titleTTL)}
+	if err != nil {
+		entry = pageTitle{title: f.titles[page].title, expires: time.Now().Add(failedTitleTTL)}
+	}
+
+	if _, ok := f.titles[page]; !ok && len(f.titles) >= maxCachedTitles {
+		now := time.Now()
+		for cached, old := range f.titles {
+			if now.After(old.expires) {
+				delete(f.titles, cached)
+			}
+		}
+		for cached := range f.titles {
+			if len(f.titles) < maxCachedTitles {
+				break
+			}
+			delete(f.titles, cached)
+		}
+	}
+	f.titles[page] = entry
+}
+
+// fetch gets title of HTML page, only beginning of page is read.
+func (f *TitleFetcher) fetch(ctx context.Context, page string) (string, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, page, nil)
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("Accept", "text/html")
+
+	response, err := f.client.Do(request)
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status %s", response.Status)
+	}
+	if !strings.Contains(response.Header.Get("Content-Type"), "html") {
+		return "", nil
+	}
+	return parseTitle(io.LimitReader(response.Body, maxTitlePage))
+}
+
+// parseTitle gets text of first title element of HTML, spaces are collapsed and long title is cut.
+func parseTitle(r io.Reader) (string, error) {
+	tokenizer := html.NewTokenizer(r)
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			if errors.Is(tokenizer.Err(), io.EOF) {
+				return "", nil
+			}
+			return "", tokenizer.Err()
+		case html.StartTagToken:
+			if name, _ := tokenizer.TagName(); string(name) != "title" {
+				continue
+			}
+			if tokenizer.Next() != html.TextToken {
+				return "", nil
+			}
+			title := strings.Join(strings.Fields(string(tokenizer.Text())), " ")
+			if utf8.RuneCountInString(title) > maxTitleLength {
+				title = string([]rune(title)[:maxTitleLength])
+			}
+			return title, nil
+		}
+	}
+}
+
+// SetTitleFetcher sets fetcher of titles of destination pages. Without it previews have no titles.
+func (s *ShortenerService) SetTitleFetcher(fetcher *TitleFetcher) {
+	s.titles = fetcher
+}
+
+// PreviewLink gets preview of link with title of destination page, it doesn't take click of link.
+// Protected link needs password instead of preview, link of blocked domain is returned with ErrBlockedURL.
+func (s ShortenerService) PreviewLink(id string) (entity.LinkPreview, error) {
+	original, err := s.storage.GetOriginal(id)
+	if err != nil {
+		return entity.LinkPreview{}, err
+	}
+	hash, err := s.storage.GetPasswordHash(id)
+	if err != nil {
+		return entity.LinkPreview{}, err
+	}
+	if hash != "" {
+		return entity.LinkPreview{}, ErrPasswordRequired
+	}
+
+	preview := entity.LinkPreview{ID: id, ShortURL: s.cfg.BaseURL + "/" + id, OriginalURL: original}
+	if u, err := url.Parse(original); err == nil {
+		preview.Domain = u.Hostname()
+	}
+	if err = s.checkPolicy(original); err != nil {
+		return preview, err
+	}
+	if s.titles != nil {
+		preview.Title = s.titles.Title(original)
+	}
+	return preview, nil
+}
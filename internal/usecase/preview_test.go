@@ -0,0 +1,85 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTitle(t *testing.T) {
+	tests := []struct {
+		name string
+		page string
+		want string
+	}{
+		{name: "title", page: "<html><head><title>Go</title></head></html>", want: "Go"},
+		{name: "spaces", page: "<title>\n  The Go\tProgramming  Language </title>", want: "The Go Programming Language"},
+		{name: "entities", page: "<title>Tom &amp; Jerry</title>", want: "Tom & Jerry"},
+		{name: "first title", page: "<title>One</title><svg><title>Two</title></svg>", want: "One"},
+		{name: "empty title", page: "<title></title>", want: ""},
+		{name: "without title", page: "<html><body>text</body></html>", want: ""},
+		{name: "long title", page: "<title>" + strings.Repeat("a", maxTitleLength+10) + "</title>", want: strings.Repeat("a", maxTitleLength)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseTitle(strings.NewReader(tt.page))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestTitleFetcher(t *testing.T) {
+	title := "First"
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/text":
+			w.Header().Set("Content-Type", "text/plain")
+			io.WriteString(w, "<title>Text</title>")
+		case "/missing":
+			http.NotFound(w, r)
+		default:
+			w.Header().Set("Content-Type", "text/html")
+			io.WriteString(w, "<title>"+title+"</title>")
+		}
+	}))
+	defer server.Close()
+
+	f := NewTitleFetcher(server.Client())
+	ctx := context.Background()
+
+	got, err := f.fetch(ctx, server.URL+"/page")
+	require.NoError(t, err)
+	assert.Equal(t, "First", got)
+	got, err = f.fetch(ctx, server.URL+"/text")
+	require.NoError(t, err)
+	assert.Equal(t, "", got)
+	_, err = f.fetch(ctx, server.URL+"/missing")
+	assert.Error(t, err)
+
+	// title is queued once and kept after failed refresh.
+	assert.Equal(t, "", f.Title(server.URL+"/page"))
+	assert.Equal(t, "", f.Title(server.URL+"/page"))
+	assert.Len(t, f.queue, 1)
+	f.store(<-f.queue, "First", nil)
+	assert.Equal(t, "First", f.Title(server.URL+"/page"))
+	assert.Len(t, f.queue, 0)
+
+	f.titles[server.URL+"/page"] = pageTitle{title: "First", expires: time.Now().Add(-time.Second)}
+	assert.Equal(t, "First", f.Title(server.URL+"/page"))
+	f.store(<-f.queue, "", errors.New("timeout"))
+	assert.Equal(t, "First", f.Title(server.URL+"/page"))
+	assert.True(t, f.titles[server.URL+"/page"].expires.After(time.Now()))
+
+	// public client doesn't connect to loopback of test server.
+	_, err = NewTitleFetcher(NewPublicClient(time.Second)).fetch(ctx, server.URL+"/page")
+	assert.ErrorIs(t, err, errPrivateAddress)
+}
@@ -0,0 +1,215 @@
+package usecase
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/jpeg" // Logo may be JPEG.
+	"image/png"
+	"os"
+	"strings"
+
+	"github.com/bbt-t/lets-go-shortener/internal/config"
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+
+	qrcode "github.com/skip2/go-qrcode"
+)
+
+// Sizes of QR codes in pixels.
+const (
+	defaultQRSize = 256
+	minQRSize     = 64
+	maxQRSize     = 2048
+)
+
+// qrLogoScale is part of width of QR code covered by logo.
+const qrLogoScale = 5
+
+// qrLevels are error-correction levels of QR codes by their names.
+var qrLevels = map[string]qrcode.RecoveryLevel{
+	"L": qrcode.Low,
+	"M": qrcode.Medium,
+	"Q": qrcode.High,
+	"H": qrcode.Highest,
+}
+
+// qrContentTypes are content types of QR codes by their formats.
+var qrContentTypes = map[string]string{
+	entity.QRFormatPNG: "image/png",
+	entity.QRFormatSVG: "image/svg+xml",
+}
+
+// ErrInvalidQR is returned if options of QR code are wrong.
+var ErrInvalidQR = errors.New("wrong qr code options")
+
+// LoadQRLogo loads logo of QR codes from file of config, it's nil if file isn't set.
+func LoadQRLogo(cfg config.Config) (image.Image, error) {
+	if cfg.QRLogoFile == "" {
+		return nil, nil
+	}
+	file, err := os.Open(cfg.QRLogoFile)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	logo, _, err := image.Decode(file)
+	if err != nil {
+		return nil, fmt.Errorf("wrong logo %q: %w", cfg.QRLogoFile, err)
+	}
+	return logo, nil
+}
+
+// SetQRLogo sets logo embedded in QR codes. Without it QR codes can't have logo.
+func (s *ShortenerService) SetQRLogo(logo image.Image) {
+	s.qrLogo = logo
+}
+
+// RenderQR gets QR code of short url and its content type.
+// Link must exist and be active, but opening QR code doesn't take its click.
+// Logo covers the middle of code, so level is raised to Q at least.
+func (s ShortenerService) RenderQR(id string, options entity.QROptions) ([]byte, string, error) {
+	if options.Size == 0 {
+		options.Size = defaultQRSize
+	}
+	if options.Size < minQRSize || options.Size > maxQRSize {
+		return nil, "", fmt.Errorf("%w: size must be from %d to %d", ErrInvalidQR, minQRSize, maxQRSize)
+	}
+	if options.Format == "" {
+		options.Format = entity.QRFormatPNG
+	}
+	contentType, ok := qrContentTypes[strings.ToLower(options.Format)]
+	if !ok {
+		return nil, "", fmt.Errorf("%w: format must be png or svg", ErrInvalidQR)
+	}
+	if options.Level == "" {
+		options.Level = "M"
+	}
+	level, ok := qrLevels[strings.ToUpper(options.Level)]
+	if !ok {
+		return nil, "", fmt.Errorf("%w: level must be L, M, Q or H", ErrInvalidQR)
+	}
+	if options.Logo {
+		if s.qrLogo == nil {
+			return nil, "", fmt.Errorf("%w: logo isn't configured", ErrInvalidQR)
+		}
+		if level < qrcode.High {
+			level = qrcode.High
+		}
+	}
+
+	if _, err := s.storage.GetOriginal(id); err != nil {
+		return nil, "", err
+	}
+
+	code, err := qrcode.New(s.cfg.BaseURL+"/"+id, level)
+	if err != nil {
+		return nil, "", err
+	}
+	var logo image.Image
+	if options.Logo {
+		logo = s.qrLogo
+	}
+	if contentType == qrContentTypes[entity.QRFormatSVG] {
+		content, err := qrSVG(code, options.Size, logo)
+		return content, contentType, err
+	}
+	content, err := qrPNG(code, options.Size, logo)
+	return content, contentType, err
+}
+
+// qrPNG draws QR code as PNG, logo is drawn on white square in the middle of code.
+func qrPNG(code *qrcode.QRCode, size int, logo image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+
+	if logo == nil {
+		if err := png.Encode(&buf, code.Image(size)); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	src := code.Image(size)
+	img := image.NewRGBA(src.Bounds())
+	draw.Draw(img, img.Bounds(), src, image.Point{}, draw.Src)
+
+	width := img.Bounds().Dx()
+	box := width / qrLogoScale
+	pad := box / 10
+	center := image.Rect((width-box)/2, (width-box)/2, (width+box)/2, (width+box)/2)
+	draw.Draw(img, center.Inset(-pad), image.NewUniform(color.White), image.Point{}, draw.Src)
+
+	scaled := scaleImage(logo, center.Dx())
+	at := center.Min.Add(image.Pt((center.Dx()-scaled.Bounds().Dx())/2, (center.Dy()-scaled.Bounds().Dy())/2))
+	draw.Draw(img, scaled.Bounds().Add(at), scaled, image.Point{}, draw.Over)
+
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// scaleImage scales image to fit square of side keeping its proportions, pixels are taken by nearest neighbour.
+func scaleImage(src image.Image, side int) *image.RGBA {
+	bounds := src.Bounds()
+	width, height := side, side
+	if bounds.Dx() > bounds.Dy() {
+		height = side * bounds.Dy() / bounds.Dx()
+	} else {
+		width = side * bounds.Dx() / bounds.Dy()
+	}
+	if width == 0 || height == 0 {
+		return image.NewRGBA(image.Rect(0, 0, 0, 0))
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			dst.Set(x, y, src.At(bounds.Min.X+x*bounds.Dx()/width, bounds.Min.Y+y*bounds.Dy()/height))
+		}
+	}
+	return dst
+}
+
+// qrSVG draws QR code as SVG with one path of dark modules, logo is embedded as PNG.
+func qrSVG(code *qrcode.QRCode, size int, logo image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+
+	bitmap := code.Bitmap()
+	modules := len(bitmap)
+	fmt.Fprintf(&buf, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		size, size, modules, modules)
+	fmt.Fprintf(&buf, `<rect width="%d" height="%d" fill="#fff"/><path fill="#000" d="`, modules, modules)
+	for y, row := range bitmap {
+		for x := 0; x < len(row); x++ {
+			if !row[x] {
+				continue
+			}
+			start := x
+			for x < len(row) && row[x] {
+				x++
+			}
+			fmt.Fprintf(&buf, "M%d %dh%dv1h-%dz", start, y, x-start, x-start)
+		}
+	}
+	buf.WriteString(`"/>`)
+
+	if logo != nil {
+		var encoded bytes.Buffer
+		if err := png.Encode(&encoded, logo); err != nil {
+			return nil, err
+		}
+		box := float64(modules) / qrLogoScale
+		pad := box / 10
+		at := (float64(modules) - box) / 2
+		fmt.Fprintf(&buf, `<rect x="%g" y="%g" width="%g" height="%g" fill="#fff"/>`, at-pad, at-pad, box+2*pad, box+2*pad)
+		fmt.Fprintf(&buf, `<image x="%g" y="%g" width="%g" height="%g" href="data:image/png;base64,%s"/>`,
+			at, at, box, box, base64.StdEncoding.EncodeToString(encoded.Bytes()))
+	}
+	buf.WriteString("</svg>")
+	return buf.Bytes(), nil
+}
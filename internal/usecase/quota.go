@@ -0,0 +1,48 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+)
+
+// ErrQuotaExceeded is returned if user can't create more links.
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// GetQuota gets usage of link quotas by user. Day of quota is UTC day.
+func (s ShortenerService) GetQuota(userID string) (entity.QuotaUsage, error) {
+	day := time.Now().UTC().Truncate(24 * time.Hour)
+
+	active, created, err := s.storage.CountUserURLs(userID, day)
+	if err != nil {
+		return entity.QuotaUsage{}, err
+	}
+	return entity.QuotaUsage{
+		ActiveLinks:    active,
+		MaxActiveLinks: s.cfg.QuotaActive,
+		LinksToday:     created,
+		MaxLinksPerDay: s.cfg.QuotaPerDay,
+		ResetsAt:       day.Add(24 * time.Hour),
+	}, nil
+}
+
+// checkQuota checks if user can create count of new links.
+func (s ShortenerService) checkQuota(userID string, count int) error {
+	if count == 0 || s.cfg.QuotaActive <= 0 && s.cfg.QuotaPerDay <= 0 {
+		return nil
+	}
+
+	usage, err := s.GetQuota(userID)
+	if err != nil {
+		return err
+	}
+	if usage.MaxActiveLinks > 0 && usage.ActiveLinks+count > usage.MaxActiveLinks {
+		return fmt.Errorf("%w: %d of %d active links are used", ErrQuotaExceeded, usage.ActiveLinks, usage.MaxActiveLinks)
+	}
+	if usage.MaxLinksPerDay > 0 && usage.LinksToday+count > usage.MaxLinksPerDay {
+		return fmt.Errorf("%w: %d of %d links per day are used", ErrQuotaExceeded, usage.LinksToday, usage.MaxLinksPerDay)
+	}
+	return nil
+}
@@ -0,0 +1,238 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+)
+
+// Limits of redirect rules.
+const (
+	maxRedirectRules = 20
+	maxLanguageTag   = 35
+	ruleTimeLayout   = "15:04"
+)
+
+// ErrInvalidRule is returned if redirect rule is wrong.
+var ErrInvalidRule = errors.New("wrong redirect rule")
+
+// SetGeoIP sets GeoIP database. Without it country of visitor is known only if caller tells it.
+func (s *ShortenerService) SetGeoIP(geo *GeoIP) {
+	s.geoIP = geo
+}
+
+// normalizeRules validates redirect rules and gets their canonical form.
+// Platform and language are lower case, country is upper case, urls are canonical and allowed by policy.
+func (s ShortenerService) normalizeRules(rules []entity.RedirectRule) ([]entity.RedirectRule, error) {
+	if len(rules) > maxRedirectRules {
+		return nil, fmt.Errorf("%w: more than %d rules", ErrInvalidRule, maxRedirectRules)
+	}
+
+	result := make([]entity.RedirectRule, 0, len(rules))
+	for i, rule := range rules {
+		rule.Platform = strings.ToLower(strings.TrimSpace(rule.Platform))
+		rule.Language = strings.ToLower(strings.TrimSpace(rule.Language))
+		rule.Country = strings.ToUpper(strings.TrimSpace(rule.Country))
+		rule.From, rule.To = strings.TrimSpace(rule.From), strings.TrimSpace(rule.To)
+
+		if rule.Platform != "" && !knownPlatform(rule.Platform) {
+			return nil, fmt.Errorf("%w %d: unknown platform %q", ErrInvalidRule, i+1, rule.Platform)
+		}
+		if len(rule.Language) > maxLanguageTag || strings.ContainsAny(rule.Language, ",;*") {
+			return nil, fmt.Errorf("%w %d: wrong language %q", ErrInvalidRule, i+1, rule.Language)
+		}
+		if rule.Country != "" && !validCountry(rule.Country) {
+			return nil, fmt.Errorf("%w %d: country must be ISO 3166 code", ErrInvalidRule, i+1)
+		}
+		if err := checkWindow(rule.From, rule.To); err != nil {
+			return nil, fmt.Errorf("%w %d: %v", ErrInvalidRule, i+1, err)
+		}
+
+		var err error
+		if rule.URL, err = NormalizeURL(rule.URL); err != nil {
+			return nil, err
+		}
+		if err = s.checkPolicy(rule.URL); err != nil {
+			return nil, err
+		}
+		result = append(result, rule)
+	}
+	return result, nil
+}
+
+// knownPlatform checks if platform is known to redirect rules.
+func knownPlatform(platform string) bool {
+	for _, known := range entity.AllPlatforms {
+		if known == platform {
+			return true
+		}
+	}
+	return false
+}
+
+// checkWindow checks if time window is empty or both its ends are set and differ.
+func checkWindow(from, to string) error {
+	if from == "" && to == "" {
+		return nil
+	}
+	start, err := time.Parse(ruleTimeLayout, from)
+	if err != nil {
+		return fmt.Errorf("from must be like %q", ruleTimeLayout)
+	}
+	end, err := time.Parse(ruleTimeLayout, to)
+	if err != nil {
+		return fmt.Errorf("to must be like %q", ruleTimeLayout)
+	}
+	if start.Equal(end) {
+		return errors.New("time window is empty")
+	}
+	return nil
+}
+
+// SetRedirectRules replaces redirect rules of link of actor, first rule which matches visitor redirects them.
+// Empty rules remove them, so link redirects everyone to original url.
+func (s ShortenerService) SetRedirectRules(actor entity.Actor, id string, rules []entity.RedirectRule) ([]entity.RedirectRule, error) {
+	rules, err := s.normalizeRules(rules)
+	if err != nil {
+		return nil, err
+	}
+	before, err := s.GetRedirectRules(actor.UserID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.storage.SetRedirectRules(actor.UserID, id, rules); err != nil {
+		return nil, err
+	}
+	s.audit(actor, "link.rules", id, before, rules)
+	return rules, nil
+}
+
+// GetRedirectRules gets redirect rules of link of user in order of evaluation.
+func (s ShortenerService) GetRedirectRules(userID, id string) ([]entity.RedirectRule, error) {
+	if _, err := s.storage.GetUserURL(userID, id); err != nil {
+		return nil, err
+	}
+	rules, err := s.storage.GetRedirectRules(id)
+	if rules == nil {
+		rules = []entity.RedirectRule{}
+	}
+	return rules, err
+}
+
+// RouteOriginal gets url of link for visitor, it's url of first matching redirect rule or original.
+// Time of visitor is now if it's zero. Returns url with ErrBlockedURL if domain of url of rule was blocked later.
+func (s ShortenerService) RouteOriginal(id, original string, visitor entity.Visitor) (string, error) {
+	rules, err := s.storage.GetRedirectRules(id)
+	if err != nil || len(rules) == 0 {
+		return original, err
+	}
+
+	if visitor.Time.IsZero() {
+		visitor.Time = time.Now()
+	}
+	if visitor.Country == "" {
+		visitor.Country = s.geoIP.Country(visitor.IP)
+	}
+	platform := detectPlatform(visitor.UserAgent)
+	language := preferredLanguage(visitor.AcceptLanguage)
+
+	for _, rule := range rules {
+		if matchRule(rule, platform, language, visitor) {
+			return rule.URL, s.checkPolicy(rule.URL)
+		}
+	}
+	return original, nil
+}
+
+// matchRule checks if all conditions of rule match visitor.
+func matchRule(rule entity.RedirectRule, platform, language string, visitor entity.Visitor) bool {
+	if rule.Platform != "" && rule.Platform != platform {
+		return false
+	}
+	if rule.Language != "" && language != rule.Language && !strings.HasPrefix(language, rule.Language+"-") {
+		return false
+	}
+	if rule.Country != "" && !strings.EqualFold(rule.Country, visitor.Country) {
+		return false
+	}
+	return rule.From == "" || inWindow(rule.From, rule.To, visitor.Time)
+}
+
+// inWindow checks if time of day in UTC is in window [from, to), window may pass midnight.
+func inWindow(from, to string, at time.Time) bool {
+	start, errFrom := time.Parse(ruleTimeLayout, from)
+	end, errTo := time.Parse(ruleTimeLayout, to)
+	if errFrom != nil || errTo != nil {
+		return false
+	}
+
+	at = at.UTC()
+	minute := at.Hour()*60 + at.Minute()
+	first, last := start.Hour()*60+start.Minute(), end.Hour()*60+end.Minute()
+	if first < last {
+		return first <= minute && minute < last
+	}
+	return minute >= first || minute < last
+}
+
+// detectPlatform gets platform of visitor from User-Agent, it's empty if platform is unknown.
+func detectPlatform(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+	switch {
+	case strings.Contains(ua, "iphone"), strings.Contains(ua, "ipad"), strings.Contains(ua, "ipod"):
+		return entity.PlatformIOS
+	case strings.Contains(ua, "android"):
+		return entity.PlatformAndroid
+	case strings.Contains(ua, "windows"):
+		return entity.PlatformWindows
+	case strings.Contains(ua, "macintosh"), strings.Contains(ua, "mac os x"):
+		return entity.PlatformMacOS
+	case strings.Contains(ua, "linux"), strings.Contains(ua, "x11"):
+		return entity.PlatformLinux
+	}
+	return ""
+}
+
+// preferredLanguage gets language with highest quality from Accept-Language in lower case,
+// first one wins among equal. It's empty if no language is accepted.
+func preferredLanguage(header string) string {
+	type accepted struct {
+		tag     string
+		quality float64
+	}
+
+	var languages []accepted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(part, ";")
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || tag == "*" {
+			continue
+		}
+
+		quality := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			q, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			quality = q
+		}
+		if quality > 0 {
+			languages = append(languages, accepted{tag: tag, quality: quality})
+		}
+	}
+	if len(languages) == 0 {
+		return ""
+	}
+
+	sort.SliceStable(languages, func(i, j int) bool {
+		return languages[i].quality > languages[j].quality
+	})
+	return languages[0].tag
+}
@@ -0,0 +1,180 @@
+package usecase
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bbt-t/lets-go-shortener/internal/adapter/storage"
+	"github.com/bbt-t/lets-go-shortener/internal/config"
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testIPhone  = "Mozilla/5.0 (iPhone; CPU iPhone OS 16_5 like Mac OS X) AppleWebKit/605.1.15 Mobile/15E148"
+	testAndroid = "Mozilla/5.0 (Linux; Android 13; Pixel 7) AppleWebKit/537.36 Chrome/114.0 Mobile Safari/537.36"
+	testWindows = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 Chrome/114.0 Safari/537.36"
+)
+
+func TestGeoIP(t *testing.T) {
+	geo, err := parseGeoIP(strings.NewReader(`network,country
+# networks
+10.0.0.0/8,ru
+192.168.1.0, 192.168.1.127, DE
+2001:db8::/32,FR
+`))
+	require.NoError(t, err)
+	for ip, country := range map[string]string{
+		"10.1.2.3":            "RU",
+		"::ffff:10.255.0.1":   "RU",
+		"192.168.1.127":       "DE",
+		"192.168.1.128":       "",
+		"2001:db8:1::1":       "FR",
+		"2001:db9::1":         "",
+		"9.255.255.255":       "",
+		"not an ip":           "",
+		"2001:db8:ffff::ffff": "FR",
+	} {
+		assert.Equal(t, country, geo.Country(ip), ip)
+	}
+
+	var empty *GeoIP
+	assert.Empty(t, empty.Country("10.1.2.3"))
+
+	for _, database := range []string{"10.0.0.0/8,RU\n10.0.0.0/33,RU", "10.0.0.0/8,RUS", "10.0.0.9,10.0.0.1,RU", "10.0.0.0/8"} {
+		_, err = parseGeoIP(strings.NewReader("header\n" + database))
+		assert.Error(t, err, database)
+	}
+
+	cfg := config.GetTestConfig()
+	geo, err = LoadGeoIP(cfg)
+	require.NoError(t, err)
+	assert.Nil(t, geo)
+	cfg.GeoIPFile = filepath.Join(t.TempDir(), "geoip.csv")
+	require.NoError(t, os.WriteFile(cfg.GeoIPFile, []byte("10.0.0.0/8,RU\n"), 0o600))
+	geo, err = LoadGeoIP(cfg)
+	require.NoError(t, err)
+	assert.Equal(t, "RU", geo.Country("10.0.0.1"))
+}
+
+func TestVisitorConditions(t *testing.T) {
+	for ua, platform := range map[string]string{
+		testIPhone:  entity.PlatformIOS,
+		testAndroid: entity.PlatformAndroid,
+		testWindows: entity.PlatformWindows,
+		"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_7) Safari/605.1.15": entity.PlatformMacOS,
+		"Mozilla/5.0 (X11; Ubuntu; Linux x86_64; rv:109.0) Firefox/115.0": entity.PlatformLinux,
+		"curl/8.0.1": "",
+	} {
+		assert.Equal(t, platform, detectPlatform(ua), ua)
+	}
+
+	for header, language := range map[string]string{
+		"":                               "",
+		"ru-RU,ru;q=0.9,en-US;q=0.8":     "ru-ru",
+		"en;q=0.5, DE;q=0.9":             "de",
+		"fr, en":                         "fr",
+		"*;q=1, es;q=0.1":                "es",
+		"pt-BR;q=0, it;q=wrong, ja;q=.3": "ja",
+	} {
+		assert.Equal(t, language, preferredLanguage(header), header)
+	}
+
+	at := func(clock string) time.Time {
+		moment, err := time.Parse(ruleTimeLayout, clock)
+		require.NoError(t, err)
+		return moment
+	}
+	assert.True(t, inWindow("09:00", "18:00", at("09:00")))
+	assert.False(t, inWindow("09:00", "18:00", at("18:00")))
+	assert.True(t, inWindow("22:00", "06:00", at("23:30")), "window passes midnight")
+	assert.True(t, inWindow("22:00", "06:00", at("05:59")))
+	assert.False(t, inWindow("22:00", "06:00", at("12:00")))
+	assert.True(t, inWindow("09:00", "10:00", time.Date(2023, 5, 1, 12, 30, 0, 0, time.FixedZone("UTC+3", 3*60*60))), "window is in UTC")
+}
+
+func TestRouteOriginal(t *testing.T) {
+	cfg := config.GetDefaultConfig()
+	cfg.DomainDenylist = "evil.com"
+	s, err := storage.NewMapStorage(cfg)
+	require.NoError(t, err)
+	service := NewShortenerService(cfg, s)
+	policy, err := NewPolicy(cfg)
+	require.NoError(t, err)
+	service.SetPolicy(policy)
+	geo, err := parseGeoIP(strings.NewReader("10.0.0.0/8,DE\n"))
+	require.NoError(t, err)
+	service.SetGeoIP(geo)
+	actor := entity.Actor{UserID: "user1"}
+
+	ids, err := service.CreateShort(actor, "https://example.com")
+	require.NoError(t, err)
+	id := ids[0]
+
+	for _, rules := range [][]entity.RedirectRule{
+		{{Platform: "blackberry", URL: "https://example.com"}},
+		{{Country: "Germany", URL: "https://example.com"}},
+		{{From: "09:00", URL: "https://example.com"}},
+		{{From: "09:00", To: "9:00pm", URL: "https://example.com"}},
+		{{From: "09:00", To: "09:00", URL: "https://example.com"}},
+		{{Language: "en,ru", URL: "https://example.com"}},
+		make([]entity.RedirectRule, maxRedirectRules+1),
+	} {
+		_, err = service.SetRedirectRules(actor, id, rules)
+		assert.ErrorIs(t, err, ErrInvalidRule, rules)
+	}
+	_, err = service.SetRedirectRules(actor, id, []entity.RedirectRule{{URL: "javascript:alert(1)"}})
+	assert.ErrorIs(t, err, ErrInvalidURL)
+	_, err = service.SetRedirectRules(actor, id, []entity.RedirectRule{{URL: "https://evil.com/app"}})
+	assert.ErrorIs(t, err, ErrBlockedURL)
+	_, err = service.SetRedirectRules(entity.Actor{UserID: "user2"}, id, nil)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+
+	rules, err := service.SetRedirectRules(actor, id, []entity.RedirectRule{
+		{Platform: " iOS ", URL: "https://apps.apple.com/app/id1"},
+		{Platform: entity.PlatformAndroid, URL: "https://play.google.com/store/apps/details?id=app"},
+		{Language: "DE", Country: "de", URL: "https://example.com/de"},
+		{Country: "DE", URL: "https://example.com/intl"},
+		{From: "00:00", To: "23:59", Language: "fr", URL: "https://example.com/fr"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, entity.PlatformIOS, rules[0].Platform)
+	assert.Equal(t, "de", rules[2].Language)
+	assert.Equal(t, "DE", rules[2].Country)
+	got, err := service.GetRedirectRules("user1", id)
+	require.NoError(t, err)
+	assert.Equal(t, rules, got)
+	_, err = service.GetRedirectRules("user2", id)
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+
+	for _, tt := range []struct {
+		visitor entity.Visitor
+		want    string
+	}{
+		{entity.Visitor{UserAgent: testIPhone, AcceptLanguage: "de"}, "https://apps.apple.com/app/id1"},
+		{entity.Visitor{UserAgent: testAndroid}, "https://play.google.com/store/apps/details?id=app"},
+		{entity.Visitor{UserAgent: testWindows}, "https://example.com"},
+		{entity.Visitor{AcceptLanguage: "de-AT,en;q=0.5", IP: "10.1.1.1"}, "https://example.com/de"},
+		{entity.Visitor{AcceptLanguage: "en,de;q=0.5", IP: "10.1.1.1"}, "https://example.com/intl"},
+		{entity.Visitor{AcceptLanguage: "de", Country: "de"}, "https://example.com/de"},
+		{entity.Visitor{AcceptLanguage: "de", IP: "11.0.0.1"}, "https://example.com"},
+		{entity.Visitor{AcceptLanguage: "fr", Time: time.Date(2023, 5, 1, 23, 59, 30, 0, time.UTC)}, "https://example.com"},
+		{entity.Visitor{AcceptLanguage: "fr", Time: time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)}, "https://example.com/fr"},
+	} {
+		url, err := service.RouteOriginal(id, "https://example.com", tt.visitor)
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, url, tt.visitor)
+	}
+
+	rules, err = service.SetRedirectRules(actor, id, nil)
+	require.NoError(t, err)
+	assert.Empty(t, rules)
+	url, err := service.RouteOriginal(id, "https://example.com", entity.Visitor{UserAgent: testIPhone})
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.com", url)
+}
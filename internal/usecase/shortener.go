@@ -1,25 +1,89 @@
 package usecase
 
 import (
+	"errors"
+	"image"
+	"time"
+
+	"github.com/bbt-t/lets-go-shortener/internal/adapter/storage"
 	"github.com/bbt-t/lets-go-shortener/internal/config"
 	"github.com/bbt-t/lets-go-shortener/internal/entity"
+
+	"golang.org/x/crypto/bcrypt"
 )
 
 // Repository is an interface that describes storage.
 type Repository interface {
 	CreateShort(userID string, urls ...string) ([]string, error)
+	GetUserURLIDs(userID string, urls ...string) (map[string]string, error)
 	GetOriginal(id string) (string, error)
 	MarkAsDeleted(userID string, ids ...string) error
-	GetURLArrayByUser(userID string) ([]entity.URLs, error)
+	GetURLArrayByUser(userID string, filter entity.HistoryFilter) ([]entity.URLs, error)
 	PingDB() error
 	GetConfig() config.Config
 	GetStatistic() (entity.Statistic, error)
+
+	AddAPIKey(key entity.APIKey) error
+	GetAPIKey(id string) (entity.APIKey, error)
+	GetAPIKeysByUser(userID string) ([]entity.APIKey, error)
+	RevokeAPIKey(userID, id string) error
+
+	AddUser(user entity.User) error
+	GetUserByLogin(login string) (entity.User, error)
+	AddSession(session entity.Session) error
+	GetSession(hash string) (entity.Session, error)
+	DeleteSession(hash string) error
+	ClaimURLs(fromUserID, toUserID string) (int, error)
+
+	SearchURLs(filter entity.LinkFilter) ([]entity.LinkInfo, error)
+	GetURLInfo(id string) (entity.LinkInfo, error)
+	SetDisabled(id string, disabled bool) error
+	ReassignURL(id, userID string) error
+
+	CountUserURLs(userID string, since time.Time) (active, created int, err error)
+
+	CreateShortWithOptions(userID, original, passwordHash string, maxClicks int) (string, error)
+	GetPasswordHash(id string) (string, error)
+	TakeClick(id string) error
+
+	UpdateURL(userID, id, original string) error
+	GetURLVersions(id string) ([]entity.LinkVersion, error)
+
+	SetURLMeta(userID, id string, meta entity.LinkMeta) error
+	GetURLMeta(id string) (entity.LinkMeta, error)
+	GetUserURL(userID, id string) (entity.URLs, error)
+
+	SetURLHealth(id string, health entity.LinkHealth) error
+	GetHealthTargets(now time.Time, limit int) ([]entity.HealthTarget, error)
+	GetHealthReport(filter entity.HealthFilter) ([]entity.LinkHealthInfo, error)
+
+	AddWebhook(hook entity.Webhook) error
+	GetWebhook(id string) (entity.Webhook, error)
+	GetWebhooksByUser(userID string) ([]entity.Webhook, error)
+	DeleteWebhook(userID, id string) error
+	AddWebhookDeliveries(deliveries ...entity.WebhookDelivery) error
+	GetDueDeliveries(now time.Time, limit int) ([]entity.WebhookDelivery, error)
+	UpdateDelivery(delivery entity.WebhookDelivery) error
+	DeleteDelivery(id int64) error
+	GetDeadDeliveries(userID string) ([]entity.WebhookDelivery, error)
+	RequeueDelivery(userID string, id int64, at time.Time) error
+
+	SetRedirectRules(userID, id string, rules []entity.RedirectRule) error
+	GetRedirectRules(id string) ([]entity.RedirectRule, error)
 }
 
 // ShortenerService init struct
 type ShortenerService struct {
-	cfg     config.Config
-	storage Repository
+	cfg      config.Config
+	storage  Repository
+	auditLog AuditLog
+	policy   *Policy
+	limiter  Limiter
+	qrLogo   image.Image
+	titles   *TitleFetcher
+	webhooks *WebhookDispatcher
+	clickHub *ClickHub
+	geoIP    *GeoIP
 }
 
 // NewShortenerService gets new service.
@@ -30,24 +94,194 @@ func NewShortenerService(cfg config.Config, s Repository) *ShortenerService {
 	}
 }
 
-// CreateShort creates short url from original.
-func (s ShortenerService) CreateShort(userID string, urls ...string) ([]string, error) {
-	return s.storage.CreateShort(userID, urls...)
+// CreateShort creates short url from canonical form of original if policy and quotas of actor allow it.
+// Links which actor already has are returned with storage.ErrExists, they aren't recorded and sent to webhooks again.
+func (s ShortenerService) CreateShort(actor entity.Actor, urls ...string) ([]string, error) {
+	normalized := make([]string, len(urls))
+	for i, original := range urls {
+		var err error
+		if normalized[i], err = NormalizeURL(original); err != nil {
+			return nil, err
+		}
+	}
+	urls = normalized
+
+	if err := s.checkPolicy(urls...); err != nil {
+		return nil, err
+	}
+	owned, err := s.storage.GetUserURLIDs(actor.UserID, urls...)
+	if err != nil {
+		return nil, err
+	}
+	fresh := make(map[string]bool, len(urls))
+	for _, original := range urls {
+		if _, ok := owned[original]; !ok {
+			fresh[original] = true
+		}
+	}
+	if err = s.checkQuota(actor.UserID, len(fresh)); err != nil {
+		return nil, err
+	}
+
+	ids, err := s.storage.CreateShort(actor.UserID, urls...)
+	if err != nil && !errors.Is(err, storage.ErrExists) {
+		return ids, err
+	}
+	created := make(map[string]bool, len(ids))
+	for i, id := range ids {
+		if _, ok := owned[urls[i]]; ok || created[id] {
+			continue
+		}
+		created[id] = true
+		link := entity.LinkInfo{ID: id, ShortURL: s.cfg.BaseURL + "/" + id, OriginalURL: urls[i], UserID: actor.UserID}
+		s.audit(actor, "link.create", id, nil, link)
+		s.emit(entity.EventLinkCreated, link)
+	}
+	return ids, err
 }
 
-// GetOriginal gets original url from short.
-func (s ShortenerService) GetOriginal(id string) (string, error) {
-	return s.storage.GetOriginal(id)
+// CreateShortWithOptions creates short url of actor with options.
+// Protected link redirects only after correct password, limited link redirects MaxClicks times.
+// Link without password and clicks limit is deduplicated like by CreateShort,
+// metadata of options replaces metadata of existing link then.
+func (s ShortenerService) CreateShortWithOptions(actor entity.Actor, original string, options entity.LinkOptions) (string, error) {
+	if len(options.Password) > maxPasswordLength {
+		return "", ErrLinkPassword
+	}
+	if options.MaxClicks < 0 {
+		return "", ErrMaxClicks
+	}
+	meta, err := normalizeMeta(options.Meta)
+	if err != nil {
+		return "", err
+	}
+
+	var (
+		id        string
+		errCreate error
+	)
+	if options.Password == "" && options.MaxClicks == 0 {
+		ids, err := s.CreateShort(actor, original)
+		if err != nil && !errors.Is(err, storage.ErrExists) {
+			return "", err
+		}
+		id, errCreate = ids[0], err
+	} else if id, err = s.createWithOptions(actor, original, options); err != nil {
+		return "", err
+	}
+
+	if !emptyMeta(meta) {
+		if err = s.storage.SetURLMeta(actor.UserID, id, meta); err != nil {
+			return "", err
+		}
+	}
+	return id, errCreate
 }
 
-// MarkAsDeleted deletes url.
-func (s ShortenerService) MarkAsDeleted(userID string, ids ...string) error {
-	return s.storage.MarkAsDeleted(userID, ids...)
+// createWithOptions creates new short url of actor with password or clicks limit, it's never deduplicated.
+func (s ShortenerService) createWithOptions(actor entity.Actor, original string, options entity.LinkOptions) (string, error) {
+	original, err := NormalizeURL(original)
+	if err != nil {
+		return "", err
+	}
+	if err = s.checkPolicy(original); err != nil {
+		return "", err
+	}
+	if err = s.checkQuota(actor.UserID, 1); err != nil {
+		return "", err
+	}
+
+	var hash []byte
+	if options.Password != "" {
+		if hash, err = bcrypt.GenerateFromPassword([]byte(options.Password), bcrypt.DefaultCost); err != nil {
+			return "", err
+		}
+	}
+	id, err := s.storage.CreateShortWithOptions(actor.UserID, original, string(hash), options.MaxClicks)
+	if err != nil {
+		return "", err
+	}
+	if link, errInfo := s.storage.GetURLInfo(id); errInfo == nil {
+		s.audit(actor, "link.create", id, nil, link)
+		s.emit(entity.EventLinkCreated, link)
+	}
+	return id, nil
+}
+
+// UpdateLink changes original url of link of actor to canonical form of new one.
+// Previous url is kept in versions, link is deduplicated by its new url since then.
+func (s ShortenerService) UpdateLink(actor entity.Actor, id, original string) (string, error) {
+	original, err := NormalizeURL(original)
+	if err != nil {
+		return "", err
+	}
+	if err = s.checkPolicy(original); err != nil {
+		return "", err
+	}
+
+	before, errInfo := s.storage.GetURLInfo(id)
+	if err = s.storage.UpdateURL(actor.UserID, id, original); err != nil {
+		return "", err
+	}
+	if errInfo == nil && before.OriginalURL != original {
+		after := before
+		after.OriginalURL = original
+		s.audit(actor, "link.update", id, before, after)
+	}
+	return original, nil
 }
 
-// GetURLArrayByUser gets all urls.
-func (s ShortenerService) GetURLArrayByUser(userID string) ([]entity.URLs, error) {
-	return s.storage.GetURLArrayByUser(userID)
+// GetLinkVersions gets past original urls of link of user, newest first.
+func (s ShortenerService) GetLinkVersions(userID, id string) ([]entity.LinkVersion, error) {
+	link, err := s.storage.GetURLInfo(id)
+	if err != nil {
+		return nil, err
+	}
+	if link.UserID != userID {
+		return nil, storage.ErrNotFound
+	}
+	return s.storage.GetURLVersions(id)
+}
+
+// GetOriginal gets original url from short. Returns ErrPasswordRequired if link is protected
+// and ErrInterstitial if link shows preview before redirect, click isn't taken then.
+// Returns url with ErrBlockedURL if its domain was blocked later.
+func (s ShortenerService) GetOriginal(id string) (string, error) {
+	meta, err := s.storage.GetURLMeta(id)
+	if err != nil {
+		return "", err
+	}
+	if meta.Interstitial {
+		return "", ErrInterstitial
+	}
+	return s.UnlockOriginal(entity.Actor{}, id, "")
+}
+
+// MarkAsDeleted deletes urls of actor.
+func (s ShortenerService) MarkAsDeleted(actor entity.Actor, ids ...string) error {
+	before := make(map[string]entity.LinkInfo, len(ids))
+	for _, id := range ids {
+		if link, err := s.storage.GetURLInfo(id); err == nil && link.UserID == actor.UserID && !link.Deleted {
+			before[id] = link
+		}
+	}
+
+	if err := s.storage.MarkAsDeleted(actor.UserID, ids...); err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		link, ok := before[id]
+		if !ok {
+			continue
+		}
+		delete(before, id)
+		if after, err := s.storage.GetURLInfo(id); err == nil {
+			s.audit(actor, "link.delete", id, link, after)
+			s.emit(entity.EventLinkDeleted, after)
+		}
+	}
+	return nil
 }
 
 // PingDB for ping DataBase.
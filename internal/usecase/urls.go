@@ -0,0 +1,142 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// maxURLLength is max length of original url, it's size of items.url column.
+const maxURLLength = 512
+
+// ErrInvalidURL is returned if url can't be shortened.
+var ErrInvalidURL = errors.New("wrong url")
+
+// defaultPorts are ports of allowed schemes which are removed from canonical url.
+var defaultPorts = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// hostProfile converts international domain names to punycode.
+// Underscores are kept, they are found in real hosts though not allowed by STD3.
+var hostProfile = idna.New(
+	idna.MapForLookup(),
+	idna.StrictDomainName(false),
+	idna.Transitional(false),
+)
+
+// NormalizeURL validates url and gets its canonical form.
+// Scheme and host are lowercased, host is converted to punycode,
+// default port and path of root are removed. Only http and https urls are allowed.
+// IPv4 host in short or numeric form, like 127.1 or 2130706433, is written in dotted form.
+func NormalizeURL(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", fmt.Errorf("%w: empty url", ErrInvalidURL)
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidURL, err)
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	defaultPort, ok := defaultPorts[u.Scheme]
+	if !ok {
+		return "", fmt.Errorf("%w: scheme %q is not allowed", ErrInvalidURL, u.Scheme)
+	}
+	if u.Opaque != "" || u.Hostname() == "" {
+		return "", fmt.Errorf("%w: host is missing", ErrInvalidURL)
+	}
+
+	host, port := strings.ToLower(u.Hostname()), u.Port()
+	if !strings.Contains(host, ":") {
+		if host, err = hostProfile.ToASCII(host); err != nil {
+			return "", fmt.Errorf("%w: wrong host: %v", ErrInvalidURL, err)
+		}
+		if host, err = canonicalIPv4(host); err != nil {
+			return "", err
+		}
+	}
+	switch {
+	case port != "" && port != defaultPort:
+		u.Host = net.JoinHostPort(host, port)
+	case strings.Contains(host, ":"):
+		u.Host = "[" + host + "]"
+	default:
+		u.Host = host
+	}
+
+	if u.Path == "/" && u.RawPath == "" {
+		u.Path = ""
+	}
+
+	normalized := u.String()
+	if len(normalized) > maxURLLength {
+		return "", fmt.Errorf("%w: longer than %d characters", ErrInvalidURL, maxURLLength)
+	}
+	return normalized, nil
+}
+
+// canonicalIPv4 gets dotted form of IPv4 host written like browsers read it: with 1-4 parts,
+// each part is decimal, octal with leading 0 or hex with 0x, the last part fills the rest of address.
+// Host is IPv4 if its last label is number, other hosts are returned as they are.
+func canonicalIPv4(host string) (string, error) {
+	parts := strings.Split(strings.TrimSuffix(host, "."), ".")
+	if !isIPv4Number(parts[len(parts)-1]) {
+		return host, nil
+	}
+	if len(parts) > 4 {
+		return "", fmt.Errorf("%w: wrong ip %s", ErrInvalidURL, host)
+	}
+
+	var ip uint64
+	for i, part := range parts {
+		n, err := parseIPv4Part(part)
+		if err != nil {
+			return "", fmt.Errorf("%w: wrong ip %s", ErrInvalidURL, host)
+		}
+		if i < len(parts)-1 {
+			if n > 255 {
+				return "", fmt.Errorf("%w: wrong ip %s", ErrInvalidURL, host)
+			}
+			ip |= n << (8 * (3 - i))
+			continue
+		}
+		if n >= 1<<(8*(4-i)) {
+			return "", fmt.Errorf("%w: wrong ip %s", ErrInvalidURL, host)
+		}
+		ip |= n
+	}
+	return net.IPv4(byte(ip>>24), byte(ip>>16), byte(ip>>8), byte(ip)).String(), nil
+}
+
+// isIPv4Number checks if label of host is number, so host must be IPv4.
+func isIPv4Number(label string) bool {
+	if label == "" {
+		return false
+	}
+	if hex, ok := strings.CutPrefix(label, "0x"); ok {
+		_, err := strconv.ParseUint("0"+hex, 16, 64)
+		return err == nil
+	}
+	return strings.Trim(label, "0123456789") == ""
+}
+
+// parseIPv4Part parses part of IPv4 host.
+func parseIPv4Part(part string) (uint64, error) {
+	base := 10
+	switch {
+	case strings.HasPrefix(part, "0x"):
+		part, base = "0"+part[2:], 16
+	case len(part) > 1 && part[0] == '0':
+		base = 8
+	}
+	return strconv.ParseUint(part, base, 32)
+}
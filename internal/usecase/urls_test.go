@@ -0,0 +1,59 @@
+package usecase
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeURL(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  string
+		want string
+		err  bool
+	}{
+		{name: "canonical", raw: "https://example.com/path?q=1", want: "https://example.com/path?q=1"},
+		{name: "spaces", raw: "  https://example.com/a \n", want: "https://example.com/a"},
+		{name: "scheme and host case", raw: "HTTP://Example.COM/Path", want: "http://example.com/Path"},
+		{name: "root path", raw: "http://example.com/", want: "http://example.com"},
+		{name: "default http port", raw: "http://example.com:80/a", want: "http://example.com/a"},
+		{name: "default https port", raw: "https://example.com:443", want: "https://example.com"},
+		{name: "other port", raw: "https://example.com:8443/a", want: "https://example.com:8443/a"},
+		{name: "port of other scheme", raw: "https://example.com:80", want: "https://example.com:80"},
+		{name: "idn", raw: "https://Пример.рф/путь", want: "https://xn--e1afmkfd.xn--p1ai/%D0%BF%D1%83%D1%82%D1%8C"},
+		{name: "underscore", raw: "https://my_host.example.com", want: "https://my_host.example.com"},
+		{name: "ipv6", raw: "http://[::1]:80/a", want: "http://[::1]/a"},
+		{name: "ipv6 with port", raw: "http://[::1]:8080", want: "http://[::1]:8080"},
+		{name: "ipv4", raw: "http://127.0.0.1:8080/a", want: "http://127.0.0.1:8080/a"},
+		{name: "numeric ipv4", raw: "http://2130706433/a", want: "http://127.0.0.1/a"},
+		{name: "short ipv4", raw: "http://127.1", want: "http://127.0.0.1"},
+		{name: "hex and octal ipv4", raw: "http://0x7f.0.0.01:8080", want: "http://127.0.0.1:8080"},
+		{name: "ipv4 with trailing dot", raw: "http://10.1.", want: "http://10.0.0.1"},
+		{name: "host ending with digit", raw: "https://web1.example.com", want: "https://web1.example.com"},
+		{name: "empty", raw: " ", err: true},
+		{name: "javascript", raw: "javascript:alert(1)", err: true},
+		{name: "ftp", raw: "ftp://example.com/file", err: true},
+		{name: "relative", raw: "example.com/path", err: true},
+		{name: "without host", raw: "http:///path", err: true},
+		{name: "opaque", raw: "http:example.com", err: true},
+		{name: "wrong host", raw: "http://exa mple.com", err: true},
+		{name: "too big ipv4", raw: "http://4294967296", err: true},
+		{name: "too big part of ipv4", raw: "http://256.1", err: true},
+		{name: "too many parts of ipv4", raw: "http://1.2.3.4.5", err: true},
+		{name: "wrong octal ipv4", raw: "http://09.1", err: true},
+		{name: "too long", raw: "https://example.com/" + strings.Repeat("a", maxURLLength), err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeURL(tt.raw)
+			if tt.err {
+				assert.ErrorIs(t, err, ErrInvalidURL)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package usecase
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bbt-t/lets-go-shortener/internal/adapter/storage"
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// SessionTTL is lifetime of session.
+const SessionTTL = 30 * 24 * time.Hour
+
+// Limits of credentials.
+const (
+	minPasswordLength = 8
+	maxPasswordLength = 72 // bcrypt ignores longer passwords.
+	maxLoginLength    = 256
+)
+
+// Errors of accounts.
+var (
+	ErrInvalidCredentials = errors.New("invalid login or password")
+	ErrInvalidLogin       = errors.New("invalid login")
+	ErrWeakPassword       = fmt.Errorf("password must be %d to %d bytes long", minPasswordLength, maxPasswordLength)
+	ErrLoginTaken         = errors.New("login is already taken")
+	ErrInvalidSession     = errors.New("invalid session")
+)
+
+// dummyHash is compared when user isn't found, so login takes the same time for unknown users.
+var dummyHash = []byte("$2a$10$ujMpPBy5fm30LDYM40.QJeQzi8xJulKa5HBec1zTelLopWMhMUlw2")
+
+// externalLoginPrefix is prefix of logins of users from external identity providers.
+// Password logins can't contain ':', so they never clash with external ones.
+const externalLoginPrefix = "ext:"
+
+// normalizeLogin gets login in the form it is stored.
+func normalizeLogin(login string) string {
+	return strings.ToLower(strings.TrimSpace(login))
+}
+
+// ExternalLogin gets login of user mapped to subject of identity provider.
+func ExternalLogin(issuer, subject string) string {
+	sum := sha256.Sum256([]byte(issuer + "\x00" + subject))
+	return externalLoginPrefix + hex.EncodeToString(sum[:16])
+}
+
+// roleOf gets role of user by login. Only users of identity provider can be admins,
+// password logins are open for registration, so anyone could take listed login which isn't registered yet.
+func (s ShortenerService) roleOf(login string) string {
+	if !strings.HasPrefix(login, externalLoginPrefix) {
+		return ""
+	}
+	for _, admin := range strings.Split(s.cfg.AdminLogins, ",") {
+		if admin = normalizeLogin(admin); admin != "" && admin == login {
+			return entity.RoleAdmin
+		}
+	}
+	return ""
+}
+
+// Register creates new user with password.
+func (s ShortenerService) Register(login, password string) (entity.User, error) {
+	login = normalizeLogin(login)
+	if login == "" || len(login) > maxLoginLength || strings.Contains(login, ":") {
+		return entity.User{}, ErrInvalidLogin
+	}
+	if len(password) < minPasswordLength || len(password) > maxPasswordLength {
+		return entity.User{}, ErrWeakPassword
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return entity.User{}, err
+	}
+	id, err := randomHex(16)
+	if err != nil {
+		return entity.User{}, err
+	}
+
+	user := entity.User{
+		ID:           id,
+		Login:        login,
+		PasswordHash: string(hash),
+		CreatedAt:    time.Now().UTC(),
+		Role:         s.roleOf(login),
+	}
+	err = s.storage.AddUser(user)
+	if errors.Is(err, storage.ErrExists) {
+		return entity.User{}, ErrLoginTaken
+	}
+	if err != nil {
+		return entity.User{}, err
+	}
+	return user, nil
+}
+
+// Login checks password of user.
+func (s ShortenerService) Login(login, password string) (entity.User, error) {
+	user, err := s.storage.GetUserByLogin(normalizeLogin(login))
+	if errors.Is(err, storage.ErrNotFound) {
+		bcrypt.CompareHashAndPassword(dummyHash, []byte(password))
+		return entity.User{}, ErrInvalidCredentials
+	}
+	if err != nil {
+		return entity.User{}, err
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil {
+		return entity.User{}, ErrInvalidCredentials
+	}
+	user.Role = s.roleOf(user.Login)
+	return user, nil
+}
+
+// LoginExternal gets user mapped to subject of identity provider, user is created on first login.
+func (s ShortenerService) LoginExternal(issuer, subject string) (entity.User, error) {
+	if issuer == "" || subject == "" {
+		return entity.User{}, ErrInvalidLogin
+	}
+
+	login := ExternalLogin(issuer, subject)
+
+	user, err := s.storage.GetUserByLogin(login)
+	if err == nil {
+		user.Role = s.roleOf(login)
+	}
+	if !errors.Is(err, storage.ErrNotFound) {
+		return user, err
+	}
+
+	id, err := randomHex(16)
+	if err != nil {
+		return entity.User{}, err
+	}
+	user = entity.User{
+		ID:        id,
+		Login:     login,
+		CreatedAt: time.Now().UTC(),
+		Role:      s.roleOf(login),
+	}
+	err = s.storage.AddUser(user)
+	if errors.Is(err, storage.ErrExists) {
+		// user is created by concurrent login.
+		user, err = s.storage.GetUserByLogin(login)
+		user.Role = s.roleOf(login)
+		return user, err
+	}
+	if err != nil {
+		return entity.User{}, err
+	}
+	return user, nil
+}
+
+// CreateSession creates session of user. Plain token is returned only here, storage keeps its hash.
+// Role of user is kept in session, so it's changed on next login.
+func (s ShortenerService) CreateSession(user entity.User) (string, time.Time, error) {
+	token, err := randomHex(32)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	session := entity.Session{
+		Hash:      hashSecret(token),
+		UserID:    user.ID,
+		ExpiresAt: time.Now().Add(SessionTTL).UTC(),
+		Role:      user.Role,
+	}
+	if err = s.storage.AddSession(session); err != nil {
+		return "", time.Time{}, err
+	}
+	return token, session.ExpiresAt, nil
+}
+
+// AuthenticateSession gets identity of session owner. Expired sessions are deleted.
+func (s ShortenerService) AuthenticateSession(token string) (entity.Identity, error) {
+	hash := hashSecret(token)
+
+	session, err := s.storage.GetSession(hash)
+	if errors.Is(err, storage.ErrNotFound) {
+		return entity.Identity{}, ErrInvalidSession
+	}
+	if err != nil {
+		return entity.Identity{}, err
+	}
+
+	if time.Now().After(session.ExpiresAt) {
+		if err = s.storage.DeleteSession(hash); err != nil {
+			return entity.Identity{}, err
+		}
+		return entity.Identity{}, ErrInvalidSession
+	}
+
+	scopes := entity.AllScopes
+	if session.Role == entity.RoleAdmin {
+		scopes = append(append([]string{}, entity.AllScopes...), entity.ScopeModerate)
+	}
+	return entity.Identity{
+		UserID:  session.UserID,
+		Scopes:  scopes,
+		Account: true,
+	}, nil
+}
+
+// Logout deletes session.
+func (s ShortenerService) Logout(token string) error {
+	return s.storage.DeleteSession(hashSecret(token))
+}
+
+// ClaimURLs moves urls created by anonymous user to account of actor.
+func (s ShortenerService) ClaimURLs(actor entity.Actor, anonymousID string) (int, error) {
+	claimed, err := s.storage.ClaimURLs(anonymousID, actor.UserID)
+	if err != nil || claimed == 0 {
+		return claimed, err
+	}
+	s.audit(actor, "link.claim", anonymousID, nil, entity.ClaimResponse{Claimed: claimed})
+	return claimed, nil
+}
@@ -0,0 +1,397 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bbt-t/lets-go-shortener/internal/adapter/storage"
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+)
+
+// Limits of webhook deliveries.
+const (
+	webhookSecretSize   = 32
+	webhookTick         = time.Second
+	webhookClickWindow  = time.Minute
+	webhookBatchSize    = 100
+	webhookWorkers      = 4
+	maxDeliveryAttempts = 8
+	deliveryBackoff     = 30 * time.Second
+	maxDeliveryBackoff  = time.Hour
+	maxWebhookResponse  = 64 << 10
+	webhookUserAgent    = "lets-go-shortener webhooks"
+)
+
+// Headers of webhook requests. Signature is "sha256=" and hex of HMAC-SHA256 of timestamp, "." and payload
+// with secret of webhook, see SignWebhook.
+const (
+	HeaderWebhookEvent     = "X-Shortener-Event"
+	HeaderWebhookDelivery  = "X-Shortener-Delivery"
+	HeaderWebhookTimestamp = "X-Shortener-Timestamp"
+	HeaderWebhookSignature = "X-Shortener-Signature"
+)
+
+// ErrUnknownEvent is returned if webhook subscribes to unknown event.
+var ErrUnknownEvent = errors.New("unknown webhook event")
+
+// WebhookStorage is an interface that describes storage of webhooks and queue of their deliveries.
+type WebhookStorage interface {
+	GetOriginal(id string) (string, error)
+	GetURLInfo(id string) (entity.LinkInfo, error)
+	GetWebhook(id string) (entity.Webhook, error)
+	GetWebhooksByUser(userID string) ([]entity.Webhook, error)
+	AddWebhookDeliveries(deliveries ...entity.WebhookDelivery) error
+	GetDueDeliveries(now time.Time, limit int) ([]entity.WebhookDelivery, error)
+	UpdateDelivery(delivery entity.WebhookDelivery) error
+	DeleteDelivery(id int64) error
+}
+
+// WebhookDispatcher queues events of links and delivers them to webhooks of owners of links.
+// Deliveries are kept in storage until webhook responds with 2xx, failed ones are retried with exponential backoff
+// and become dead after maxDeliveryAttempts attempts. Clicks are counted in memory and sent as one clicked
+// event per link in window, limited link expires with its last click.
+type WebhookDispatcher struct {
+	storage WebhookStorage
+	client  *http.Client
+	clicks  map[string]int
+	mu      sync.Mutex
+}
+
+// NewWebhookDispatcher creates dispatcher of webhooks, client doesn't follow redirects of webhooks.
+func NewWebhookDispatcher(s WebhookStorage, client *http.Client) *WebhookDispatcher {
+	noRedirects := *client
+	noRedirects.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	return &WebhookDispatcher{
+		storage: s,
+		client:  &noRedirects,
+		clicks:  make(map[string]int),
+	}
+}
+
+// SignWebhook gets signature of payload sent at timestamp, receivers check it with their secret.
+func SignWebhook(secret, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Emit queues event of link to webhooks of its owner which receive it.
+func (d *WebhookDispatcher) Emit(event string, link entity.LinkInfo, clicks int) error {
+	if link.UserID == "" {
+		return nil
+	}
+	hooks, err := d.storage.GetWebhooksByUser(link.UserID)
+	if err != nil || len(hooks) == 0 {
+		return err
+	}
+
+	id, err := randomHex(16)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	payload, err := json.Marshal(entity.WebhookEvent{
+		ID:          id,
+		Type:        event,
+		Time:        now,
+		LinkID:      link.ID,
+		ShortURL:    link.ShortURL,
+		OriginalURL: link.OriginalURL,
+		Clicks:      clicks,
+	})
+	if err != nil {
+		return err
+	}
+
+	deliveries := make([]entity.WebhookDelivery, 0, len(hooks))
+	for _, hook := range hooks {
+		if hook.Receives(event) {
+			deliveries = append(deliveries, entity.WebhookDelivery{
+				WebhookID:     hook.ID,
+				UserID:        link.UserID,
+				Event:         event,
+				Payload:       payload,
+				CreatedAt:     now,
+				NextAttemptAt: now,
+			})
+		}
+	}
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return d.storage.AddWebhookDeliveries(deliveries...)
+}
+
+// Click counts click of link till next flush.
+func (d *WebhookDispatcher) Click(id string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.clicks[id]++
+}
+
+// FlushClicks queues clicked events with counted clicks, limited link without clicks left expires.
+func (d *WebhookDispatcher) FlushClicks() {
+	d.mu.Lock()
+	clicks := d.clicks
+	d.clicks = make(map[string]int)
+	d.mu.Unlock()
+
+	ids := make([]string, 0, len(clicks))
+	for id := range clicks {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	for _, id := range ids {
+		link, err := d.storage.GetURLInfo(id)
+		if err != nil {
+			log.Printf("webhooks: clicks of %q: %v", id, err)
+			continue
+		}
+		if err = d.Emit(entity.EventLinkClicked, link, clicks[id]); err != nil {
+			log.Printf("webhooks: failed to queue %s of %q: %v", entity.EventLinkClicked, id, err)
+		}
+		if _, err = d.storage.GetOriginal(id); !errors.Is(err, storage.ErrExhausted) {
+			continue
+		}
+		if err = d.Emit(entity.EventLinkExpired, link, 0); err != nil {
+			log.Printf("webhooks: failed to queue %s of %q: %v", entity.EventLinkExpired, id, err)
+		}
+	}
+}
+
+// Run delivers due events until context is done, clicks are flushed once in window and before return.
+func (d *WebhookDispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(webhookTick)
+	defer ticker.Stop()
+
+	flushAt := time.Now().Add(webhookClickWindow)
+	for {
+		select {
+		case <-ctx.Done():
+			d.FlushClicks()
+			return
+		case now := <-ticker.C:
+			if !now.Before(flushAt) {
+				d.FlushClicks()
+				flushAt = now.Add(webhookClickWindow)
+			}
+			if _, err := d.Deliver(ctx); err != nil {
+				log.Printf("webhooks: %v", err)
+			}
+		}
+	}
+}
+
+// Deliver sends batch of due deliveries and gets count of delivered ones.
+func (d *WebhookDispatcher) Deliver(ctx context.Context) (int, error) {
+	deliveries, err := d.storage.GetDueDeliveries(time.Now(), webhookBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		delivered int32
+		wg        sync.WaitGroup
+	)
+	slots := make(chan struct{}, webhookWorkers)
+	for _, delivery := range deliveries {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return int(delivered), nil
+		}
+		wg.Add(1)
+		go func(delivery entity.WebhookDelivery) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+
+			ok, err := d.deliver(ctx, delivery)
+			if err != nil {
+				log.Printf("webhooks: delivery %d: %v", delivery.ID, err)
+			}
+			if ok {
+				atomic.AddInt32(&delivered, 1)
+			}
+		}(delivery)
+	}
+	wg.Wait()
+	return int(delivered), nil
+}
+
+// deliver sends delivery and records its result. Delivery of deleted webhook is dropped.
+// Attempt isn't counted if context is done.
+func (d *WebhookDispatcher) deliver(ctx context.Context, delivery entity.WebhookDelivery) (bool, error) {
+	hook, err := d.storage.GetWebhook(delivery.WebhookID)
+	if errors.Is(err, storage.ErrNotFound) {
+		return false, d.storage.DeleteDelivery(delivery.ID)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	errSend := d.send(ctx, hook, delivery)
+	if ctx.Err() != nil {
+		return false, nil
+	}
+	if errSend == nil {
+		return true, d.storage.DeleteDelivery(delivery.ID)
+	}
+
+	delivery.Attempts++
+	delivery.LastError = errSend.Error()
+	if delivery.Attempts >= maxDeliveryAttempts {
+		delivery.Dead = true
+	} else {
+		delivery.NextAttemptAt = time.Now().UTC().Add(deliveryDelay(delivery.Attempts))
+	}
+	return false, d.storage.UpdateDelivery(delivery)
+}
+
+// deliveryDelay gets delay before next attempt after failed ones, it grows twice after every attempt.
+func deliveryDelay(attempts int) time.Duration {
+	delay := deliveryBackoff
+	for i := 1; i < attempts && delay < maxDeliveryBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxDeliveryBackoff {
+		delay = maxDeliveryBackoff
+	}
+	return delay
+}
+
+// send posts signed payload of delivery to webhook, only 2xx response is success.
+func (d *WebhookDispatcher) send(ctx context.Context, hook entity.Webhook, delivery entity.WebhookDelivery) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", webhookUserAgent)
+	request.Header.Set(HeaderWebhookEvent, delivery.Event)
+	request.Header.Set(HeaderWebhookDelivery, strconv.FormatInt(delivery.ID, 10))
+	request.Header.Set(HeaderWebhookTimestamp, timestamp)
+	request.Header.Set(HeaderWebhookSignature, SignWebhook(hook.Secret, timestamp, delivery.Payload))
+
+	response, err := d.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(response.Body, maxWebhookResponse))
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with status %d", response.StatusCode)
+	}
+	return nil
+}
+
+// SetWebhooks sets dispatcher of webhooks. Without it events of links aren't sent.
+func (s *ShortenerService) SetWebhooks(webhooks *WebhookDispatcher) {
+	s.webhooks = webhooks
+}
+
+// emit queues event of link to webhooks. Failed queue doesn't fail action, it's written to log.
+func (s ShortenerService) emit(event string, link entity.LinkInfo) {
+	if s.webhooks == nil {
+		return
+	}
+	if err := s.webhooks.Emit(event, link, 0); err != nil {
+		log.Printf("webhooks: failed to queue %s of %q: %v", event, link.ID, err)
+	}
+}
+
+// CreateWebhook creates webhook of actor for events, empty events are all events.
+// Secret of webhook is returned only here.
+func (s ShortenerService) CreateWebhook(actor entity.Actor, rawURL string, events []string) (entity.Webhook, string, error) {
+	target, err := NormalizeURL(rawURL)
+	if err != nil {
+		return entity.Webhook{}, "", err
+	}
+	for _, event := range events {
+		known := false
+		for _, e := range entity.AllEvents {
+			known = known || e == event
+		}
+		if !known {
+			return entity.Webhook{}, "", fmt.Errorf("%w: %s", ErrUnknownEvent, event)
+		}
+	}
+
+	id, err := randomHex(8)
+	if err != nil {
+		return entity.Webhook{}, "", err
+	}
+	secret, err := randomHex(webhookSecretSize)
+	if err != nil {
+		return entity.Webhook{}, "", err
+	}
+
+	hook := entity.Webhook{
+		ID:        id,
+		UserID:    actor.UserID,
+		URL:       target,
+		Events:    events,
+		Secret:    secret,
+		CreatedAt: time.Now().UTC(),
+	}
+	if err = s.storage.AddWebhook(hook); err != nil {
+		return entity.Webhook{}, "", err
+	}
+	s.audit(actor, "webhook.create", id, nil, hook)
+	return hook, secret, nil
+}
+
+// GetWebhooksByUser gets webhooks of user.
+func (s ShortenerService) GetWebhooksByUser(userID string) ([]entity.Webhook, error) {
+	return s.storage.GetWebhooksByUser(userID)
+}
+
+// DeleteWebhook deletes webhook of actor with its queued deliveries.
+func (s ShortenerService) DeleteWebhook(actor entity.Actor, id string) error {
+	before, err := s.storage.GetWebhook(id)
+	if err != nil {
+		return err
+	}
+	if err = s.storage.DeleteWebhook(actor.UserID, id); err != nil {
+		return err
+	}
+	s.audit(actor, "webhook.delete", id, before, nil)
+	return nil
+}
+
+// GetDeadDeliveries gets deliveries to webhooks of user which failed all attempts, newest first.
+func (s ShortenerService) GetDeadDeliveries(userID string) ([]entity.WebhookDelivery, error) {
+	return s.storage.GetDeadDeliveries(userID)
+}
+
+// RetryDelivery queues dead delivery of actor again with fresh attempts.
+func (s ShortenerService) RetryDelivery(actor entity.Actor, id int64) error {
+	if err := s.storage.RequeueDelivery(actor.UserID, id, time.Now().UTC()); err != nil {
+		return err
+	}
+	s.audit(actor, "webhook.retry", strconv.FormatInt(id, 10), nil, nil)
+	return nil
+}
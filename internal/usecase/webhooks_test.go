@@ -0,0 +1,209 @@
+package usecase
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bbt-t/lets-go-shortener/internal/adapter/storage"
+	"github.com/bbt-t/lets-go-shortener/internal/config"
+	"github.com/bbt-t/lets-go-shortener/internal/entity"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// webhookReceiver records events received by webhooks and answers with status, secrets are kept by path.
+type webhookReceiver struct {
+	secrets map[string]string
+	status  int
+	events  []entity.WebhookEvent
+	mu      sync.Mutex
+}
+
+func (rec *webhookReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	body, _ := io.ReadAll(r.Body)
+	signature := SignWebhook(rec.secrets[r.URL.Path], r.Header.Get(HeaderWebhookTimestamp), body)
+	if r.Header.Get(HeaderWebhookSignature) != signature {
+		http.Error(w, "wrong signature", http.StatusUnauthorized)
+		return
+	}
+	if rec.status != 0 && rec.status != http.StatusOK {
+		w.WriteHeader(rec.status)
+		return
+	}
+
+	var event entity.WebhookEvent
+	if err := json.Unmarshal(body, &event); err != nil || event.Type != r.Header.Get(HeaderWebhookEvent) {
+		http.Error(w, "wrong event", http.StatusBadRequest)
+		return
+	}
+	rec.events = append(rec.events, event)
+}
+
+// received gets received events and forgets them.
+func (rec *webhookReceiver) received() []entity.WebhookEvent {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	events := rec.events
+	rec.events = nil
+	return events
+}
+
+func TestWebhooks(t *testing.T) {
+	receiver := &webhookReceiver{secrets: make(map[string]string)}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	cfg := config.GetDefaultConfig()
+	s, err := storage.NewMapStorage(cfg)
+	require.NoError(t, err)
+	service := NewShortenerService(cfg, s)
+	webhooks := NewWebhookDispatcher(s, server.Client())
+	service.SetWebhooks(webhooks)
+	ctx := context.Background()
+	actor := entity.Actor{UserID: "user1"}
+
+	_, _, err = service.CreateWebhook(actor, "ftp://example.com", nil)
+	assert.ErrorIs(t, err, ErrInvalidURL)
+	_, _, err = service.CreateWebhook(actor, server.URL, []string{"link.renamed"})
+	assert.ErrorIs(t, err, ErrUnknownEvent)
+
+	hook, secret, err := service.CreateWebhook(actor, server.URL+"/hook", nil)
+	require.NoError(t, err)
+	assert.Len(t, secret, 2*webhookSecretSize)
+	receiver.secrets["/hook"] = secret
+	_, secret, err = service.CreateWebhook(actor, server.URL+"/clicks", []string{entity.EventLinkClicked})
+	require.NoError(t, err)
+	receiver.secrets["/clicks"] = secret
+
+	// events of other users aren't sent.
+	_, err = service.CreateShort(entity.Actor{UserID: "user2"}, "https://example.org")
+	require.NoError(t, err)
+	ids, err := service.CreateShort(actor, "https://example.com")
+	require.NoError(t, err)
+	limited, err := service.CreateShortWithOptions(actor, "https://example.com/once", entity.LinkOptions{MaxClicks: 2})
+	require.NoError(t, err)
+	// existing link isn't created again.
+	again, err := service.CreateShort(actor, "https://example.com")
+	assert.ErrorIs(t, err, storage.ErrExists)
+	assert.Equal(t, ids, again)
+
+	delivered, err := webhooks.Deliver(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, delivered)
+	events := receiver.received()
+	require.Len(t, events, 2)
+	created := make(map[string]entity.WebhookEvent)
+	for _, event := range events {
+		created[event.LinkID] = event
+	}
+	require.Contains(t, created, ids[0])
+	assert.Equal(t, entity.EventLinkCreated, created[ids[0]].Type)
+	assert.Equal(t, "https://example.com", created[ids[0]].OriginalURL)
+	assert.Equal(t, cfg.BaseURL+"/"+ids[0], created[ids[0]].ShortURL)
+	assert.NotEmpty(t, created[ids[0]].ID)
+	assert.Contains(t, created, limited)
+
+	// clicks are aggregated, limited link expires with its last click.
+	for _, id := range []string{ids[0], ids[0], ids[0], limited, limited} {
+		_, err = service.GetOriginal(id)
+		require.NoError(t, err)
+	}
+	webhooks.FlushClicks()
+	delivered, err = webhooks.Deliver(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 5, delivered, "clicked events go to both webhooks")
+	got := make(map[string]int)
+	for _, event := range receiver.received() {
+		got[event.Type+" "+event.LinkID] += event.Clicks
+	}
+	assert.Equal(t, map[string]int{
+		entity.EventLinkClicked + " " + ids[0]:  6,
+		entity.EventLinkClicked + " " + limited: 4,
+		entity.EventLinkExpired + " " + limited: 0,
+	}, got)
+
+	webhooks.FlushClicks()
+	delivered, err = webhooks.Deliver(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, delivered, "nothing is clicked since last flush")
+
+	// failed delivery is retried with backoff and becomes dead after all attempts.
+	receiver.status = http.StatusInternalServerError
+	require.NoError(t, service.MarkAsDeleted(actor, ids[0]))
+	delivered, err = webhooks.Deliver(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, delivered)
+
+	due, err := s.GetDueDeliveries(time.Now().Add(time.Hour), 0)
+	require.NoError(t, err)
+	require.Len(t, due, 1)
+	assert.Equal(t, 1, due[0].Attempts)
+	assert.Equal(t, "webhook responded with status 500", due[0].LastError)
+	assert.WithinDuration(t, time.Now().Add(deliveryBackoff), due[0].NextAttemptAt, time.Second)
+	assert.Equal(t, time.Minute, deliveryDelay(2))
+	assert.Equal(t, maxDeliveryBackoff, deliveryDelay(maxDeliveryAttempts+10))
+
+	for attempt := 2; attempt <= maxDeliveryAttempts; attempt++ {
+		delivery := due[0]
+		delivery.NextAttemptAt = time.Now().Add(-time.Second)
+		require.NoError(t, s.UpdateDelivery(delivery))
+		_, err = webhooks.Deliver(ctx)
+		require.NoError(t, err)
+		due, err = s.GetDueDeliveries(time.Now().Add(2*maxDeliveryBackoff), 0)
+		require.NoError(t, err)
+		if attempt < maxDeliveryAttempts {
+			require.Len(t, due, 1)
+			assert.Equal(t, attempt, due[0].Attempts)
+		}
+	}
+	assert.Empty(t, due)
+	dead, err := service.GetDeadDeliveries("user1")
+	require.NoError(t, err)
+	require.Len(t, dead, 1)
+	assert.Equal(t, maxDeliveryAttempts, dead[0].Attempts)
+	assert.Equal(t, entity.EventLinkDeleted, dead[0].Event)
+
+	// dead delivery is delivered after retry.
+	receiver.status = http.StatusOK
+	assert.ErrorIs(t, service.RetryDelivery(entity.Actor{UserID: "user2"}, dead[0].ID), storage.ErrNotFound)
+	require.NoError(t, service.RetryDelivery(actor, dead[0].ID))
+	delivered, err = webhooks.Deliver(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, delivered)
+	events = receiver.received()
+	require.Len(t, events, 1)
+	assert.Equal(t, entity.EventLinkDeleted, events[0].Type)
+	dead, err = service.GetDeadDeliveries("user1")
+	require.NoError(t, err)
+	assert.Empty(t, dead)
+
+	// redirect isn't followed, it's failure of delivery.
+	receiver.status = http.StatusFound
+	_, err = service.CreateShort(actor, "https://example.com/redirect")
+	require.NoError(t, err)
+	delivered, err = webhooks.Deliver(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, delivered)
+
+	// deliveries of deleted webhook are dropped.
+	assert.ErrorIs(t, service.DeleteWebhook(entity.Actor{UserID: "user2"}, hook.ID), storage.ErrNotFound)
+	require.NoError(t, service.DeleteWebhook(actor, hook.ID))
+	due, err = s.GetDueDeliveries(time.Now().Add(2*maxDeliveryBackoff), 0)
+	require.NoError(t, err)
+	assert.Empty(t, due)
+	hooks, err := service.GetWebhooksByUser("user1")
+	require.NoError(t, err)
+	require.Len(t, hooks, 1)
+	assert.Equal(t, []string{entity.EventLinkClicked}, hooks[0].Events)
+}
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS api_keys (
+    id VARCHAR(64) PRIMARY KEY,
+    hash VARCHAR(128) NOT NULL,
+    user_id VARCHAR(256) NOT NULL,
+    name VARCHAR(256),
+    scopes VARCHAR(256),
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    revoked BOOL DEFAULT false
+);
+CREATE INDEX IF NOT EXISTS api_keys_user_id_idx ON api_keys (user_id);
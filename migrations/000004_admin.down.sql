@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS items_cookie_idx;
+ALTER TABLE sessions DROP COLUMN IF EXISTS role;
+ALTER TABLE items DROP COLUMN IF EXISTS disabled;
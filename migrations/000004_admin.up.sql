@@ -0,0 +1,3 @@
+ALTER TABLE items ADD COLUMN IF NOT EXISTS disabled BOOL DEFAULT false;
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS role VARCHAR(32) NOT NULL DEFAULT '';
+CREATE INDEX IF NOT EXISTS items_cookie_idx ON items (cookie);
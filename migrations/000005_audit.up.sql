@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    user_id VARCHAR(64) NOT NULL DEFAULT '',
+    ip VARCHAR(64) NOT NULL DEFAULT '',
+    action VARCHAR(64) NOT NULL,
+    target VARCHAR(256) NOT NULL DEFAULT '',
+    before JSONB,
+    after JSONB
+);
+CREATE INDEX IF NOT EXISTS audit_log_user_id_idx ON audit_log (user_id);
+CREATE INDEX IF NOT EXISTS audit_log_target_idx ON audit_log (target);
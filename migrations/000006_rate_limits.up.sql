@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS rate_limits (
+    key VARCHAR(256) PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL,
+    allowed BOOL NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT now()
+);
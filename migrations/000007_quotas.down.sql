@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS items_cookie_created_at_idx;
+ALTER TABLE items DROP COLUMN IF EXISTS created_at;
@@ -0,0 +1,3 @@
+ALTER TABLE items ADD COLUMN IF NOT EXISTS created_at TIMESTAMP;
+ALTER TABLE items ALTER COLUMN created_at SET DEFAULT now();
+CREATE INDEX IF NOT EXISTS items_cookie_created_at_idx ON items (cookie, created_at);
@@ -0,0 +1 @@
+ALTER TABLE items ADD COLUMN IF NOT EXISTS password_hash TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS link_versions (
+    id BIGSERIAL PRIMARY KEY,
+    link_id VARCHAR(256) NOT NULL,
+    url VARCHAR(512) NOT NULL,
+    replaced_at TIMESTAMP NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS link_versions_link_id_idx ON link_versions (link_id);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS link_tags;
+ALTER TABLE items DROP COLUMN IF EXISTS note;
+ALTER TABLE items DROP COLUMN IF EXISTS title;
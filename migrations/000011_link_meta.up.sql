@@ -0,0 +1,8 @@
+ALTER TABLE items ADD COLUMN IF NOT EXISTS title VARCHAR(256) NOT NULL DEFAULT '';
+ALTER TABLE items ADD COLUMN IF NOT EXISTS note VARCHAR(1024) NOT NULL DEFAULT '';
+CREATE TABLE IF NOT EXISTS link_tags (
+    link_id VARCHAR(256) NOT NULL,
+    tag VARCHAR(64) NOT NULL,
+    PRIMARY KEY (link_id, tag)
+);
+CREATE INDEX IF NOT EXISTS link_tags_tag_idx ON link_tags (tag, link_id);
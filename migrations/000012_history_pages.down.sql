@@ -0,0 +1,6 @@
+CREATE INDEX IF NOT EXISTS items_cookie_created_at_idx ON items (cookie, created_at);
+DROP INDEX IF EXISTS items_cookie_url_id_idx;
+DROP INDEX IF EXISTS items_cookie_clicks_id_idx;
+DROP INDEX IF EXISTS items_cookie_created_at_id_idx;
+ALTER TABLE items ALTER COLUMN created_at DROP NOT NULL;
+ALTER TABLE items DROP COLUMN IF EXISTS clicks;
@@ -0,0 +1,7 @@
+ALTER TABLE items ADD COLUMN IF NOT EXISTS clicks BIGINT NOT NULL DEFAULT 0;
+UPDATE items SET created_at = TIMESTAMP 'epoch' WHERE created_at IS NULL;
+ALTER TABLE items ALTER COLUMN created_at SET NOT NULL;
+CREATE INDEX IF NOT EXISTS items_cookie_created_at_id_idx ON items (cookie, created_at, id);
+CREATE INDEX IF NOT EXISTS items_cookie_clicks_id_idx ON items (cookie, clicks, id);
+CREATE INDEX IF NOT EXISTS items_cookie_url_id_idx ON items (cookie, url, id);
+DROP INDEX IF EXISTS items_cookie_created_at_idx;
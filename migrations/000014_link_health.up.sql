@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS link_health (
+    link_id VARCHAR(256) PRIMARY KEY,
+    status INT NOT NULL DEFAULT 0,
+    latency_ms BIGINT NOT NULL DEFAULT 0,
+    error TEXT NOT NULL DEFAULT '',
+    failures INT NOT NULL DEFAULT 0,
+    checked_at TIMESTAMP NOT NULL,
+    next_check_at TIMESTAMP NOT NULL
+);
+CREATE INDEX IF NOT EXISTS link_health_next_check_idx ON link_health (next_check_at);
@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS webhooks (
+    id VARCHAR(64) PRIMARY KEY,
+    user_id VARCHAR(256) NOT NULL,
+    url TEXT NOT NULL,
+    events VARCHAR(256) NOT NULL DEFAULT '',
+    secret VARCHAR(128) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS webhooks_user_id_idx ON webhooks (user_id);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id BIGSERIAL PRIMARY KEY,
+    webhook_id VARCHAR(64) NOT NULL REFERENCES webhooks (id) ON DELETE CASCADE,
+    user_id VARCHAR(256) NOT NULL,
+    event VARCHAR(64) NOT NULL,
+    payload TEXT NOT NULL,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP NOT NULL DEFAULT now(),
+    next_attempt_at TIMESTAMP NOT NULL,
+    dead BOOL NOT NULL DEFAULT false
+);
+CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx ON webhook_deliveries (next_attempt_at) WHERE NOT dead;
+CREATE INDEX IF NOT EXISTS webhook_deliveries_dead_idx ON webhook_deliveries (user_id) WHERE dead;
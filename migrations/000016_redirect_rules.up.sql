@@ -0,0 +1 @@
+ALTER TABLE items ADD COLUMN IF NOT EXISTS redirect_rules JSONB;
@@ -0,0 +1 @@
+DROP SEQUENCE IF EXISTS items_id_seq;
@@ -0,0 +1,2 @@
+CREATE SEQUENCE IF NOT EXISTS items_id_seq;
+SELECT setval('items_id_seq', (SELECT COUNT(*) + 1 FROM items), false);
@@ -0,0 +1 @@
+DROP INDEX IF EXISTS items_id_idx;
@@ -0,0 +1,2 @@
+SELECT setval('items_id_seq', (SELECT COALESCE(MAX(id::BIGINT), 0) + 1 FROM items), false);
+CREATE UNIQUE INDEX IF NOT EXISTS items_id_idx ON items (id);
@@ -0,0 +1,199 @@
+// Package client is typed client of shortener service.
+//
+// Client works over HTTP or gRPC transport, keeps signed user identity
+// between calls and retries idempotent calls with backoff.
+package client
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Errors mirroring storage errors of the service.
+var (
+	ErrExists    = errors.New("url is already exists")
+	ErrDeleted   = errors.New("url is deleted")
+	ErrNotFound  = errors.New("not found")
+	ErrBlocked   = errors.New("url is blocked by policy")
+	ErrExhausted = errors.New("url clicks are exhausted")
+)
+
+// Link is shortened url.
+type Link struct {
+	ID            string `json:"id,omitempty"`
+	CorrelationID string `json:"correlation_id,omitempty"`
+	OriginalURL   string `json:"original_url,omitempty"`
+	ShortURL      string `json:"short_url,omitempty"`
+}
+
+// Statistic is total count of urls and users.
+type Statistic struct {
+	Urls  int `json:"urls"`
+	Users int `json:"users"`
+}
+
+// Transport is a way to call shortener service.
+type Transport interface {
+	// Shorten shortens single url. Returns ErrExists with link if url was shortened before.
+	Shorten(ctx context.Context, url string) (Link, error)
+	// Batch shortens many urls, links are matched by CorrelationID.
+	Batch(ctx context.Context, links []Link) ([]Link, error)
+	// Resolve gets original url by short id.
+	Resolve(ctx context.Context, id string) (string, error)
+	// History gets all links of current user.
+	History(ctx context.Context) ([]Link, error)
+	// Delete deletes links of current user.
+	Delete(ctx context.Context, ids ...string) error
+	// Stats gets statistic of the service.
+	Stats(ctx context.Context) (Statistic, error)
+	// Identity gets signed user identity.
+	Identity() string
+	// SetIdentity sets signed user identity.
+	SetIdentity(identity string)
+	// SetAPIKey sets API key, which is used instead of identity.
+	SetAPIKey(key string)
+}
+
+// RetryPolicy describes retries of idempotent calls.
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+// DefaultRetryPolicy is used if other isn't set.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   100 * time.Millisecond,
+	MaxDelay:    2 * time.Second,
+}
+
+// Option changes client.
+type Option func(c *Client)
+
+// WithRetryPolicy sets retry policy.
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Client) {
+		c.retry = policy
+	}
+}
+
+// WithIdentity sets signed user identity.
+func WithIdentity(identity string) Option {
+	return func(c *Client) {
+		c.transport.SetIdentity(identity)
+	}
+}
+
+// WithAPIKey sets API key for server-to-server calls.
+func WithAPIKey(key string) Option {
+	return func(c *Client) {
+		c.transport.SetAPIKey(key)
+	}
+}
+
+// Client is typed client of shortener service.
+type Client struct {
+	transport Transport
+	retry     RetryPolicy
+}
+
+// New gets new client over transport.
+func New(transport Transport, opts ...Option) *Client {
+	c := &Client{
+		transport: transport,
+		retry:     DefaultRetryPolicy,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Identity gets signed user identity, which can be saved and passed to WithIdentity later.
+func (c *Client) Identity() string {
+	return c.transport.Identity()
+}
+
+// Shorten shortens single url. Isn't retried as it's not idempotent.
+func (c *Client) Shorten(ctx context.Context, url string) (Link, error) {
+	return c.transport.Shorten(ctx, url)
+}
+
+// Batch shortens many urls. Isn't retried as it's not idempotent.
+func (c *Client) Batch(ctx context.Context, links []Link) ([]Link, error) {
+	return c.transport.Batch(ctx, links)
+}
+
+// Resolve gets original url by short id. Isn't retried as every call takes click of link,
+// lost response could have used up one-time link or max clicks.
+func (c *Client) Resolve(ctx context.Context, id string) (string, error) {
+	return c.transport.Resolve(ctx, id)
+}
+
+// History gets all links of current user.
+func (c *Client) History(ctx context.Context) ([]Link, error) {
+	var history []Link
+	err := c.do(ctx, func() (err error) {
+		history, err = c.transport.History(ctx)
+		return err
+	})
+	return history, err
+}
+
+// Delete deletes links of current user.
+func (c *Client) Delete(ctx context.Context, ids ...string) error {
+	return c.do(ctx, func() error {
+		return c.transport.Delete(ctx, ids...)
+	})
+}
+
+// Stats gets statistic of the service.
+func (c *Client) Stats(ctx context.Context) (Statistic, error) {
+	var stat Statistic
+	err := c.do(ctx, func() (err error) {
+		stat, err = c.transport.Stats(ctx)
+		return err
+	})
+	return stat, err
+}
+
+// do calls f and retries it with exponential backoff while error is temporary.
+func (c *Client) do(ctx context.Context, f func() error) error {
+	delay := c.retry.BaseDelay
+
+	for attempt := 1; ; attempt++ {
+		err := f()
+		if err == nil || attempt >= c.retry.MaxAttempts || !isTemporary(err) {
+			return err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+
+		delay *= 2
+		if c.retry.MaxDelay > 0 && delay > c.retry.MaxDelay {
+			delay = c.retry.MaxDelay
+		}
+	}
+}
+
+// temporary is implemented by errors after which call can be repeated.
+type temporary interface {
+	Temporary() bool
+}
+
+// isTemporary checks if call can be repeated after error.
+func isTemporary(err error) bool {
+	var t temporary
+	if errors.As(err, &t) {
+		return t.Temporary()
+	}
+	return false
+}
@@ -0,0 +1,194 @@
+package client
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/bbt-t/lets-go-shortener/internal/adapter/storage"
+	"github.com/bbt-t/lets-go-shortener/internal/config"
+	"github.com/bbt-t/lets-go-shortener/internal/controller/handlers"
+	"github.com/bbt-t/lets-go-shortener/internal/usecase"
+	pb "github.com/bbt-t/lets-go-shortener/pkg/grpc"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newTestService gets service over map storage.
+func newTestService(t *testing.T, cfg config.Config) *usecase.ShortenerService {
+	s, err := storage.NewMapStorage(cfg)
+	require.NoError(t, err)
+	return usecase.NewShortenerService(cfg, s)
+}
+
+// newHTTPTestClient gets client over HTTP transport to in-process server.
+func newHTTPTestClient(t *testing.T) *Client {
+	srv := httptest.NewUnstartedServer(nil)
+	cfg := config.GetTestConfig()
+	cfg.BaseURL = "http://" + srv.Listener.Addr().String()
+	cfg.TrustedSubnet = "127.0.0.0/8"
+
+	h := handlers.NewShortenerHandler(cfg, newTestService(t, cfg))
+	router := chi.NewRouter()
+	router.Use(handlers.CookieMiddleware)
+	router.Get("/{id}", handlers.RecoverOriginalURL(h))
+	router.Get("/api/user/urls", handlers.RecoverAllURL(h))
+	router.Delete("/api/user/urls", handlers.DeleteURL(h))
+	router.Post("/api/shorten", handlers.RecoverOriginalURLPost(h))
+	router.Post("/api/shorten/batch", handlers.URLBatch(h))
+	router.Get("/api/internal/stats", handlers.StatisticHandler(h))
+
+	srv.Config.Handler = router
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	return New(NewHTTPTransport(srv.URL, srv.Client()))
+}
+
+// newGRPCTestClient gets client over gRPC transport to in-process server.
+func newGRPCTestClient(t *testing.T) *Client {
+	cfg := config.GetTestConfig()
+	listen := bufconn.Listen(1 << 20)
+
+	grpcServ := grpc.NewServer()
+	pb.RegisterShortenerServer(grpcServ, handlers.NewShortenerServer(cfg, newTestService(t, cfg)))
+	go grpcServ.Serve(listen)
+	t.Cleanup(grpcServ.Stop)
+
+	conn, err := grpc.Dial(
+		"bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listen.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	return New(NewGRPCTransport(conn))
+}
+
+func TestClient(t *testing.T) {
+	clients := map[string]func(t *testing.T) *Client{
+		"http": newHTTPTestClient,
+		"grpc": newGRPCTestClient,
+	}
+
+	for name, newClient := range clients {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			c := newClient(t)
+
+			link, err := c.Shorten(ctx, "https://yandex.ru")
+			require.NoError(t, err)
+			assert.Equal(t, "1", link.ID)
+			assert.NotEmpty(t, c.Identity(), "identity must be issued by service")
+
+			batch, err := c.Batch(ctx, []Link{
+				{CorrelationID: "a", OriginalURL: "https://google.com"},
+				{CorrelationID: "b", OriginalURL: "https://go.dev"},
+			})
+			require.NoError(t, err)
+			require.Len(t, batch, 2)
+			assert.Equal(t, "a", batch[0].CorrelationID)
+			assert.Equal(t, "2", batch[0].ID)
+
+			original, err := c.Resolve(ctx, batch[1].ID)
+			require.NoError(t, err)
+			assert.Equal(t, "https://go.dev", original)
+
+			_, err = c.Resolve(ctx, "404")
+			assert.ErrorIs(t, err, ErrNotFound)
+
+			history, err := c.History(ctx)
+			require.NoError(t, err)
+			assert.Len(t, history, 3)
+
+			require.NoError(t, c.Delete(ctx, link.ID))
+			require.Eventually(t, func() bool {
+				_, err = c.Resolve(ctx, link.ID)
+				return err == ErrDeleted
+			}, time.Second, 10*time.Millisecond)
+
+			stat, err := c.Stats(ctx)
+			require.NoError(t, err)
+			assert.Equal(t, Statistic{Urls: 3, Users: 1}, stat)
+		})
+	}
+}
+
+func TestTransport_Exists(t *testing.T) {
+	clients := map[string]func(t *testing.T) *Client{
+		"http": newHTTPTestClient,
+		"grpc": newGRPCTestClient,
+	}
+
+	for name, newClient := range clients {
+		t.Run(name, func(t *testing.T) {
+			ctx := context.Background()
+			c := newClient(t)
+
+			first, err := c.Shorten(ctx, "https://yandex.ru")
+			require.NoError(t, err)
+
+			second, err := c.Shorten(ctx, "https://yandex.ru")
+			assert.ErrorIs(t, err, ErrExists)
+			assert.Equal(t, first, second)
+		})
+	}
+}
+
+func TestClient_Retry(t *testing.T) {
+	var calls int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			http.Error(w, "try later", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"urls":1,"users":1}`))
+	}))
+	defer srv.Close()
+
+	policy := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+	c := New(NewHTTPTransport(srv.URL, nil), WithRetryPolicy(policy))
+
+	stat, err := c.Stats(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, Statistic{Urls: 1, Users: 1}, stat)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+
+	// not idempotent calls aren't retried.
+	atomic.StoreInt32(&calls, 0)
+	_, err = c.Shorten(context.Background(), "https://yandex.ru")
+	var statusErr *StatusError
+	require.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, http.StatusServiceUnavailable, statusErr.Code)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	atomic.StoreInt32(&calls, 0)
+	_, err = c.Resolve(context.Background(), "1")
+	require.ErrorAs(t, err, &statusErr)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls), "resolve takes click, so it isn't retried")
+}
+
+func TestNextCursor(t *testing.T) {
+	header := http.Header{}
+	assert.Empty(t, nextCursor(header))
+
+	header.Add("Link", `<http://localhost:8080/api/user/urls?limit=2>; rel="first"`)
+	header.Add("Link", `<http://localhost:8080/api/user/urls?cursor=abc&limit=2>; rel="next"`)
+	assert.Equal(t, "abc", nextCursor(header))
+
+	header = http.Header{"Link": {`<http://a/urls>; rel="first", <http://a/urls?cursor=def>; rel="next"`}}
+	assert.Equal(t, "def", nextCursor(header))
+}
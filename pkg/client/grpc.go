@@ -0,0 +1,227 @@
+package client
+
+import (
+	"context"
+	"sync"
+
+	pb "github.com/bbt-t/lets-go-shortener/pkg/grpc"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// userIDMetadata is metadata key with signed user identity.
+const userIDMetadata = "userID"
+
+// linkExistsMetadata is header key which is set if url was shortened before.
+const linkExistsMetadata = "link-exists"
+
+// rpcError wraps gRPC status errors.
+type rpcError struct {
+	err error
+}
+
+// Error gets error text.
+func (e *rpcError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap gets wrapped error.
+func (e *rpcError) Unwrap() error {
+	return e.err
+}
+
+// Temporary checks if call can be repeated.
+func (e *rpcError) Temporary() bool {
+	switch status.Code(e.err) {
+	case codes.Unavailable, codes.ResourceExhausted, codes.Aborted:
+		return true
+	}
+	return false
+}
+
+// GRPCTransport calls service over gRPC.
+type GRPCTransport struct {
+	client   pb.ShortenerClient
+	identity string
+	apiKey   string
+	mu       sync.Mutex
+}
+
+// NewGRPCTransport gets new gRPC transport over connection.
+func NewGRPCTransport(conn grpc.ClientConnInterface) *GRPCTransport {
+	return &GRPCTransport{
+		client: pb.NewShortenerClient(conn),
+	}
+}
+
+// Identity gets signed user identity.
+func (t *GRPCTransport) Identity() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.identity
+}
+
+// SetIdentity sets signed user identity.
+func (t *GRPCTransport) SetIdentity(identity string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.identity = identity
+}
+
+// SetAPIKey sets API key.
+func (t *GRPCTransport) SetAPIKey(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.apiKey = key
+}
+
+// Shorten shortens single url. Service tells that url was shortened before by link-exists header.
+func (t *GRPCTransport) Shorten(ctx context.Context, url string) (Link, error) {
+	var header metadata.MD
+
+	resp, err := t.client.CreateShort(t.outgoing(ctx), &pb.Link{LongUrl: url}, grpc.Header(&header))
+	if err != nil {
+		return Link{}, rpcErr(err)
+	}
+	t.saveIdentity(header)
+
+	link := Link{ID: resp.Id, OriginalURL: url, ShortURL: resp.ShortUrl}
+	if len(header.Get(linkExistsMetadata)) != 0 {
+		return link, ErrExists
+	}
+	return link, nil
+}
+
+// Batch shortens many urls.
+func (t *GRPCTransport) Batch(ctx context.Context, links []Link) ([]Link, error) {
+	req := &pb.Batch{Result: make([]*pb.Link, 0, len(links))}
+	for _, link := range links {
+		req.Result = append(req.Result, &pb.Link{
+			CorrelationId: link.CorrelationID,
+			LongUrl:       link.OriginalURL,
+		})
+	}
+
+	var header metadata.MD
+
+	resp, err := t.client.BatchShort(t.outgoing(ctx), req, grpc.Header(&header))
+	if err != nil {
+		return nil, rpcErr(err)
+	}
+	t.saveIdentity(header)
+
+	result := make([]Link, 0, len(resp.Result))
+	for _, link := range resp.Result {
+		result = append(result, Link{
+			ID:            link.Id,
+			CorrelationID: link.CorrelationId,
+			ShortURL:      link.ShortUrl,
+		})
+	}
+	return result, nil
+}
+
+// Resolve gets original url by short id.
+func (t *GRPCTransport) Resolve(ctx context.Context, id string) (string, error) {
+	resp, err := t.client.GetLong(t.outgoing(ctx), &pb.Link{Id: id})
+	if err != nil {
+		return "", rpcErr(err)
+	}
+	return resp.LongUrl, nil
+}
+
+// History gets all links of current user, pages are read by their cursors.
+func (t *GRPCTransport) History(ctx context.Context) ([]Link, error) {
+	history := make([]Link, 0)
+	filter := &pb.HistoryFilter{Limit: historyPageLimit}
+	for {
+		resp, err := t.client.GetHistory(t.outgoing(ctx), filter)
+		if err != nil {
+			return nil, rpcErr(err)
+		}
+
+		for _, link := range resp.Result {
+			history = append(history, Link{
+				ID:          link.Id,
+				OriginalURL: link.LongUrl,
+				ShortURL:    link.ShortUrl,
+			})
+		}
+		if resp.NextCursor == "" {
+			return history, nil
+		}
+		filter.Cursor = resp.NextCursor
+	}
+}
+
+// Delete deletes links of current user.
+func (t *GRPCTransport) Delete(ctx context.Context, ids ...string) error {
+	ctx = t.outgoing(ctx)
+	for _, id := range ids {
+		if _, err := t.client.Delete(ctx, &pb.Link{Id: id}); err != nil {
+			return rpcErr(err)
+		}
+	}
+	return nil
+}
+
+// Stats gets statistic of the service.
+func (t *GRPCTransport) Stats(ctx context.Context) (Statistic, error) {
+	resp, err := t.client.GetStatistics(t.outgoing(ctx), &emptypb.Empty{})
+	if err != nil {
+		return Statistic{}, rpcErr(err)
+	}
+	return Statistic{Urls: int(resp.Urls), Users: int(resp.Users)}, nil
+}
+
+// outgoing adds API key or identity to outgoing metadata.
+func (t *GRPCTransport) outgoing(ctx context.Context) context.Context {
+	t.mu.Lock()
+	apiKey, identity := t.apiKey, t.identity
+	t.mu.Unlock()
+
+	if apiKey != "" {
+		return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+apiKey)
+	}
+	if identity != "" {
+		return metadata.AppendToOutgoingContext(ctx, userIDMetadata, identity)
+	}
+	return ctx
+}
+
+// saveIdentity saves identity which was issued by the service.
+func (t *GRPCTransport) saveIdentity(header metadata.MD) {
+	if values := header.Get(userIDMetadata); len(values) != 0 && values[0] != "" {
+		t.SetIdentity(values[0])
+	}
+}
+
+// rpcErr converts gRPC status error to typed one.
+func rpcErr(err error) error {
+	st := status.Convert(err)
+	for _, detail := range st.Details() {
+		info, ok := detail.(*errdetails.ErrorInfo)
+		if !ok || info.Domain != pb.ErrorDomain {
+			continue
+		}
+		switch info.Reason {
+		case pb.ReasonNotFound:
+			return ErrNotFound
+		case pb.ReasonDeleted:
+			return ErrDeleted
+		case pb.ReasonBlocked:
+			return ErrBlocked
+		case pb.ReasonExhausted:
+			return ErrExhausted
+		}
+	}
+	if st.Code() == codes.NotFound {
+		return ErrNotFound
+	}
+	return &rpcError{err: err}
+}
@@ -0,0 +1,351 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// userIDCookie is name of cookie with signed user identity.
+const userIDCookie = "userID"
+
+// historyPageLimit is count of links in page of history read by client.
+const historyPageLimit = 1000
+
+// StatusError is unexpected HTTP response.
+type StatusError struct {
+	Code    int
+	Message string
+}
+
+// Error gets error text.
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status %d: %s", e.Code, e.Message)
+}
+
+// Temporary checks if request can be repeated.
+func (e *StatusError) Temporary() bool {
+	return e.Code >= http.StatusInternalServerError || e.Code == http.StatusTooManyRequests
+}
+
+// netError wraps network errors, they are always temporary.
+type netError struct {
+	err error
+}
+
+// Error gets error text.
+func (e *netError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap gets wrapped error.
+func (e *netError) Unwrap() error {
+	return e.err
+}
+
+// Temporary checks if request can be repeated.
+func (e *netError) Temporary() bool {
+	return true
+}
+
+// HTTPTransport calls service over HTTP API.
+type HTTPTransport struct {
+	baseURL  string
+	client   *http.Client
+	identity string
+	apiKey   string
+	mu       sync.Mutex
+}
+
+// NewHTTPTransport gets new HTTP transport. If client is nil, http.DefaultClient settings are used.
+func NewHTTPTransport(baseURL string, client *http.Client) *HTTPTransport {
+	c := &http.Client{}
+	if client != nil {
+		*c = *client
+	}
+	// redirects are answers of Resolve, they mustn't be followed.
+	c.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	return &HTTPTransport{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		client:  c,
+	}
+}
+
+// Identity gets signed user identity.
+func (t *HTTPTransport) Identity() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.identity
+}
+
+// SetIdentity sets signed user identity.
+func (t *HTTPTransport) SetIdentity(identity string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.identity = identity
+}
+
+// SetAPIKey sets API key.
+func (t *HTTPTransport) SetAPIKey(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.apiKey = key
+}
+
+// Shorten shortens single url.
+func (t *HTTPTransport) Shorten(ctx context.Context, url string) (Link, error) {
+	var resp struct {
+		Result string `json:"result"`
+	}
+
+	code, body, err := t.call(ctx, http.MethodPost, "/api/shorten", map[string]string{"url": url})
+	if err != nil {
+		return Link{}, err
+	}
+	if code == http.StatusUnprocessableEntity {
+		return Link{}, ErrBlocked
+	}
+	if code != http.StatusCreated && code != http.StatusConflict {
+		return Link{}, statusError(code, body)
+	}
+	if err = json.Unmarshal(body, &resp); err != nil {
+		return Link{}, err
+	}
+
+	link := Link{ID: idFromShort(resp.Result), OriginalURL: url, ShortURL: resp.Result}
+	if code == http.StatusConflict {
+		return link, ErrExists
+	}
+	return link, nil
+}
+
+// Batch shortens many urls.
+func (t *HTTPTransport) Batch(ctx context.Context, links []Link) ([]Link, error) {
+	var resp []Link
+
+	req := make([]Link, len(links))
+	for i, link := range links {
+		req[i] = Link{CorrelationID: link.CorrelationID, OriginalURL: link.OriginalURL}
+	}
+
+	code, body, err := t.call(ctx, http.MethodPost, "/api/shorten/batch", req)
+	if err != nil {
+		return nil, err
+	}
+	if code == http.StatusUnprocessableEntity {
+		return nil, ErrBlocked
+	}
+	if code != http.StatusCreated {
+		return nil, statusError(code, body)
+	}
+	if err = json.Unmarshal(body, &resp); err != nil {
+		return nil, err
+	}
+
+	for i := range resp {
+		resp[i].ID = idFromShort(resp[i].ShortURL)
+	}
+	return resp, nil
+}
+
+// Resolve gets original url by short id.
+func (t *HTTPTransport) Resolve(ctx context.Context, id string) (string, error) {
+	req, err := t.newRequest(ctx, http.MethodGet, "/"+id, nil)
+	if err != nil {
+		return "", err
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return "", &netError{err: err}
+	}
+	defer resp.Body.Close()
+	t.saveIdentity(resp)
+
+	switch resp.StatusCode {
+	case http.StatusTemporaryRedirect:
+		return resp.Header.Get("Location"), nil
+	case http.StatusNotFound:
+		return "", ErrNotFound
+	case http.StatusGone:
+		body, _ := io.ReadAll(resp.Body)
+		if strings.TrimSpace(string(body)) == ErrExhausted.Error() {
+			return "", ErrExhausted
+		}
+		return "", ErrDeleted
+	case http.StatusForbidden:
+		return "", ErrBlocked
+	default:
+		body, _ := io.ReadAll(resp.Body)
+		return "", statusError(resp.StatusCode, body)
+	}
+}
+
+// History gets all links of current user, pages are read by cursors of Link header.
+func (t *HTTPTransport) History(ctx context.Context) ([]Link, error) {
+	var history []Link
+
+	query := url.Values{"limit": {strconv.Itoa(historyPageLimit)}}
+	for {
+		code, header, body, err := t.send(ctx, http.MethodGet, "/api/user/urls?"+query.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+		if code == http.StatusNoContent {
+			break
+		}
+		if code != http.StatusOK {
+			return nil, statusError(code, body)
+		}
+
+		var page []Link
+		if err = json.Unmarshal(body, &page); err != nil {
+			return nil, err
+		}
+		history = append(history, page...)
+
+		cursor := nextCursor(header)
+		if cursor == "" {
+			break
+		}
+		query.Set("cursor", cursor)
+	}
+
+	for i := range history {
+		history[i].ID = idFromShort(history[i].ShortURL)
+	}
+	return history, nil
+}
+
+// nextCursor gets cursor of next page from Link header.
+func nextCursor(header http.Header) string {
+	for _, value := range header.Values("Link") {
+		for _, link := range strings.Split(value, ",") {
+			target, params, ok := strings.Cut(strings.TrimSpace(link), ";")
+			if !ok || !strings.Contains(params, `rel="next"`) {
+				continue
+			}
+			u, err := url.Parse(strings.Trim(strings.TrimSpace(target), "<>"))
+			if err != nil {
+				return ""
+			}
+			return u.Query().Get("cursor")
+		}
+	}
+	return ""
+}
+
+// Delete deletes links of current user.
+func (t *HTTPTransport) Delete(ctx context.Context, ids ...string) error {
+	code, body, err := t.call(ctx, http.MethodDelete, "/api/user/urls", ids)
+	if err != nil {
+		return err
+	}
+	if code != http.StatusAccepted {
+		return statusError(code, body)
+	}
+	return nil
+}
+
+// Stats gets statistic of the service.
+func (t *HTTPTransport) Stats(ctx context.Context) (Statistic, error) {
+	var stat Statistic
+
+	code, body, err := t.call(ctx, http.MethodGet, "/api/internal/stats", nil)
+	if err != nil {
+		return stat, err
+	}
+	if code != http.StatusOK {
+		return stat, statusError(code, body)
+	}
+	err = json.Unmarshal(body, &stat)
+	return stat, err
+}
+
+// call sends JSON request and reads response.
+func (t *HTTPTransport) call(ctx context.Context, method, path string, data interface{}) (int, []byte, error) {
+	code, _, body, err := t.send(ctx, method, path, data)
+	return code, body, err
+}
+
+// send sends request with JSON body and gets status, headers and body of response.
+func (t *HTTPTransport) send(ctx context.Context, method, path string, data interface{}) (int, http.Header, []byte, error) {
+	var reqBody io.Reader
+
+	if data != nil {
+		b, err := json.Marshal(data)
+		if err != nil {
+			return 0, nil, nil, err
+		}
+		reqBody = bytes.NewReader(b)
+	}
+
+	req, err := t.newRequest(ctx, method, path, reqBody)
+	if err != nil {
+		return 0, nil, nil, err
+	}
+	if data != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return 0, nil, nil, &netError{err: err}
+	}
+	defer resp.Body.Close()
+	t.saveIdentity(resp)
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, nil, &netError{err: err}
+	}
+	return resp.StatusCode, resp.Header, body, nil
+}
+
+// newRequest creates request with API key or identity cookie.
+func (t *HTTPTransport) newRequest(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, method, t.baseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	apiKey, identity := t.apiKey, t.identity
+	t.mu.Unlock()
+
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	} else if identity != "" {
+		req.AddCookie(&http.Cookie{Name: userIDCookie, Value: identity})
+	}
+	return req, nil
+}
+
+// saveIdentity saves identity which was issued by the service.
+func (t *HTTPTransport) saveIdentity(resp *http.Response) {
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == userIDCookie && cookie.Value != "" {
+			t.SetIdentity(cookie.Value)
+		}
+	}
+}
+
+// statusError gets error from unexpected response.
+func statusError(code int, body []byte) error {
+	return &StatusError{Code: code, Message: strings.TrimSpace(string(body))}
+}
+
+// idFromShort gets id from short url.
+func idFromShort(short string) string {
+	return short[strings.LastIndex(short, "/")+1:]
+}
@@ -0,0 +1,19 @@
+package url_shortener
+
+// Error reasons sent in google.rpc.ErrorInfo details of gRPC status.
+const (
+	// ErrorDomain is domain of shortener errors.
+	ErrorDomain = "shortener"
+	// ReasonNotFound means that url isn't in storage.
+	ReasonNotFound = "URL_NOT_FOUND"
+	// ReasonDeleted means that url was deleted by owner.
+	ReasonDeleted = "URL_DELETED"
+	// ReasonExhausted means that url was opened max count of times.
+	ReasonExhausted = "URL_EXHAUSTED"
+	// ReasonBlocked means that domain of url is blocked by policy.
+	ReasonBlocked = "URL_BLOCKED"
+	// ReasonPasswordRequired means that url is protected by password.
+	ReasonPasswordRequired = "URL_PASSWORD_REQUIRED"
+	// ReasonWrongPassword means that password of url is wrong.
+	ReasonWrongPassword = "URL_WRONG_PASSWORD"
+)
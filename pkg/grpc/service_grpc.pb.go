@@ -1,7 +1,7 @@
 // Code generated by protoc-gen-go-grpc. DO NOT EDIT.
 // versions:
 // - protoc-gen-go-grpc v1.3.0
-// - protoc             v3.21.3
+// - protoc             (unknown)
 // source: proto/service.proto
 
 package url_shortener
@@ -20,13 +20,32 @@ import (
 const _ = grpc.SupportPackageIsVersion7
 
 const (
-	Shortener_Ping_FullMethodName          = "/url_shortener.Shortener/Ping"
-	Shortener_CreateShort_FullMethodName   = "/url_shortener.Shortener/CreateShort"
-	Shortener_GetStatistics_FullMethodName = "/url_shortener.Shortener/GetStatistics"
-	Shortener_GetLong_FullMethodName       = "/url_shortener.Shortener/GetLong"
-	Shortener_BatchShort_FullMethodName    = "/url_shortener.Shortener/BatchShort"
-	Shortener_Delete_FullMethodName        = "/url_shortener.Shortener/Delete"
-	Shortener_GetHistory_FullMethodName    = "/url_shortener.Shortener/GetHistory"
+	Shortener_Ping_FullMethodName               = "/url_shortener.Shortener/Ping"
+	Shortener_CreateShort_FullMethodName        = "/url_shortener.Shortener/CreateShort"
+	Shortener_GetStatistics_FullMethodName      = "/url_shortener.Shortener/GetStatistics"
+	Shortener_GetLong_FullMethodName            = "/url_shortener.Shortener/GetLong"
+	Shortener_GetQRCode_FullMethodName          = "/url_shortener.Shortener/GetQRCode"
+	Shortener_BatchShort_FullMethodName         = "/url_shortener.Shortener/BatchShort"
+	Shortener_Delete_FullMethodName             = "/url_shortener.Shortener/Delete"
+	Shortener_UpdateLink_FullMethodName         = "/url_shortener.Shortener/UpdateLink"
+	Shortener_GetHistory_FullMethodName         = "/url_shortener.Shortener/GetHistory"
+	Shortener_SetRedirectRules_FullMethodName   = "/url_shortener.Shortener/SetRedirectRules"
+	Shortener_GetRedirectRules_FullMethodName   = "/url_shortener.Shortener/GetRedirectRules"
+	Shortener_WatchClicks_FullMethodName        = "/url_shortener.Shortener/WatchClicks"
+	Shortener_CreateAPIKey_FullMethodName       = "/url_shortener.Shortener/CreateAPIKey"
+	Shortener_ListAPIKeys_FullMethodName        = "/url_shortener.Shortener/ListAPIKeys"
+	Shortener_RevokeAPIKey_FullMethodName       = "/url_shortener.Shortener/RevokeAPIKey"
+	Shortener_CreateWebhook_FullMethodName      = "/url_shortener.Shortener/CreateWebhook"
+	Shortener_ListWebhooks_FullMethodName       = "/url_shortener.Shortener/ListWebhooks"
+	Shortener_DeleteWebhook_FullMethodName      = "/url_shortener.Shortener/DeleteWebhook"
+	Shortener_ListDeadDeliveries_FullMethodName = "/url_shortener.Shortener/ListDeadDeliveries"
+	Shortener_RetryDelivery_FullMethodName      = "/url_shortener.Shortener/RetryDelivery"
+	Shortener_SearchLinks_FullMethodName        = "/url_shortener.Shortener/SearchLinks"
+	Shortener_GetLinkInfo_FullMethodName        = "/url_shortener.Shortener/GetLinkInfo"
+	Shortener_DisableLink_FullMethodName        = "/url_shortener.Shortener/DisableLink"
+	Shortener_RestoreLink_FullMethodName        = "/url_shortener.Shortener/RestoreLink"
+	Shortener_ReassignLink_FullMethodName       = "/url_shortener.Shortener/ReassignLink"
+	Shortener_HealthReport_FullMethodName       = "/url_shortener.Shortener/HealthReport"
 )
 
 // ShortenerClient is the client API for Shortener service.
@@ -37,9 +56,37 @@ type ShortenerClient interface {
 	CreateShort(ctx context.Context, in *Link, opts ...grpc.CallOption) (*Link, error)
 	GetStatistics(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*Statistic, error)
 	GetLong(ctx context.Context, in *Link, opts ...grpc.CallOption) (*Link, error)
+	// GetQRCode renders QR code of short url, it doesn't take click of link.
+	GetQRCode(ctx context.Context, in *QRRequest, opts ...grpc.CallOption) (*QRCode, error)
 	BatchShort(ctx context.Context, in *Batch, opts ...grpc.CallOption) (*Batch, error)
 	Delete(ctx context.Context, in *Link, opts ...grpc.CallOption) (*emptypb.Empty, error)
-	GetHistory(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*Batch, error)
+	// UpdateLink changes long_url and meta of link of caller, previous url is kept in versions.
+	// Empty long_url and missing meta are unchanged.
+	UpdateLink(ctx context.Context, in *Link, opts ...grpc.CallOption) (*Link, error)
+	GetHistory(ctx context.Context, in *HistoryFilter, opts ...grpc.CallOption) (*Batch, error)
+	// SetRedirectRules replaces redirect rules of link of caller, empty rules remove them.
+	SetRedirectRules(ctx context.Context, in *RedirectRules, opts ...grpc.CallOption) (*RedirectRules, error)
+	GetRedirectRules(ctx context.Context, in *Link, opts ...grpc.CallOption) (*RedirectRules, error)
+	// WatchClicks pushes clicks of link of caller until call is canceled.
+	// Slow watcher which doesn't read clicks in time gets RESOURCE_EXHAUSTED.
+	WatchClicks(ctx context.Context, in *Link, opts ...grpc.CallOption) (Shortener_WatchClicksClient, error)
+	CreateAPIKey(ctx context.Context, in *APIKey, opts ...grpc.CallOption) (*APIKey, error)
+	ListAPIKeys(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*APIKeyList, error)
+	RevokeAPIKey(ctx context.Context, in *APIKey, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	CreateWebhook(ctx context.Context, in *Webhook, opts ...grpc.CallOption) (*Webhook, error)
+	ListWebhooks(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*WebhookList, error)
+	DeleteWebhook(ctx context.Context, in *Webhook, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// ListDeadDeliveries gets dead-letter list of webhooks of caller, newest first.
+	ListDeadDeliveries(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*WebhookDeliveryList, error)
+	// RetryDelivery queues dead delivery again.
+	RetryDelivery(ctx context.Context, in *WebhookDelivery, opts ...grpc.CallOption) (*emptypb.Empty, error)
+	// Admin API, allowed for admin role or trusted subnet.
+	SearchLinks(ctx context.Context, in *LinkFilter, opts ...grpc.CallOption) (*AdminLinkList, error)
+	GetLinkInfo(ctx context.Context, in *AdminLink, opts ...grpc.CallOption) (*AdminLink, error)
+	DisableLink(ctx context.Context, in *AdminLink, opts ...grpc.CallOption) (*AdminLink, error)
+	RestoreLink(ctx context.Context, in *AdminLink, opts ...grpc.CallOption) (*AdminLink, error)
+	ReassignLink(ctx context.Context, in *AdminLink, opts ...grpc.CallOption) (*AdminLink, error)
+	HealthReport(ctx context.Context, in *HealthFilter, opts ...grpc.CallOption) (*AdminLinkList, error)
 }
 
 type shortenerClient struct {
@@ -86,6 +133,15 @@ func (c *shortenerClient) GetLong(ctx context.Context, in *Link, opts ...grpc.Ca
 	return out, nil
 }
 
+func (c *shortenerClient) GetQRCode(ctx context.Context, in *QRRequest, opts ...grpc.CallOption) (*QRCode, error) {
+	out := new(QRCode)
+	err := c.cc.Invoke(ctx, Shortener_GetQRCode_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *shortenerClient) BatchShort(ctx context.Context, in *Batch, opts ...grpc.CallOption) (*Batch, error) {
 	out := new(Batch)
 	err := c.cc.Invoke(ctx, Shortener_BatchShort_FullMethodName, in, out, opts...)
@@ -104,7 +160,16 @@ func (c *shortenerClient) Delete(ctx context.Context, in *Link, opts ...grpc.Cal
 	return out, nil
 }
 
-func (c *shortenerClient) GetHistory(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*Batch, error) {
+func (c *shortenerClient) UpdateLink(ctx context.Context, in *Link, opts ...grpc.CallOption) (*Link, error) {
+	out := new(Link)
+	err := c.cc.Invoke(ctx, Shortener_UpdateLink_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerClient) GetHistory(ctx context.Context, in *HistoryFilter, opts ...grpc.CallOption) (*Batch, error) {
 	out := new(Batch)
 	err := c.cc.Invoke(ctx, Shortener_GetHistory_FullMethodName, in, out, opts...)
 	if err != nil {
@@ -113,6 +178,182 @@ func (c *shortenerClient) GetHistory(ctx context.Context, in *emptypb.Empty, opt
 	return out, nil
 }
 
+func (c *shortenerClient) SetRedirectRules(ctx context.Context, in *RedirectRules, opts ...grpc.CallOption) (*RedirectRules, error) {
+	out := new(RedirectRules)
+	err := c.cc.Invoke(ctx, Shortener_SetRedirectRules_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerClient) GetRedirectRules(ctx context.Context, in *Link, opts ...grpc.CallOption) (*RedirectRules, error) {
+	out := new(RedirectRules)
+	err := c.cc.Invoke(ctx, Shortener_GetRedirectRules_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerClient) WatchClicks(ctx context.Context, in *Link, opts ...grpc.CallOption) (Shortener_WatchClicksClient, error) {
+	stream, err := c.cc.NewStream(ctx, &Shortener_ServiceDesc.Streams[0], Shortener_WatchClicks_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &shortenerWatchClicksClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type Shortener_WatchClicksClient interface {
+	Recv() (*ClickEvent, error)
+	grpc.ClientStream
+}
+
+type shortenerWatchClicksClient struct {
+	grpc.ClientStream
+}
+
+func (x *shortenerWatchClicksClient) Recv() (*ClickEvent, error) {
+	m := new(ClickEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *shortenerClient) CreateAPIKey(ctx context.Context, in *APIKey, opts ...grpc.CallOption) (*APIKey, error) {
+	out := new(APIKey)
+	err := c.cc.Invoke(ctx, Shortener_CreateAPIKey_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerClient) ListAPIKeys(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*APIKeyList, error) {
+	out := new(APIKeyList)
+	err := c.cc.Invoke(ctx, Shortener_ListAPIKeys_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerClient) RevokeAPIKey(ctx context.Context, in *APIKey, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Shortener_RevokeAPIKey_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerClient) CreateWebhook(ctx context.Context, in *Webhook, opts ...grpc.CallOption) (*Webhook, error) {
+	out := new(Webhook)
+	err := c.cc.Invoke(ctx, Shortener_CreateWebhook_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerClient) ListWebhooks(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*WebhookList, error) {
+	out := new(WebhookList)
+	err := c.cc.Invoke(ctx, Shortener_ListWebhooks_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerClient) DeleteWebhook(ctx context.Context, in *Webhook, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Shortener_DeleteWebhook_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerClient) ListDeadDeliveries(ctx context.Context, in *emptypb.Empty, opts ...grpc.CallOption) (*WebhookDeliveryList, error) {
+	out := new(WebhookDeliveryList)
+	err := c.cc.Invoke(ctx, Shortener_ListDeadDeliveries_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerClient) RetryDelivery(ctx context.Context, in *WebhookDelivery, opts ...grpc.CallOption) (*emptypb.Empty, error) {
+	out := new(emptypb.Empty)
+	err := c.cc.Invoke(ctx, Shortener_RetryDelivery_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerClient) SearchLinks(ctx context.Context, in *LinkFilter, opts ...grpc.CallOption) (*AdminLinkList, error) {
+	out := new(AdminLinkList)
+	err := c.cc.Invoke(ctx, Shortener_SearchLinks_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerClient) GetLinkInfo(ctx context.Context, in *AdminLink, opts ...grpc.CallOption) (*AdminLink, error) {
+	out := new(AdminLink)
+	err := c.cc.Invoke(ctx, Shortener_GetLinkInfo_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerClient) DisableLink(ctx context.Context, in *AdminLink, opts ...grpc.CallOption) (*AdminLink, error) {
+	out := new(AdminLink)
+	err := c.cc.Invoke(ctx, Shortener_DisableLink_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerClient) RestoreLink(ctx context.Context, in *AdminLink, opts ...grpc.CallOption) (*AdminLink, error) {
+	out := new(AdminLink)
+	err := c.cc.Invoke(ctx, Shortener_RestoreLink_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerClient) ReassignLink(ctx context.Context, in *AdminLink, opts ...grpc.CallOption) (*AdminLink, error) {
+	out := new(AdminLink)
+	err := c.cc.Invoke(ctx, Shortener_ReassignLink_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *shortenerClient) HealthReport(ctx context.Context, in *HealthFilter, opts ...grpc.CallOption) (*AdminLinkList, error) {
+	out := new(AdminLinkList)
+	err := c.cc.Invoke(ctx, Shortener_HealthReport_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ShortenerServer is the server API for Shortener service.
 // All implementations must embed UnimplementedShortenerServer
 // for forward compatibility
@@ -121,9 +362,37 @@ type ShortenerServer interface {
 	CreateShort(context.Context, *Link) (*Link, error)
 	GetStatistics(context.Context, *emptypb.Empty) (*Statistic, error)
 	GetLong(context.Context, *Link) (*Link, error)
+	// GetQRCode renders QR code of short url, it doesn't take click of link.
+	GetQRCode(context.Context, *QRRequest) (*QRCode, error)
 	BatchShort(context.Context, *Batch) (*Batch, error)
 	Delete(context.Context, *Link) (*emptypb.Empty, error)
-	GetHistory(context.Context, *emptypb.Empty) (*Batch, error)
+	// UpdateLink changes long_url and meta of link of caller, previous url is kept in versions.
+	// Empty long_url and missing meta are unchanged.
+	UpdateLink(context.Context, *Link) (*Link, error)
+	GetHistory(context.Context, *HistoryFilter) (*Batch, error)
+	// SetRedirectRules replaces redirect rules of link of caller, empty rules remove them.
+	SetRedirectRules(context.Context, *RedirectRules) (*RedirectRules, error)
+	GetRedirectRules(context.Context, *Link) (*RedirectRules, error)
+	// WatchClicks pushes clicks of link of caller until call is canceled.
+	// Slow watcher which doesn't read clicks in time gets RESOURCE_EXHAUSTED.
+	WatchClicks(*Link, Shortener_WatchClicksServer) error
+	CreateAPIKey(context.Context, *APIKey) (*APIKey, error)
+	ListAPIKeys(context.Context, *emptypb.Empty) (*APIKeyList, error)
+	RevokeAPIKey(context.Context, *APIKey) (*emptypb.Empty, error)
+	CreateWebhook(context.Context, *Webhook) (*Webhook, error)
+	ListWebhooks(context.Context, *emptypb.Empty) (*WebhookList, error)
+	DeleteWebhook(context.Context, *Webhook) (*emptypb.Empty, error)
+	// ListDeadDeliveries gets dead-letter list of webhooks of caller, newest first.
+	ListDeadDeliveries(context.Context, *emptypb.Empty) (*WebhookDeliveryList, error)
+	// RetryDelivery queues dead delivery again.
+	RetryDelivery(context.Context, *WebhookDelivery) (*emptypb.Empty, error)
+	// Admin API, allowed for admin role or trusted subnet.
+	SearchLinks(context.Context, *LinkFilter) (*AdminLinkList, error)
+	GetLinkInfo(context.Context, *AdminLink) (*AdminLink, error)
+	DisableLink(context.Context, *AdminLink) (*AdminLink, error)
+	RestoreLink(context.Context, *AdminLink) (*AdminLink, error)
+	ReassignLink(context.Context, *AdminLink) (*AdminLink, error)
+	HealthReport(context.Context, *HealthFilter) (*AdminLinkList, error)
 	mustEmbedUnimplementedShortenerServer()
 }
 
@@ -143,15 +412,72 @@ func (UnimplementedShortenerServer) GetStatistics(context.Context, *emptypb.Empt
 func (UnimplementedShortenerServer) GetLong(context.Context, *Link) (*Link, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetLong not implemented")
 }
+func (UnimplementedShortenerServer) GetQRCode(context.Context, *QRRequest) (*QRCode, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetQRCode not implemented")
+}
 func (UnimplementedShortenerServer) BatchShort(context.Context, *Batch) (*Batch, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method BatchShort not implemented")
 }
 func (UnimplementedShortenerServer) Delete(context.Context, *Link) (*emptypb.Empty, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Delete not implemented")
 }
-func (UnimplementedShortenerServer) GetHistory(context.Context, *emptypb.Empty) (*Batch, error) {
+func (UnimplementedShortenerServer) UpdateLink(context.Context, *Link) (*Link, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateLink not implemented")
+}
+func (UnimplementedShortenerServer) GetHistory(context.Context, *HistoryFilter) (*Batch, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetHistory not implemented")
 }
+func (UnimplementedShortenerServer) SetRedirectRules(context.Context, *RedirectRules) (*RedirectRules, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetRedirectRules not implemented")
+}
+func (UnimplementedShortenerServer) GetRedirectRules(context.Context, *Link) (*RedirectRules, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRedirectRules not implemented")
+}
+func (UnimplementedShortenerServer) WatchClicks(*Link, Shortener_WatchClicksServer) error {
+	return status.Errorf(codes.Unimplemented, "method WatchClicks not implemented")
+}
+func (UnimplementedShortenerServer) CreateAPIKey(context.Context, *APIKey) (*APIKey, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateAPIKey not implemented")
+}
+func (UnimplementedShortenerServer) ListAPIKeys(context.Context, *emptypb.Empty) (*APIKeyList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListAPIKeys not implemented")
+}
+func (UnimplementedShortenerServer) RevokeAPIKey(context.Context, *APIKey) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RevokeAPIKey not implemented")
+}
+func (UnimplementedShortenerServer) CreateWebhook(context.Context, *Webhook) (*Webhook, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateWebhook not implemented")
+}
+func (UnimplementedShortenerServer) ListWebhooks(context.Context, *emptypb.Empty) (*WebhookList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListWebhooks not implemented")
+}
+func (UnimplementedShortenerServer) DeleteWebhook(context.Context, *Webhook) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteWebhook not implemented")
+}
+func (UnimplementedShortenerServer) ListDeadDeliveries(context.Context, *emptypb.Empty) (*WebhookDeliveryList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListDeadDeliveries not implemented")
+}
+func (UnimplementedShortenerServer) RetryDelivery(context.Context, *WebhookDelivery) (*emptypb.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RetryDelivery not implemented")
+}
+func (UnimplementedShortenerServer) SearchLinks(context.Context, *LinkFilter) (*AdminLinkList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SearchLinks not implemented")
+}
+func (UnimplementedShortenerServer) GetLinkInfo(context.Context, *AdminLink) (*AdminLink, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetLinkInfo not implemented")
+}
+func (UnimplementedShortenerServer) DisableLink(context.Context, *AdminLink) (*AdminLink, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DisableLink not implemented")
+}
+func (UnimplementedShortenerServer) RestoreLink(context.Context, *AdminLink) (*AdminLink, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RestoreLink not implemented")
+}
+func (UnimplementedShortenerServer) ReassignLink(context.Context, *AdminLink) (*AdminLink, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReassignLink not implemented")
+}
+func (UnimplementedShortenerServer) HealthReport(context.Context, *HealthFilter) (*AdminLinkList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method HealthReport not implemented")
+}
 func (UnimplementedShortenerServer) mustEmbedUnimplementedShortenerServer() {}
 
 // UnsafeShortenerServer may be embedded to opt out of forward compatibility for this service.
@@ -237,6 +563,24 @@ func _Shortener_GetLong_Handler(srv interface{}, ctx context.Context, dec func(i
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Shortener_GetQRCode_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QRRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).GetQRCode(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_GetQRCode_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).GetQRCode(ctx, req.(*QRRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Shortener_BatchShort_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(Batch)
 	if err := dec(in); err != nil {
@@ -273,8 +617,26 @@ func _Shortener_Delete_Handler(srv interface{}, ctx context.Context, dec func(in
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Shortener_UpdateLink_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Link)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).UpdateLink(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_UpdateLink_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).UpdateLink(ctx, req.(*Link))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _Shortener_GetHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
-	in := new(emptypb.Empty)
+	in := new(HistoryFilter)
 	if err := dec(in); err != nil {
 		return nil, err
 	}
@@ -286,7 +648,316 @@ func _Shortener_GetHistory_Handler(srv interface{}, ctx context.Context, dec fun
 		FullMethod: Shortener_GetHistory_FullMethodName,
 	}
 	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
-		return srv.(ShortenerServer).GetHistory(ctx, req.(*emptypb.Empty))
+		return srv.(ShortenerServer).GetHistory(ctx, req.(*HistoryFilter))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shortener_SetRedirectRules_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RedirectRules)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).SetRedirectRules(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_SetRedirectRules_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).SetRedirectRules(ctx, req.(*RedirectRules))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shortener_GetRedirectRules_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Link)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).GetRedirectRules(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_GetRedirectRules_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).GetRedirectRules(ctx, req.(*Link))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shortener_WatchClicks_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(Link)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ShortenerServer).WatchClicks(m, &shortenerWatchClicksServer{stream})
+}
+
+type Shortener_WatchClicksServer interface {
+	Send(*ClickEvent) error
+	grpc.ServerStream
+}
+
+type shortenerWatchClicksServer struct {
+	grpc.ServerStream
+}
+
+func (x *shortenerWatchClicksServer) Send(m *ClickEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _Shortener_CreateAPIKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(APIKey)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).CreateAPIKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_CreateAPIKey_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).CreateAPIKey(ctx, req.(*APIKey))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shortener_ListAPIKeys_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).ListAPIKeys(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_ListAPIKeys_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).ListAPIKeys(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shortener_RevokeAPIKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(APIKey)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).RevokeAPIKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_RevokeAPIKey_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).RevokeAPIKey(ctx, req.(*APIKey))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shortener_CreateWebhook_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Webhook)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).CreateWebhook(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_CreateWebhook_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).CreateWebhook(ctx, req.(*Webhook))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shortener_ListWebhooks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).ListWebhooks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_ListWebhooks_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).ListWebhooks(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shortener_DeleteWebhook_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Webhook)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).DeleteWebhook(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_DeleteWebhook_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).DeleteWebhook(ctx, req.(*Webhook))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shortener_ListDeadDeliveries_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(emptypb.Empty)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).ListDeadDeliveries(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_ListDeadDeliveries_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).ListDeadDeliveries(ctx, req.(*emptypb.Empty))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shortener_RetryDelivery_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WebhookDelivery)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).RetryDelivery(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_RetryDelivery_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).RetryDelivery(ctx, req.(*WebhookDelivery))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shortener_SearchLinks_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(LinkFilter)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).SearchLinks(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_SearchLinks_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).SearchLinks(ctx, req.(*LinkFilter))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shortener_GetLinkInfo_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AdminLink)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).GetLinkInfo(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_GetLinkInfo_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).GetLinkInfo(ctx, req.(*AdminLink))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shortener_DisableLink_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AdminLink)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).DisableLink(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_DisableLink_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).DisableLink(ctx, req.(*AdminLink))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shortener_RestoreLink_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AdminLink)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).RestoreLink(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_RestoreLink_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).RestoreLink(ctx, req.(*AdminLink))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shortener_ReassignLink_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AdminLink)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).ReassignLink(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_ReassignLink_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).ReassignLink(ctx, req.(*AdminLink))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Shortener_HealthReport_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HealthFilter)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ShortenerServer).HealthReport(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Shortener_HealthReport_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ShortenerServer).HealthReport(ctx, req.(*HealthFilter))
 	}
 	return interceptor(ctx, in, info, handler)
 }
@@ -314,6 +985,10 @@ var Shortener_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetLong",
 			Handler:    _Shortener_GetLong_Handler,
 		},
+		{
+			MethodName: "GetQRCode",
+			Handler:    _Shortener_GetQRCode_Handler,
+		},
 		{
 			MethodName: "BatchShort",
 			Handler:    _Shortener_BatchShort_Handler,
@@ -322,11 +997,85 @@ var Shortener_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "Delete",
 			Handler:    _Shortener_Delete_Handler,
 		},
+		{
+			MethodName: "UpdateLink",
+			Handler:    _Shortener_UpdateLink_Handler,
+		},
 		{
 			MethodName: "GetHistory",
 			Handler:    _Shortener_GetHistory_Handler,
 		},
+		{
+			MethodName: "SetRedirectRules",
+			Handler:    _Shortener_SetRedirectRules_Handler,
+		},
+		{
+			MethodName: "GetRedirectRules",
+			Handler:    _Shortener_GetRedirectRules_Handler,
+		},
+		{
+			MethodName: "CreateAPIKey",
+			Handler:    _Shortener_CreateAPIKey_Handler,
+		},
+		{
+			MethodName: "ListAPIKeys",
+			Handler:    _Shortener_ListAPIKeys_Handler,
+		},
+		{
+			MethodName: "RevokeAPIKey",
+			Handler:    _Shortener_RevokeAPIKey_Handler,
+		},
+		{
+			MethodName: "CreateWebhook",
+			Handler:    _Shortener_CreateWebhook_Handler,
+		},
+		{
+			MethodName: "ListWebhooks",
+			Handler:    _Shortener_ListWebhooks_Handler,
+		},
+		{
+			MethodName: "DeleteWebhook",
+			Handler:    _Shortener_DeleteWebhook_Handler,
+		},
+		{
+			MethodName: "ListDeadDeliveries",
+			Handler:    _Shortener_ListDeadDeliveries_Handler,
+		},
+		{
+			MethodName: "RetryDelivery",
+			Handler:    _Shortener_RetryDelivery_Handler,
+		},
+		{
+			MethodName: "SearchLinks",
+			Handler:    _Shortener_SearchLinks_Handler,
+		},
+		{
+			MethodName: "GetLinkInfo",
+			Handler:    _Shortener_GetLinkInfo_Handler,
+		},
+		{
+			MethodName: "DisableLink",
+			Handler:    _Shortener_DisableLink_Handler,
+		},
+		{
+			MethodName: "RestoreLink",
+			Handler:    _Shortener_RestoreLink_Handler,
+		},
+		{
+			MethodName: "ReassignLink",
+			Handler:    _Shortener_ReassignLink_Handler,
+		},
+		{
+			MethodName: "HealthReport",
+			Handler:    _Shortener_HealthReport_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchClicks",
+			Handler:       _Shortener_WatchClicks_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "proto/service.proto",
 }